@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"embed"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -16,14 +17,17 @@ import (
 	"os/signal"
 	"reflect"
 	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/duso-org/duso/pkg/cli"
 	"github.com/duso-org/duso/pkg/core"
 	"github.com/duso-org/duso/pkg/lsp"
 	dusoruntime "github.com/duso-org/duso/pkg/runtime"
+	"github.com/duso-org/duso/pkg/runtime/color"
 	"github.com/duso-org/duso/pkg/script"
 )
 
@@ -79,6 +83,14 @@ func setupInterpreter(scriptPath string) (*script.Interpreter, error) {
 		return nil, fmt.Errorf("could not register CLI functions: %w", err)
 	}
 
+	// -trace logs execution to stderr; -profile times calls for printProfile
+	if cli.GetSysFlag("-trace", false) {
+		interp.SetTrace(os.Stderr)
+	}
+	if cli.GetSysFlag("-profile", false) {
+		interp.SetProfile(script.NewProfile())
+	}
+
 	// Set global interpreter for builtins that need it (spawn, run)
 	dusoruntime.SetInterpreter(interp)
 
@@ -117,9 +129,36 @@ func runScript(scriptPath string, source []byte) (string, error) {
 
 	// Execute the script with panic recovery
 	defer core.RecoverPanic("script_execution")
+	defer printProfile(interp)
 	return interp.Execute(string(source))
 }
 
+// printProfile prints the -profile summary table to stderr, if profiling is on
+func printProfile(interp *script.Interpreter) {
+	profile := interp.GetEvaluator().Profile()
+	if profile == nil {
+		return
+	}
+	entries := profile.Entries()
+	if len(entries) == 0 {
+		return
+	}
+
+	nameWidth := len("function")
+	for _, entry := range entries {
+		nameWidth = max(nameWidth, len(entry.Name))
+	}
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+	}
+
+	fmt.Fprintf(os.Stderr, "\nProfile (times in ms, most total time first):\n")
+	fmt.Fprintf(os.Stderr, "  %-*s %10s %12s %12s\n", nameWidth, "function", "calls", "total", "self")
+	for _, entry := range entries {
+		fmt.Fprintf(os.Stderr, "  %-*s %10d %12s %12s\n", nameWidth, entry.Name, entry.Calls, ms(entry.Total), ms(entry.Self))
+	}
+}
+
 // truncateVersion truncates long version strings (between git tags) to semver + build number,
 // and appends * if truncation occurred
 func truncateVersion(version string) string {
@@ -158,18 +197,18 @@ func printLogo() {
 	}
 
 	// ANSI codes
-	reset := "\033[0m"
-	cyan := "\033[38;2;143;203;225m"   // #8FCBE1
-	blue := "\033[38;2;109;165;196m"   // #6DA5C4 darker blue
-	headBg := "\033[48;2;143;203;225m" // #8FCBE1 as background
-	shade := headBg + blue             // blue half-blocks on the head color
-	lid := headBg + "\033[30m"         // black eye lines on the head color
-	mouth := "\033[30;107m"            // black on the white muzzle
+	reset := color.Reset
+	cyan := color.RGB(143, 203, 225)           // #8FCBE1
+	blue := color.RGB(109, 165, 196)           // #6DA5C4 darker blue
+	headBg := color.BgRGB(143, 203, 225)       // #8FCBE1 as background
+	shade := headBg + blue                     // blue half-blocks on the head color
+	lid := headBg + color.Black                // black eye lines on the head color
+	mouth := color.Black + color.BgBrightWhite // black on the white muzzle
 	unshade := reset + cyan
-	white := "\033[97m"          // bright white
-	nose := "\033[38;5;205;107m" // 256-color hot pink on white background
-	bold := "\033[1;96m"
-	gray := "\033[90m"
+	white := color.BrightWhite
+	nose := "\033[38;5;205m" + color.BgBrightWhite // 256-color hot pink on white background
+	bold := color.Bold + color.BrightCyan
+	gray := color.Gray
 
 	// Cat face on the right: cyan head, white muzzle,
 	// pink nose as a half block over a white background
@@ -225,7 +264,7 @@ func isExpressionStatement(program *script.Program) script.Node {
 	// Check if the statement is an expression node type that should be auto-printed
 	switch stmt.(type) {
 	case *script.BinaryExpr, *script.TernaryExpr, *script.UnaryExpr,
-		*script.CallExpr, *script.IndexExpr, *script.PropertyAccess,
+		*script.CallExpr, *script.IndexExpr, *script.PropertyAccess, *script.OptionalChain,
 		*script.Identifier, *script.NumberLiteral, *script.StringLiteral,
 		*script.BoolLiteral, *script.ArrayLiteral, *script.ObjectLiteral,
 		*script.TemplateLiteral, *script.FunctionExpr:
@@ -349,8 +388,8 @@ func handleDebugEvent(interp *script.Interpreter, event *script.DebugEvent, noCo
 
 	// Print invocation stack (how we got here)
 	if !noColor {
-		brightRed := "\033[91m"
-		reset := "\033[0m"
+		brightRed := color.BrightRed
+		reset := color.Reset
 		if loc != "" {
 			fmt.Fprintf(os.Stderr, "\n%s[Debug] Error in child script at %s%s\n", brightRed, loc, reset)
 		} else {
@@ -415,8 +454,8 @@ func debugREPL(interp *script.Interpreter, bpErr *script.BreakpointError, noColo
 
 	// Add bright red color if colors are enabled
 	if !noColor {
-		brightRed := "\033[91m"
-		reset := "\033[0m"
+		brightRed := color.BrightRed
+		reset := color.Reset
 		if loc != "" {
 			fmt.Fprintf(os.Stderr, "\n%s[Debug] Breakpoint hit at %s%s\n", brightRed, loc, reset)
 		} else {
@@ -436,21 +475,7 @@ func debugREPL(interp *script.Interpreter, bpErr *script.BreakpointError, noColo
 	}
 
 	// Print call stack from the breakpoint error
-	if len(bpErr.CallStack) > 0 {
-		fmt.Fprintf(os.Stderr, "\nCall stack:\n")
-		for i := len(bpErr.CallStack) - 1; i >= 0; i-- {
-			frame := bpErr.CallStack[i]
-			fmt.Fprintf(os.Stderr, "  at %s", frame.FunctionName)
-			if frame.FilePath != "" {
-				fmt.Fprintf(os.Stderr, " (%s:%d", frame.FilePath, frame.Position.Line)
-				if frame.Position.Column > 0 {
-					fmt.Fprintf(os.Stderr, ":%d", frame.Position.Column)
-				}
-				fmt.Fprintf(os.Stderr, ")")
-			}
-			fmt.Fprintf(os.Stderr, "\n")
-		}
-	}
+	printCallStack(bpErr.CallStack)
 
 	// If stdin is disabled, print warning and skip REPL
 	sysDs := dusoruntime.GetDatastore("sys", nil)
@@ -466,7 +491,61 @@ func debugREPL(interp *script.Interpreter, bpErr *script.BreakpointError, noColo
 	return runREPLLoop(interp, "debug> ", true, true, bpErr.Env)
 }
 
-// showSourceContext displays the source code around a breakpoint
+// maxPrintedFrames caps the frames printCallStack lists, as DusoError.Error()
+// does; runaway recursion can leave thousands of identical frames
+const maxPrintedFrames = 40
+
+// printCallStack prints a call stack, most recent call first
+func printCallStack(stack []script.CallFrame) {
+	if len(stack) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nCall stack:\n")
+	for i := len(stack) - 1; i >= 0; i-- {
+		if len(stack) > maxPrintedFrames && i == len(stack)-maxPrintedFrames/2 {
+			skipped := len(stack) - maxPrintedFrames
+			fmt.Fprintf(os.Stderr, "  ... %d more frames ...\n", skipped)
+			i -= skipped - 1
+			continue
+		}
+		frame := stack[i]
+		fmt.Fprintf(os.Stderr, "  at %s", frame.FunctionName)
+		if frame.FilePath != "" {
+			fmt.Fprintf(os.Stderr, " (%s:%d", frame.FilePath, frame.Position.Line)
+			if frame.Position.Column > 0 {
+				fmt.Fprintf(os.Stderr, ":%d", frame.Position.Column)
+			}
+			fmt.Fprintf(os.Stderr, ")")
+		}
+		fmt.Fprintf(os.Stderr, "\n")
+	}
+}
+
+// printScriptError reports an error that ended the script. Errors with a
+// source position get the code around it and the call stack, like a
+// breakpoint; others print as a single line.
+func printScriptError(err error, noColor bool) {
+	var dusoErr *script.DusoError
+	if !errors.As(err, &dusoErr) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	// The message without the call stack DusoError.Error() appends
+	msg := (&script.DusoError{Message: dusoErr.Message, FilePath: dusoErr.FilePath, Position: dusoErr.Position}).Error()
+	if !noColor {
+		fmt.Fprintf(os.Stderr, "%sError: %s%s\n", color.BrightRed, msg, color.Reset)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+	}
+
+	if dusoErr.FilePath != "" && dusoErr.Position.Line > 0 {
+		showSourceContext(dusoErr.FilePath, dusoErr.Position.Line, dusoErr.Position.Column, noColor)
+	}
+	printCallStack(dusoErr.CallStack)
+}
+
+// showSourceContext displays the source code around a breakpoint or error
 func showSourceContext(filePath string, line int, col int, noColor bool) {
 	// Read the file
 	source, err := os.ReadFile(filePath)
@@ -500,15 +579,15 @@ func showSourceContext(filePath string, line int, col int, noColor bool) {
 			lineContent = lines[i-1]
 		}
 
-		// Blank line before the breakpoint line
+		// Blank line before the highlighted line
 		if i == line {
 			fmt.Fprintf(os.Stderr, "\n")
 		}
 
-		// Highlight the line with the breakpoint
+		// Highlight the line with the breakpoint or error
 		if i == line {
 			if !noColor {
-				fmt.Fprintf(os.Stderr, "\033[93m%*d | %s\033[0m\n", lineNumWidth, i, lineContent)
+				fmt.Fprintf(os.Stderr, "%s%*d | %s%s\n", color.BrightYellow, lineNumWidth, i, lineContent, color.Reset)
 			} else {
 				fmt.Fprintf(os.Stderr, "%*d | %s\n", lineNumWidth, i, lineContent)
 			}
@@ -518,7 +597,7 @@ func showSourceContext(filePath string, line int, col int, noColor bool) {
 				// Account for line number width + " | " separator
 				marker := strings.Repeat(" ", lineNumWidth+3+col-1) + "^"
 				if !noColor {
-					fmt.Fprintf(os.Stderr, "\033[91m%s\033[0m\n", marker) // Bright red for caret
+					fmt.Fprintf(os.Stderr, "%s%s%s\n", color.BrightRed, marker, color.Reset)
 				} else {
 					fmt.Fprintf(os.Stderr, "%s\n", marker)
 				}
@@ -531,32 +610,37 @@ func showSourceContext(filePath string, line int, col int, noColor bool) {
 	fmt.Fprintf(os.Stderr, "\n")
 }
 
+// exitStatus reports whether err is the script calling exit(), and the
+// process status to end with: exit(n) with a number n, otherwise 0
+func exitStatus(err error) (int, bool) {
+	var exit *script.ExitExecution
+	if !errors.As(err, &exit) {
+		return 0, false
+	}
+	if len(exit.Values) > 0 {
+		if n, ok := exit.Values[0].(float64); ok {
+			return int(n), true
+		}
+	}
+	return 0, true
+}
+
 // parseConfigString parses a config string like "key=value, key=value" into a map
 func parseConfigString(configStr string) (map[string]any, error) {
 	if configStr == "" {
 		return nil, nil
 	}
 
-	// Create temp interpreter to parse the config as Duso code
-	interp := script.NewInterpreter()
-
-	// Execute assignment to parse the object
-	_, err := interp.Execute("__cfg__ = {" + configStr + "}")
+	// Read the pairs as an object literal; nothing in them is executed
+	objMap, err := script.ParseObjectLiteral(configStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid -config format: %v", err)
 	}
 
-	// Get the value from environment
-	cfgVal, err := interp.GetEvaluator().GetEnv().Get("__cfg__")
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert to map[string]any by extracting .Data from each Value
-	objMap := cfgVal.AsObject()
+	// Convert to map[string]any for the sys datastore
 	result := make(map[string]any)
 	for k, v := range objMap {
-		result[k] = v.Data
+		result[k] = script.ValueToInterface(v)
 	}
 
 	return result, nil
@@ -660,30 +744,22 @@ func initProject(projectName string) error {
 
 	// Show templates and let user choose
 	fmt.Println("Available templates:")
-	for i, tmpl := range templates {
-		fmt.Printf("\n  %d. %s", i+1, tmpl)
+	choice, err := cli.PromptSelect(os.Stdout, "Select a template", templates)
+	if err != nil {
+		return err
 	}
-	fmt.Print("\n\nSelect a template (1-" + fmt.Sprintf("%d", len(templates)) + "): ")
-
-	// Read user input
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-
-	// Parse selection
-	choice := 0
-	fmt.Sscanf(input, "%d", &choice)
-
-	if choice < 1 || choice > len(templates) {
-		return fmt.Errorf("invalid selection")
+	if choice < 0 {
+		return fmt.Errorf("no template selected")
 	}
-
-	selectedTemplate := templates[choice-1]
+	selectedTemplate := templates[choice]
 
 	// Confirm creation
-	fmt.Printf("\nCreate new project at:\n%s? [y/N]: ", projectPath)
-	input, _ = reader.ReadString('\n')
-	if strings.ToLower(strings.TrimSpace(input)) != "y" {
+	fmt.Printf("\nCreate new project at:\n%s?", projectPath)
+	create, err := cli.PromptConfirm(os.Stdout, "", false)
+	if err != nil {
+		return err
+	}
+	if !create {
 		fmt.Println("Cancelled.")
 		return nil
 	}
@@ -1152,6 +1228,9 @@ func main() {
 	_ = flag.Bool("no-files", false, "Restrict to /STORE/ and /EMBED/ only (disable filesystem access)")
 	ignoreWarnings := flag.Bool("ignore-warnings", false, "Suppress warnings in lint")
 	tcpPort := flag.String("tcp", "", "TCP port for LSP server")
+	maxDepth := flag.Int("max-depth", 0, fmt.Sprintf("Maximum nested function call depth (default %d)", script.DefaultMaxCallDepth))
+	_ = flag.Bool("trace", false, "Log each line executed and each function call and return to stderr")
+	_ = flag.Bool("profile", false, "Print call counts and time spent per function to stderr on exit")
 
 	// Allow unknown flags to pass through to scripts
 	flag.CommandLine.Init(flag.CommandLine.Name(), flag.ContinueOnError)
@@ -1166,6 +1245,15 @@ func main() {
 	// 	go func() { _ = http.ListenAndServe(addr, nil) }()
 	// }
 
+	// Raise (or lower) the recursion limit; deeper limits need a Go stack to
+	// match or runaway recursion still ends in a fatal stack overflow
+	if *maxDepth > 0 {
+		script.SetMaxCallDepth(*maxDepth)
+		if *maxDepth > script.DefaultMaxCallDepth {
+			debug.SetMaxStack(*maxDepth * 20 << 10)
+		}
+	}
+
 	// Store all command-line flags in the sys datastore for access by scripts
 	storeAllCliFlags()
 
@@ -1179,6 +1267,14 @@ func main() {
 	// Store version in sys datastore for access by scripts
 	sysDs.Set("version", Version)
 
+	// Store whether each standard stream is a terminal, so scripts can skip
+	// colors and redrawn output when piped
+	sysDs.Set("tty", map[string]any{
+		"stdin":  cli.IsTerminal(os.Stdin),
+		"stdout": cli.IsTerminal(os.Stdout),
+		"stderr": cli.IsTerminal(os.Stderr),
+	})
+
 	// Register all builtin functions in the global registry
 	dusoruntime.RegisterBuiltins()
 
@@ -1590,6 +1686,9 @@ func main() {
 
 				// Execute the script
 				_, err = interp.Execute(string(source))
+				if status, ok := exitStatus(err); ok {
+					os.Exit(status)
+				}
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 					os.Exit(1)
@@ -1633,6 +1732,13 @@ func main() {
 
 		scriptPath := args[0]
 
+		// Everything after the script name is the script's own operands
+		scriptArgs := make([]any, len(args)-1)
+		for i, arg := range args[1:] {
+			scriptArgs[i] = arg
+		}
+		sysDs.Set("script_args", scriptArgs)
+
 		// Read the script file (try local first, then embedded)
 		source, err := os.ReadFile(scriptPath)
 		if err != nil {
@@ -1684,11 +1790,19 @@ func main() {
 		go func() {
 			defer core.RecoverPanic("signal_handler")
 			<-sigChan
+			// A running http_server() shuts down gracefully on the same
+			// signal and start() returns; a second signal forces the exit
+			if dusoruntime.RunningHTTPServers() > 0 {
+				<-sigChan
+			}
 			dusoruntime.SignalInterrupt()
+			dusoruntime.FlushDatastores()
+			printProfile(interp)
 			os.Exit(1)
 		}()
 
 		// Execute the script
+		noColor := cli.GetSysFlag("-no-color", false)
 		debugVal, _ := sysDs.Get("-debug")
 		debug := false
 		if b, ok := debugVal.(bool); ok {
@@ -1699,14 +1813,14 @@ func main() {
 			lexer := script.NewLexer(string(source))
 			tokens, err := lexer.Tokenize()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				printScriptError(err, noColor)
 				os.Exit(1)
 			}
 
 			parser := script.NewParserWithFile(tokens, scriptPath)
 			program, parseErr := parser.Parse()
 			if parseErr != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", parseErr)
+				printScriptError(parseErr, noColor)
 				os.Exit(1)
 			}
 
@@ -1727,18 +1841,30 @@ func main() {
 			defer script.ClearRequestContext(gid)
 			defer core.RecoverPanic("script_execution_debug")
 			result := script.ExecuteScript(program, interp, frame, ctx, context.Background())
+			dusoruntime.FlushDatastores()
+			printProfile(interp)
 			if result.Error != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", result.Error)
+				printScriptError(result.Error, noColor)
 				os.Exit(1)
 			}
+			// ExecuteScript turns exit() into the result value, so exit(n)
+			// sets the status here as exitStatus does for the normal path
+			if status, ok := result.Value.(float64); ok {
+				os.Exit(int(status))
+			}
 
 		} else {
 			// Normal mode: fast path execution
 			defer core.RecoverPanic("script_execution")
 			var err error
 			_, err = interp.Execute(string(source))
+			dusoruntime.FlushDatastores()
+			printProfile(interp)
+			if status, ok := exitStatus(err); ok {
+				os.Exit(status)
+			}
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				printScriptError(err, noColor)
 				os.Exit(1)
 			}
 		}
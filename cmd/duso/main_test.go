@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// TestMain lets the test binary stand in for the duso command: runDuso
+// re-runs it with DUSO_TEST_MAIN set, and main() then sees the arguments
+// exactly as the real binary would.
+func TestMain(m *testing.M) {
+	if os.Getenv("DUSO_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// dusoResult is what a run of the duso command produced
+type dusoResult struct {
+	stdout, stderr string
+	code           int
+}
+
+// runDuso writes source to a script file and runs duso on it with args
+// after the script name. stdin is /dev/null unless given, and stdout and
+// stderr are pipes. Modules are resolved from the repository's stdlib
+// rather than the embedded copy, which is only refreshed by the build.
+func runDuso(t *testing.T, source string, env []string, stdin *os.File, args ...string) dusoResult {
+	t.Helper()
+	return runDusoWithFlags(t, nil, source, env, stdin, args...)
+}
+
+// runDusoWithFlags is runDuso with duso arguments placed first, before
+// -no-color and the script name (a subcommand such as -debug has to be first)
+func runDusoWithFlags(t *testing.T, flags []string, source string, env []string, stdin *os.File, args ...string) dusoResult {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.du")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdArgs := append(append(flags, "-no-color"), path)
+	cmd := exec.Command(os.Args[0], append(cmdArgs, args...)...)
+	cmd.Env = append(os.Environ(), append([]string{"DUSO_TEST_MAIN=1", "DUSO_LIB=" + root}, env...)...)
+	if stdin == nil {
+		null, err := os.Open(os.DevNull)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer null.Close()
+		stdin = null
+	}
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	res := dusoResult{}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatal(err)
+		}
+		res.code = exitErr.ExitCode()
+	}
+	res.stdout, res.stderr = stdout.String(), stderr.String()
+	return res
+}
+
+// TestSysTTY checks sys("tty") when no stream is a terminal: stdin is
+// /dev/null, a character device, and stdout and stderr are pipes
+func TestSysTTY(t *testing.T) {
+	res := runDuso(t, `tty = sys("tty")
+print(tty.stdin, tty.stdout, tty.stderr)`, nil, nil)
+	if res.code != 0 || strings.TrimSpace(res.stdout) != "false false false" {
+		t.Errorf("sys(\"tty\") = %q (exit %d, stderr %q)", res.stdout, res.code, res.stderr)
+	}
+}
+
+// TestProgressNotTerminal checks the progress module's fallback when stderr
+// isn't a terminal: a line every 10%, no redraw escapes, and a final line
+// from done()
+func TestProgressNotTerminal(t *testing.T) {
+	res := runDuso(t, `progress = require("progress")
+bar = progress(4, "Copying")
+for i = 1, 4 do bar.tick() end
+bar.done()
+spin = progress(nil, "Scanning")
+spin.tick(3)
+print(spin.done())`, nil, nil)
+	if res.code != 0 {
+		t.Fatalf("exit %d: %s", res.code, res.stderr)
+	}
+	want := "Copying 0% (0/4)\nCopying 25% (1/4)\nCopying 50% (2/4)\nCopying 75% (3/4)\nCopying 100% (4/4)\nCopying 100% (4/4) done\nScanning 3 done\n"
+	if res.stderr != want {
+		t.Errorf("stderr = %q, want %q", res.stderr, want)
+	}
+	if strings.TrimSpace(res.stdout) != "3" {
+		t.Errorf("done() = %q, want 3", res.stdout)
+	}
+}
+
+// TestProgressNotTerminalDoneShort checks that done() prints the final
+// count when the bar stops short of its total, even within the 10% step the
+// last line was printed for
+func TestProgressNotTerminalDoneShort(t *testing.T) {
+	res := runDuso(t, `progress = require("progress")
+bar = progress(3)
+bar.tick()
+bar.tick()
+bar.done()
+big = progress(100, "Rows")
+big.tick(51)
+big.tick(3)
+big.done()`, nil, nil)
+	if res.code != 0 {
+		t.Fatalf("exit %d: %s", res.code, res.stderr)
+	}
+	want := "0% (0/3)\n33% (1/3)\n66% (2/3)\n66% (2/3) done\nRows 0% (0/100)\nRows 51% (51/100)\nRows 54% (54/100) done\n"
+	if res.stderr != want {
+		t.Errorf("stderr = %q, want %q", res.stderr, want)
+	}
+}
+
+// TestLogLevelEnv checks that DUSO_LOG_LEVEL sets the log module's starting
+// threshold, and that an unknown level leaves the default of info
+func TestLogLevelEnv(t *testing.T) {
+	src := `log = require("log")
+log.debug("debug line")
+log.info("info line")
+log.warn("warn line", {n = 1, who = "a b"})
+log.error("error line")
+print(log.level())`
+
+	tests := []struct {
+		level     string
+		shown     []string
+		threshold string
+	}{
+		{"warn", []string{"WARN  warn line n=1 who=\"a b\"", "ERROR error line"}, "warn"},
+		{" DEBUG ", []string{"DEBUG debug line", "INFO  info line", "WARN  warn line", "ERROR error line"}, "debug"},
+		{"off", nil, "off"},
+		{"loud", []string{"INFO  info line", "WARN  warn line", "ERROR error line"}, "info"},
+	}
+	for _, tt := range tests {
+		res := runDuso(t, src, []string{"DUSO_LOG_LEVEL=" + tt.level}, nil)
+		if res.code != 0 {
+			t.Fatalf("%q: exit %d: %s", tt.level, res.code, res.stderr)
+		}
+		lines := strings.Split(strings.TrimSpace(res.stderr), "\n")
+		if res.stderr == "" {
+			lines = nil
+		}
+		if len(lines) != len(tt.shown) {
+			t.Errorf("DUSO_LOG_LEVEL=%q wrote %q, want %d lines", tt.level, res.stderr, len(tt.shown))
+			continue
+		}
+		for i, want := range tt.shown {
+			if !strings.Contains(lines[i], want) {
+				t.Errorf("DUSO_LOG_LEVEL=%q line %d = %q, want it to contain %q", tt.level, i, lines[i], want)
+			}
+		}
+		if got := strings.TrimSpace(res.stdout); got != tt.threshold {
+			t.Errorf("DUSO_LOG_LEVEL=%q: level() = %q, want %q", tt.level, got, tt.threshold)
+		}
+	}
+}
+
+// TestScriptOperands checks that the words after the script name reach
+// args() and arg(), and duso's own flags before it don't
+func TestScriptOperands(t *testing.T) {
+	res := runDuso(t, `print(format_json(args()))
+print(arg(0), arg(1), arg(2, "none"))`, nil, nil, "build", "-v")
+	want := "[\"build\",\"-v\"]\nbuild -v none\n"
+	if res.code != 0 || res.stdout != want {
+		t.Errorf("stdout = %q (exit %d, stderr %q), want %q", res.stdout, res.code, res.stderr, want)
+	}
+}
+
+// TestExitStatus checks that exit(n) sets the process status, and that other
+// exit values, a script that just ends, and an uncaught error don't
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		src  string
+		code int
+		out  string
+	}{
+		{`print("a") exit(3) print("b")`, 3, "a\n"},
+		{`function f() exit(2) end f() print("after")`, 2, ""},
+		{`exit()`, 0, ""},
+		{`exit("done")`, 0, ""},
+		{`exit(0)`, 0, ""},
+		{`print("ok")`, 0, "ok\n"},
+		{`throw("boom")`, 1, ""},
+	}
+	for _, tt := range tests {
+		res := runDuso(t, tt.src, nil, nil)
+		if res.code != tt.code || res.stdout != tt.out {
+			t.Errorf("%s: exit %d, stdout %q; want %d, %q (stderr %q)", tt.src, res.code, res.stdout, tt.code, tt.out, res.stderr)
+		}
+		if tt.code != 1 && res.stderr != "" {
+			t.Errorf("%s: exit() should print nothing to stderr, got %q", tt.src, res.stderr)
+		}
+	}
+
+	// The -debug run path sets the status the same way
+	for _, tt := range tests {
+		if tt.code == 1 {
+			continue // an uncaught error opens the debugger instead
+		}
+		res := runDusoWithFlags(t, []string{"-debug"}, tt.src, nil, nil)
+		if res.code != tt.code || res.stdout != tt.out {
+			t.Errorf("-debug %s: exit %d, stdout %q; want %d, %q (stderr %q)", tt.src, res.code, res.stdout, tt.code, tt.out, res.stderr)
+		}
+	}
+
+	if code, ok := exitStatus(fmt.Errorf("wrapped: %w", &script.ExitExecution{Values: []any{float64(4)}})); !ok || code != 4 {
+		t.Errorf("exitStatus(wrapped exit(4)) = %d, %v", code, ok)
+	}
+	if _, ok := exitStatus(errors.New("exit")); ok {
+		t.Error("an ordinary error mentioning exit isn't an exit() call")
+	}
+}
+
+// TestUncaughtErrorReport checks the report for an error that ends the
+// script: the message with its position, the surrounding source, and the
+// call stack, most recent call first and trimmed for deep recursion
+func TestUncaughtErrorReport(t *testing.T) {
+	res := runDuso(t, `function inner(x)
+  return x + undefined_var
+end
+
+function outer()
+  return inner(1)
+end
+
+outer()
+`, nil, nil)
+	if res.code != 1 {
+		t.Fatalf("exit %d, want 1", res.code)
+	}
+	wantInOrder := []string{
+		"script.du:2:14: undefined variable: undefined_var\n",
+		"2 |   return x + undefined_var\n                 ^\n",
+		"3 | end\n",
+		"Call stack:\n",
+		"  at inner (",
+		"script.du:6:15)\n",
+		"  at outer (",
+		"script.du:9:6)\n",
+	}
+	rest := res.stderr
+	for _, want := range wantInOrder {
+		i := strings.Index(rest, want)
+		if i < 0 {
+			t.Fatalf("stderr is missing %q (in order):\n%s", want, res.stderr)
+		}
+		rest = rest[i+len(want):]
+	}
+	if n := strings.Count(res.stderr, "Call stack:"); n != 1 {
+		t.Errorf("call stack printed %d times:\n%s", n, res.stderr)
+	}
+
+	res = runDuso(t, `function f(n)
+  if n == 100 then throw("deep") end
+  return f(n + 1)
+end
+f(0)
+`, nil, nil)
+	if !strings.Contains(res.stderr, "  ... 61 more frames ...\n") || strings.Count(res.stderr, "  at f (") != maxPrintedFrames {
+		t.Errorf("deep stack not trimmed to %d frames:\n%s", maxPrintedFrames, res.stderr)
+	}
+
+	res = runDuso(t, "x = (1 +\n", nil, nil)
+	if res.code != 1 || !strings.Contains(res.stderr, "1 | x = (1 +") {
+		t.Errorf("parse error report (exit %d):\n%s", res.code, res.stderr)
+	}
+}
@@ -289,6 +289,10 @@ func generateSyntax() error {
 						"name":  "keyword.operator.postfix.duso",
 						"match": "(\\+\\+|--)",
 					},
+					{
+						"name":  "punctuation.accessor.optional.duso",
+						"match": "\\?\\.(?![0-9])",
+					},
 					{
 						"name":  "keyword.operator.ternary.duso",
 						"match": "[?:]",
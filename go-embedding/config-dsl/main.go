@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+
+	"github.com/duso-org/duso/pkg/runtime"
 	"github.com/duso-org/duso/pkg/script"
 )
 
@@ -10,13 +12,31 @@ import (
 // This example demonstrates:
 // - Writing configuration in Duso
 // - Executing the config script
-// - Reading the output via GetOutput()
-// - Parsing config values
+// - Returning the config to Go with exit() and ExecuteWithResult()
+// - Decoding it into Go structs with script.Unmarshal
 //
 // Real-world use case: Config files, app settings, feature flags
 //
 // Run: go run ./config-dsl
+
+// Config is the Go side of the configuration script
+type Config struct {
+	Database struct {
+		Host string `duso:"host"`
+		Port int    `duso:"port"`
+		Name string `duso:"name"`
+	} `duso:"database"`
+	Server struct {
+		Port    int  `duso:"port"`
+		Debug   bool `duso:"debug"`
+		Timeout int  `duso:"timeout"`
+	} `duso:"server"`
+	Features map[string]bool `duso:"features"`
+}
+
 func main() {
+	// Standard builtins (exit, print, etc.) are opt-in for embedders
+	runtime.RegisterBuiltins()
 	interp := script.NewInterpreter()
 
 	// Configuration written in Duso (could be loaded from file)
@@ -39,14 +59,28 @@ func main() {
 			}
 		}
 
-		// Print configuration for debugging
-		print("Configuration loaded successfully")
+		// Hand the configuration back to the host
+		exit(config)
 	`
 
-	// Execute the configuration script
-	_, err := interp.Execute(configScript)
+	// Execute the configuration script and collect its exit() values
+	values, err := interp.ExecuteWithResult(configScript)
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
+	if len(values) == 0 {
+		fmt.Println("Error: config script did not exit() a config")
+		return
+	}
+
+	var cfg Config
+	if err := script.Unmarshal(values[0], &cfg); err != nil {
+		fmt.Println("Invalid config:", err)
+		return
+	}
+
+	fmt.Printf("Database: %s:%d/%s\n", cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
+	fmt.Printf("Server:   port %d, debug %v, timeout %ds\n", cfg.Server.Port, cfg.Server.Debug, cfg.Server.Timeout)
+	fmt.Printf("Features: %v\n", cfg.Features)
 }
@@ -10,32 +10,52 @@ import (
 // This example demonstrates:
 // - Registering custom Go functions
 // - Calling them from Duso scripts
-// - Handling named arguments
+// - Handling named arguments with script.Args
+// - Converting Go structs with script.Marshal/Unmarshal
 // - Returning values
 //
 // Run: go run ./custom-functions
+
+// Person is converted to and from Duso objects by script.Marshal/Unmarshal
+type Person struct {
+	Name string `duso:"name"`
+	Age  int    `duso:"age"`
+}
+
 func main() {
 	interp := script.NewInterpreter()
 
 	// Register a custom function: add(a, b) -> a + b
+	// script.Args gives typed accessors that return an error (instead of
+	// panicking) when an argument is missing or has the wrong type
 	interp.RegisterFunction("add", func(evaluator *script.Evaluator, args map[string]any) (any, error) {
-		a := args["a"].(float64)
-		b := args["b"].(float64)
+		a, err := script.Args(args).Number("a")
+		if err != nil {
+			return nil, err
+		}
+		b, err := script.Args(args).Number("b")
+		if err != nil {
+			return nil, err
+		}
 		return a + b, nil
 	})
 
 	// Register another function: greet(name) -> "Hello, <name>"
 	interp.RegisterFunction("greet", func(evaluator *script.Evaluator, args map[string]any) (any, error) {
-		name := args["name"].(string)
+		name, err := script.Args(args).String("name")
+		if err != nil {
+			return nil, err
+		}
 		return "Hello, " + name + "!", nil
 	})
 
-	// Register a function that returns an object
+	// Register a function that returns an object, built from a Go struct
 	interp.RegisterFunction("person", func(evaluator *script.Evaluator, args map[string]any) (any, error) {
-		return map[string]any{
-			"name": args["name"].(string),
-			"age":  args["age"].(float64),
-		}, nil
+		var p Person
+		if err := script.Unmarshal(args, &p); err != nil {
+			return nil, err
+		}
+		return script.Marshal(p)
 	})
 
 	// Execute script that uses custom functions
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/duso-org/duso/pkg/runtime"
 	"github.com/duso-org/duso/pkg/script"
 )
 
@@ -10,13 +15,15 @@ import (
 // This example demonstrates:
 // - Registering multiple custom functions
 // - Defining workflows in Duso that use those functions
-// - Executing the workflow
-// - Capturing results via GetOutput()
+// - Executing the workflow with a timeout (ExecuteContext)
+// - Capturing printed output via CaptureOutput()
 //
 // Real-world use case: ETL pipelines, task orchestration, agent workflows
 //
 // Run: go run ./task-scripting
 func main() {
+	// Standard builtins (print, etc.) are opt-in for embedders
+	runtime.RegisterBuiltins()
 	interp := script.NewInterpreter()
 
 	// Register functions that represent tasks
@@ -84,10 +91,24 @@ func main() {
 		end
 	`
 
-	// Execute the workflow
-	_, err := interp.Execute(workflow)
+	// Execute the workflow, giving up if it runs too long. Cancelling ctx
+	// (e.g. when the user aborts the job) stops the script the same way.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	log, err := interp.CaptureOutput(func() error {
+		_, err := interp.ExecuteContext(ctx, workflow)
+		return err
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Println("Workflow timed out")
+		return
+	}
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
+
+	// The workflow's print() output, e.g. to store with the job record
+	fmt.Printf("Workflow log (%d bytes):\n%s", len(log), log)
 }
@@ -1,9 +1,7 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 
@@ -144,23 +142,12 @@ func builtinInput(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	line, err := reader.ReadString('\n')
-	if err == io.EOF {
-		return nil, nil // EOF returns nil
-	}
+	line, ok, err := ReadLine()
 	if err != nil {
 		return nil, fmt.Errorf("input() error: %v", err)
 	}
-
-	// Remove the trailing newline
-	if len(line) > 0 && line[len(line)-1] == '\n' {
-		line = line[:len(line)-1]
-	}
-	// Also remove carriage return if on Windows
-	if len(line) > 0 && line[len(line)-1] == '\r' {
-		line = line[:len(line)-1]
+	if !ok {
+		return nil, nil // EOF returns nil
 	}
-
 	return line, nil
 }
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/duso-org/duso/pkg/runtime"
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// Interactive prompts, used by the prompt_* builtins (and the stdlib prompt
+// module on top of them) and by duso init.
+
+// stdinReaderMu serializes reads from the shared stdinReader
+var stdinReaderMu sync.Mutex
+
+// stdinDisabled reports whether -no-stdin was passed
+func stdinDisabled() bool {
+	return GetSysFlag("-no-stdin", false)
+}
+
+// ReadLine reads one line from stdin without its line ending. ok is false
+// at end of input.
+func ReadLine() (line string, ok bool, err error) {
+	stdinReaderMu.Lock()
+	defer stdinReaderMu.Unlock()
+
+	line, err = stdinReader.ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return "", false, nil
+		}
+		err = nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, true, nil
+}
+
+// PromptSelect lists options numbered from 1 and asks until one is picked.
+// Returns the chosen index, or -1 at end of input or with -no-stdin.
+func PromptSelect(w io.Writer, question string, options []string) (int, error) {
+	if len(options) == 0 {
+		return -1, fmt.Errorf("no options to choose from")
+	}
+	if stdinDisabled() {
+		return -1, nil
+	}
+	ClearBusySpinner()
+
+	for i, opt := range options {
+		fmt.Fprintf(w, "\n  %d. %s", i+1, opt)
+	}
+	fmt.Fprint(w, "\n\n")
+	for {
+		fmt.Fprintf(w, "%s (1-%d): ", question, len(options))
+		line, ok, err := ReadLine()
+		if err != nil || !ok {
+			return -1, err
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		fmt.Fprintf(w, "Please enter a number from 1 to %d.\n", len(options))
+	}
+}
+
+// PromptConfirm asks a yes/no question until it gets y/yes/n/no. An empty
+// answer, end of input or -no-stdin gives def.
+func PromptConfirm(w io.Writer, question string, def bool) (bool, error) {
+	if stdinDisabled() {
+		return def, nil
+	}
+	ClearBusySpinner()
+
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	for {
+		fmt.Fprintf(w, "%s %s: ", question, hint)
+		line, ok, err := ReadLine()
+		if err != nil || !ok {
+			return def, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(w, "Please answer y or n.")
+	}
+}
+
+// PromptText asks for a line of text. An empty answer, end of input or
+// -no-stdin gives def.
+func PromptText(w io.Writer, question, def string) (string, error) {
+	if stdinDisabled() {
+		return def, nil
+	}
+	ClearBusySpinner()
+
+	if def != "" {
+		fmt.Fprintf(w, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w, "%s: ", question)
+	}
+	line, ok, err := ReadLine()
+	if err != nil || !ok {
+		return def, err
+	}
+	if strings.TrimSpace(line) == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// builtinPromptSelect asks the user to pick one of a list of options
+// Usage: prompt_select(question, options)
+// Returns the chosen option, or nil at end of input or with -no-stdin.
+func builtinPromptSelect(evaluator *Evaluator, args map[string]any) (any, error) {
+	question, ok := args["0"].(string)
+	if !ok {
+		return nil, fmt.Errorf("prompt_select() requires a question string as first argument")
+	}
+	optsVal := runtime.InterfaceToValue(args["1"])
+	if !optsVal.IsArray() || len(optsVal.AsArray()) == 0 {
+		return nil, fmt.Errorf("prompt_select() requires a non-empty array of options as second argument")
+	}
+	options := optsVal.AsArray()
+	labels := make([]string, len(options))
+	for i, opt := range options {
+		labels[i] = script.ValueForDisplay(opt)
+	}
+
+	choice, err := PromptSelect(os.Stdout, question, labels)
+	if err != nil {
+		return nil, fmt.Errorf("prompt_select(): %w", err)
+	}
+	if choice < 0 {
+		return nil, nil
+	}
+	return options[choice], nil
+}
+
+// builtinPromptConfirm asks a yes/no question
+// Usage: prompt_confirm(question [, default])
+func builtinPromptConfirm(evaluator *Evaluator, args map[string]any) (any, error) {
+	question, ok := args["0"].(string)
+	if !ok {
+		return nil, fmt.Errorf("prompt_confirm() requires a question string as first argument")
+	}
+	def, _ := args["1"].(bool)
+
+	answer, err := PromptConfirm(os.Stdout, question, def)
+	if err != nil {
+		return nil, fmt.Errorf("prompt_confirm(): %w", err)
+	}
+	return answer, nil
+}
+
+// builtinPromptText asks for a line of text
+// Usage: prompt_text(question [, default])
+func builtinPromptText(evaluator *Evaluator, args map[string]any) (any, error) {
+	question, ok := args["0"].(string)
+	if !ok {
+		return nil, fmt.Errorf("prompt_text() requires a question string as first argument")
+	}
+	def := ""
+	if d, ok := args["1"]; ok && d != nil {
+		def = script.ValueForDisplay(runtime.InterfaceToValue(d))
+	}
+
+	answer, err := PromptText(os.Stdout, question, def)
+	if err != nil {
+		return nil, fmt.Errorf("prompt_text(): %w", err)
+	}
+	return answer, nil
+}
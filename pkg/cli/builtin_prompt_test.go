@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/runtime"
+)
+
+// withStdin replaces the shared stdin reader with input for the test
+func withStdin(t *testing.T, input string) {
+	prev := stdinReader
+	stdinReader = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { stdinReader = prev })
+}
+
+func TestPromptSelect(t *testing.T) {
+	withStdin(t, "0\nblue\n2\n")
+	var out strings.Builder
+	choice, err := PromptSelect(&out, "Pick", []string{"red", "green"})
+	if err != nil || choice != 1 {
+		t.Fatalf("PromptSelect = %d, %v; want 1", choice, err)
+	}
+	if n := strings.Count(out.String(), "Please enter a number from 1 to 2."); n != 2 {
+		t.Errorf("expected 2 retries, output:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "  1. red\n  2. green") {
+		t.Errorf("options not listed:\n%s", out.String())
+	}
+
+	withStdin(t, "")
+	if choice, err := PromptSelect(&out, "Pick", []string{"a"}); err != nil || choice != -1 {
+		t.Errorf("PromptSelect at end of input = %d, %v; want -1", choice, err)
+	}
+	if _, err := PromptSelect(&out, "Pick", nil); err == nil {
+		t.Error("PromptSelect with no options should fail")
+	}
+}
+
+func TestPromptConfirmAndText(t *testing.T) {
+	withStdin(t, "maybe\r\nYES\n\nn\n")
+	var out strings.Builder
+	tests := []struct {
+		def  bool
+		want bool
+	}{
+		{false, true}, // "maybe" is asked again, then "YES"
+		{true, true},  // empty answer takes the default
+		{true, false},
+		{true, true}, // end of input takes the default
+	}
+	for i, tt := range tests {
+		if got, err := PromptConfirm(&out, "Sure?", tt.def); err != nil || got != tt.want {
+			t.Errorf("confirm %d = %v, %v; want %v", i, got, err, tt.want)
+		}
+	}
+	if !strings.Contains(out.String(), "Please answer y or n.") || !strings.Contains(out.String(), "Sure? [Y/n]: ") {
+		t.Errorf("unexpected confirm output:\n%s", out.String())
+	}
+
+	withStdin(t, "  \nAda Lovelace\n")
+	if got, _ := PromptText(&out, "Name", "anon"); got != "anon" {
+		t.Errorf("blank answer = %q, want the default", got)
+	}
+	if got, _ := PromptText(&out, "Name", "anon"); got != "Ada Lovelace" {
+		t.Errorf("PromptText = %q", got)
+	}
+	if got, _ := PromptText(&out, "Name", ""); got != "" {
+		t.Errorf("PromptText at end of input = %q, want empty", got)
+	}
+}
+
+// TestPromptNoStdin checks that -no-stdin answers every prompt with its
+// default without reading
+func TestPromptNoStdin(t *testing.T) {
+	withStdin(t, "y\nhello\n1\n")
+	sysDs := runtime.GetDatastore("sys", nil)
+	sysDs.Set("-no-stdin", true)
+	defer sysDs.Set("-no-stdin", false)
+
+	var out strings.Builder
+	if got, _ := PromptConfirm(&out, "Sure?", false); got {
+		t.Error("confirm with -no-stdin should give the default")
+	}
+	if got, _ := PromptText(&out, "Name", "anon"); got != "anon" {
+		t.Errorf("text with -no-stdin = %q", got)
+	}
+	if got, _ := PromptSelect(&out, "Pick", []string{"a"}); got != -1 {
+		t.Errorf("select with -no-stdin = %d", got)
+	}
+	if out.Len() != 0 {
+		t.Errorf("nothing should be printed with -no-stdin, got %q", out.String())
+	}
+
+	if got, err := builtinPromptText(nil, map[string]any{"0": "Port", "1": float64(8080)}); err != nil || got != "8080" {
+		t.Errorf("prompt_text() default = %v, %v; want \"8080\"", got, err)
+	}
+	if got, err := builtinPromptSelect(nil, map[string]any{"0": "Pick", "1": []any{"a"}}); err != nil || got != nil {
+		t.Errorf("prompt_select() with -no-stdin = %v, %v; want nil", got, err)
+	}
+	if _, err := builtinPromptSelect(nil, map[string]any{"0": "Pick", "1": []any{}}); err == nil {
+		t.Error("prompt_select() with no options should fail")
+	}
+}
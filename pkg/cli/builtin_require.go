@@ -42,6 +42,11 @@ func builtinRequire(evaluator *Evaluator, args map[string]any) (any, error) {
 	}
 	interp := globalInterpreter
 
+	// Go modules registered by the host take precedence over script files
+	if exports, ok := interp.LookupModule(filename); ok {
+		return exports, nil
+	}
+
 	// Resolve module path using standard resolution algorithm
 	fullPath, searchedPaths, err := globalResolver.ResolveModule(filename)
 	if err != nil {
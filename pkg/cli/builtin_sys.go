@@ -2,9 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 
 	"github.com/duso-org/duso/pkg/runtime"
 	"github.com/duso-org/duso/pkg/script"
+	"golang.org/x/term"
 )
 
 // GetSysFlag retrieves a flag from the sys datastore with type preservation and default fallback.
@@ -41,3 +44,64 @@ func builtinSys(evaluator *script.Evaluator, args map[string]any) (any, error) {
 	// Return the value (or nil if not found)
 	return val, nil
 }
+
+// builtinArgs returns the positional arguments given after the script name
+// Usage: args()
+// For "duso tool.du build -v out" this is ["build", "-v", "out"]; duso's own
+// flags before the script name aren't included.
+func builtinArgs(evaluator *script.Evaluator, args map[string]any) (any, error) {
+	sysDs := runtime.GetDatastore("sys", nil)
+	val, _ := sysDs.Get("script_args")
+	if val == nil {
+		return []any{}, nil
+	}
+	return val, nil
+}
+
+// builtinArg returns one positional argument by index, counting from 0
+// Usage: arg(n [, default])
+// Returns default (or nil) when fewer arguments were given.
+func builtinArg(evaluator *script.Evaluator, args map[string]any) (any, error) {
+	n, ok := args["0"].(float64)
+	if !ok || n < 0 || n != float64(int(n)) {
+		return nil, fmt.Errorf("arg() requires a non-negative integer index")
+	}
+	sysDs := runtime.GetDatastore("sys", nil)
+	val, _ := sysDs.Get("script_args")
+	if list, ok := val.([]any); ok && int(n) < len(list) {
+		return list[int(n)], nil
+	}
+	return args["1"], nil
+}
+
+// IsTerminal reports whether f is attached to a terminal rather than a
+// pipe, file or other device. A character device check isn't enough:
+// /dev/null is one too.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// builtinTermWidth returns the terminal width in columns
+// Usage: term_width([default])
+// Asks the terminal on stdout, then stderr, then reads $COLUMNS. When none
+// of those give a width (output is piped), returns default, or 80.
+func builtinTermWidth(evaluator *script.Evaluator, args map[string]any) (any, error) {
+	def := 80.0
+	if d, ok := args["0"]; ok && d != nil {
+		n, ok := d.(float64)
+		if !ok {
+			return nil, fmt.Errorf("term_width() default must be a number")
+		}
+		def = n
+	}
+
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if cols := terminalColumns(f); cols > 0 {
+			return float64(cols), nil
+		}
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return float64(cols), nil
+	}
+	return def, nil
+}
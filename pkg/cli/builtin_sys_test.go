@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/runtime"
+)
+
+// TestIsTerminal checks that pipes, files and character devices that aren't
+// terminals, like /dev/null, aren't reported as terminals
+func TestIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if IsTerminal(r) || IsTerminal(w) {
+		t.Error("a pipe is not a terminal")
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsTerminal(f) {
+		t.Error("a regular file is not a terminal")
+	}
+
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	if IsTerminal(null) {
+		t.Errorf("%s is not a terminal", os.DevNull)
+	}
+}
+
+// TestArgs checks args() and arg(), including indexes past the end
+func TestArgs(t *testing.T) {
+	sysDs := runtime.GetDatastore("sys", nil)
+	sysDs.Set("script_args", nil)
+	if got, _ := builtinArgs(nil, nil); len(got.([]any)) != 0 {
+		t.Errorf("args() with no operands = %v, want []", got)
+	}
+
+	sysDs.Set("script_args", []any{"build", "-v"})
+	defer sysDs.Set("script_args", nil)
+	if got, _ := builtinArgs(nil, nil); len(got.([]any)) != 2 {
+		t.Errorf("args() = %v", got)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want any
+	}{
+		{map[string]any{"0": float64(0)}, "build"},
+		{map[string]any{"0": float64(1)}, "-v"},
+		{map[string]any{"0": float64(2)}, nil},
+		{map[string]any{"0": float64(2), "1": "out"}, "out"},
+		{map[string]any{"0": float64(100), "1": float64(0)}, float64(0)},
+	}
+	for _, tt := range tests {
+		got, err := builtinArg(nil, tt.args)
+		if err != nil || got != tt.want {
+			t.Errorf("arg(%v) = %v, %v; want %v", tt.args, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []any{float64(-1), float64(1.5), "0", nil} {
+		if _, err := builtinArg(nil, map[string]any{"0": bad}); err == nil {
+			t.Errorf("arg(%v) should fail", bad)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/duso-org/duso/pkg/runtime/color"
 	"github.com/duso-org/duso/pkg/script"
 )
 
@@ -74,8 +75,8 @@ func handleConsoleDebugEvent(interp *script.Interpreter, event *script.DebugEven
 		}
 	}
 
-	// brightRed := "\033[91m"
-	// reset := "\033[0m"
+	// brightRed := color.BrightRed
+	// reset := color.Reset
 
 	// Print invocation stack (how we got here via spawn/run calls)
 	if event.InvocationStack != nil {
@@ -133,8 +134,8 @@ func openConsoleDebugREPL(interp *script.Interpreter, bpErr *script.BreakpointEr
 	sessionMu.Lock()
 	defer sessionMu.Unlock()
 
-	brightRed := "\033[91m"
-	reset := "\033[0m"
+	brightRed := color.BrightRed
+	reset := color.Reset
 
 	// Show message if present (from breakpoint() or watch())
 	if message != "" {
@@ -228,9 +229,9 @@ func showConsoleSourceContext(filePath string, line int, col int) {
 	// Calculate width needed for line numbers
 	lineNumWidth := len(fmt.Sprintf("%d", end))
 
-	yellow := "\033[93m"
-	brightRed := "\033[91m"
-	reset := "\033[0m"
+	yellow := color.BrightYellow
+	brightRed := color.BrightRed
+	reset := color.Reset
 
 	for i := start; i <= end; i++ {
 		lineContent := ""
@@ -176,6 +176,8 @@ func RegisterCLIBuiltins(resolver *ModuleResolver) {
 
 	// System/config access
 	script.RegisterBuiltin("sys", builtinSys)
+	script.RegisterBuiltin("args", builtinArgs)
+	script.RegisterBuiltin("arg", builtinArg)
 
 	// Console functions (CLI versions override runtime versions)
 	script.RegisterBuiltin("print", builtinPrint)
@@ -183,8 +185,12 @@ func RegisterCLIBuiltins(resolver *ModuleResolver) {
 	script.RegisterBuiltin("write", builtinWrite)
 	script.RegisterBuiltin("debug", builtinDebug)
 	script.RegisterBuiltin("input", builtinInput)
+	script.RegisterBuiltin("prompt_select", builtinPromptSelect)
+	script.RegisterBuiltin("prompt_confirm", builtinPromptConfirm)
+	script.RegisterBuiltin("prompt_text", builtinPromptText)
 
 	script.RegisterBuiltin("busy", builtinBusy)
+	script.RegisterBuiltin("term_width", builtinTermWidth)
 	script.RegisterBuiltin("require", builtinRequire)
 	script.RegisterBuiltin("include", builtinInclude)
 	script.RegisterBuiltin("load", builtinLoad)
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+
+package cli
+
+import "os"
+
+// terminalColumns isn't supported on this platform; term_width() falls back
+// to $COLUMNS or its default
+func terminalColumns(f *os.File) int {
+	return 0
+}
@@ -39,7 +39,7 @@ func ProvideCompletion(server *Server, doc *Document, pos Position, entry *ASTCa
 		"return", "break", "continue",
 		"try", "catch",
 		"and", "or", "not",
-		"var", "raw",
+		"var", "const", "raw",
 		"true", "false", "nil",
 	}
 	for _, name := range keywords {
@@ -226,6 +226,10 @@ func GetBuiltinFunctions() []string {
 		"tostring",
 		"tonumber",
 		"tobool",
+		"as_array",
+		"as_object",
+		"coerce",
+		"inspect",
 
 		// String operations
 		"upper",
@@ -237,6 +241,18 @@ func GetBuiltinFunctions() []string {
 		"contains",
 		"find",
 		"replace",
+		"dedent",
+		"lines",
+		"unlines",
+		"wrap_text",
+		"format_number",
+		"to_fixed",
+		"to_precision",
+		"html_escape",
+		"html_unescape",
+		"strip_ansi",
+		"colorize",
+		"string_builder",
 
 		// Collections
 		"len",
@@ -247,12 +263,41 @@ func GetBuiltinFunctions() []string {
 		"shift",
 		"unshift",
 		"sort",
+		"concat",
+		"fill",
+		"insert",
+		"remove_at",
+		"splice",
+		"take",
+		"drop",
+		"take_while",
+		"drop_while",
+		"partition",
+		"window",
+		"filter_object",
+		"map_keys",
+		"map_values",
+		"min_by",
+		"max_by",
+		"shallow_copy",
+		"deep_equal",
+		"freeze",
+		"is_frozen",
+		"ordered_object",
+		"set",
+		"table",
 
 		// Functional
 		"map",
 		"filter",
 		"reduce",
 		"range",
+		"flat_map",
+		"scan",
+		"any",
+		"all",
+		"debounce",
+		"throttle",
 
 		// Math
 		"abs",
@@ -263,20 +308,47 @@ func GetBuiltinFunctions() []string {
 		"max",
 		"sqrt",
 		"pow",
+		"div",
+		"mod",
 		"clamp",
+		"clamp01",
+		"lerp",
+		"inv_lerp",
+		"remap",
+		"is_nan",
+		"is_finite",
+		"is_infinite",
+		"bit_and",
+		"bit_or",
+		"bit_xor",
+		"bit_not",
+		"shift_left",
+		"shift_right",
+		"to_radians",
+		"to_degrees",
+		"sin_deg",
+		"cos_deg",
 		"random",
 
-		// JSON
+		// Data formats
 		"parse_json",
 		"format_json",
+		"parse_xml",
+		"format_xml",
+		"parse_query",
+		"build_query",
 
 		// Date/Time
 		"now",
 		"format_time",
 		"parse_time",
+		"set_timeout",
+		"set_interval",
+		"cron",
 
 		// Concurrency/Control
 		"sleep",
+		"sleep_ms",
 		"parallel",
 		"breakpoint",
 		"watch",
@@ -288,6 +360,11 @@ func GetBuiltinFunctions() []string {
 		// Utility
 		"uuid",
 		"template",
+		"register_template",
+		"short_id",
+		"sign_jwt",
+		"verify_jwt",
+		"decode_jwt",
 
 		// CLI-specific functions
 		"fetch",
@@ -314,5 +391,11 @@ func GetBuiltinFunctions() []string {
 		"rename_file",
 		"remove_file",
 		"append_file",
+		"args",
+		"arg",
+		"term_width",
+		"prompt_text",
+		"prompt_confirm",
+		"prompt_select",
 	}
 }
@@ -161,6 +161,9 @@ func formatParameters(params []*script.Parameter) string {
 		if i > 0 {
 			result += ", "
 		}
+		if param.Variadic {
+			result += "..."
+		}
 		result += param.Name
 		if param.Default != nil {
 			result += "?"
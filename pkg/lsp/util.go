@@ -387,6 +387,11 @@ func FindNodeAtPosition(node script.Node, pos script.Position) script.Node {
 			return found
 		}
 
+	case *script.OptionalChain:
+		if found := FindNodeAtPosition(n.Expr, pos); found != nil {
+			return found
+		}
+
 	case *script.TryStatement:
 		// Recurse into the try block
 		for _, stmt := range n.Block {
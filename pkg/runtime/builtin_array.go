@@ -2,14 +2,28 @@ package runtime
 
 import (
 	"fmt"
+	"math"
 	"strings"
+
+	"github.com/duso-org/duso/pkg/script"
 )
 
 // Array/Object functions
 
 // builtinKeys returns array of object keys or array indices
+// Ordered objects list their keys in insertion order.
 func builtinKeys(evaluator *Evaluator, args map[string]any) (any, error) {
 	if arg, ok := args["0"].(map[string]any); ok {
+		if err := evaluator.ChargeElements(len(arg)); err != nil {
+			return nil, err
+		}
+		if order, ok := script.OrderedKeys(arg); ok {
+			keys := make([]any, len(order))
+			for i, k := range order {
+				keys[i] = k
+			}
+			return keys, nil
+		}
 		keys := make([]any, 0, len(arg))
 		for k := range arg {
 			keys = append(keys, k)
@@ -20,8 +34,19 @@ func builtinKeys(evaluator *Evaluator, args map[string]any) (any, error) {
 }
 
 // builtinValues returns array of object values or array items
+// Ordered objects list their values in insertion order.
 func builtinValues(evaluator *Evaluator, args map[string]any) (any, error) {
 	if arg, ok := args["0"].(map[string]any); ok {
+		if err := evaluator.ChargeElements(len(arg)); err != nil {
+			return nil, err
+		}
+		if order, ok := script.OrderedKeys(arg); ok {
+			values := make([]any, len(order))
+			for i, k := range order {
+				values[i] = arg[k]
+			}
+			return values, nil
+		}
 		values := make([]any, 0, len(arg))
 		for _, v := range arg {
 			values = append(values, v)
@@ -31,16 +56,59 @@ func builtinValues(evaluator *Evaluator, args map[string]any) (any, error) {
 	return nil, fmt.Errorf("values() requires an object")
 }
 
+// builtinOrderedObject creates an object that keeps its keys in insertion
+// order for keys(), values(), for loops, format_json() and tostring()
+// Usage: ordered_object([source])
+// source is an array of [key, value] pairs, taken in order, or an object,
+// whose keys are taken sorted (or in order, if it's ordered itself).
+func builtinOrderedObject(evaluator *Evaluator, args map[string]any) (any, error) {
+	obj := make(map[string]Value)
+	var order []string
+
+	switch source := args["0"].(type) {
+	case nil:
+	case map[string]any:
+		for _, k := range script.SortedKeys(source) {
+			obj[k] = InterfaceToValue(source[k])
+			order = append(order, k)
+		}
+	case *[]Value:
+		for i, pair := range *source {
+			if !pair.IsArray() || len(pair.AsArray()) != 2 {
+				return nil, fmt.Errorf("ordered_object() element %d is not a [key, value] pair", i)
+			}
+			key := pair.AsArray()[0].String()
+			if _, exists := obj[key]; !exists {
+				order = append(order, key)
+			}
+			obj[key] = pair.AsArray()[1]
+		}
+	default:
+		return nil, fmt.Errorf("ordered_object() requires an array of [key, value] pairs or an object")
+	}
+
+	if err := evaluator.ChargeElements(len(obj)); err != nil {
+		return nil, err
+	}
+	return script.NewOrderedObject(obj, order), nil
+}
+
 // builtinPush appends items to the end of an array, returns new length
 func builtinPush(evaluator *Evaluator, args map[string]any) (any, error) {
 	arrPtr, ok := args["0"].(*[]Value)
 	if !ok {
 		return nil, fmt.Errorf("push() requires an array as first argument")
 	}
+	if IsFrozenArray(arrPtr) {
+		return nil, fmt.Errorf("push() cannot modify a frozen array")
+	}
 
 	// Fast path: single item (common case in loops)
 	if itemArg, ok := args["1"]; ok {
 		if _, ok := args["2"]; !ok {
+			if err := evaluator.ChargeElements(1); err != nil {
+				return nil, err
+			}
 			// Only one item to push - avoid temporary slice allocation
 			*arrPtr = append(*arrPtr, InterfaceToValue(itemArg))
 			return float64(len(*arrPtr)), nil
@@ -60,6 +128,9 @@ func builtinPush(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 	}
 
+	if err := evaluator.ChargeElements(len(items)); err != nil {
+		return nil, err
+	}
 	*arrPtr = append(*arrPtr, items...)
 	return float64(len(*arrPtr)), nil
 }
@@ -70,6 +141,9 @@ func builtinPop(evaluator *Evaluator, args map[string]any) (any, error) {
 	if !ok {
 		return nil, fmt.Errorf("pop() requires an array as first argument")
 	}
+	if IsFrozenArray(arrPtr) {
+		return nil, fmt.Errorf("pop() cannot modify a frozen array")
+	}
 
 	arr := *arrPtr
 	if len(arr) == 0 {
@@ -87,6 +161,9 @@ func builtinShift(evaluator *Evaluator, args map[string]any) (any, error) {
 	if !ok {
 		return nil, fmt.Errorf("shift() requires an array as first argument")
 	}
+	if IsFrozenArray(arrPtr) {
+		return nil, fmt.Errorf("shift() cannot modify a frozen array")
+	}
 
 	arr := *arrPtr
 	if len(arr) == 0 {
@@ -104,10 +181,16 @@ func builtinUnshift(evaluator *Evaluator, args map[string]any) (any, error) {
 	if !ok {
 		return nil, fmt.Errorf("unshift() requires an array as first argument")
 	}
+	if IsFrozenArray(arrPtr) {
+		return nil, fmt.Errorf("unshift() cannot modify a frozen array")
+	}
 
 	// Fast path: single item (common case)
 	if itemArg, ok := args["1"]; ok {
 		if _, ok := args["2"]; !ok {
+			if err := evaluator.ChargeElements(1); err != nil {
+				return nil, err
+			}
 			// Only one item to unshift
 			item := InterfaceToValue(itemArg)
 			newArr := make([]Value, len(*arrPtr)+1)
@@ -131,10 +214,171 @@ func builtinUnshift(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 	}
 
+	if err := evaluator.ChargeElements(len(items)); err != nil {
+		return nil, err
+	}
 	*arrPtr = append(items, *arrPtr...)
 	return float64(len(*arrPtr)), nil
 }
 
+// positionalValues collects a builtin's positional arguments in order
+func positionalValues(args map[string]any) []Value {
+	var values []Value
+	for i := 0; ; i++ {
+		arg, ok := args[ArgKey(i)]
+		if !ok {
+			return values
+		}
+		values = append(values, InterfaceToValue(arg))
+	}
+}
+
+// arrayPosition converts an index argument to a position in an array of
+// length n, counting from the end when negative. ok is false when the
+// argument isn't a whole number.
+func arrayPosition(arg Value, n int) (pos int, ok bool) {
+	if !arg.IsNumber() || arg.AsNumber() != math.Trunc(arg.AsNumber()) {
+		return 0, false
+	}
+	pos = int(arg.AsNumber())
+	if pos < 0 {
+		pos += n
+	}
+	return pos, true
+}
+
+// mutableArray checks the array argument of insert(), remove_at() and splice()
+func mutableArray(name string, args []Value) (*[]Value, error) {
+	if len(args) < 1 || !args[0].IsArray() {
+		return nil, fmt.Errorf("%s() requires an array as first argument", name)
+	}
+	arrPtr := args[0].AsArrayPtr()
+	if IsFrozenArray(arrPtr) {
+		return nil, fmt.Errorf("%s() cannot modify a frozen array", name)
+	}
+	return arrPtr, nil
+}
+
+// spliceArray replaces deleteCount elements of *arrPtr at start with items
+// and returns the removed elements. start and deleteCount must be in range.
+func spliceArray(evaluator *Evaluator, arrPtr *[]Value, start, deleteCount int, items []Value) ([]Value, error) {
+	if err := evaluator.ChargeElements(len(items)); err != nil {
+		return nil, err
+	}
+	arr := *arrPtr
+	removed := make([]Value, deleteCount)
+	copy(removed, arr[start:start+deleteCount])
+
+	newArr := make([]Value, 0, len(arr)-deleteCount+len(items))
+	newArr = append(newArr, arr[:start]...)
+	newArr = append(newArr, items...)
+	newArr = append(newArr, arr[start+deleteCount:]...)
+	*arrPtr = newArr
+	return removed, nil
+}
+
+// builtinInsert inserts a value before the element at index, returns new length
+// Usage: insert(array, index, value)
+// index may equal the length to append; negative indexes count from the end.
+func builtinInsert(evaluator *Evaluator, args map[string]any) (any, error) {
+	return arrayInsert(evaluator, positionalValues(args))
+}
+
+func arrayInsert(evaluator *Evaluator, args []Value) (Value, error) {
+	arrPtr, err := mutableArray("insert", args)
+	if err != nil {
+		return script.NewNil(), err
+	}
+	if len(args) < 3 {
+		return script.NewNil(), fmt.Errorf("insert() requires an array, an index and a value")
+	}
+	n := len(*arrPtr)
+	pos, ok := arrayPosition(args[1], n)
+	if !ok {
+		return script.NewNil(), fmt.Errorf("insert() index must be a whole number")
+	}
+	if pos < 0 || pos > n {
+		return script.NewNil(), fmt.Errorf("insert() index %s is out of range for an array of length %d", args[1].String(), n)
+	}
+	if _, err := spliceArray(evaluator, arrPtr, pos, 0, args[2:3]); err != nil {
+		return script.NewNil(), err
+	}
+	return script.NewNumber(float64(len(*arrPtr))), nil
+}
+
+// builtinRemoveAt removes and returns the element at index
+// Usage: remove_at(array, index)
+// Negative indexes count from the end, so remove_at(arr, -1) is like pop().
+func builtinRemoveAt(evaluator *Evaluator, args map[string]any) (any, error) {
+	return arrayRemoveAt(evaluator, positionalValues(args))
+}
+
+func arrayRemoveAt(evaluator *Evaluator, args []Value) (Value, error) {
+	arrPtr, err := mutableArray("remove_at", args)
+	if err != nil {
+		return script.NewNil(), err
+	}
+	if len(args) < 2 {
+		return script.NewNil(), fmt.Errorf("remove_at() requires an array and an index")
+	}
+	n := len(*arrPtr)
+	pos, ok := arrayPosition(args[1], n)
+	if !ok {
+		return script.NewNil(), fmt.Errorf("remove_at() index must be a whole number")
+	}
+	if pos < 0 || pos >= n {
+		return script.NewNil(), fmt.Errorf("remove_at() index %s is out of range for an array of length %d", args[1].String(), n)
+	}
+	removed, err := spliceArray(evaluator, arrPtr, pos, 1, nil)
+	if err != nil {
+		return script.NewNil(), err
+	}
+	return removed[0], nil
+}
+
+// builtinSplice removes elements and inserts new ones in their place, like
+// JavaScript's Array.prototype.splice, returns the removed elements
+// Usage: splice(array, start [, delete_count] [, items...])
+// A negative start counts from the end; start and delete_count are clamped
+// to the array, and without delete_count everything from start is removed.
+func builtinSplice(evaluator *Evaluator, args map[string]any) (any, error) {
+	return arraySplice(evaluator, positionalValues(args))
+}
+
+func arraySplice(evaluator *Evaluator, args []Value) (Value, error) {
+	arrPtr, err := mutableArray("splice", args)
+	if err != nil {
+		return script.NewNil(), err
+	}
+	if len(args) < 2 {
+		return script.NewNil(), fmt.Errorf("splice() requires an array and a start index")
+	}
+	n := len(*arrPtr)
+	start, ok := arrayPosition(args[1], n)
+	if !ok {
+		return script.NewNil(), fmt.Errorf("splice() start must be a whole number")
+	}
+	start = min(max(start, 0), n)
+
+	deleteCount := n - start
+	if len(args) > 2 && !args[2].IsNil() {
+		if !args[2].IsNumber() {
+			return script.NewNil(), fmt.Errorf("splice() delete_count must be a number")
+		}
+		deleteCount = min(max(int(args[2].AsNumber()), 0), n-start)
+	}
+	var items []Value
+	if len(args) > 3 {
+		items = args[3:]
+	}
+
+	removed, err := spliceArray(evaluator, arrPtr, start, deleteCount, items)
+	if err != nil {
+		return script.NewNil(), err
+	}
+	return script.NewArray(removed), nil
+}
+
 // builtinSplit splits string by separator
 func builtinSplit(evaluator *Evaluator, args map[string]any) (any, error) {
 	s, ok := args["0"].(string)
@@ -148,6 +392,9 @@ func builtinSplit(evaluator *Evaluator, args map[string]any) (any, error) {
 	}
 
 	parts := strings.Split(s, sep)
+	if err := evaluator.ChargeElements(len(parts)); err != nil {
+		return nil, err
+	}
 	result := make([]any, len(parts))
 	for i, p := range parts {
 		result[i] = p
@@ -175,8 +422,52 @@ func builtinJoin(evaluator *Evaluator, args map[string]any) (any, error) {
 	return strings.Join(parts, sep), nil
 }
 
-// builtinRange creates an array of numbers in range
+// builtinLines splits text into lines on \n or \r\n. A trailing newline
+// doesn't produce an empty last line.
+func builtinLines(evaluator *Evaluator, args map[string]any) (any, error) {
+	s, ok := args["0"].(string)
+	if !ok {
+		return nil, fmt.Errorf("lines() requires a string argument")
+	}
+
+	if s == "" {
+		return []any{}, nil
+	}
+	parts := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if err := evaluator.ChargeElements(len(parts)); err != nil {
+		return nil, err
+	}
+	result := make([]any, len(parts))
+	for i, p := range parts {
+		result[i] = strings.TrimSuffix(p, "\r")
+	}
+	return result, nil
+}
+
+// builtinUnlines joins array elements with newlines
+func builtinUnlines(evaluator *Evaluator, args map[string]any) (any, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, fmt.Errorf("unlines() requires an array argument")
+	}
+
+	arr := *arrPtr
+	parts := make([]string, len(arr))
+	for i, item := range arr {
+		parts[i] = item.String()
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// builtinRange creates an array of numbers from start to end, inclusive
+// Usage: range(start, end [, step])
+// Without a step, the range counts down when end is below start. With
+// single-character strings it makes a range of characters: range("a", "e").
 func builtinRange(evaluator *Evaluator, args map[string]any) (any, error) {
+	if first, ok := args["0"].(string); ok {
+		return charRange(evaluator, first, args)
+	}
+
 	start, ok := args["0"].(float64)
 	if !ok {
 		return nil, fmt.Errorf("range() requires a number as first argument")
@@ -190,11 +481,18 @@ func builtinRange(evaluator *Evaluator, args map[string]any) (any, error) {
 	step := 1.0
 	if s, ok := args["2"].(float64); ok {
 		step = s
+	} else if end < start {
+		step = -1
 	}
 
 	if step == 0 {
 		return nil, fmt.Errorf("range() step cannot be zero")
 	}
+	if count := (end-start)/step + 1; count >= 1 {
+		if err := evaluator.ChargeElements(int(min(count, math.MaxInt32))); err != nil {
+			return nil, err
+		}
+	}
 
 	var result []any
 	if step > 0 {
@@ -210,3 +508,206 @@ func builtinRange(evaluator *Evaluator, args map[string]any) (any, error) {
 	return result, nil
 }
 
+// charRange is range() over single characters, stepping by code point
+func charRange(evaluator *Evaluator, first string, args map[string]any) (any, error) {
+	last, ok := args["1"].(string)
+	from, to := []rune(first), []rune(last)
+	if !ok || len(from) != 1 || len(to) != 1 {
+		return nil, fmt.Errorf("range() with strings requires two single characters")
+	}
+	start, end := int(from[0]), int(to[0])
+
+	step := 1
+	if s, ok := args["2"].(float64); ok {
+		if s != math.Trunc(s) {
+			return nil, fmt.Errorf("range() step must be a whole number for characters")
+		}
+		step = int(s)
+	} else if end < start {
+		step = -1
+	}
+
+	if step == 0 {
+		return nil, fmt.Errorf("range() step cannot be zero")
+	}
+	if count := (end-start)/step + 1; count >= 1 {
+		if err := evaluator.ChargeElements(count); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []any
+	for i := start; (step > 0 && i <= end) || (step < 0 && i >= end); i += step {
+		result = append(result, string(rune(i)))
+	}
+	return result, nil
+}
+
+
+// sliceCountArgs validates (array, n) for take()/drop() and returns the array
+// and n truncated to an integer
+func sliceCountArgs(args map[string]any, name string) ([]Value, int, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, 0, fmt.Errorf("%s() requires an array as first argument", name)
+	}
+	n, ok := args["1"].(float64)
+	if !ok {
+		return nil, 0, fmt.Errorf("%s() requires a number as second argument", name)
+	}
+	return *arrPtr, int(n), nil
+}
+
+// builtinTake returns the first n elements (last n when n is negative) as a new array
+func builtinTake(evaluator *Evaluator, args map[string]any) (any, error) {
+	arr, n, err := sliceCountArgs(args, "take")
+	if err != nil {
+		return nil, err
+	}
+
+	var part []Value
+	if n >= 0 {
+		part = arr[:min(n, len(arr))]
+	} else {
+		part = arr[max(len(arr)+n, 0):]
+	}
+
+	if err := evaluator.ChargeElements(len(part)); err != nil {
+		return nil, err
+	}
+	result := make([]Value, len(part))
+	copy(result, part)
+	return &result, nil
+}
+
+// builtinDrop returns the array without its first n elements (last n when n is negative)
+func builtinDrop(evaluator *Evaluator, args map[string]any) (any, error) {
+	arr, n, err := sliceCountArgs(args, "drop")
+	if err != nil {
+		return nil, err
+	}
+
+	var part []Value
+	if n >= 0 {
+		part = arr[min(n, len(arr)):]
+	} else {
+		part = arr[:max(len(arr)+n, 0)]
+	}
+
+	if err := evaluator.ChargeElements(len(part)); err != nil {
+		return nil, err
+	}
+	result := make([]Value, len(part))
+	copy(result, part)
+	return &result, nil
+}
+
+// builtinFill creates an array of n copies of value: fill(value, n)
+// Arrays and objects are deep-copied for each element, so changing one
+// element doesn't change the others.
+func builtinFill(evaluator *Evaluator, args map[string]any) (any, error) {
+	arg, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("fill() requires a value and a count")
+	}
+	n, ok := args["1"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("fill() requires a number as second argument")
+	}
+	if n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("fill() count must be a non-negative whole number")
+	}
+	if err := evaluator.ChargeElements(int(min(n, math.MaxInt32))); err != nil {
+		return nil, err
+	}
+
+	value := InterfaceToValue(arg)
+	result := make([]Value, int(n))
+	for i := range result {
+		if value.IsArray() || value.IsObject() {
+			if err := chargeCopy(evaluator, value, true); err != nil {
+				return nil, err
+			}
+			result[i] = deepCopyValue(value, true)
+		} else {
+			result[i] = value
+		}
+	}
+	return &result, nil
+}
+
+// builtinConcat returns a new array of the elements of each array argument,
+// in order: concat(arr1, arr2, ...). None of the arrays are modified.
+func builtinConcat(evaluator *Evaluator, args map[string]any) (any, error) {
+	var arrays [][]Value
+	total := 0
+	for i := 0; ; i++ {
+		arg, ok := args[ArgKey(i)]
+		if !ok {
+			break
+		}
+		arrPtr, ok := arg.(*[]Value)
+		if !ok {
+			return nil, fmt.Errorf("concat() requires arrays, argument %d is %s", i+1, InterfaceToValue(arg).Type.String())
+		}
+		arrays = append(arrays, *arrPtr)
+		total += len(*arrPtr)
+	}
+	if err := evaluator.ChargeElements(total); err != nil {
+		return nil, err
+	}
+
+	result := make([]Value, 0, total)
+	for _, arr := range arrays {
+		result = append(result, arr...)
+	}
+	return &result, nil
+}
+
+// builtinWindow returns sliding windows over an array as new sub-arrays
+// Usage: window(array, size [, step] [, partial=false])
+// Windows start every step elements (default 1). A trailing window shorter
+// than size is dropped unless partial is true.
+func builtinWindow(evaluator *Evaluator, args map[string]any) (any, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, fmt.Errorf("window() requires an array as first argument")
+	}
+	size, ok := GetArg(args, 1, "size").(float64)
+	if !ok || size < 1 || size != math.Trunc(size) {
+		return nil, fmt.Errorf("window() size must be a positive whole number")
+	}
+	step := 1.0
+	if arg := GetArg(args, 2, "step"); arg != nil {
+		if step, ok = arg.(float64); !ok || step < 1 || step != math.Trunc(step) {
+			return nil, fmt.Errorf("window() step must be a positive whole number")
+		}
+	}
+	partial, _ := args["partial"].(bool)
+
+	arr := *arrPtr
+	n := len(arr)
+	var bounds [][2]int
+	total := 0
+	for start := 0; start < n; start += int(step) {
+		end := n
+		if size <= float64(n-start) {
+			end = start + int(size)
+		} else if !partial {
+			break
+		}
+		bounds = append(bounds, [2]int{start, end})
+		total += end - start
+	}
+	if err := evaluator.ChargeElements(len(bounds) + total); err != nil {
+		return nil, err
+	}
+
+	result := make([]Value, len(bounds))
+	for i, b := range bounds {
+		part := make([]Value, b[1]-b[0])
+		copy(part, arr[b[0]:b[1]])
+		result[i] = NewArray(part)
+	}
+	return &result, nil
+}
@@ -0,0 +1,259 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []any
+	}{
+		{"", []any{}},
+		{"one", []any{"one"}},
+		{"one\n", []any{"one"}},
+		{"\n", []any{""}},
+		{"a\r\nb\r\n", []any{"a", "b"}},
+		{"a\n\nb\r\n\r\n", []any{"a", "", "b", ""}},
+		{"mixed\r\nendings\nhere", []any{"mixed", "endings", "here"}},
+	}
+	for _, tt := range tests {
+		out, err := builtinLines(nil, map[string]any{"0": tt.in})
+		if err != nil || !reflect.DeepEqual(out, tt.want) {
+			t.Errorf("lines(%q) = %#v, %v; want %#v", tt.in, out, err, tt.want)
+		}
+	}
+
+	arr := []Value{NewString("a"), NewNumber(2), NewString("")}
+	if out, err := builtinUnlines(nil, map[string]any{"0": &arr}); err != nil || out != "a\n2\n" {
+		t.Errorf("unlines = %q, %v", out, err)
+	}
+}
+
+func TestInsertRemoveAtSplice(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	_, err := script.NewInterpreter().Execute(`
+a = [1, 2, 3]
+if insert(a, 1, "x") != 4 or tostring(a) != "[1, \"x\", 2, 3]" then throw("insert in the middle: " + tostring(a)) end
+insert(a, len(a), "end")
+insert(a, -1, "before end")
+insert(a, 0, "start")
+if tostring(a) != "[\"start\", 1, \"x\", 2, 3, \"before end\", \"end\"]" then throw("insert at the ends: " + tostring(a)) end
+
+b = [10, 20, 30, 40]
+if remove_at(b, 1) != 20 or remove_at(b, -1) != 40 then throw("remove_at should return the removed element") end
+if tostring(b) != "[10, 30]" then throw("remove_at: " + tostring(b)) end
+
+c = [1, 2, 3, 4, 5]
+removed = splice(c, 1, 2, "a", "b", "c")
+if tostring(removed) != "[2, 3]" or tostring(c) != "[1, \"a\", \"b\", \"c\", 4, 5]" then throw("splice replace: " + tostring(c)) end
+if tostring(splice(c, -2)) != "[4, 5]" or len(c) != 4 then throw("splice without delete_count removes to the end") end
+if len(splice(c, 10, 5, "z")) != 0 or c[len(c) - 1] != "z" then throw("splice clamps start and delete_count") end
+splice(c, 0, -3, "first")
+if c[0] != "first" or len(c) != 6 then throw("negative delete_count removes nothing") end
+
+item = {n = 1}
+d = []
+insert(d, 0, item)
+item.n = 2
+if d[0].n != 2 then throw("insert should keep object identity") end
+`)
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+
+	for _, src := range []string{
+		`insert([1], 5, 0)`,
+		`insert([1], -3, 0)`,
+		`insert([1], 0.5, 0)`,
+		`remove_at([], 0)`,
+		`remove_at([1, 2], 2)`,
+		`splice("text", 0)`,
+		`insert(freeze([1]), 0, 0)`,
+		`remove_at(freeze([1]), 0)`,
+		`splice(freeze([1]), 0)`,
+	} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestFillConcat(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	_, err := script.NewInterpreter().Execute(`
+zeros = fill(0, 3)
+if tostring(zeros) != "[0, 0, 0]" then throw("fill: " + tostring(zeros)) end
+if len(fill("x", 0)) != 0 then throw("fill with 0 should be empty") end
+
+rows = fill([], 2)
+push(rows[0], 1)
+if len(rows[1]) != 0 then throw("filled arrays should be separate copies") end
+cells = fill({n = 0, tags = []}, 2)
+cells[0].n = 1
+push(cells[0].tags, "a")
+if cells[1].n != 0 or len(cells[1].tags) != 0 then throw("filled objects should be deep copies") end
+
+a = [1, 2]
+b = [3]
+item = {id = 1}
+c = concat(a, b, [], [item])
+if tostring(c) != "[1, 2, 3, {id=1}]" then throw("concat: " + tostring(c)) end
+if len(a) != 2 or len(b) != 1 then throw("concat should not modify its arguments") end
+item.id = 2
+if c[3].id != 2 then throw("concat should keep element identity") end
+if len(concat()) != 0 then throw("concat with no arrays should be empty") end
+`)
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+
+	for _, src := range []string{
+		`fill(0, -1)`,
+		`fill(0, 1.5)`,
+		`fill(0)`,
+		`concat([1], 2)`,
+	} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`window([1, 2, 3, 4], 2)`, `[[1, 2], [2, 3], [3, 4]]`},
+		{`window([1, 2, 3, 4, 5], 2, 2)`, `[[1, 2], [3, 4]]`},
+		{`window([1, 2, 3, 4, 5], 2, 2, partial=true)`, `[[1, 2], [3, 4], [5]]`},
+		{`window([1, 2, 3], 3, partial=true)`, `[[1, 2, 3], [2, 3], [3]]`},
+		{`window([1, 2], 5)`, `[]`},
+		{`window([1, 2], 5, partial=true)`, `[[1, 2], [2]]`},
+		{`window([], 2)`, `[]`},
+		{`window([1, 2, 3, 4], 1, 3)`, `[[1], [4]]`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{`window([1], 0)`, `window([1], 1.5)`, `window([1], 1, 0)`, `window("ab", 1)`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`range(1, 3)`, `[1, 2, 3]`},
+		{`range(0, 6, 3)`, `[0, 3, 6]`},
+		{`range(3, 1)`, `[3, 2, 1]`},
+		{`range(3, 1, 1)`, `[]`},
+		{`range(6, 0, -3)`, `[6, 3, 0]`},
+		{`range("a", "d")`, `["a", "b", "c", "d"]`},
+		{`range("d", "a")`, `["d", "c", "b", "a"]`},
+		{`range("a", "e", 2)`, `["a", "c", "e"]`},
+		{`range("a", "c", -1)`, `[]`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{`range("ab", "c")`, `range("a", 3)`, `range("a", "c", 0.5)`, `range(1, 3, 0)`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestOrderedObject(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	_, err := script.NewInterpreter().Execute(`
+o = ordered_object()
+o.zeta = 1
+o.alpha = 2
+o["mid"] = 3
+o.zeta = 10
+if tostring(keys(o)) != "[\"zeta\", \"alpha\", \"mid\"]" then throw("keys: " + tostring(keys(o))) end
+if tostring(values(o)) != "[10, 2, 3]" then throw("values: " + tostring(values(o))) end
+seen = ""
+for k in o do seen = seen + k + " " end
+if seen != "zeta alpha mid " then throw("for loop: " + seen) end
+if format_json(o) != "{\"zeta\":10,\"alpha\":2,\"mid\":3}" then throw("format_json: " + format_json(o)) end
+if format_json({outer = o}, pretty=true) != "{\n  \"outer\": {\n    \"zeta\": 10,\n    \"alpha\": 2,\n    \"mid\": 3\n  }\n}" then throw("nested format_json") end
+if tostring(o) != "{zeta=10, alpha=2, mid=3}" then throw("tostring: " + tostring(o)) end
+
+copy = o(extra = true)
+if tostring(keys(copy)) != "[\"zeta\", \"alpha\", \"mid\", \"extra\"]" then throw("constructor copy: " + tostring(keys(copy))) end
+if tostring(keys(deep_copy(o))) != tostring(keys(o)) then throw("deep_copy should keep the order") end
+
+pairs = ordered_object([["b", 1], ["a", 2], ["b", 3]])
+if tostring(pairs) != "{b=3, a=2}" then throw("pairs: " + tostring(pairs)) end
+if tostring(keys(ordered_object({y = 1, x = 2}))) != "[\"x\", \"y\"]" then throw("from an object, keys are sorted") end
+`)
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+
+	for _, src := range []string{`ordered_object("a")`, `ordered_object([["a"]])`, `ordered_object([1])`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestTakeDrop(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	_, err := script.NewInterpreter().Execute(`
+a = [1, 2, 3, 4, 5]
+if tostring(take(a, 2)) != "[1, 2]" or tostring(drop(a, 2)) != "[3, 4, 5]" then throw("positive counts") end
+if tostring(take(a, -2)) != "[4, 5]" or tostring(drop(a, -2)) != "[1, 2, 3]" then throw("negative counts") end
+if tostring(take(a, 0)) != "[]" or tostring(drop(a, 0)) != "[1, 2, 3, 4, 5]" then throw("zero count") end
+if tostring(take(a, 10)) != "[1, 2, 3, 4, 5]" or tostring(drop(a, 10)) != "[]" then throw("counts past the end") end
+if tostring(take(a, -10)) != "[1, 2, 3, 4, 5]" or tostring(drop(a, -10)) != "[]" then throw("negative counts past the start") end
+if tostring(take([], 3)) != "[]" or tostring(drop([], -3)) != "[]" then throw("empty array") end
+
+part = take(a, 2)
+part[0] = 99
+if a[0] != 1 then throw("take should return a copy") end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []string{`take("abc", 1)`, `drop([1], "1")`, `take([1])`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bitwise functions
+//
+// Duso numbers are float64, so bitwise operations work on the integer part of
+// each operand converted to a signed 64-bit integer. Results convert back to
+// float64, so only integers within ±2^53 round-trip exactly.
+
+// maxSafeInteger is the largest integer a float64 represents exactly (2^53)
+const maxSafeInteger = 1 << 53
+
+// bitOperand extracts an argument as an int64, truncating any fractional part
+func bitOperand(args map[string]any, key, name string) (int64, error) {
+	n, ok := args[key].(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s() requires number arguments", name)
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("%s() requires finite numbers", name)
+	}
+	if math.Abs(n) > maxSafeInteger {
+		return 0, fmt.Errorf("%s() operand %v exceeds the safe integer range (±2^53)", name, n)
+	}
+	return int64(n), nil
+}
+
+// bitFoldHelper applies op across two or more integer arguments left to right
+func bitFoldHelper(args map[string]any, name string, op func(a, b int64) int64) (any, error) {
+	if _, ok := args["1"]; !ok {
+		return nil, fmt.Errorf("%s() requires at least two arguments", name)
+	}
+
+	result, err := bitOperand(args, "0", name)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; ; i++ {
+		key := ArgKey(i)
+		if _, ok := args[key]; !ok {
+			break
+		}
+		n, err := bitOperand(args, key, name)
+		if err != nil {
+			return nil, err
+		}
+		result = op(result, n)
+	}
+	return float64(result), nil
+}
+
+// builtinBitAnd returns the bitwise AND of its arguments
+func builtinBitAnd(evaluator *Evaluator, args map[string]any) (any, error) {
+	return bitFoldHelper(args, "bit_and", func(a, b int64) int64 { return a & b })
+}
+
+// builtinBitOr returns the bitwise OR of its arguments
+func builtinBitOr(evaluator *Evaluator, args map[string]any) (any, error) {
+	return bitFoldHelper(args, "bit_or", func(a, b int64) int64 { return a | b })
+}
+
+// builtinBitXor returns the bitwise XOR of its arguments
+func builtinBitXor(evaluator *Evaluator, args map[string]any) (any, error) {
+	return bitFoldHelper(args, "bit_xor", func(a, b int64) int64 { return a ^ b })
+}
+
+// builtinBitNot returns the bitwise complement (two's complement, so bit_not(x) == -x - 1)
+func builtinBitNot(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, err := bitOperand(args, "0", "bit_not")
+	if err != nil {
+		return nil, err
+	}
+	return float64(^n), nil
+}
+
+// shiftArgs validates (value, count) for the shift builtins
+func shiftArgs(args map[string]any, name string) (int64, uint, error) {
+	n, err := bitOperand(args, "0", name)
+	if err != nil {
+		return 0, 0, err
+	}
+	count, ok := args["1"].(float64)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s() requires a number as second argument (shift count)", name)
+	}
+	if count < 0 || count > 63 {
+		return 0, 0, fmt.Errorf("%s() shift count must be between 0 and 63", name)
+	}
+	return n, uint(count), nil
+}
+
+// builtinShiftLeft shifts bits left by count positions
+func builtinShiftLeft(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, count, err := shiftArgs(args, "shift_left")
+	if err != nil {
+		return nil, err
+	}
+	return float64(n << count), nil
+}
+
+// builtinShiftRight shifts bits right by count positions (sign-preserving)
+func builtinShiftRight(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, count, err := shiftArgs(args, "shift_right")
+	if err != nil {
+		return nil, err
+	}
+	return float64(n >> count), nil
+}
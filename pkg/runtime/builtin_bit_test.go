@@ -0,0 +1,54 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestBitwise(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`[bit_and(12, 10), bit_or(12, 10), bit_xor(12, 10)]`, `[8, 14, 6]`},
+		{`[bit_and(15, 7, 3), bit_or(1, 2, 4, 8), bit_xor(1, 3, 7)]`, `[3, 15, 5]`},
+		{`[bit_not(0), bit_not(-6), bit_not(5)]`, `[-1, 5, -6]`},
+		{`[bit_and(-1, 255), bit_or(-16, 3), bit_and(7.9, 3.2)]`, `[255, -13, 3]`},
+		{`[bit_and(9007199254740992, -1), bit_or(-9007199254740992, 0)]`, `[9007199254740992, -9007199254740992]`},
+		{`[shift_left(1, 10), shift_left(5, 0), shift_right(1024, 3), shift_right(-16, 2), shift_right(-1, 1)]`, `[1024, 5, 128, -4, -1]`},
+		// Counts of 53 and up are allowed; results past ±2^53 follow int64
+		// arithmetic and only stay exact while they are powers of two.
+		{`[shift_left(1, 53), shift_left(1, 62), shift_left(1, 63)]`, `[9007199254740992, 4611686018427387904, -9223372036854775808]`},
+		{`[shift_right(9007199254740992, 53), shift_right(9007199254740992, 63), shift_right(-1, 63), shift_right(-9007199254740992, 60)]`, `[1, 0, -1, -1]`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{
+		`bit_and(1)`,
+		`bit_or("1", 2)`,
+		`bit_not(nil)`,
+		`bit_xor(1, 0/0)`,
+		`bit_and(9007199254740994, 1)`,
+		`bit_not(-9007199254740994)`,
+		`shift_left(1, 64)`,
+		`shift_right(1, -1)`,
+		`shift_left(1, "2")`,
+		`shift_right(18014398509481984, 1)`,
+	} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/duso-org/duso/pkg/runtime/color"
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// colorsEnabled reports whether colorize() should emit escape codes for text
+// written to stream ("stdout" or "stderr"): not with -no-color or NO_COLOR
+// set, and not when the stream isn't a terminal. Without terminal info in
+// sys (when duso is embedded) colors stay on.
+func colorsEnabled(stream string) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	sysDs := GetDatastore("sys", nil)
+	if noColor, _ := sysDs.Get("-no-color"); noColor == true {
+		return false
+	}
+	if tty, _ := sysDs.Get("tty"); tty != nil {
+		if streams, ok := tty.(map[string]any); ok && streams[stream] == false {
+			return false
+		}
+	}
+	return true
+}
+
+// builtinColorize wraps text in ANSI styling
+// Usage: colorize(text, style [, force] [, stream = "stderr"])
+// style is one or more space-separated names: "red", "bold green",
+// "bg_blue white". Returns text unchanged when colors are disabled for the
+// stream the text is headed for (stdout unless given), unless force is true.
+func builtinColorize(evaluator *Evaluator, args map[string]any) (any, error) {
+	text, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("colorize() requires text as first argument")
+	}
+	str, ok := text.(string)
+	if !ok {
+		str = script.ValueForDisplay(InterfaceToValue(text))
+	}
+	style, ok := args["1"].(string)
+	if !ok {
+		return nil, fmt.Errorf("colorize() requires a style name as second argument")
+	}
+
+	code, err := color.Codes(style)
+	if err != nil {
+		return nil, fmt.Errorf("colorize(): %w", err)
+	}
+	stream := "stdout"
+	if arg, ok := args["stream"]; ok {
+		if stream, ok = arg.(string); !ok || (stream != "stdout" && stream != "stderr") {
+			return nil, fmt.Errorf("colorize() stream must be \"stdout\" or \"stderr\"")
+		}
+	}
+	if force, _ := GetArg(args, 2, "force").(bool); !force && !colorsEnabled(stream) {
+		return str, nil
+	}
+	return color.Wrap(str, code), nil
+}
+
+// builtinStripANSI removes ANSI escape sequences from a string
+// Usage: strip_ansi(text)
+func builtinStripANSI(evaluator *Evaluator, args map[string]any) (any, error) {
+	text, ok := args["0"].(string)
+	if !ok {
+		return nil, fmt.Errorf("strip_ansi() requires a string argument")
+	}
+	return color.Strip(text), nil
+}
@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/runtime/color"
+)
+
+func TestColorize(t *testing.T) {
+	out, err := builtinColorize(nil, map[string]any{"0": "ok", "1": "bold green", "2": true})
+	if err != nil || out != color.Bold+color.Green+"ok"+color.Reset {
+		t.Errorf("colorize = %q, %v", out, err)
+	}
+	if _, err := builtinColorize(nil, map[string]any{"0": "ok", "1": "sparkly"}); err == nil {
+		t.Error("unknown style should fail")
+	}
+
+	// Each stream follows its own terminal
+	sysDs := GetDatastore("sys", nil)
+	prevTTY, _ := sysDs.Get("tty")
+	sysDs.Set("tty", map[string]any{"stdout": true, "stderr": false})
+	defer sysDs.Set("tty", prevTTY)
+	if out, _ := builtinColorize(nil, map[string]any{"0": "x", "1": "red"}); out != color.Red+"x"+color.Reset {
+		t.Errorf("stdout terminal: colorize = %q", out)
+	}
+	if out, _ := builtinColorize(nil, map[string]any{"0": "x", "1": "red", "stream": "stderr"}); out != "x" {
+		t.Errorf("stderr piped: colorize = %q", out)
+	}
+	if _, err := builtinColorize(nil, map[string]any{"0": "x", "1": "red", "stream": "stdin"}); err == nil {
+		t.Error("unknown stream should fail")
+	}
+
+	// NO_COLOR leaves text plain unless forced
+	t.Setenv("NO_COLOR", "1")
+	if out, _ := builtinColorize(nil, map[string]any{"0": 42.0, "1": "red"}); out != "42" {
+		t.Errorf("NO_COLOR: colorize = %q", out)
+	}
+	if out, _ := builtinColorize(nil, map[string]any{"0": "x", "1": "red", "2": true}); out != color.Red+"x"+color.Reset {
+		t.Errorf("forced: colorize = %q", out)
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "plain"},
+		{color.Bold + color.Red + "hi" + color.Reset, "hi"},
+		{color.RGB(1, 2, 3) + "x" + color.Reset + "\033[2K\033[1A", "x"},
+		{"\033]0;title\007a\033]8;;http://x\033\\b", "ab"},
+	}
+	for _, tt := range tests {
+		if out, err := builtinStripANSI(nil, map[string]any{"0": tt.in}); err != nil || out != tt.want {
+			t.Errorf("strip_ansi(%q) = %q, %v; want %q", tt.in, out, err, tt.want)
+		}
+	}
+}
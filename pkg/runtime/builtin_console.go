@@ -10,7 +10,8 @@ import (
 	"github.com/duso-org/duso/pkg/script"
 )
 
-// builtinPrint prints values to stdout with newline
+// builtinPrint prints values with newline to the execution's OutputWriter,
+// or stdout if none is set
 func builtinPrint(evaluator *Evaluator, args map[string]any) (any, error) {
 	var parts []string
 	for i := 0; ; i++ {
@@ -25,6 +26,19 @@ func builtinPrint(evaluator *Evaluator, args map[string]any) (any, error) {
 	}
 
 	output := strings.Join(parts, " ")
+
+	// Get per-execution OutputWriter from context, fall back to global
+	ctx, ok := script.CurrentRequestContext(evaluator)
+	var writer func(string) error
+	if ok && ctx != nil && ctx.OutputWriter != nil {
+		writer = ctx.OutputWriter
+	} else if globalInterpreter != nil && globalInterpreter.OutputWriter != nil {
+		writer = globalInterpreter.OutputWriter
+	}
+
+	if writer != nil {
+		return nil, writer(output + "\n")
+	}
 	fmt.Println(output)
 	return nil, nil
 }
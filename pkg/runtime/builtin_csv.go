@@ -43,6 +43,9 @@ func builtinParseCSV(evaluator *Evaluator, args map[string]any) (any, error) {
 			return nil, fmt.Errorf("parse_csv() error: %v", err)
 		}
 
+		if err := evaluator.ChargeElements(len(record) + 1); err != nil {
+			return nil, err
+		}
 		recordArray := make([]any, len(record))
 		for i, field := range record {
 			recordArray[i] = field
@@ -33,8 +33,10 @@ import (
 //   - .keys() - Get array of all keys in the store
 //
 // Configuration options:
-//   - persist (string) - Path to persistence file (binary gob format) for snapshots and recovery
+//   - persist (string | bool) - Path to persistence file for snapshots and recovery; true uses <namespace>.<backend>
 //   - persist_interval (number) - Auto-save snapshot interval in seconds
+//   - persist_delay (number) - Without persist_interval, save this many seconds after a change (default: 1)
+//   - backend (string) - "gob" (binary, default), "json" (readable file) or "memory" (never touches disk)
 //   - wal (string) - Path to Write-Ahead Log file for crash durability
 //   - wal_sync_interval (number) - WAL sync mode: 0 = sync every write (durable, default), >0 = batch writes every N seconds (faster)
 //
@@ -95,6 +97,15 @@ func builtinDatastore(evaluator *Evaluator, args map[string]any) (any, error) {
 
 		// Resolve paths BEFORE getting datastore (so timer will use resolved paths)
 		if hasConfig && ResolvePath != nil {
+			// persist = true picks a file named after the namespace
+			if persist, ok := config["persist"].(bool); ok && persist {
+				backend, _ := config["backend"].(string)
+				if backend == "" {
+					backend = "gob"
+				}
+				config["persist"] = defaultPersistPath(namespace, backend)
+			}
+
 			// Resolve persist path
 			if persistPath, ok := config["persist"].(string); ok && persistPath != "" {
 				config["persist"] = ResolvePath(persistPath)
@@ -117,7 +128,9 @@ func builtinDatastore(evaluator *Evaluator, args map[string]any) (any, error) {
 			}
 
 			// Apply config and do recovery (paths already resolved)
-			applyDatastoreConfig(store, config)
+			if err := applyDatastoreConfig(store, config); err != nil {
+				return nil, fmt.Errorf("datastore(\"%s\"): %w", store.namespace, err)
+			}
 		}
 
 		// Create set(key, value) method
@@ -59,21 +59,9 @@ func builtinTimestamp(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 	}
 
-	var loc *time.Location
-	var err error
-
-	// Try to parse as fixed offset (starts with + or -)
-	if len(tzArg) > 0 && (tzArg[0] == '+' || tzArg[0] == '-') {
-		loc, err = parseFixedOffset(tzArg)
-		if err != nil {
-			return nil, fmt.Errorf("timestamp() invalid offset: %v", err)
-		}
-	} else {
-		// Try to load as IANA timezone
-		loc, err = time.LoadLocation(tzArg)
-		if err != nil {
-			return nil, fmt.Errorf("timestamp() unknown timezone %q: %v", tzArg, err)
-		}
+	loc, err := parseLocation(tzArg)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp() %v", err)
 	}
 
 	// Get the offset for this location at current time
@@ -83,6 +71,26 @@ func builtinTimestamp(evaluator *Evaluator, args map[string]any) (any, error) {
 	return float64(utc + int64(offsetSeconds)), nil
 }
 
+// parseLocation resolves a timezone argument: an IANA name such as
+// "Europe/Paris" or a fixed offset such as "+7" or "-5:30"
+func parseLocation(tz string) (*time.Location, error) {
+	// Try to parse as fixed offset (starts with + or -)
+	if len(tz) > 0 && (tz[0] == '+' || tz[0] == '-') {
+		loc, err := parseFixedOffset(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset: %v", err)
+		}
+		return loc, nil
+	}
+
+	// Try to load as IANA timezone
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %v", tz, err)
+	}
+	return loc, nil
+}
+
 // parseFixedOffset parses offset strings like "+7", "-5:30", "+05:30"
 func parseFixedOffset(offset string) (*time.Location, error) {
 	// Handle +/-N or +/-N:MM format
@@ -0,0 +1,116 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/duso-org/duso/pkg/core"
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// rateLimitArgs reads the (fn, ms) arguments shared by debounce() and
+// throttle()
+func rateLimitArgs(name string, args map[string]any) (Value, time.Duration, error) {
+	fn := InterfaceToValue(GetArg(args, 0, "fn"))
+	if !fn.IsFunction() {
+		return fn, 0, fmt.Errorf("%s() requires a function as first argument", name)
+	}
+	ms, ok := GetArg(args, 1, "ms").(float64)
+	if !ok || ms < 0 {
+		return fn, 0, fmt.Errorf("%s() requires a non-negative number of milliseconds as second argument", name)
+	}
+	return fn, time.Duration(ms * float64(time.Millisecond)), nil
+}
+
+// callArgs converts the arguments a wrapper was called with for CallFunction
+func callArgs(args map[string]any) map[string]Value {
+	callArgs := make(map[string]Value, len(args))
+	for k, v := range args {
+		callArgs[k] = InterfaceToValue(v)
+	}
+	return callArgs
+}
+
+// builtinDebounce wraps fn so it only runs once calls have stopped
+// Usage: debounce(fn, ms)
+// Each call restarts an ms timer; when it runs out, fn is called in the
+// background with the arguments of the last call. fn sees outer variables
+// as they were at that call and its assignments stay local; state it shares
+// with the rest of the script belongs in a datastore. Runs never overlap.
+func builtinDebounce(evaluator *Evaluator, args map[string]any) (any, error) {
+	fn, wait, err := rateLimitArgs("debounce", args)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu      sync.Mutex
+		running sync.Mutex // serializes runs of fn
+		timer   *time.Timer
+	)
+	debounced := func(callEval *Evaluator, callArgsIn map[string]any) (any, error) {
+		// Copy now: the caller may change its arrays and objects before fn runs
+		pending := callArgs(script.DeepCopyAny(callArgsIn).(map[string]any))
+		childEval, bgFn := backgroundEvaluator(callEval, fn)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(wait, func() {
+			defer core.RecoverPanic("debounce")
+			running.Lock()
+			defer running.Unlock()
+			if childEval.Context().Err() != nil {
+				return
+			}
+			if _, err := childEval.CallFunction(bgFn, pending); err != nil {
+				reportBackgroundError("debounce", err)
+			}
+		})
+		return nil, nil
+	}
+	return NewGoFunction(debounced), nil
+}
+
+// builtinThrottle wraps fn so it runs at most once per window
+// Usage: throttle(fn, ms)
+// A call runs fn right away and returns its result if ms have passed since
+// fn last ran; otherwise it's dropped and returns nil.
+func builtinThrottle(evaluator *Evaluator, args map[string]any) (any, error) {
+	fn, window, err := rateLimitArgs("throttle", args)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu   sync.Mutex
+		last time.Time
+	)
+	throttled := func(callEval *Evaluator, callArgsIn map[string]any) (any, error) {
+		mu.Lock()
+		now := time.Now()
+		if !last.IsZero() && now.Sub(last) < window {
+			mu.Unlock()
+			return nil, nil
+		}
+		last = now
+		mu.Unlock()
+
+		return callEval.CallFunction(fn, callArgs(callArgsIn))
+	}
+	return NewGoFunction(throttled), nil
+}
+
+// reportBackgroundError prints an error from a function run with no caller
+// to return it to
+func reportBackgroundError(name string, err error) {
+	msg := err.Error()
+	if dusoErr, ok := err.(*script.DusoError); ok {
+		msg = script.FormatErrorWithStack(dusoErr)
+	}
+	fmt.Fprintf(os.Stderr, "%s: error: %s\n", name, msg)
+}
@@ -0,0 +1,78 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestDebounce(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	got, err := script.NewInterpreter().ExecuteWithResult(`
+store = datastore("test_debounce")
+store.set("calls", [])
+record = debounce(function(name) store.push("calls", name) end, 30)
+for i = 1, 5 do
+  record("call" + i)
+  sleep_ms(2)
+end
+sleep_ms(100)
+exit(store.get("calls"))
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{[]any{"call5"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("debounce ran with %#v, want %#v", got, want)
+	}
+}
+
+// TestDebounceWhileCallerRuns runs a debounced function while the script
+// keeps defining variables, which must not race (run with -race)
+func TestDebounceWhileCallerRuns(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	got, err := script.NewInterpreter().ExecuteWithResult(`
+store = datastore("test_debounce_race")
+limit = 3
+function over(n) return n > limit end
+check = debounce(function(n) store.set("over", over(n)) end, 0)
+check(5)
+limit = 10
+a1 = 1 a2 = 2 a3 = 3 a4 = 4
+for i = 1, 2000 do
+  total = i
+end
+sleep_ms(20)
+exit(store.get("over"))
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v (limit as of the call)", got, want)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	got, err := script.NewInterpreter().ExecuteWithResult(`
+double = throttle(function(n) return n * 2 end, 1000)
+exit(double(1), double(2), double(3))
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{float64(2), nil, nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("throttle results = %#v, want %#v", got, want)
+	}
+
+	for _, bad := range []string{`debounce(1, 10)`, `throttle(function() end)`, `throttle(function() end, -1)`} {
+		if _, err := script.NewInterpreter().ExecuteWithResult(bad); err == nil {
+			t.Errorf("%s should fail", bad)
+		}
+	}
+}
@@ -1,27 +1,37 @@
 package runtime
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/duso-org/duso/pkg/script"
+)
 
 // builtinDeepCopy creates a deep copy of a value (recursively copies arrays and objects)
+// With keep_functions=true, functions are kept by reference instead of removed.
 func builtinDeepCopy(evaluator *Evaluator, args map[string]any) (any, error) {
 	val, ok := args["0"]
 	if !ok {
 		return nil, fmt.Errorf("deep_copy() requires 1 argument")
 	}
+	keepFunctions, _ := args["keep_functions"].(bool)
 
 	scriptVal := InterfaceToValue(val)
-	return deepCopyValue(scriptVal), nil
+	if err := chargeCopy(evaluator, scriptVal, true); err != nil {
+		return nil, err
+	}
+	return deepCopyValue(scriptVal, keepFunctions), nil
 }
 
 // deepCopyValue recursively deep copies a value
 // Functions are excluded from deep copy (they don't work out of scope)
-func deepCopyValue(v Value) Value {
+// unless keepFunctions is set, in which case they are shared by reference
+func deepCopyValue(v Value, keepFunctions bool) Value {
 	switch v.Type {
 	case VAL_ARRAY:
 		arr := v.AsArray()
 		newArr := make([]Value, len(arr))
 		for i, item := range arr {
-			newArr[i] = deepCopyValue(item)
+			newArr[i] = deepCopyValue(item, keepFunctions)
 		}
 		return NewArray(newArr)
 
@@ -30,14 +40,18 @@ func deepCopyValue(v Value) Value {
 		newObj := make(map[string]Value)
 		for k, item := range obj {
 			// Skip functions - they don't work out of scope
-			if item.IsFunction() {
+			if item.IsFunction() && !keepFunctions {
 				continue
 			}
-			newObj[k] = deepCopyValue(item)
+			newObj[k] = deepCopyValue(item, keepFunctions)
 		}
+		script.CopyOrder(obj, newObj)
 		return NewObject(newObj)
 
 	case VAL_FUNCTION:
+		if keepFunctions {
+			return v
+		}
 		// Functions are not copied (they don't work out of scope)
 		return NewNil()
 
@@ -46,3 +60,66 @@ func deepCopyValue(v Value) Value {
 		return v
 	}
 }
+
+// builtinShallowCopy copies an array or object one level deep: shallow_copy(value)
+// The copy holds the same elements, so nested arrays and objects are shared
+// with the original. Other values are returned as they are.
+func builtinShallowCopy(evaluator *Evaluator, args map[string]any) (any, error) {
+	val, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("shallow_copy() requires 1 argument")
+	}
+	scriptVal := InterfaceToValue(val)
+	if err := chargeCopy(evaluator, scriptVal, false); err != nil {
+		return nil, err
+	}
+	return shallowCopyValue(scriptVal), nil
+}
+
+// shallowCopyValue copies the top-level array or object of v
+func shallowCopyValue(v Value) Value {
+	switch v.Type {
+	case VAL_ARRAY:
+		arr := v.AsArray()
+		newArr := make([]Value, len(arr))
+		copy(newArr, arr)
+		return NewArray(newArr)
+
+	case VAL_OBJECT:
+		obj := v.AsObject()
+		newObj := make(map[string]Value, len(obj))
+		for k, item := range obj {
+			newObj[k] = item
+		}
+		script.CopyOrder(obj, newObj)
+		return NewObject(newObj)
+
+	default:
+		return v
+	}
+}
+
+// builtinFreeze returns a frozen copy of an array or object: freeze(value [, deep])
+// Nested arrays and objects are frozen too when deep=true.
+func builtinFreeze(evaluator *Evaluator, args map[string]any) (any, error) {
+	val, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("freeze() requires 1 argument")
+	}
+	deep, _ := GetArg(args, 1, "deep").(bool)
+
+	scriptVal := InterfaceToValue(val)
+	if err := chargeCopy(evaluator, scriptVal, deep); err != nil {
+		return nil, err
+	}
+	return Freeze(scriptVal, deep), nil
+}
+
+// builtinIsFrozen reports whether a value is a frozen array or object
+func builtinIsFrozen(evaluator *Evaluator, args map[string]any) (any, error) {
+	val, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("is_frozen() requires 1 argument")
+	}
+	return IsFrozen(InterfaceToValue(val)), nil
+}
@@ -0,0 +1,140 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// TestDeepCopyFunctions checks that functions are removed by default and
+// shared by reference with keep_functions=true.
+func TestDeepCopyFunctions(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "functions removed by default",
+			script: `
+cfg = {name = "app", on_save = function() return "saved" end, hooks = [function() return 1 end]}
+c = deep_copy(cfg)
+if c.on_save != nil then throw("object function should be removed") end
+if c.hooks[0] != nil then throw("array function should become nil") end
+if c.name != "app" then throw("data should be copied") end
+`,
+		},
+		{
+			name: "keep_functions shares functions",
+			script: `
+cfg = {name = "app", on_save = function() return "saved" end, hooks = [function() return 1 end]}
+c = deep_copy(cfg, keep_functions = true)
+if c.on_save() != "saved" then throw("object function should survive") end
+if c.hooks[0]() != 1 then throw("array function should survive") end
+if not deep_equal(c.on_save, cfg.on_save) then throw("function should be the same reference") end
+c.hooks[0] = nil
+if cfg.hooks[0] == nil then throw("arrays should still be copied") end
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := script.NewInterpreter()
+			if _, err := interp.Execute(tt.script); err != nil {
+				t.Fatalf("script failed: %v", err)
+			}
+		})
+	}
+}
+
+// TestFreeze checks that frozen values reject every mutation path and that
+// nested values are only frozen with deep=true.
+func TestFreeze(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	src := `
+function fails(fn)
+  try
+    fn()
+  catch (e)
+    return true
+  end
+  return false
+end
+
+cfg = freeze({name = "app", ports = [80], count = 1})
+if not is_frozen(cfg) then throw("cfg should be frozen") end
+if not fails(function() cfg.name = "x" end) then throw("property assign") end
+if not fails(function() cfg["name"] = "x" end) then throw("index assign") end
+if not fails(function() cfg.count += 1 end) then throw("compound assign") end
+if is_frozen(cfg.ports) then throw("shallow freeze should leave nested arrays mutable") end
+push(cfg.ports, 443)
+
+// Passed as a value, is_frozen takes objects as copied maps
+check_frozen = is_frozen
+if not check_frozen(cfg) then throw("is_frozen as a value") end
+if map([cfg], is_frozen)[0] != true then throw("is_frozen as a map() callback") end
+if not all([cfg], is_frozen) then throw("is_frozen as an all() callback") end
+if any([{a = 1}], is_frozen) then throw("is_frozen callback on a mutable object") end
+
+arr = freeze([1, 2])
+if not fails(function() push(arr, 3) end) then throw("push") end
+if not fails(function() pop(arr) end) then throw("pop") end
+if not fails(function() shift(arr) end) then throw("shift") end
+if not fails(function() unshift(arr, 0) end) then throw("unshift") end
+if not fails(function() arr[0] = 9 end) then throw("array index assign") end
+if len(arr) != 2 or arr[0] != 1 then throw("array changed") end
+
+deep = freeze({db = {host = "x"}, tags = ["a"]}, deep = true)
+if not fails(function() deep.db.host = "y" end) then throw("deep object") end
+if not fails(function() push(deep.tags, "b") end) then throw("deep array") end
+
+original = {a = 1}
+frozen = freeze(original)
+original.a = 2
+if frozen.a != 1 then throw("freeze should copy") end
+copy = deep_copy(frozen)
+copy.a = 3
+if is_frozen(copy) then throw("deep_copy should be mutable") end
+`
+	interp := script.NewInterpreter()
+	if _, err := interp.Execute(src); err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+}
+
+// TestShallowCopy checks that the top level is copied and nested values are
+// shared with the original.
+func TestShallowCopy(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	_, err := script.NewInterpreter().Execute(`
+original = {name = "app", tags = ["a"], db = {port = 1}}
+c = shallow_copy(original)
+c.name = "copy"
+if original.name != "app" then throw("top-level keys should be copied") end
+push(c.tags, "b")
+c.db.port = 2
+if len(original.tags) != 2 then throw("nested arrays should be shared") end
+if original.db.port != 2 then throw("nested objects should be shared") end
+
+list = [1, [2]]
+copy = shallow_copy(list)
+push(copy, 3)
+push(copy[1], 4)
+if len(list) != 2 then throw("array should be copied") end
+if len(list[1]) != 2 then throw("nested array should be shared") end
+
+frozen = freeze([1, 2])
+thawed = shallow_copy(frozen)
+push(thawed, 3)
+if is_frozen(frozen) != true or len(frozen) != 2 then throw("original should stay frozen") end
+
+if shallow_copy("text") != "text" or shallow_copy(nil) != nil then throw("other values are returned as-is") end
+`)
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// builtinDeepEqual reports whether two values are structurally equal
+// (recursively compares arrays and objects; functions compare by reference)
+func builtinDeepEqual(evaluator *Evaluator, args map[string]any) (any, error) {
+	a, okA := args["0"]
+	b, okB := args["1"]
+	if !okA || !okB {
+		return nil, fmt.Errorf("deep_equal() requires 2 arguments")
+	}
+
+	return deepEqual(InterfaceToValue(a), InterfaceToValue(b)), nil
+}
+
+// deepEqual is the one structural comparison used by deep_equal(), watch()
+// and datastore wait conditions, so they can't disagree about equality
+func deepEqual(a, b Value) bool {
+	return deepEqualValues(a, b, make(map[[2]uintptr]bool))
+}
+
+// deepEqualValues recursively compares two values.
+// seen records array/object pairs already being compared so that
+// self-referencing structures terminate instead of recursing forever.
+func deepEqualValues(a, b Value, seen map[[2]uintptr]bool) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	switch a.Type {
+	case VAL_NIL:
+		return true
+	case VAL_NUMBER:
+		return a.AsNumber() == b.AsNumber()
+	case VAL_STRING:
+		return a.AsString() == b.AsString()
+	case VAL_BOOL:
+		return a.AsBool() == b.AsBool()
+
+	case VAL_ARRAY:
+		pa, pb := a.AsArrayPtr(), b.AsArrayPtr()
+		if pa == pb {
+			return true
+		}
+		key := [2]uintptr{reflect.ValueOf(pa).Pointer(), reflect.ValueOf(pb).Pointer()}
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+
+		arrA, arrB := *pa, *pb
+		if len(arrA) != len(arrB) {
+			return false
+		}
+		for i := range arrA {
+			if !deepEqualValues(arrA[i], arrB[i], seen) {
+				return false
+			}
+		}
+		return true
+
+	case VAL_OBJECT:
+		objA, objB := a.AsObject(), b.AsObject()
+		key := [2]uintptr{reflect.ValueOf(objA).Pointer(), reflect.ValueOf(objB).Pointer()}
+		if key[0] == key[1] || seen[key] {
+			return true
+		}
+		seen[key] = true
+
+		if len(objA) != len(objB) {
+			return false
+		}
+		for k, va := range objA {
+			vb, ok := objB[k]
+			if !ok || !deepEqualValues(va, vb, seen) {
+				return false
+			}
+		}
+		return true
+
+	case VAL_FUNCTION:
+		// Functions are equal only if they are the same function
+		if fa, ok := a.Data.(*script.ScriptFunction); ok {
+			fb, ok := b.Data.(*script.ScriptFunction)
+			return ok && fa == fb
+		}
+		ra, rb := reflect.ValueOf(a.Data), reflect.ValueOf(b.Data)
+		return ra.Kind() == reflect.Func && rb.Kind() == reflect.Func && ra.Pointer() == rb.Pointer()
+
+	case script.VAL_BINARY:
+		ba, okA := a.Data.(*script.BinaryValue)
+		bb, okB := b.Data.(*script.BinaryValue)
+		return okA && okB && bytes.Equal(*ba.Data, *bb.Data)
+
+	case script.VAL_REGEX:
+		ra, okA := a.Data.(*script.RegexValue)
+		rb, okB := b.Data.(*script.RegexValue)
+		return okA && okB && ra.Pattern == rb.Pattern
+
+	case script.VAL_CODE:
+		ca, okA := a.Data.(*script.CodeValue)
+		cb, okB := b.Data.(*script.CodeValue)
+		return okA && okB && ca.Source == cb.Source
+
+	case script.VAL_ERROR:
+		ea, okA := a.Data.(*script.ErrorValue)
+		eb, okB := b.Data.(*script.ErrorValue)
+		return okA && okB && deepEqualValues(ea.Message, eb.Message, seen)
+	}
+
+	return false
+}
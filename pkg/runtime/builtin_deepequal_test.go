@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// TestDeepEqual checks structural comparison, including cycles and the
+// non-plain value types.
+func TestDeepEqual(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	src := `
+if not deep_equal({a = [1, {b = "x"}], c = nil}, {c = nil, a = [1, {b = "x"}]}) then throw("nested objects") end
+if deep_equal([1, 2], [2, 1]) then throw("array order matters") end
+if deep_equal({a = 1}, {a = 1, b = 2}) then throw("extra key") end
+if deep_equal(1, "1") then throw("types differ") end
+if not deep_equal(nil, nil) then throw("nil") end
+
+f = function() return 1 end
+if not deep_equal([f], [f]) then throw("same function") end
+if deep_equal(function() return 1 end, function() return 1 end) then throw("different functions") end
+if not deep_equal([upper], [upper]) then throw("same builtin") end
+
+a = {name = "a"}
+a.self = a
+b = {name = "a"}
+b.self = b
+if not deep_equal(a, b) then throw("cycles should compare equal") end
+b.name = "b"
+if deep_equal(a, b) then throw("cycles with different data") end
+`
+	if _, err := script.NewInterpreter().Execute(src); err != nil {
+		t.Fatal(err)
+	}
+
+	// The datastore's wait conditions share the same comparison
+	if !valuesEqual(map[string]any{"a": []any{float64(1)}}, map[string]any{"a": []any{1}}) {
+		t.Error("valuesEqual should compare objects structurally")
+	}
+	if valuesEqual(map[string]any{"a": float64(1)}, map[string]any{"a": float64(2)}) {
+		t.Error("valuesEqual treated different objects as equal")
+	}
+}
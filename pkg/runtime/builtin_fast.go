@@ -17,6 +17,9 @@ func registerFastBuiltins() {
 	script.RegisterBuiltinFast("push", fastPush)
 	script.RegisterBuiltinFast("pop", fastPop)
 	script.RegisterBuiltinFast("shift", fastShift)
+	script.RegisterBuiltinFast("insert", arrayInsert) // shared with the map form, see builtin_array.go
+	script.RegisterBuiltinFast("remove_at", arrayRemoveAt)
+	script.RegisterBuiltinFast("splice", arraySplice)
 	script.RegisterBuiltinFast("len", fastLen)
 	script.RegisterBuiltinFast("keys", fastKeys)
 	script.RegisterBuiltinFast("values", fastValues)
@@ -29,6 +32,45 @@ func registerFastBuiltins() {
 	script.RegisterBuiltinFast("min", fastMin)
 	script.RegisterBuiltinFast("max", fastMax)
 	script.RegisterBuiltinFast("fibonacci", fastFibonacci)
+	script.RegisterBuiltinFast("is_frozen", fastIsFrozen)
+	script.RegisterBuiltinFast("inspect", fastInspect)
+	script.RegisterBuiltinFast("shallow_copy", fastShallowCopy)
+}
+
+// fastShallowCopy shares the caller's nested objects; the map path can only
+// pass it copies of them
+func fastShallowCopy(evaluator *Evaluator, args []Value) (Value, error) {
+	if len(args) < 1 {
+		return script.NewNil(), fmt.Errorf("shallow_copy() requires 1 argument")
+	}
+	if err := chargeCopy(evaluator, args[0], false); err != nil {
+		return script.NewNil(), err
+	}
+	return shallowCopyValue(args[0]), nil
+}
+
+// fastInspect formats the caller's value itself, without converting it first
+func fastInspect(evaluator *Evaluator, args []Value) (Value, error) {
+	if len(args) < 1 {
+		return script.NewNil(), fmt.Errorf("inspect() requires a value")
+	}
+	var depthArg any
+	if len(args) > 1 {
+		depthArg = script.ValueToInterface(args[1])
+	}
+	depth, err := inspectDepth(depthArg)
+	if err != nil {
+		return script.NewNil(), err
+	}
+	return script.NewString(script.InspectValue(args[0], depth)), nil
+}
+
+// fastIsFrozen sees the caller's object itself; the map path only gets a copy
+func fastIsFrozen(evaluator *Evaluator, args []Value) (Value, error) {
+	if len(args) < 1 {
+		return script.NewNil(), fmt.Errorf("is_frozen() requires 1 argument")
+	}
+	return script.NewBool(IsFrozen(args[0])), nil
 }
 
 func fastFibonacci(evaluator *Evaluator, args []Value) (Value, error) {
@@ -47,6 +89,12 @@ func fastPush(evaluator *Evaluator, args []Value) (Value, error) {
 		return script.NewNil(), fmt.Errorf("push() requires an array as first argument")
 	}
 	arrPtr := args[0].AsArrayPtr()
+	if IsFrozenArray(arrPtr) {
+		return script.NewNil(), fmt.Errorf("push() cannot modify a frozen array")
+	}
+	if err := evaluator.ChargeElements(len(args) - 1); err != nil {
+		return script.NewNil(), err
+	}
 	*arrPtr = append(*arrPtr, args[1:]...)
 	return script.NewNumber(float64(len(*arrPtr))), nil
 }
@@ -56,6 +104,9 @@ func fastPop(evaluator *Evaluator, args []Value) (Value, error) {
 		return script.NewNil(), fmt.Errorf("pop() requires an array as first argument")
 	}
 	arrPtr := args[0].AsArrayPtr()
+	if IsFrozenArray(arrPtr) {
+		return script.NewNil(), fmt.Errorf("pop() cannot modify a frozen array")
+	}
 	arr := *arrPtr
 	if len(arr) == 0 {
 		return script.NewNil(), nil
@@ -70,6 +121,9 @@ func fastShift(evaluator *Evaluator, args []Value) (Value, error) {
 		return script.NewNil(), fmt.Errorf("shift() requires an array as first argument")
 	}
 	arrPtr := args[0].AsArrayPtr()
+	if IsFrozenArray(arrPtr) {
+		return script.NewNil(), fmt.Errorf("shift() cannot modify a frozen array")
+	}
 	arr := *arrPtr
 	if len(arr) == 0 {
 		return script.NewNil(), nil
@@ -106,6 +160,16 @@ func fastKeys(evaluator *Evaluator, args []Value) (Value, error) {
 		return script.NewNil(), fmt.Errorf("keys() requires an object")
 	}
 	obj := args[0].AsObject()
+	if err := evaluator.ChargeElements(len(obj)); err != nil {
+		return script.NewNil(), err
+	}
+	if order, ok := script.OrderedKeys(obj); ok {
+		keys := make([]Value, len(order))
+		for i, k := range order {
+			keys[i] = script.NewString(k)
+		}
+		return script.NewArray(keys), nil
+	}
 	keys := make([]Value, 0, len(obj))
 	for k := range obj {
 		keys = append(keys, script.NewString(k))
@@ -118,6 +182,16 @@ func fastValues(evaluator *Evaluator, args []Value) (Value, error) {
 		return script.NewNil(), fmt.Errorf("values() requires an object")
 	}
 	obj := args[0].AsObject()
+	if err := evaluator.ChargeElements(len(obj)); err != nil {
+		return script.NewNil(), err
+	}
+	if order, ok := script.OrderedKeys(obj); ok {
+		values := make([]Value, len(order))
+		for i, k := range order {
+			values[i] = obj[k]
+		}
+		return script.NewArray(values), nil
+	}
 	values := make([]Value, 0, len(obj))
 	for _, v := range obj {
 		values = append(values, v)
@@ -191,6 +265,13 @@ func fastMinMax(args []Value, name string, isMin bool) (Value, error) {
 	if len(args) == 0 {
 		return script.NewNil(), fmt.Errorf("%s() requires at least one argument", name)
 	}
+	if len(args) == 1 && args[0].IsArray() {
+		best, err := arrayMinMax(args[0].AsArray(), name, isMin)
+		if err != nil {
+			return script.NewNil(), err
+		}
+		return script.NewNumber(best), nil
+	}
 	// Mirrors minMaxHelper: stop at the first non-number rather than erroring
 	var best float64
 	var set bool
@@ -3,6 +3,8 @@ package runtime
 import (
 	"fmt"
 	"sort"
+
+	"github.com/duso-org/duso/pkg/script"
 )
 
 // builtinMap applies a function to each element of an array (returns new array)
@@ -24,6 +26,9 @@ func builtinMap(evaluator *Evaluator, args map[string]any) (any, error) {
 	fn := InterfaceToValue(fnArg)
 
 	arr := *arrPtr
+	if err := evaluator.ChargeElements(len(arr)); err != nil {
+		return nil, err
+	}
 	result := make([]Value, len(arr))
 	for i, item := range arr {
 		// Use public CallFunction API
@@ -38,6 +43,50 @@ func builtinMap(evaluator *Evaluator, args map[string]any) (any, error) {
 	return &result, nil
 }
 
+// builtinFlatMap maps each element and flattens the results one level
+// (returns new array). A function result that isn't an array is added as a
+// single element.
+func builtinFlatMap(evaluator *Evaluator, args map[string]any) (any, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, fmt.Errorf("flat_map() requires an array as first argument")
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, fmt.Errorf("flat_map() requires a function as second argument")
+	}
+
+	if evaluator == nil {
+		return nil, fmt.Errorf("flat_map() requires evaluator context")
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	arr := *arrPtr
+	result := make([]Value, 0, len(arr))
+	for _, item := range arr {
+		retVal, err := evaluator.CallFunction(fn, map[string]Value{"0": item})
+		if err != nil {
+			return nil, fmt.Errorf("error in flat_map function: %w", err)
+		}
+		if !retVal.IsArray() {
+			if err := evaluator.ChargeElements(1); err != nil {
+				return nil, err
+			}
+			result = append(result, retVal)
+			continue
+		}
+		elements := retVal.AsArray()
+		if err := evaluator.ChargeElements(len(elements)); err != nil {
+			return nil, err
+		}
+		result = append(result, elements...)
+	}
+
+	return &result, nil
+}
+
 // builtinFilter keeps only array elements that match a predicate (returns new array)
 func builtinFilter(evaluator *Evaluator, args map[string]any) (any, error) {
 	arrPtr, ok := args["0"].(*[]Value)
@@ -70,6 +119,9 @@ func builtinFilter(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 	}
 
+	if err := evaluator.ChargeElements(len(result)); err != nil {
+		return nil, err
+	}
 	return &result, nil
 }
 
@@ -115,6 +167,51 @@ func builtinReduce(evaluator *Evaluator, args map[string]any) (any, error) {
 	return accumulator, nil
 }
 
+// builtinScan is reduce() that keeps every intermediate accumulator value
+// (returns new array, without the initial value)
+func builtinScan(evaluator *Evaluator, args map[string]any) (any, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, fmt.Errorf("scan() requires an array as first argument")
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, fmt.Errorf("scan() requires a function as second argument")
+	}
+
+	if evaluator == nil {
+		return nil, fmt.Errorf("scan() requires evaluator context")
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	accumulator := NewNil()
+	if initVal, ok := args["2"]; ok {
+		accumulator = InterfaceToValue(initVal)
+	}
+
+	arr := *arrPtr
+	if err := evaluator.ChargeElements(len(arr)); err != nil {
+		return nil, err
+	}
+	result := make([]Value, len(arr))
+	for i, item := range arr {
+		fnArgs := map[string]Value{
+			"0": accumulator,
+			"1": item,
+		}
+		retVal, err := evaluator.CallFunction(fn, fnArgs)
+		if err != nil {
+			return nil, fmt.Errorf("error in scan function: %w", err)
+		}
+		accumulator = retVal
+		result[i] = accumulator
+	}
+
+	return &result, nil
+}
+
 // builtinSort sorts an array with optional comparison function
 func builtinSort(evaluator *Evaluator, args map[string]any) (any, error) {
 	arrPtr, ok := args["0"].(*[]Value)
@@ -124,6 +221,9 @@ func builtinSort(evaluator *Evaluator, args map[string]any) (any, error) {
 
 	// Make a copy to avoid modifying original
 	arr := *arrPtr
+	if err := evaluator.ChargeElements(len(arr)); err != nil {
+		return nil, err
+	}
 	result := make([]Value, len(arr))
 	copy(result, arr)
 
@@ -184,3 +284,335 @@ func builtinSort(evaluator *Evaluator, args map[string]any) (any, error) {
 
 	return &result, nil
 }
+
+// builtinMinBy returns the array element whose selector result is smallest
+func builtinMinBy(evaluator *Evaluator, args map[string]any) (any, error) {
+	return extremeByHelper(evaluator, args, "min_by", true)
+}
+
+// builtinMaxBy returns the array element whose selector result is largest
+func builtinMaxBy(evaluator *Evaluator, args map[string]any) (any, error) {
+	return extremeByHelper(evaluator, args, "max_by", false)
+}
+
+// extremeByHelper implements min_by/max_by. Selector results must all be
+// numbers or all be strings. Returns nil for an empty array; on ties the
+// first element wins.
+func extremeByHelper(evaluator *Evaluator, args map[string]any, name string, isMin bool) (any, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, fmt.Errorf("%s() requires an array as first argument", name)
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, fmt.Errorf("%s() requires a function as second argument", name)
+	}
+
+	if evaluator == nil {
+		return nil, fmt.Errorf("%s() requires evaluator context", name)
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	arr := *arrPtr
+	if len(arr) == 0 {
+		return nil, nil
+	}
+
+	var best, bestKey Value
+	for i, item := range arr {
+		key, err := evaluator.CallFunction(fn, map[string]Value{"0": item})
+		if err != nil {
+			return nil, fmt.Errorf("error in %s function: %w", name, err)
+		}
+		if !key.IsNumber() && !key.IsString() {
+			return nil, fmt.Errorf("%s() selector must return a number or string, got %s", name, key.Type)
+		}
+		if i == 0 {
+			best, bestKey = item, key
+			continue
+		}
+		if key.Type != bestKey.Type {
+			return nil, fmt.Errorf("%s() selector returned mixed types (%s and %s)", name, bestKey.Type, key.Type)
+		}
+
+		var better bool
+		if key.IsNumber() {
+			if isMin {
+				better = key.AsNumber() < bestKey.AsNumber()
+			} else {
+				better = key.AsNumber() > bestKey.AsNumber()
+			}
+		} else {
+			if isMin {
+				better = key.AsString() < bestKey.AsString()
+			} else {
+				better = key.AsString() > bestKey.AsString()
+			}
+		}
+		if better {
+			best, bestKey = item, key
+		}
+	}
+
+	return best, nil
+}
+
+// builtinAny reports whether any element is truthy (under the predicate, if given)
+func builtinAny(evaluator *Evaluator, args map[string]any) (any, error) {
+	return predicateHelper(evaluator, args, "any", true)
+}
+
+// builtinAll reports whether every element is truthy (under the predicate, if given)
+func builtinAll(evaluator *Evaluator, args map[string]any) (any, error) {
+	return predicateHelper(evaluator, args, "all", false)
+}
+
+// predicateHelper implements any/all with short-circuit evaluation: iteration
+// stops at the first element whose truthiness equals stopOn. Without a
+// predicate the elements themselves are tested.
+func predicateHelper(evaluator *Evaluator, args map[string]any, name string, stopOn bool) (any, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, fmt.Errorf("%s() requires an array as first argument", name)
+	}
+
+	var fn Value
+	fnArg, hasFn := args["1"]
+	if hasFn {
+		if evaluator == nil {
+			return nil, fmt.Errorf("%s() requires evaluator context", name)
+		}
+		fn = InterfaceToValue(fnArg)
+	}
+
+	for _, item := range *arrPtr {
+		test := item
+		if hasFn {
+			retVal, err := evaluator.CallFunction(fn, map[string]Value{"0": item})
+			if err != nil {
+				return nil, fmt.Errorf("error in %s function: %w", name, err)
+			}
+			test = retVal
+		}
+		if test.IsTruthy() == stopOn {
+			return stopOn, nil
+		}
+	}
+
+	return !stopOn, nil
+}
+
+// builtinTakeWhile returns the leading elements for which the predicate is truthy
+func builtinTakeWhile(evaluator *Evaluator, args map[string]any) (any, error) {
+	arr, cut, err := whilePrefixHelper(evaluator, args, "take_while")
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluator.ChargeElements(cut); err != nil {
+		return nil, err
+	}
+	result := make([]Value, cut)
+	copy(result, arr[:cut])
+	return &result, nil
+}
+
+// builtinDropWhile returns the elements after the leading run for which the predicate is truthy
+func builtinDropWhile(evaluator *Evaluator, args map[string]any) (any, error) {
+	arr, cut, err := whilePrefixHelper(evaluator, args, "drop_while")
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluator.ChargeElements(len(arr) - cut); err != nil {
+		return nil, err
+	}
+	result := make([]Value, len(arr)-cut)
+	copy(result, arr[cut:])
+	return &result, nil
+}
+
+// whilePrefixHelper returns the array and the length of its leading run of
+// elements passing the predicate. The predicate is not called past the first failure.
+func whilePrefixHelper(evaluator *Evaluator, args map[string]any, name string) ([]Value, int, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, 0, fmt.Errorf("%s() requires an array as first argument", name)
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, 0, fmt.Errorf("%s() requires a function as second argument", name)
+	}
+
+	if evaluator == nil {
+		return nil, 0, fmt.Errorf("%s() requires evaluator context", name)
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	arr := *arrPtr
+	for i, item := range arr {
+		retVal, err := evaluator.CallFunction(fn, map[string]Value{"0": item})
+		if err != nil {
+			return nil, 0, fmt.Errorf("error in %s function: %w", name, err)
+		}
+		if !retVal.IsTruthy() {
+			return arr, i, nil
+		}
+	}
+	return arr, len(arr), nil
+}
+
+// builtinPartition splits an array into [matching, non_matching] in a single pass
+func builtinPartition(evaluator *Evaluator, args map[string]any) (any, error) {
+	arrPtr, ok := args["0"].(*[]Value)
+	if !ok {
+		return nil, fmt.Errorf("partition() requires an array as first argument")
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, fmt.Errorf("partition() requires a function as second argument")
+	}
+
+	if evaluator == nil {
+		return nil, fmt.Errorf("partition() requires evaluator context")
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	if err := evaluator.ChargeElements(len(*arrPtr)); err != nil {
+		return nil, err
+	}
+	var matching, rest []Value
+	for _, item := range *arrPtr {
+		retVal, err := evaluator.CallFunction(fn, map[string]Value{"0": item})
+		if err != nil {
+			return nil, fmt.Errorf("error in partition function: %w", err)
+		}
+		if retVal.IsTruthy() {
+			matching = append(matching, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+
+	result := []Value{NewArray(matching), NewArray(rest)}
+	return &result, nil
+}
+
+// builtinMapValues applies a function to each value of an object (returns new object)
+func builtinMapValues(evaluator *Evaluator, args map[string]any) (any, error) {
+	obj, ok := args["0"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("map_values() requires an object as first argument")
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, fmt.Errorf("map_values() requires a function as second argument")
+	}
+
+	if evaluator == nil {
+		return nil, fmt.Errorf("map_values() requires evaluator context")
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	if err := evaluator.ChargeElements(len(obj)); err != nil {
+		return nil, err
+	}
+	result := make(map[string]Value, len(obj))
+	for k, v := range obj {
+		fnArgs := map[string]Value{"0": InterfaceToValue(v), "1": NewString(k)}
+		retVal, err := evaluator.CallFunction(fn, fnArgs)
+		if err != nil {
+			return nil, fmt.Errorf("error in map_values function: %w", err)
+		}
+		result[k] = retVal
+	}
+
+	script.CopyOrder(obj, result)
+	return NewObject(result), nil
+}
+
+// builtinMapKeys applies a function to each key of an object (returns new object).
+// Two keys mapping to the same new key is an error, since object order is unspecified.
+func builtinMapKeys(evaluator *Evaluator, args map[string]any) (any, error) {
+	obj, ok := args["0"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("map_keys() requires an object as first argument")
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, fmt.Errorf("map_keys() requires a function as second argument")
+	}
+
+	if evaluator == nil {
+		return nil, fmt.Errorf("map_keys() requires evaluator context")
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	if err := evaluator.ChargeElements(len(obj)); err != nil {
+		return nil, err
+	}
+	result := make(map[string]Value, len(obj))
+	sources := make(map[string]string, len(obj))
+	for k, v := range obj {
+		val := InterfaceToValue(v)
+		fnArgs := map[string]Value{"0": NewString(k), "1": val}
+		retVal, err := evaluator.CallFunction(fn, fnArgs)
+		if err != nil {
+			return nil, fmt.Errorf("error in map_keys function: %w", err)
+		}
+		newKey := retVal.String()
+		if prev, exists := sources[newKey]; exists {
+			return nil, fmt.Errorf("map_keys() maps both %q and %q to key %q", prev, k, newKey)
+		}
+		sources[newKey] = k
+		result[newKey] = val
+	}
+
+	return NewObject(result), nil
+}
+
+// builtinFilterObject keeps only object entries for which fn(key, value) is truthy (returns new object)
+func builtinFilterObject(evaluator *Evaluator, args map[string]any) (any, error) {
+	obj, ok := args["0"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("filter_object() requires an object as first argument")
+	}
+
+	fnArg, ok := args["1"]
+	if !ok {
+		return nil, fmt.Errorf("filter_object() requires a function as second argument")
+	}
+
+	if evaluator == nil {
+		return nil, fmt.Errorf("filter_object() requires evaluator context")
+	}
+
+	fn := InterfaceToValue(fnArg)
+
+	result := make(map[string]Value)
+	for k, v := range obj {
+		val := InterfaceToValue(v)
+		fnArgs := map[string]Value{"0": NewString(k), "1": val}
+		retVal, err := evaluator.CallFunction(fn, fnArgs)
+		if err != nil {
+			return nil, fmt.Errorf("error in filter_object function: %w", err)
+		}
+		if retVal.IsTruthy() {
+			result[k] = val
+		}
+	}
+
+	if err := evaluator.ChargeElements(len(result)); err != nil {
+		return nil, err
+	}
+	script.CopyOrder(obj, result)
+	return NewObject(result), nil
+}
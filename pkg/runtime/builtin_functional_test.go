@@ -0,0 +1,158 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// TestFunctionalBuiltins runs each case as a script that throws on a wrong
+// result, so failures report the script's own message.
+func TestFunctionalBuiltins(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "min_by and max_by return the element",
+			script: `
+items = [{name = "coffee", price = 4.5}, {name = "tea", price = 3}, {name = "juice", price = 3}]
+cheapest = min_by(items, function(i) return i.price end)
+if cheapest.name != "tea" then throw("min_by: got " + cheapest.name) end
+priciest = max_by(items, function(i) return i.price end)
+if priciest.name != "coffee" then throw("max_by: got " + priciest.name) end
+if min_by([], function(x) return x end) != nil then throw("min_by: empty should be nil") end
+`,
+		},
+		{
+			name: "any and all short-circuit",
+			script: `
+calls = 0
+found = any([1, 2, 3, 4], function(n)
+  calls += 1
+  return n == 2
+end)
+if not found or calls != 2 then throw("any: found=" + found + " calls=" + calls) end
+calls = 0
+ok = all([1, -1, 2], function(n)
+  calls += 1
+  return n > 0
+end)
+if ok or calls != 2 then throw("all: ok=" + ok + " calls=" + calls) end
+if not all([]) or any([]) then throw("empty array: all should be true, any false") end
+if not any([false, true]) then throw("any without predicate") end
+`,
+		},
+		{
+			name: "map_values and map_keys build new objects",
+			script: `
+prices = {coffee = 4, tea = 3}
+sale = map_values(prices, function(p, k) return k + ":" + p * 2 end)
+if sale.tea != "tea:6" or prices.tea != 3 then throw("map_values: got " + sale.tea) end
+upper_keys = map_keys(prices, function(k) return upper(k) end)
+if upper_keys.COFFEE != 4 or upper_keys.coffee != nil then throw("map_keys: wrong keys") end
+collided = false
+try
+  map_keys({a = 1, A = 2}, function(k) return lower(k) end)
+catch (e)
+  collided = true
+end
+if not collided then throw("map_keys: collision should throw") end
+`,
+		},
+		{
+			name: "filter_object keeps matching entries",
+			script: `
+stock = {coffee = 4, tea = 0, juice = 2}
+in_stock = filter_object(stock, function(k, v) return v > 0 end)
+if len(keys(in_stock)) != 2 or in_stock.coffee != 4 or in_stock.tea != nil then throw("filter_object: got " + tostring(in_stock)) end
+if stock.tea != 0 then throw("filter_object: source should be unchanged") end
+by_key = filter_object(stock, function(k) return starts_with(k, "j") end)
+if len(keys(by_key)) != 1 or by_key.juice != 2 then throw("filter_object: key-only predicate") end
+if len(keys(filter_object({}, function(k, v) return true end))) != 0 then throw("filter_object: empty object") end
+failed = false
+try
+  filter_object([1, 2], function(k, v) return true end)
+catch (e)
+  failed = contains(tostring(e), "requires an object")
+end
+if not failed then throw("filter_object: an array should be rejected") end
+`,
+		},
+		{
+			name: "flat_map flattens one level",
+			script: `
+words = flat_map(["a b", "c"], function(line) return split(line, " ") end)
+if tostring(words) != "[\"a\", \"b\", \"c\"]" then throw("flat_map: got " + tostring(words)) end
+mixed = flat_map([1, 2, 3], function(n)
+  if n == 2 then return [] end
+  if n == 3 then return [[3]] end
+  return n
+end)
+if tostring(mixed) != "[1, [3]]" then throw("flat_map: scalars and nesting, got " + tostring(mixed)) end
+`,
+		},
+		{
+			name: "take_while and drop_while stop at the first failure",
+			script: `
+calls = 0
+small = take_while([1, 2, 5, 1], function(n)
+  calls += 1
+  return n < 3
+end)
+if tostring(small) != "[1, 2]" or calls != 3 then throw("take_while: got " + tostring(small) + " after " + calls + " calls") end
+if tostring(drop_while([1, 2, 5, 1], function(n) return n < 3 end)) != "[5, 1]" then throw("drop_while: keeps the rest, even later passes") end
+if tostring(take_while([1, 2], function(n) return true end)) != "[1, 2]" then throw("take_while: all pass") end
+if tostring(drop_while([1, 2], function(n) return n end)) != "[]" then throw("drop_while: all pass") end
+if tostring(take_while([], function(n) return true end)) != "[]" then throw("take_while: empty array") end
+failed = false
+try
+  take_while([1], function(n) throw("inner") end)
+catch (e)
+  failed = contains(tostring(e), "inner")
+end
+if not failed then throw("take_while: predicate errors should propagate") end
+`,
+		},
+		{
+			name: "partition splits in one pass",
+			script: `
+parts = partition([1, 2, 3, 4, 5], function(n) return n % 2 == 0 end)
+if tostring(parts[0]) != "[2, 4]" or tostring(parts[1]) != "[1, 3, 5]" then throw("partition: got " + tostring(parts)) end
+empty = partition([], function(n) return true end)
+if len(empty) != 2 or len(empty[0]) != 0 or len(empty[1]) != 0 then throw("partition: empty array gives " + tostring(empty)) end
+none = partition([1, 2], function(n) return false end)
+if len(none[0]) != 0 or tostring(none[1]) != "[1, 2]" then throw("partition: nothing matches") end
+push(empty[0], 1)
+if len(empty[1]) != 0 then throw("partition: empty halves must be separate arrays") end
+failed = false
+try
+  partition("abc", function(n) return true end)
+catch (e)
+  failed = contains(tostring(e), "requires an array")
+end
+if not failed then throw("partition: a string should be rejected") end
+`,
+		},
+		{
+			name: "scan keeps each accumulator",
+			script: `
+totals = scan([1, 2, 3, 4], function(acc, n) return acc + n end, 0)
+if tostring(totals) != "[1, 3, 6, 10]" then throw("scan: got " + tostring(totals)) end
+if totals[len(totals) - 1] != reduce([1, 2, 3, 4], function(acc, n) return acc + n end, 0) then throw("scan: last should match reduce") end
+if len(scan([], function(acc, n) return acc + n end, 0)) != 0 then throw("scan: empty array") end
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := script.NewInterpreter()
+			if _, err := interp.Execute(tt.script); err != nil {
+				t.Fatalf("script failed: %v", err)
+			}
+		})
+	}
+}
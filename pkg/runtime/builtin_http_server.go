@@ -3,6 +3,7 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -87,6 +88,7 @@ func builtinHTTPServer(evaluator *Evaluator, args map[string]any) (any, error) {
 		FileStatter:           globalInterpreter.FileStatter, // Use host's FileStatter capability
 		DirReader:             globalInterpreter.DirReader,   // Use host's DirReader capability
 		Interpreter:           globalInterpreter,             // Store interpreter for optional script path
+		metrics:               NewHTTPMetrics(),
 	}
 
 	// Parse port
@@ -137,39 +139,7 @@ func builtinHTTPServer(evaluator *Evaluator, args map[string]any) (any, error) {
 
 	// Parse default files
 	if defaultFiles, ok := config["default"]; ok {
-		switch v := defaultFiles.(type) {
-		case nil:
-			// nil clears defaults
-			server.DefaultFiles = nil
-		case string:
-			if v == "" {
-				// Empty string clears defaults
-				server.DefaultFiles = nil
-			} else {
-				// Parse comma-separated list of default files
-				server.DefaultFiles = nil // Clear first
-				parts := strings.Split(v, ",")
-				for _, part := range parts {
-					part = strings.TrimSpace(part)
-					if part != "" {
-						server.DefaultFiles = append(server.DefaultFiles, part)
-					}
-				}
-			}
-		case []any:
-			if len(v) == 0 {
-				// Empty array clears defaults
-				server.DefaultFiles = nil
-			} else {
-				// Array of strings
-				server.DefaultFiles = nil // Clear first
-				for _, item := range v {
-					if itemStr, ok := item.(string); ok {
-						server.DefaultFiles = append(server.DefaultFiles, itemStr)
-					}
-				}
-			}
-		}
+		server.DefaultFiles = parseDefaultFiles(defaultFiles)
 	}
 
 	// Parse cache control header
@@ -247,20 +217,34 @@ func builtinHTTPServer(evaluator *Evaluator, args map[string]any) (any, error) {
 				server.JWT.Enabled = enabled
 			}
 
-			// Parse secret (for HS256)
+			// Parse secret (for HS256/HS384/HS512)
 			if secret, ok := jwtMap["secret"].(string); ok {
 				server.JWT.Secret = secret
 			}
 
-			// Parse RS256 private key (PEM-encoded)
+			// Parse RSA or EC private key (PEM-encoded); rs256_private_key is the older name
 			if privKey, ok := jwtMap["rs256_private_key"].(string); ok {
 				server.JWT.RS256PrivateKey = privKey
 			}
+			if privKey, ok := jwtMap["private_key"].(string); ok {
+				server.JWT.RS256PrivateKey = privKey
+			}
 
-			// Parse RS256 public key (PEM-encoded)
+			// Parse RSA or EC public key (PEM-encoded); rs256_public_key is the older name
 			if pubKey, ok := jwtMap["rs256_public_key"].(string); ok {
 				server.JWT.RS256PublicKey = pubKey
 			}
+			if pubKey, ok := jwtMap["public_key"].(string); ok {
+				server.JWT.RS256PublicKey = pubKey
+			}
+
+			// Parse expected issuer and audience claims
+			if issuer, ok := jwtMap["issuer"].(string); ok {
+				server.JWT.Issuer = issuer
+			}
+			if audience, ok := jwtMap["audience"].(string); ok {
+				server.JWT.Audience = audience
+			}
 
 			// Parse required flag
 			if required, ok := jwtMap["required"].(bool); ok {
@@ -368,16 +352,52 @@ func builtinHTTPServer(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 	}
 
+	// access_log = true/false, or the format: "plain" or "json"
 	if accessLog, ok := config["access_log"]; ok {
-		if accessLogBool, ok := accessLog.(bool); ok {
-			server.AccessLog = accessLogBool
+		switch v := accessLog.(type) {
+		case bool:
+			server.AccessLog = v
+		case string:
+			if v != "plain" && v != "json" {
+				return nil, fmt.Errorf("http_server() access_log must be true, false, \"plain\" or \"json\", got %q", v)
+			}
+			server.AccessLog = true
+			server.AccessLogFormat = v
+		}
+	}
+
+	// access_log_file appends log lines to a file instead of stderr
+	if logFile, ok := config["access_log_file"].(string); ok && logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("http_server() cannot open access_log_file: %w", err)
+		}
+		server.AccessLogWriter = f
+	}
+
+	// metrics = true serves counters at /metrics, or give the path
+	switch m := config["metrics"].(type) {
+	case bool:
+		if m {
+			server.MetricsPath = "/metrics"
 		}
+	case string:
+		if !strings.HasPrefix(m, "/") {
+			return nil, fmt.Errorf("http_server() metrics path must start with /, got %q", m)
+		}
+		server.MetricsPath = m
 	}
 
 	if staticCacheControl, ok := config["static_cache_control"]; ok {
 		server.StaticCacheControl = fmt.Sprintf("%v", staticCacheControl)
 	}
 
+	if renderMarkdown, ok := config["render_markdown"]; ok {
+		if renderBool, ok := renderMarkdown.(bool); ok {
+			server.RenderMarkdown = renderBool
+		}
+	}
+
 	// Create route() method
 	routeFn := script.NewGoFunction(func(evaluator *script.Evaluator, routeArgs map[string]any) (any, error) {
 		// Get the directory of the calling script for path resolution
@@ -494,14 +514,115 @@ func builtinHTTPServer(evaluator *Evaluator, args map[string]any) (any, error) {
 			}
 		}
 
+		// Optional per-route overrides
+		var opts StaticOptions
+		if optArg, ok := staticArgs["2"]; ok && optArg != nil {
+			optMap, ok := optArg.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("static() options must be an object")
+			}
+			if defaultFiles, ok := optMap["default_files"]; ok {
+				opts.DefaultFiles = parseDefaultFiles(defaultFiles)
+				if opts.DefaultFiles == nil {
+					opts.DefaultFiles = []string{}
+				}
+			}
+			if listing, ok := optMap["directory_listing"]; ok {
+				listingBool, ok := listing.(bool)
+				if !ok {
+					return nil, fmt.Errorf("static() directory_listing must be a boolean")
+				}
+				opts.DirectoryListing = &listingBool
+			}
+		}
+
 		// Register the static route
-		return nil, server.StaticRoute(path, absDir)
+		return nil, server.StaticRoute(path, absDir, opts)
+	})
+
+	// Create reload() method
+	reloadFn := script.NewGoFunction(func(evaluator *script.Evaluator, reloadArgs map[string]any) (any, error) {
+		// Optional handler paths, as separate args or one array
+		var paths []string
+		for i := 0; ; i++ {
+			arg, ok := reloadArgs[ArgKey(i)]
+			if !ok {
+				break
+			}
+			switch p := arg.(type) {
+			case string:
+				paths = append(paths, p)
+			case *[]script.Value:
+				for _, v := range *p {
+					if !v.IsString() {
+						return nil, fmt.Errorf("reload() paths must be strings, got %v", v.Type)
+					}
+					paths = append(paths, v.AsString())
+				}
+			default:
+				return nil, fmt.Errorf("reload() paths must be strings, got %T", arg)
+			}
+		}
+		server.Reload(paths...)
+		return nil, nil
+	})
+
+	// Create on_shutdown() method
+	onShutdownFn := script.NewGoFunction(func(evaluator *script.Evaluator, hookArgs map[string]any) (any, error) {
+		fn := script.InterfaceToValue(hookArgs["0"])
+		if !fn.IsFunction() {
+			return nil, fmt.Errorf("on_shutdown() requires a function")
+		}
+		// Runs on the goroutine blocked in start(), which is the one that
+		// registered the hook in the usual server script
+		server.OnShutdown(func() error {
+			_, err := evaluator.CallFunction(fn, nil)
+			return err
+		})
+		return nil, nil
+	})
+
+	// Create metrics() method
+	metricsFn := script.NewGoFunction(func(evaluator *script.Evaluator, metricsArgs map[string]any) (any, error) {
+		return server.metrics.Snapshot(), nil
 	})
 
 	// Return server object with methods
 	return map[string]any{
-		"route":  routeFn,
-		"static": staticFn,
-		"start":  startFn,
+		"route":       routeFn,
+		"static":      staticFn,
+		"start":       startFn,
+		"reload":      reloadFn,
+		"metrics":     metricsFn,
+		"on_shutdown": onShutdownFn,
 	}, nil
 }
+
+// parseDefaultFiles reads a default-files setting: a comma-separated string
+// or an array of strings. nil, "" and [] all mean no default files.
+func parseDefaultFiles(v any) []string {
+	var files []string
+	switch v := v.(type) {
+	case string:
+		// Parse comma-separated list of default files
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" {
+				files = append(files, part)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if itemStr, ok := item.(string); ok {
+				files = append(files, itemStr)
+			}
+		}
+	case *[]script.Value:
+		for _, item := range *v {
+			if item.IsString() {
+				files = append(files, item.AsString())
+			}
+		}
+	}
+	return files
+}
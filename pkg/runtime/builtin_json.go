@@ -1,8 +1,11 @@
 package runtime
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"strings"
 
 	"github.com/duso-org/duso/pkg/script"
@@ -22,7 +25,7 @@ func builtinParseJSON(evaluator *Evaluator, args map[string]any) (any, error) {
 	}
 
 	// Convert JSON types to Duso-friendly types
-	return jsonToValue(result), nil
+	return chargedValue(evaluator, jsonToValue(result))
 }
 
 // jsonToValue recursively converts JSON-unmarshaled values to Duso values
@@ -63,9 +66,12 @@ func builtinFormatJSON(evaluator *Evaluator, args map[string]any) (any, error) {
 
 	value := args["0"]
 
-	// Check if indent is specified
+	// Check if indent is specified; pretty=true defaults to 2 spaces
 	var indent string
-	if indentArg, ok := args["1"]; ok {
+	if pretty, _ := args["pretty"].(bool); pretty {
+		indent = "  "
+	}
+	if indentArg := GetArg(args, 1, "indent"); indentArg != nil {
 		switch i := indentArg.(type) {
 		case float64:
 			// Create indent string (spaces)
@@ -100,15 +106,106 @@ func builtinFormatJSON(evaluator *Evaluator, args map[string]any) (any, error) {
 
 // valueToJSON recursively converts Duso values to JSON-marshable values
 // Functions, errors, and code types (when alone) return nil to skip them
+// An array or object that contains itself becomes "[Circular]" where it repeats
 func valueToJSON(v any) any {
+	return valueToJSONPath(v, make(map[uintptr]bool))
+}
+
+// jsonNumber converts NaN and ±Inf, which JSON can't represent, to nil (null),
+// as JavaScript's JSON.stringify does
+func jsonNumber(n float64) any {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil
+	}
+	return n
+}
+
+// jsonCircular replaces an array or object inside itself
+const jsonCircular = "[Circular]"
+
+// jsonContainerID identifies a non-empty array or object, or returns 0 for
+// anything else; empty ones can't contain themselves
+func jsonContainerID(v any) uintptr {
+	switch c := v.(type) {
+	case *[]Value:
+		if len(*c) == 0 {
+			return 0
+		}
+	case []any:
+		if len(c) == 0 {
+			return 0
+		}
+	case map[string]any:
+		if len(c) == 0 {
+			return 0
+		}
+	case map[string]Value:
+		if len(c) == 0 {
+			return 0
+		}
+	default:
+		return 0
+	}
+	return reflect.ValueOf(v).Pointer()
+}
+
+// jsonObject is an ordered object on its way to JSON. encoding/json writes
+// maps with sorted keys, so it writes its own members in order.
+type jsonObject struct {
+	keys   []string
+	values []any
+}
+
+// orderedJSONObject converts the members of an ordered object, leaving out
+// those that convert to nil as plain objects do
+func orderedJSONObject(keys []string, convert func(string) any) jsonObject {
+	obj := jsonObject{}
+	for _, k := range keys {
+		if converted := convert(k); converted != nil {
+			obj.keys = append(obj.keys, k)
+			obj.values = append(obj.values, converted)
+		}
+	}
+	return obj
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// valueToJSONPath converts v; path holds the arrays and objects being
+// converted around it
+func valueToJSONPath(v any, path map[uintptr]bool) any {
 	// Handle ValueRef wrappers (used for code and error types)
 	if ref, ok := v.(*script.ValueRef); ok {
-		return valueToJSON(ref.Val)
+		return valueToJSONPath(ref.Val, path)
 	}
 
 	// Handle Value structs
 	if val, ok := v.(script.Value); ok {
 		switch val.Type {
+		case script.VAL_NUMBER:
+			// Numbers are stored inline in Num, not in Data
+			return jsonNumber(val.Num)
 		case script.VAL_FUNCTION:
 			return "<function>" // Stringify functions
 		case script.VAL_ERROR:
@@ -134,39 +231,54 @@ func valueToJSON(v any) any {
 			return nil
 		default:
 			// Recurse on the wrapped data
-			return valueToJSON(val.Data)
+			return valueToJSONPath(val.Data, path)
 		}
 	}
 
+	// Mark arrays and objects while their elements are converted
+	if id := jsonContainerID(v); id != 0 {
+		if path[id] {
+			return jsonCircular
+		}
+		path[id] = true
+		defer delete(path, id)
+	}
+
 	switch val := v.(type) {
 	case *[]Value:
 		// Handle array of Value structs (preserve nils for sparse arrays)
 		arr := make([]any, len(*val))
 		for i, item := range *val {
-			arr[i] = valueToJSON(item)
+			arr[i] = valueToJSONPath(item, path)
 		}
 		return arr
 	case []any:
 		// Handle generic array (preserve nils for sparse arrays)
 		arr := make([]any, len(val))
 		for i, item := range val {
-			arr[i] = valueToJSON(item)
+			arr[i] = valueToJSONPath(item, path)
 		}
 		return arr
 	case map[string]any:
 		// Handle generic object
+		if keys, ok := script.OrderedKeys(val); ok {
+			return orderedJSONObject(keys, func(k string) any { return valueToJSONPath(val[k], path) })
+		}
 		obj := make(map[string]any)
 		for k, item := range val {
-			if converted := valueToJSON(item); converted != nil {
+			if converted := valueToJSONPath(item, path); converted != nil {
 				obj[k] = converted
 			}
 		}
 		return obj
 	case map[string]Value:
 		// Handle Value object
+		if keys, ok := script.OrderedKeys(val); ok {
+			return orderedJSONObject(keys, func(k string) any { return valueToJSONPath(val[k], path) })
+		}
 		obj := make(map[string]any)
 		for k, item := range val {
-			if converted := valueToJSON(item); converted != nil {
+			if converted := valueToJSONPath(item, path); converted != nil {
 				obj[k] = converted
 			}
 		}
@@ -176,7 +288,7 @@ func valueToJSON(v any) any {
 	case bool:
 		return val
 	case float64:
-		return val
+		return jsonNumber(val)
 	case string:
 		return val
 	case *ScriptFunction:
@@ -0,0 +1,67 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// TestFormatJSON checks serialization of nested values and the indent options.
+func TestFormatJSON(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "numbers inside arrays and objects",
+			script: `
+got = format_json({a = [1, 2.5, "x"]})
+if got != "{\"a\":[1,2.5,\"x\"]}" then throw("got " + got) end
+`,
+		},
+		{
+			name: "pretty defaults to two spaces",
+			script: `
+got = format_json([1], pretty = true)
+if got != "[\n  1\n]" then throw("got " + got) end
+got = format_json([1], 4, pretty = true)
+if got != "[\n    1\n]" then throw("explicit indent should win, got " + got) end
+`,
+		},
+		{
+			name: "circular references",
+			script: `
+node = {name = "root"}
+node.self = node
+got = format_json(node)
+if got != "{\"name\":\"root\",\"self\":\"[Circular]\"}" then throw("object cycle: " + got) end
+list = [1]
+push(list, list)
+got = format_json({list = list})
+if got != "{\"list\":[1,\"[Circular]\"]}" then throw("array cycle: " + got) end
+shared = {v = 1}
+got = format_json([shared, shared])
+if got != "[{\"v\":1},{\"v\":1}]" then throw("a shared value isn't a cycle: " + got) end
+`,
+		},
+		{
+			name: "non-finite numbers become null",
+			script: `
+inf = pow(10, 400)
+got = format_json({a = [sqrt(-1), inf, -inf, 1], b = sqrt(-1)})
+if got != "{\"a\":[null,null,null,1]}" then throw("got " + got) end
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := script.NewInterpreter()
+			if _, err := interp.Execute(tt.script); err != nil {
+				t.Fatalf("script failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/duso-org/duso/pkg/runtime/jwt"
+)
+
+// JWT functions usable anywhere, not just in HTTP handlers. The handler
+// versions (res.sign_jwt(), req.verify_jwt()) take their keys from the
+// server's jwt config; these take the key as an argument.
+
+// jwtKeys turns a key argument into jwt.Keys. A PEM block is only ever used
+// as an RSA/EC key and anything else only as an HMAC secret, so a token
+// claiming HS256 can't be verified with a public key as the secret.
+func jwtKeys(key string) jwt.Keys {
+	if strings.Contains(key, "-----BEGIN") {
+		return jwt.Keys{PrivateKey: key, PublicKey: key}
+	}
+	return jwt.Keys{Secret: key}
+}
+
+// builtinSignJWT signs claims into a token
+// Usage: sign_jwt(claims, key [, options])
+// key is the HMAC secret for HS* or a PEM private key for RS*/ES*.
+// options: algorithm (default "HS256"), expires_in seconds (default 3600,
+// applied unless claims already has "exp"). A number is taken as expires_in.
+func builtinSignJWT(evaluator *Evaluator, args map[string]any) (any, error) {
+	claims, ok := args["0"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("sign_jwt() requires claims object as first argument")
+	}
+	key, ok := args["1"].(string)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("sign_jwt() requires a secret or private key as second argument")
+	}
+
+	algorithm := "HS256"
+	var expiresIn *float64
+	switch opt := args["2"].(type) {
+	case float64:
+		expiresIn = &opt
+	case map[string]any:
+		if alg, ok := opt["algorithm"].(string); ok {
+			algorithm = alg
+		}
+		if exp, ok := opt["expires_in"].(float64); ok {
+			expiresIn = &exp
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("sign_jwt() options must be an object or expires_in seconds")
+	}
+
+	tokenClaims := make(map[string]any, len(claims)+1)
+	for k, v := range claims {
+		tokenClaims[k] = v
+	}
+	if expiresIn != nil {
+		tokenClaims["exp"] = float64(time.Now().Unix()) + *expiresIn
+	} else if _, ok := tokenClaims["exp"]; !ok {
+		tokenClaims["exp"] = float64(time.Now().Unix()) + 3600
+	}
+
+	token, err := jwt.Sign(tokenClaims, algorithm, jwtKeys(key))
+	if err != nil {
+		return nil, fmt.Errorf("sign_jwt(): %w", err)
+	}
+	return token, nil
+}
+
+// builtinVerifyJWT verifies a token and returns its claims
+// Usage: verify_jwt(token, key [, options])
+// key is the HMAC secret for HS* or a PEM public key for RS*/ES*.
+// options: issuer, audience, throw_errors. Returns nil for a token that
+// fails verification unless throw_errors is true.
+func builtinVerifyJWT(evaluator *Evaluator, args map[string]any) (any, error) {
+	token, ok := args["0"].(string)
+	if !ok {
+		return nil, fmt.Errorf("verify_jwt() requires a token string as first argument")
+	}
+	key, ok := args["1"].(string)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("verify_jwt() requires a secret or public key as second argument")
+	}
+
+	var expect jwt.Expect
+	throwErrors := false
+	switch opt := args["2"].(type) {
+	case map[string]any:
+		if iss, ok := opt["issuer"].(string); ok {
+			expect.Issuer = iss
+		}
+		if aud, ok := opt["audience"].(string); ok {
+			expect.Audience = aud
+		}
+		throwErrors, _ = opt["throw_errors"].(bool)
+	case nil:
+	default:
+		return nil, fmt.Errorf("verify_jwt() options must be an object")
+	}
+
+	claims, err := jwt.Verify(token, jwtKeys(key), expect)
+	if err != nil {
+		if throwErrors {
+			return nil, fmt.Errorf("verify_jwt(): %w", err)
+		}
+		return nil, nil
+	}
+	return chargedValue(evaluator, claims)
+}
+
+// builtinDecodeJWT returns a token's claims without verifying it
+// Usage: decode_jwt(token)
+func builtinDecodeJWT(evaluator *Evaluator, args map[string]any) (any, error) {
+	token, ok := args["0"].(string)
+	if !ok {
+		return nil, fmt.Errorf("decode_jwt() requires a token string")
+	}
+	_, claims, err := jwt.Decode(token)
+	if err != nil {
+		return nil, fmt.Errorf("decode_jwt(): %w", err)
+	}
+	return chargedValue(evaluator, claims)
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/runtime/jwt"
+)
+
+// TestJWTBuiltins round-trips the standalone sign_jwt(), verify_jwt() and
+// decode_jwt() builtins.
+func TestJWTBuiltins(t *testing.T) {
+	token, err := builtinSignJWT(nil, map[string]any{"0": map[string]any{"sub": "u1"}, "1": "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := builtinVerifyJWT(nil, map[string]any{"0": token, "1": "s3cret"})
+	if err != nil || claims.(map[string]any)["sub"] != "u1" {
+		t.Errorf("verify = %v, %v", claims, err)
+	}
+	if _, ok := claims.(map[string]any)["exp"]; !ok {
+		t.Error("sign_jwt() should add a default exp")
+	}
+	if claims, _ := builtinVerifyJWT(nil, map[string]any{"0": token, "1": "other"}); claims != nil {
+		t.Errorf("wrong secret should return nil, got %v", claims)
+	}
+	opts := map[string]any{"audience": "api", "throw_errors": true}
+	if _, err := builtinVerifyJWT(nil, map[string]any{"0": token, "1": "s3cret", "2": opts}); err == nil || !strings.Contains(err.Error(), "wrong audience") {
+		t.Errorf("throw_errors: %v", err)
+	}
+
+	decoded, err := builtinDecodeJWT(nil, map[string]any{"0": token})
+	if err != nil || decoded.(map[string]any)["sub"] != "u1" {
+		t.Errorf("decode = %v, %v", decoded, err)
+	}
+	if _, err := builtinDecodeJWT(nil, map[string]any{"0": "not.a.jwt"}); err == nil {
+		t.Error("decode_jwt() of garbage should fail")
+	}
+
+	// A PEM key is never used as an HMAC secret, so an HS256 token signed
+	// with the public key doesn't pass as genuine
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	pub := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	forged, _ := jwt.Sign(map[string]any{"sub": "admin"}, "HS256", jwt.Keys{Secret: pub})
+	if claims, _ := builtinVerifyJWT(nil, map[string]any{"0": forged, "1": pub}); claims != nil {
+		t.Errorf("HS256 token signed with a public key verified: %v", claims)
+	}
+
+	priv := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+	rs, err := builtinSignJWT(nil, map[string]any{"0": map[string]any{"sub": "u2"}, "1": priv, "2": map[string]any{"algorithm": "RS256"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(rs.(string), ".")[0])
+	if !strings.Contains(string(header), `"RS256"`) {
+		t.Errorf("header = %s", header)
+	}
+	if claims, err := builtinVerifyJWT(nil, map[string]any{"0": rs, "1": pub}); err != nil || claims == nil {
+		t.Errorf("RS256 verify = %v, %v", claims, err)
+	}
+}
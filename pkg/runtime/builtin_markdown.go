@@ -198,6 +198,9 @@ func applyOptionsMap(opts *markdown.Options, m map[string]any) {
 	if v, ok := m["code_language"].(bool); ok {
 		opts.CodeLanguage = v
 	}
+	if v, ok := m["sanitize"].(bool); ok {
+		opts.Sanitize = v
+	}
 }
 
 // applyThemeMap overrides theme fields from a duso option map.
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"math"
 	mathrand "math/rand"
-	"time"
 )
 
 // Math functions
@@ -41,7 +40,27 @@ func builtinAbs(evaluator *Evaluator, args map[string]any) (any, error) {
 	return nil, fmt.Errorf("abs() requires a number")
 }
 
-// minMaxHelper computes min/max of numeric arguments
+// arrayMinMax computes min/max of an array passed as the only argument, as
+// in max(scores). Unlike the varargs form, every element must be a number.
+func arrayMinMax(arr []Value, name string, isMin bool) (float64, error) {
+	if len(arr) == 0 {
+		return 0, fmt.Errorf("%s() requires a non-empty array", name)
+	}
+	var best float64
+	for i, v := range arr {
+		if !v.IsNumber() {
+			return 0, fmt.Errorf("%s() requires an array of numbers, element %d is %s", name, i, v.Type)
+		}
+		n := v.AsNumber()
+		if i == 0 || (isMin && n < best) || (!isMin && n > best) {
+			best = n
+		}
+	}
+	return best, nil
+}
+
+// minMaxHelper computes min/max of numeric arguments, or of the elements of
+// a single array argument
 func minMaxHelper(args map[string]any, isMin bool) (any, error) {
 	if len(args) == 0 {
 		name := "min()"
@@ -50,6 +69,13 @@ func minMaxHelper(args map[string]any, isMin bool) (any, error) {
 		}
 		return nil, fmt.Errorf("%s requires at least one argument", name)
 	}
+	if arrPtr, ok := args["0"].(*[]Value); ok && len(args) == 1 {
+		name := "min"
+		if !isMin {
+			name = "max"
+		}
+		return arrayMinMax(*arrPtr, name, isMin)
+	}
 
 	var result float64
 	var set bool
@@ -113,6 +139,86 @@ func builtinPow(evaluator *Evaluator, args map[string]any) (any, error) {
 	return math.Pow(x, y), nil
 }
 
+// divModArgs reads the two numbers of div() and mod()
+func divModArgs(name string, args map[string]any) (float64, float64, error) {
+	a, ok := args["0"].(float64)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s() requires a number as first argument", name)
+	}
+	b, ok := args["1"].(float64)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s() requires a number as second argument", name)
+	}
+	if b == 0 {
+		return 0, 0, fmt.Errorf("%s() division by zero", name)
+	}
+	return a, b, nil
+}
+
+// builtinDiv returns floor(a / b), rounding toward negative infinity
+func builtinDiv(evaluator *Evaluator, args map[string]any) (any, error) {
+	a, b, err := divModArgs("div", args)
+	if err != nil {
+		return nil, err
+	}
+	return math.Floor(a / b), nil
+}
+
+// builtinMod returns the Euclidean remainder of a / b, which is never
+// negative: 0 <= mod(a, b) < abs(b). Unlike %, it works on fractions.
+func builtinMod(evaluator *Evaluator, args map[string]any) (any, error) {
+	a, b, err := divModArgs("mod", args)
+	if err != nil {
+		return nil, err
+	}
+	r := math.Mod(a, b)
+	if r < 0 {
+		r += math.Abs(b)
+		if r == math.Abs(b) {
+			r = 0 // a tiny negative a rounded up to b
+		}
+	}
+	return r + 0, nil // + 0 turns -0 into 0
+}
+
+// numberCheck reads the argument of is_nan(), is_finite() and is_infinite().
+// Anything other than a number is neither NaN, finite nor infinite.
+func numberCheck(name string, args map[string]any) (float64, bool, error) {
+	arg, present := args["0"]
+	if !present {
+		return 0, false, fmt.Errorf("%s() requires a value", name)
+	}
+	n, ok := arg.(float64)
+	return n, ok, nil
+}
+
+// builtinIsNaN reports whether x is NaN, e.g. from sqrt(-1)
+func builtinIsNaN(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, ok, err := numberCheck("is_nan", args)
+	if err != nil {
+		return nil, err
+	}
+	return ok && math.IsNaN(n), nil
+}
+
+// builtinIsFinite reports whether x is a number other than NaN and ±Inf
+func builtinIsFinite(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, ok, err := numberCheck("is_finite", args)
+	if err != nil {
+		return nil, err
+	}
+	return ok && !math.IsNaN(n) && !math.IsInf(n, 0), nil
+}
+
+// builtinIsInfinite reports whether x is +Inf or -Inf
+func builtinIsInfinite(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, ok, err := numberCheck("is_infinite", args)
+	if err != nil {
+		return nil, err
+	}
+	return ok && math.IsInf(n, 0), nil
+}
+
 // builtinClamp clamps value between min and max
 func builtinClamp(evaluator *Evaluator, args map[string]any) (any, error) {
 	val, ok := args["0"].(float64)
@@ -139,6 +245,66 @@ func builtinClamp(evaluator *Evaluator, args map[string]any) (any, error) {
 	return val, nil
 }
 
+// builtinClamp01 constrains a number to the range [0, 1]
+func builtinClamp01(evaluator *Evaluator, args map[string]any) (any, error) {
+	val, ok := args["0"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("clamp01() requires a number")
+	}
+	return math.Max(0, math.Min(1, val)), nil
+}
+
+// interpArgs reads the numbers of lerp(), inv_lerp() and remap(), whose
+// parameters are named in order
+func interpArgs(name string, args map[string]any, params ...string) ([]float64, error) {
+	nums := make([]float64, len(params))
+	for i, param := range params {
+		n, ok := GetArg(args, i, param).(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s() requires a number for %s", name, param)
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+// builtinLerp interpolates linearly from a to b: lerp(a, b, t)
+// t isn't clamped, so values outside [0, 1] extrapolate.
+func builtinLerp(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, err := interpArgs("lerp", args, "a", "b", "t")
+	if err != nil {
+		return nil, err
+	}
+	return n[0] + (n[1]-n[0])*n[2], nil
+}
+
+// builtinInvLerp returns where v falls between a and b: inv_lerp(a, b, v)
+// It's the inverse of lerp(), so lerp(a, b, inv_lerp(a, b, v)) == v.
+func builtinInvLerp(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, err := interpArgs("inv_lerp", args, "a", "b", "v")
+	if err != nil {
+		return nil, err
+	}
+	if n[0] == n[1] {
+		return nil, fmt.Errorf("inv_lerp() requires a and b to differ")
+	}
+	return (n[2] - n[0]) / (n[1] - n[0]), nil
+}
+
+// builtinRemap maps v from one range to another:
+// remap(v, in_min, in_max, out_min, out_max)
+func builtinRemap(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, err := interpArgs("remap", args, "v", "in_min", "in_max", "out_min", "out_max")
+	if err != nil {
+		return nil, err
+	}
+	if n[1] == n[2] {
+		return nil, fmt.Errorf("remap() requires in_min and in_max to differ")
+	}
+	t := (n[0] - n[1]) / (n[2] - n[1])
+	return n[3] + (n[4]-n[3])*t, nil
+}
+
 // Trigonometric functions
 
 // builtinSin returns sine of angle in radians
@@ -204,6 +370,57 @@ func builtinAtan2(evaluator *Evaluator, args map[string]any) (any, error) {
 	return math.Atan2(y, x), nil
 }
 
+// builtinToRadians converts an angle in degrees to radians
+func builtinToRadians(evaluator *Evaluator, args map[string]any) (any, error) {
+	if arg, ok := args["0"].(float64); ok {
+		return arg * math.Pi / 180, nil
+	}
+	return nil, fmt.Errorf("to_radians() requires a number (angle in degrees)")
+}
+
+// builtinToDegrees converts an angle in radians to degrees
+func builtinToDegrees(evaluator *Evaluator, args map[string]any) (any, error) {
+	if arg, ok := args["0"].(float64); ok {
+		return arg * 180 / math.Pi, nil
+	}
+	return nil, fmt.Errorf("to_degrees() requires a number (angle in radians)")
+}
+
+// exactSines are the angles, in degrees from 0 to 360, whose sine sin_deg()
+// and cos_deg() return exactly rather than rounded through radians
+var exactSines = map[float64]float64{
+	0: 0, 30: 0.5, 90: 1, 150: 0.5, 180: 0, 210: -0.5, 270: -1, 330: -0.5,
+}
+
+// sinDegrees returns the sine of an angle in degrees, reducing it to
+// [0, 360) first so that large angles keep their precision
+func sinDegrees(deg float64) float64 {
+	d := math.Mod(deg, 360)
+	if d < 0 {
+		d += 360
+	}
+	if v, ok := exactSines[d]; ok {
+		return v
+	}
+	return math.Sin(d * math.Pi / 180)
+}
+
+// builtinSinDeg returns sine of angle in degrees
+func builtinSinDeg(evaluator *Evaluator, args map[string]any) (any, error) {
+	if arg, ok := args["0"].(float64); ok {
+		return sinDegrees(arg), nil
+	}
+	return nil, fmt.Errorf("sin_deg() requires a number (angle in degrees)")
+}
+
+// builtinCosDeg returns cosine of angle in degrees
+func builtinCosDeg(evaluator *Evaluator, args map[string]any) (any, error) {
+	if arg, ok := args["0"].(float64); ok {
+		return sinDegrees(arg + 90), nil
+	}
+	return nil, fmt.Errorf("cos_deg() requires a number (angle in degrees)")
+}
+
 // Exponential and logarithmic functions
 
 // builtinExp returns e^x
@@ -236,9 +453,11 @@ func builtinPi(evaluator *Evaluator, args map[string]any) (any, error) {
 }
 
 // builtinRandom returns a random float between 0 and 1
+// Uses the package-level source, which is seeded once and safe for concurrent
+// use; a new source per call was slow and gave repeated values for calls in
+// the same clock tick.
 func builtinRandom(evaluator *Evaluator, args map[string]any) (any, error) {
-	rng := mathrand.New(mathrand.NewSource(time.Now().UnixNano()))
-	return rng.Float64(), nil
+	return mathrand.Float64(), nil
 }
 
 // fibonacci computes the nth Fibonacci number using simple iteration with static types.
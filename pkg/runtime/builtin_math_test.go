@@ -0,0 +1,174 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestDivMod(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`[div(7, 2), div(-7, 2), div(7, -2), div(7.5, 2)]`, `[3, -4, -4, 3]`},
+		{`[mod(7, 3), mod(-7, 3), mod(7, -3), mod(-7, -3)]`, `[1, 2, 1, 2]`},
+		{`[mod(5.5, 2), mod(-0.5, 2), mod(-3, 3), mod(-1e-20, 3)]`, `[1.5, 1.5, 0, 0]`},
+		{`[7 % 3, -7 % 3, 7 % -3, 7.9 % 2]`, `[1, -1, 1, 1]`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{`div(1, 0)`, `mod(1, 0)`, `div("6", 2)`, `mod(6)`, `x = 5 % 0.5`, `x = 5 x %= 0.3`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestNonFinite(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	src := `
+nan = sqrt(-1)
+inf = pow(10, 400)
+exit(tostring([
+  [is_nan(nan), is_nan(inf), is_nan(1), is_nan("NaN"), is_nan(nil)],
+  [is_finite(1.5), is_finite(nan), is_finite(-inf), is_finite("1")],
+  [is_infinite(inf), is_infinite(-inf), is_infinite(nan), is_infinite(1e308)]
+]))
+`
+	got, err := script.NewInterpreter().ExecuteWithResult(src)
+	want := "[[true, false, false, false, false], [true, false, false, false], [true, true, false, false]]"
+	if err != nil || len(got) != 1 || got[0] != want {
+		t.Errorf("got %v, %v; want %s", got, err, want)
+	}
+
+	if _, err := script.NewInterpreter().Execute(`is_nan()`); err == nil {
+		t.Error("is_nan() without a value should fail")
+	}
+}
+
+func TestMinMaxArray(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	// f = max goes through the map form, direct calls through the fast path
+	src := `
+scores = [72, 68, 75, 70, 69]
+lo = min
+hi = max
+exit(tostring([min(scores), max(scores), lo(scores), hi(scores), min(3, 1, 2), max([-5]), hi(1, 2)]))
+`
+	got, err := script.NewInterpreter().ExecuteWithResult(src)
+	want := "[68, 75, 68, 75, 1, -5, 2]"
+	if err != nil || len(got) != 1 || got[0] != want {
+		t.Errorf("got %v, %v; want %s", got, err, want)
+	}
+
+	for _, src := range []string{`min([])`, `max([1, "a"])`, `f = min f([])`, `f = max f([nil])`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`[clamp01(-0.5), clamp01(0.25), clamp01(3)]`, `[0, 0.25, 1]`},
+		{`[lerp(10, 20, 0), lerp(10, 20, 0.5), lerp(10, 20, 1.5), lerp(a = 0, b = 4, t = 0.25)]`, `[10, 15, 25, 1]`},
+		{`[inv_lerp(10, 20, 15), inv_lerp(20, 10, 15), inv_lerp(0, 4, 8)]`, `[0.5, 0.5, 2]`},
+		{`[remap(5, 0, 10, 100, 200), remap(50, 0, 100, 1, 0), remap(75, 32, 212, 0, 100) > 23.8]`, `[150, 0.5, true]`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{`clamp01("1")`, `lerp(1, 2)`, `inv_lerp(3, 3, 1)`, `remap(1, 0, 0, 0, 1)`, `remap(1, 0, 1, 0)`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestDegrees(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`[to_radians(180) == pi(), to_degrees(pi() / 2), to_degrees(to_radians(45))]`, `[true, 90, 45]`},
+		{`[sin_deg(0), sin_deg(30), sin_deg(90), sin_deg(180), sin_deg(-90), sin_deg(750)]`, `[0, 0.5, 1, 0, -1, 0.5]`},
+		{`[cos_deg(0), cos_deg(60), cos_deg(90), cos_deg(180), cos_deg(-270)]`, `[1, 0.5, 0, -1, 0]`},
+		{`[abs(sin_deg(45) - sqrt(2) / 2) < 1e-15, abs(cos_deg(10) - cos(to_radians(10))) < 1e-15]`, `[true, true]`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{`to_radians("90")`, `to_degrees()`, `sin_deg(nil)`, `cos_deg("0")`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+// TestRandom checks random() stays in [0, 1) and doesn't repeat when called
+// in a tight loop or from parallel functions, which a per-call clock-seeded
+// source did.
+func TestRandom(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	src := `
+seen = {}
+for i = 1, 1000 do
+  r = random()
+  if type(r) != "number" or r < 0 or r >= 1 then throw("random: out of range: " + r) end
+  seen[tostring(r)] = true
+end
+if len(keys(seen)) < 990 then throw("random: only " + len(keys(seen)) + " distinct values in 1000 calls") end
+draw = function()
+  out = []
+  for i = 1, 100 do push(out, random()) end
+  return out
+end
+results = parallel(draw, draw, draw, draw)
+all_draws = {}
+for batch in results do
+  for r in batch do all_draws[tostring(r)] = true end
+end
+if len(keys(all_draws)) < 390 then throw("random: parallel calls repeated values") end
+`
+	if _, err := script.NewInterpreter().Execute(src); err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+}
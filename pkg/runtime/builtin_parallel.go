@@ -52,8 +52,54 @@ func builtinParallel(evaluator *Evaluator, args map[string]any) (any, error) {
 	return nil, fmt.Errorf("parallel() requires an array, object, or functions as arguments")
 }
 
+// branchContext gives a parallel() branch its parent's output routing and
+// context data. The circular-require detector isn't carried over: it isn't
+// safe to share between goroutines.
+func branchContext(parent *script.RequestContext, childEval *Evaluator) *script.RequestContext {
+	if parent == nil {
+		return nil
+	}
+	return &script.RequestContext{
+		Data:         parent.Data,
+		Frame:        parent.Frame,
+		ProcessCtx:   parent.ProcessCtx,
+		Interpreter:  parent.Interpreter,
+		Evaluator:    childEval,
+		IOConfig:     parent.IOConfig,
+		OutputWriter: parent.OutputWriter,
+	}
+}
+
+// branchEvaluator creates an evaluator for running a function off the
+// caller's goroutine. It sees parentEnv read-only, so it can't race with the
+// caller on variable writes.
+func branchEvaluator(evaluator *Evaluator, parentEnv *script.Environment, reqCtx *script.RequestContext) *Evaluator {
+	childEval := NewEvaluator()
+	childEnv := NewChildEnvironment(parentEnv)
+	childEnv.SetParallelContext(true)
+	childEval.SetEnvironment(childEnv)
+	childEval.SetParallelContext(true) // Block parent scope writes
+	childEval.InheritLimits(evaluator)
+	childEval.SetContext(evaluator.Context())
+	childEval.SetReqCtx(branchContext(reqCtx, childEval))
+	return childEval
+}
+
+// backgroundEvaluator prepares fn to run on another goroutine while the
+// caller keeps going, which parallel() never allows: the evaluator and fn
+// read snapshots of the caller's scopes instead of the live ones (see
+// Environment.Snapshot). Call it on the caller's goroutine.
+func backgroundEvaluator(evaluator *Evaluator, fn Value) (*Evaluator, Value) {
+	reqCtx, _ := script.CurrentRequestContext(evaluator)
+	return branchEvaluator(evaluator, evaluator.GetEnv().Snapshot(), reqCtx), script.SnapshotFunction(fn)
+}
+
 // parallelArrayWithEval executes an array of functions in parallel with isolated evaluators
 func parallelArrayWithEval(evaluator *Evaluator, functions []any) (any, error) {
+	if err := evaluator.ChargeElements(len(functions)); err != nil {
+		return nil, err
+	}
+	reqCtx, _ := script.CurrentRequestContext(evaluator)
 	results := make([]any, len(functions))
 
 	var wg sync.WaitGroup
@@ -64,12 +110,7 @@ func parallelArrayWithEval(evaluator *Evaluator, functions []any) (any, error) {
 			defer wg.Done()
 
 			// Create a child evaluator for this block with parent scope access
-			childEval := NewEvaluator()
-			parentEnv := evaluator.GetEnv()
-			childEnv := NewChildEnvironment(parentEnv)
-			childEnv.SetParallelContext(true)
-			childEval.SetEnvironment(childEnv)
-			childEval.SetParallelContext(true) // Block parent scope writes
+			childEval := branchEvaluator(evaluator, evaluator.GetEnv(), reqCtx)
 
 			// Call the function in the child evaluator
 			fnVal := InterfaceToValue(fn)
@@ -100,6 +141,10 @@ func parallelArrayWithEval(evaluator *Evaluator, functions []any) (any, error) {
 
 // parallelObjectWithEval executes an object of functions in parallel with isolated evaluators
 func parallelObjectWithEval(evaluator *Evaluator, functions map[string]any) (any, error) {
+	if err := evaluator.ChargeElements(len(functions)); err != nil {
+		return nil, err
+	}
+	reqCtx, _ := script.CurrentRequestContext(evaluator)
 	results := make(map[string]any)
 	var mu sync.Mutex
 
@@ -111,12 +156,7 @@ func parallelObjectWithEval(evaluator *Evaluator, functions map[string]any) (any
 			defer wg.Done()
 
 			// Create a child evaluator for this block with parent scope access
-			childEval := NewEvaluator()
-			parentEnv := evaluator.GetEnv()
-			childEnv := NewChildEnvironment(parentEnv)
-			childEnv.SetParallelContext(true)
-			childEval.SetEnvironment(childEnv)
-			childEval.SetParallelContext(true) // Block parent scope writes
+			childEval := branchEvaluator(evaluator, evaluator.GetEnv(), reqCtx)
 
 			// Call the function in the child evaluator
 			fnVal := InterfaceToValue(fn)
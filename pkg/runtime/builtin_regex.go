@@ -3,11 +3,46 @@ package runtime
 import (
 	"fmt"
 	"regexp"
+	"sync"
 	"unicode/utf8"
 
 	"github.com/duso-org/duso/pkg/script"
 )
 
+// regexCacheMax bounds the compiled pattern cache; when it fills up it's
+// emptied and starts over
+const regexCacheMax = 256
+
+var (
+	regexCache   = make(map[string]*regexp.Regexp)
+	regexCacheMu sync.Mutex
+)
+
+// compileRegex compiles pattern, reusing the result for patterns seen
+// before, so matching the same string pattern in a loop compiles it once.
+// The ignore-case flag is part of the pattern ("(?i)..."), so it's part of
+// the key. Compiled regexes are safe to share between goroutines.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCacheMu.Lock()
+	re, ok := regexCache[pattern]
+	regexCacheMu.Unlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCacheMu.Lock()
+	if len(regexCache) >= regexCacheMax {
+		regexCache = make(map[string]*regexp.Regexp)
+	}
+	regexCache[pattern] = re
+	regexCacheMu.Unlock()
+	return re, nil
+}
+
 // unwrapValue extracts a Value from various input types (raw Value, ValueRef, or any)
 func unwrapValue(v any) script.Value {
 	// If it's a ValueRef, unwrap it
@@ -78,7 +113,7 @@ func builtinContains(evaluator *Evaluator, args map[string]any) (any, error) {
 		if ignoreCase {
 			pattern = "(?i)" + pattern
 		}
-		re, err = regexp.Compile(pattern)
+		re, err = compileRegex(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("contains() invalid pattern: %v", err)
 		}
@@ -124,7 +159,7 @@ func builtinStartsWith(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 		// Wrap regex with ^ anchor
 		wrappedPattern := "^" + regex.Pattern
-		re, err = regexp.Compile(wrappedPattern)
+		re, err = compileRegex(wrappedPattern)
 		if err != nil {
 			return nil, fmt.Errorf("starts_with() invalid regex: %v", err)
 		}
@@ -134,7 +169,7 @@ func builtinStartsWith(evaluator *Evaluator, args map[string]any) (any, error) {
 		if !ignoreCase {
 			pattern = "^" + regexp.QuoteMeta(patternVal.AsString())
 		}
-		re, err = regexp.Compile(pattern)
+		re, err = compileRegex(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("starts_with() invalid pattern: %v", err)
 		}
@@ -180,7 +215,7 @@ func builtinEndsWith(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 		// Wrap regex with $ anchor
 		wrappedPattern := regex.Pattern + "$"
-		re, err = regexp.Compile(wrappedPattern)
+		re, err = compileRegex(wrappedPattern)
 		if err != nil {
 			return nil, fmt.Errorf("ends_with() invalid regex: %v", err)
 		}
@@ -190,7 +225,7 @@ func builtinEndsWith(evaluator *Evaluator, args map[string]any) (any, error) {
 		if !ignoreCase {
 			pattern = regexp.QuoteMeta(patternVal.AsString()) + "$"
 		}
-		re, err = regexp.Compile(pattern)
+		re, err = compileRegex(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("ends_with() invalid pattern: %v", err)
 		}
@@ -241,7 +276,7 @@ func builtinFind(evaluator *Evaluator, args map[string]any) (any, error) {
 		if ignoreCase {
 			pattern = "(?i)" + pattern
 		}
-		re, err = regexp.Compile(pattern)
+		re, err = compileRegex(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("find() invalid pattern: %v", err)
 		}
@@ -251,6 +286,10 @@ func builtinFind(evaluator *Evaluator, args map[string]any) (any, error) {
 
 	// Find all matches and convert byte positions to character positions
 	matches := re.FindAllStringIndex(s, -1)
+	// Each match is an array element holding a three-field object
+	if err := evaluator.ChargeElements(len(matches) * 4); err != nil {
+		return nil, err
+	}
 	var result []Value
 	for _, match := range matches {
 		byteStart := match[0]
@@ -315,7 +354,7 @@ func builtinReplace(evaluator *Evaluator, args map[string]any) (any, error) {
 		if ignoreCase {
 			pattern = "(?i)" + pattern
 		}
-		re, err = regexp.Compile(pattern)
+		re, err = compileRegex(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("replace() invalid pattern: %v", err)
 		}
@@ -0,0 +1,43 @@
+package runtime
+
+import "testing"
+
+func TestCompileRegexCache(t *testing.T) {
+	first, err := compileRegex("(?i)abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := compileRegex("(?i)abc"); again != first {
+		t.Error("a repeated pattern should reuse its compiled regex")
+	}
+	if _, err := compileRegex("("); err == nil {
+		t.Error("an invalid pattern should fail")
+	}
+
+	// Case-sensitive and case-insensitive calls must not share an entry
+	tests := []struct {
+		args map[string]any
+		want bool
+	}{
+		{map[string]any{"0": "ABC", "1": "abc"}, false},
+		{map[string]any{"0": "ABC", "1": "abc", "2": true}, true},
+		{map[string]any{"0": "ABC", "1": "abc"}, false},
+	}
+	for _, tt := range tests {
+		if got, err := builtinContains(nil, tt.args); err != nil || got != tt.want {
+			t.Errorf("contains(%v) = %v, %v; want %v", tt.args, got, err, tt.want)
+		}
+	}
+
+	for i := 0; i <= regexCacheMax; i++ {
+		if _, err := compileRegex(string(rune('a'+i%26)) + "x" + string(rune('0'+i/26))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	regexCacheMu.Lock()
+	size := len(regexCache)
+	regexCacheMu.Unlock()
+	if size > regexCacheMax {
+		t.Errorf("cache grew to %d entries, max %d", size, regexCacheMax)
+	}
+}
@@ -0,0 +1,242 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/duso-org/duso/pkg/core"
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// Scheduled jobs run a function in the background on a timer. Each job has
+// its own goroutine and evaluator (see backgroundEvaluator), and all of them
+// stop when the process is interrupted or an http_server() shuts down.
+
+// scheduledJob is one set_interval() or set_timeout() registration
+type scheduledJob struct {
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the job's goroutine returns
+}
+
+var (
+	scheduledJobs   = make(map[int64]*scheduledJob)
+	scheduledJobsMu sync.Mutex
+	scheduledJobID  int64
+)
+
+// scheduleJob starts loop in a goroutine and returns the script handle for
+// it. loop calls run for each firing and returns when ctx is done; errors
+// from fn are reported on stderr.
+func scheduleJob(evaluator *Evaluator, name string, fn Value, loop func(ctx context.Context, run func())) map[string]any {
+	childEval, fn := backgroundEvaluator(evaluator, fn)
+	ctx, cancel := context.WithCancel(evaluator.Context())
+	childEval.SetContext(ctx) // cancel() also interrupts a run in progress
+	job := &scheduledJob{cancel: cancel, done: make(chan struct{})}
+
+	scheduledJobsMu.Lock()
+	scheduledJobID++
+	id := scheduledJobID
+	scheduledJobs[id] = job
+	scheduledJobsMu.Unlock()
+
+	remove := func() bool {
+		scheduledJobsMu.Lock()
+		defer scheduledJobsMu.Unlock()
+		if scheduledJobs[id] != job {
+			return false
+		}
+		delete(scheduledJobs, id)
+		return true
+	}
+
+	go func() {
+		defer core.RecoverPanic(name)
+		defer close(job.done)
+		defer remove()
+		defer cancel()
+
+		// Stop on Ctrl+C as well as on cancel()
+		go func() {
+			select {
+			case <-interruptChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		loop(ctx, func() {
+			_, err := childEval.CallFunction(fn, nil)
+			var cancelled *script.CancelledError
+			if err != nil && !errors.As(err, &cancelled) {
+				reportBackgroundError(name, err)
+			}
+		})
+	}()
+
+	cancelFn := NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+		active := remove()
+		cancel()
+		return active, nil
+	})
+	return map[string]any{
+		"id":     float64(id),
+		"cancel": cancelFn,
+	}
+}
+
+// StopScheduledJobs cancels every set_interval(), set_timeout() and cron() job and
+// waits for runs in progress to finish. http_server() calls it on shutdown,
+// before its on_shutdown hooks.
+func StopScheduledJobs() {
+	scheduledJobsMu.Lock()
+	jobs := make([]*scheduledJob, 0, len(scheduledJobs))
+	for id, job := range scheduledJobs {
+		jobs = append(jobs, job)
+		delete(scheduledJobs, id)
+	}
+	scheduledJobsMu.Unlock()
+
+	for _, job := range jobs {
+		job.cancel()
+	}
+	for _, job := range jobs {
+		<-job.done
+	}
+}
+
+// scheduleArgs reads the (fn, seconds) arguments of set_interval() and
+// set_timeout()
+func scheduleArgs(name string, args map[string]any) (Value, time.Duration, error) {
+	fn := InterfaceToValue(GetArg(args, 0, "fn"))
+	if !fn.IsFunction() {
+		return fn, 0, fmt.Errorf("%s() requires a function as first argument", name)
+	}
+	seconds, ok := GetArg(args, 1, "seconds").(float64)
+	if !ok || seconds < 0 {
+		return fn, 0, fmt.Errorf("%s() requires a non-negative number of seconds as second argument", name)
+	}
+	return fn, time.Duration(seconds * float64(time.Second)), nil
+}
+
+// builtinSetInterval runs fn in the background every given number of seconds
+// Usage: set_interval(fn, seconds)
+// Returns a handle with cancel(). Runs never overlap: if fn takes longer than
+// the interval, the ticks it overlaps are skipped.
+func builtinSetInterval(evaluator *Evaluator, args map[string]any) (any, error) {
+	fn, every, err := scheduleArgs("set_interval", args)
+	if err != nil {
+		return nil, err
+	}
+	if every <= 0 {
+		return nil, fmt.Errorf("set_interval() interval must be greater than 0")
+	}
+
+	return scheduleJob(evaluator, "set_interval", fn, func(ctx context.Context, run func()) {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if ctx.Err() == nil {
+					run()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}), nil
+}
+
+// builtinSetTimeout runs fn once in the background after a delay in seconds
+// Usage: set_timeout(fn, seconds)
+// Returns a handle whose cancel() stops fn from running, or interrupts it.
+func builtinSetTimeout(evaluator *Evaluator, args map[string]any) (any, error) {
+	fn, delay, err := scheduleArgs("set_timeout", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduleJob(evaluator, "set_timeout", fn, func(ctx context.Context, run func()) {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			if ctx.Err() == nil {
+				run()
+			}
+		case <-ctx.Done():
+		}
+	}), nil
+}
+
+// builtinCron runs fn in the background at the times a cron expression
+// matches
+// Usage: cron(expression, fn [, tz])
+// expression has the standard 5 fields (see cronSchedule) or is a macro
+// such as "@daily". Times are in tz, an IANA name or an offset as for
+// timestamp(), and default to the local timezone. Returns a handle with
+// cancel() and next(), the Unix time of the next run.
+func builtinCron(evaluator *Evaluator, args map[string]any) (any, error) {
+	expr, ok := GetArg(args, 0, "expression").(string)
+	if !ok {
+		return nil, fmt.Errorf("cron() requires an expression string as first argument")
+	}
+	sched, err := parseCron(expr)
+	if err != nil {
+		return nil, fmt.Errorf("cron() %v", err)
+	}
+	fn := InterfaceToValue(GetArg(args, 1, "fn"))
+	if !fn.IsFunction() {
+		return nil, fmt.Errorf("cron() requires a function as second argument")
+	}
+	loc := time.Local
+	switch tz := GetArg(args, 2, "tz").(type) {
+	case nil:
+	case string:
+		if loc, err = parseLocation(tz); err != nil {
+			return nil, fmt.Errorf("cron() %v", err)
+		}
+	case float64:
+		loc, _ = parseFixedOffset(formatOffset(tz))
+	default:
+		return nil, fmt.Errorf("cron() tz must be a timezone name or an offset")
+	}
+
+	var (
+		nextMu  sync.Mutex
+		nextRun time.Time
+	)
+	handle := scheduleJob(evaluator, "cron", fn, func(ctx context.Context, run func()) {
+		for {
+			at := sched.next(time.Now().In(loc))
+			nextMu.Lock()
+			nextRun = at
+			nextMu.Unlock()
+
+			timer := time.NewTimer(time.Until(at))
+			select {
+			case <-timer.C:
+				if ctx.Err() == nil {
+					run()
+				}
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	})
+
+	handle["next"] = NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+		nextMu.Lock()
+		defer nextMu.Unlock()
+		if nextRun.IsZero() {
+			// The job goroutine hasn't computed it yet
+			return float64(sched.next(time.Now().In(loc)).Unix()), nil
+		}
+		return float64(nextRun.Unix()), nil
+	})
+	return handle, nil
+}
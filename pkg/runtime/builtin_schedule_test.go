@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestSetInterval(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	got, err := script.NewInterpreter().ExecuteWithResult(`
+store = datastore("test_set_interval")
+store.set("ticks", 0)
+job = set_interval(function() store.increment("ticks", 1) end, 0.02)
+sleep(0.15)
+first = job.cancel()
+ticks = store.get("ticks")
+sleep(0.06)
+exit(first, job.cancel(), ticks >= 3, store.get("ticks") == ticks)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{true, false, true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	got, err := script.NewInterpreter().ExecuteWithResult(`
+store = datastore("test_set_timeout")
+store.set("fired", [])
+set_timeout(function() store.push("fired", "a") end, 0.02)
+b = set_timeout(function() store.push("fired", "b") end, 0.02)
+b.cancel()
+sleep(0.08)
+exit(store.get("fired"))
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{[]any{"a"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	for _, bad := range []string{`set_timeout(1, 1)`, `set_timeout(function() end, -1)`, `set_interval(function() end, 0)`} {
+		if _, err := script.NewInterpreter().ExecuteWithResult(bad); err == nil {
+			t.Errorf("%s should fail", bad)
+		}
+	}
+}
+
+// TestStopScheduledJobs checks the shutdown path: pending jobs never fire
+// and a run in progress is interrupted before StopScheduledJobs returns.
+func TestStopScheduledJobs(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	_, err := script.NewInterpreter().ExecuteWithResult(`
+store = datastore("test_stop_jobs")
+store.set("log", [])
+set_timeout(function() store.push("log", "late") end, 10)
+set_timeout(function()
+  store.push("log", "started")
+  sleep(10)
+  store.push("log", "finished")
+end, 0)
+sleep(0.05)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	StopScheduledJobs()
+
+	log, _ := GetDatastore("test_stop_jobs", nil).Get("log")
+	if want := []any{"started"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %#v, want %#v", log, want)
+	}
+}
@@ -0,0 +1,315 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// valueSet holds the members of a set() in insertion order. Members are
+// looked up by setKey, so values that are deep_equal() share an entry.
+type valueSet struct {
+	mu      sync.Mutex
+	index   map[string]int // setKey -> position in members
+	members []Value
+	live    []bool // false once removed; compacted when half the slots are dead
+	dead    int
+}
+
+func newValueSet() *valueSet {
+	return &valueSet{index: make(map[string]int)}
+}
+
+// add reports whether v was new. Callers hold s.mu.
+func (s *valueSet) add(v Value) bool {
+	key := setKey(v)
+	if _, ok := s.index[key]; ok {
+		return false
+	}
+	s.index[key] = len(s.members)
+	s.members = append(s.members, v)
+	s.live = append(s.live, true)
+	return true
+}
+
+// remove reports whether v was a member. Callers hold s.mu.
+func (s *valueSet) remove(v Value) bool {
+	key := setKey(v)
+	i, ok := s.index[key]
+	if !ok {
+		return false
+	}
+	delete(s.index, key)
+	s.members[i] = NewNil()
+	s.live[i] = false
+	s.dead++
+	if s.dead > len(s.members)/2 {
+		s.compact()
+	}
+	return true
+}
+
+func (s *valueSet) has(v Value) bool {
+	_, ok := s.index[setKey(v)]
+	return ok
+}
+
+// values returns the members in insertion order. Callers hold s.mu.
+func (s *valueSet) values() []Value {
+	result := make([]Value, 0, len(s.index))
+	for i, v := range s.members {
+		if s.live[i] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func (s *valueSet) compact() {
+	s.members = s.values()
+	s.live = make([]bool, len(s.members))
+	for i, v := range s.members {
+		s.live[i] = true
+		s.index[setKey(v)] = i
+	}
+	s.dead = 0
+}
+
+// setKey encodes a value so that deep_equal() values get the same key.
+// Arrays and objects are keyed by their contents at the time they're added.
+func setKey(v Value) string {
+	var sb strings.Builder
+	writeSetKey(&sb, v, nil)
+	return sb.String()
+}
+
+func writeSetKey(sb *strings.Builder, v Value, path map[uintptr]bool) {
+	switch v.Type {
+	case VAL_NIL:
+		sb.WriteString("nil")
+	case VAL_NUMBER:
+		n := v.AsNumber()
+		if n == 0 {
+			n = 0 // -0 == 0
+		}
+		sb.WriteByte('n')
+		sb.WriteString(strconv.FormatFloat(n, 'g', -1, 64))
+	case VAL_STRING:
+		sb.WriteByte('s')
+		sb.WriteString(strconv.Quote(v.AsString()))
+	case VAL_BOOL:
+		sb.WriteString(strconv.FormatBool(v.AsBool()))
+	case VAL_ARRAY, VAL_OBJECT:
+		id := reflect.ValueOf(v.Data).Pointer()
+		if path[id] {
+			sb.WriteString("@") // a cycle, as deep_equal() treats it
+			return
+		}
+		if path == nil {
+			path = make(map[uintptr]bool)
+		}
+		path[id] = true
+		defer delete(path, id)
+
+		if v.IsArray() {
+			sb.WriteByte('[')
+			for i, item := range v.AsArray() {
+				if i > 0 {
+					sb.WriteByte(',')
+				}
+				writeSetKey(sb, item, path)
+			}
+			sb.WriteByte(']')
+			return
+		}
+		obj := v.AsObject()
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(strconv.Quote(k))
+			sb.WriteByte(':')
+			writeSetKey(sb, obj[k], path)
+		}
+		sb.WriteByte('}')
+	case VAL_FUNCTION:
+		// Functions are members by identity
+		if fn, ok := v.Data.(*script.ScriptFunction); ok {
+			fmt.Fprintf(sb, "f%p", fn)
+		} else {
+			fmt.Fprintf(sb, "g%x", reflect.ValueOf(v.Data).Pointer())
+		}
+	case script.VAL_BINARY:
+		sb.WriteByte('x')
+		if b, ok := v.Data.(*script.BinaryValue); ok {
+			sb.WriteString(strconv.Quote(string(*b.Data)))
+		}
+	case script.VAL_REGEX:
+		sb.WriteByte('r')
+		if r, ok := v.Data.(*script.RegexValue); ok {
+			sb.WriteString(strconv.Quote(r.Pattern))
+		}
+	case script.VAL_CODE:
+		sb.WriteByte('c')
+		if c, ok := v.Data.(*script.CodeValue); ok {
+			sb.WriteString(strconv.Quote(c.Source))
+		}
+	case script.VAL_ERROR:
+		sb.WriteByte('e')
+		if e, ok := v.Data.(*script.ErrorValue); ok {
+			writeSetKey(sb, e.Message, path)
+		}
+	}
+}
+
+// setMembers reads the values of another set, or the elements of an array,
+// for union(), intersect() and difference()
+func setMembers(evaluator *Evaluator, name string, arg any) ([]Value, error) {
+	if arrPtr, ok := arg.(*[]Value); ok {
+		return *arrPtr, nil
+	}
+	if obj, ok := arg.(map[string]any); ok {
+		if toArray := InterfaceToValue(obj["to_array"]); toArray.IsFunction() {
+			result, err := evaluator.CallFunction(toArray, nil)
+			if err != nil {
+				return nil, err
+			}
+			if result.IsArray() {
+				return result.AsArray(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s() requires a set or an array", name)
+}
+
+// builtinSet creates a set of unique values: set([values])
+// Values that are deep_equal() count as the same member. Returns an object of
+// methods: add, has, remove, size, union, intersect, difference and to_array.
+func builtinSet(evaluator *Evaluator, args map[string]any) (any, error) {
+	s := newValueSet()
+	if arg, ok := args["0"]; ok && arg != nil {
+		arrPtr, ok := arg.(*[]Value)
+		if !ok {
+			return nil, fmt.Errorf("set() requires an array of initial values")
+		}
+		if err := evaluator.ChargeElements(len(*arrPtr)); err != nil {
+			return nil, err
+		}
+		for _, v := range *arrPtr {
+			s.add(v)
+		}
+	}
+	return setObject(s), nil
+}
+
+// setObject wraps a valueSet in the methods scripts call
+func setObject(s *valueSet) map[string]any {
+	// combine builds a new set from this one and another's members
+	combine := func(name string, build func(mine *valueSet, other []Value) *valueSet) Value {
+		return NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+			other, err := setMembers(evaluator, name, args["0"])
+			if err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			result := build(s, other)
+			s.mu.Unlock()
+			if err := evaluator.ChargeElements(len(result.index)); err != nil {
+				return nil, err
+			}
+			return setObject(result), nil
+		})
+	}
+
+	return map[string]any{
+		// add(values...) adds each value, returns true if any was new
+		"add": NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+			values := positionalValues(args)
+			if err := evaluator.ChargeElements(len(values)); err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			added := false
+			for _, v := range values {
+				if s.add(v) {
+					added = true
+				}
+			}
+			return added, nil
+		}),
+		"has": NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.has(InterfaceToValue(args["0"])), nil
+		}),
+		// remove(value) returns true if value was a member
+		"remove": NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.remove(InterfaceToValue(args["0"])), nil
+		}),
+		"size": NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return float64(len(s.index)), nil
+		}),
+		"union": combine("union", func(mine *valueSet, other []Value) *valueSet {
+			result := newValueSet()
+			for _, v := range mine.values() {
+				result.add(v)
+			}
+			for _, v := range other {
+				result.add(v)
+			}
+			return result
+		}),
+		"intersect": combine("intersect", func(mine *valueSet, other []Value) *valueSet {
+			keep := newValueSet()
+			for _, v := range other {
+				keep.add(v)
+			}
+			result := newValueSet()
+			for _, v := range mine.values() {
+				if keep.has(v) {
+					result.add(v)
+				}
+			}
+			return result
+		}),
+		"difference": combine("difference", func(mine *valueSet, other []Value) *valueSet {
+			exclude := newValueSet()
+			for _, v := range other {
+				exclude.add(v)
+			}
+			result := newValueSet()
+			for _, v := range mine.values() {
+				if !exclude.has(v) {
+					result.add(v)
+				}
+			}
+			return result
+		}),
+		// to_array() returns the members in the order they were added
+		"to_array": NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if err := evaluator.ChargeElements(len(s.index)); err != nil {
+				return nil, err
+			}
+			result := s.values()
+			return &result, nil
+		}),
+	}
+}
@@ -0,0 +1,78 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestSet(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	_, err := script.NewInterpreter().Execute(`
+s = set([3, 1, 3, 2])
+if s.size() != 3 or tostring(s.to_array()) != "[3, 1, 2]" then throw("set should drop duplicates and keep order: " + tostring(s.to_array())) end
+if s.add(1) or not s.add(4, 1) then throw("add should report whether a value was new") end
+if not s.has(4) or s.has("4") then throw("has should tell numbers and strings apart") end
+if not s.remove(3) or s.remove(3) or s.has(3) then throw("remove") end
+s.add(3)
+if tostring(s.to_array()) != "[1, 2, 4, 3]" then throw("re-added value goes last: " + tostring(s.to_array())) end
+
+points = set()
+points.add({x = 1, y = 2})
+points.add({y = 2, x = 1})
+points.add([1, 2])
+if points.size() != 2 or not points.has({x = 1, y = 2}) then throw("containers should be keyed by contents") end
+
+a = set([1, 2, 3])
+b = set([2, 3, 4])
+if tostring(a.union(b).to_array()) != "[1, 2, 3, 4]" then throw("union") end
+if tostring(a.intersect(b).to_array()) != "[2, 3]" then throw("intersect") end
+if tostring(a.difference([1]).to_array()) != "[2, 3]" then throw("difference with an array") end
+if a.size() != 3 then throw("set algebra should not modify the set") end
+
+for i = 1, 100 do a.add(i) end
+for i = 1, 90 do a.remove(i) end
+if a.size() != 10 or tostring(a.to_array()) != "[91, 92, 93, 94, 95, 96, 97, 98, 99, 100]" then throw("compaction: " + tostring(a.to_array())) end
+`)
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+
+	for _, src := range []string{`set("abc")`, `set().union(5)`} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestSetKey(t *testing.T) {
+	same := [][2]Value{
+		{NewNumber(0), NewNumber(-0.0)},
+		{NewArray([]Value{NewNumber(1)}), NewArray([]Value{NewNumber(1)})},
+	}
+	for _, pair := range same {
+		if setKey(pair[0]) != setKey(pair[1]) {
+			t.Errorf("setKey(%v) != setKey(%v)", pair[0], pair[1])
+		}
+	}
+	different := [][2]Value{
+		{NewNumber(1), NewString("1")},
+		{NewString("a,b"), NewArray([]Value{NewString("a"), NewString("b")})},
+		{NewNil(), NewString("nil")},
+		{NewBool(true), NewString("true")},
+	}
+	for _, pair := range different {
+		if setKey(pair[0]) == setKey(pair[1]) {
+			t.Errorf("setKey(%v) == setKey(%v)", pair[0], pair[1])
+		}
+	}
+
+	// A cycle is keyed without recursing forever
+	arr := []Value{NewNumber(1)}
+	cyclic := NewArray(arr)
+	*cyclic.AsArrayPtr() = append(*cyclic.AsArrayPtr(), cyclic)
+	if key := setKey(cyclic); key != "[n1,@]" {
+		t.Errorf("setKey(cyclic) = %s", key)
+	}
+}
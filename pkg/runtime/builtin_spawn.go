@@ -206,6 +206,7 @@ func builtinSpawn(evaluator *Evaluator, args map[string]any) (any, error) {
 
 		// Create fresh evaluator for this execution's environment
 		spawnedEval := script.NewEvaluator()
+		spawnedEval.InheritLimits(evaluator) // a sandboxed script's children share its limits
 
 		// Set up output writer - route to datastore if configured, otherwise to global
 		var outputWriter func(string) error
@@ -400,6 +401,8 @@ func builtinRun(evaluator *Evaluator, args map[string]any) (any, error) {
 
 	// Create fresh evaluator for this execution's environment
 	runEval := script.NewEvaluator()
+	runEval.InheritLimits(evaluator) // a sandboxed script's children share its limits
+	runEval.SetContext(evaluator.Context()) // run() is synchronous, so it's cancelled with its caller
 
 	// Set up output writer - route to datastore if configured, otherwise to global
 	var outputWriter func(string) error
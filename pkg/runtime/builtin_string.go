@@ -2,9 +2,16 @@ package runtime
 
 import (
 	"fmt"
+	"html"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode"
 	"unicode/utf8"
+
+	"github.com/duso-org/duso/pkg/runtime/color"
+	"github.com/duso-org/duso/pkg/script"
 )
 
 // builtinUpper converts string to uppercase, coercing input to string if needed
@@ -241,3 +248,326 @@ func builtinPadRight(evaluator *Evaluator, args map[string]any) (any, error) {
 	padding := strings.Repeat(padChar, w-currentLen)
 	return s + padding, nil
 }
+
+// builtinFormatNumber formats a number with digit grouping:
+// format_number(n [, decimals] [, thousands_sep] [, decimal_sep])
+func builtinFormatNumber(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, ok := GetArg(args, 0, "n").(float64)
+	if !ok {
+		return nil, fmt.Errorf("format_number() requires a number as first argument")
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, fmt.Errorf("format_number() requires a finite number")
+	}
+
+	// Without decimals, keep the shortest exact representation
+	decimals := -1
+	if d := GetArg(args, 1, "decimals"); d != nil {
+		dNum, ok := d.(float64)
+		if !ok || dNum < 0 || dNum > 20 || !IsInteger(dNum) {
+			return nil, fmt.Errorf("format_number() decimals must be an integer between 0 and 20")
+		}
+		decimals = int(dNum)
+	}
+
+	thousandsSep := ","
+	if sep := GetArg(args, 2, "thousands_sep"); sep != nil {
+		s, ok := sep.(string)
+		if !ok {
+			return nil, fmt.Errorf("format_number() thousands_sep must be a string")
+		}
+		thousandsSep = s
+	}
+
+	decimalSep := "."
+	if sep := GetArg(args, 3, "decimal_sep"); sep != nil {
+		s, ok := sep.(string)
+		if !ok {
+			return nil, fmt.Errorf("format_number() decimal_sep must be a string")
+		}
+		decimalSep = s
+	}
+
+	formatted := formatFixed(math.Abs(n), decimals)
+	intPart, fracPart, hasFrac := strings.Cut(formatted, ".")
+
+	var sb strings.Builder
+	if negativeFixed(n, formatted) {
+		sb.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteString(thousandsSep)
+		}
+		sb.WriteRune(digit)
+	}
+	if hasFrac {
+		sb.WriteString(decimalSep)
+		sb.WriteString(fracPart)
+	}
+	return sb.String(), nil
+}
+
+// formatFixed writes abs (not negative) with decimals digits after the point,
+// or the shortest exact representation for decimals < 0
+func formatFixed(abs float64, decimals int) string {
+	if decimals >= 0 {
+		// Round half away from zero like round(), rather than FormatFloat's
+		// round-half-to-even, when the scaled value is still exact
+		scale := math.Pow(10, float64(decimals))
+		if abs*scale < maxSafeInteger {
+			abs = math.Round(abs*scale) / scale
+		}
+	}
+	return strconv.FormatFloat(abs, 'f', decimals, 64)
+}
+
+// negativeFixed reports whether formatted, the digits of n, needs a minus
+// sign. Don't print "-0" or "-0.00" when rounding reaches zero.
+func negativeFixed(n float64, formatted string) bool {
+	return n < 0 && strings.Trim(formatted, "0.") != ""
+}
+
+// builtinToFixed formats a number with exactly decimals digits after the
+// point: to_fixed(n, decimals)
+func builtinToFixed(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, ok := GetArg(args, 0, "n").(float64)
+	if !ok {
+		return nil, fmt.Errorf("to_fixed() requires a number as first argument")
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, fmt.Errorf("to_fixed() requires a finite number")
+	}
+	d, ok := GetArg(args, 1, "decimals").(float64)
+	if !ok || d < 0 || d > 20 || !IsInteger(d) {
+		return nil, fmt.Errorf("to_fixed() decimals must be an integer between 0 and 20")
+	}
+
+	formatted := formatFixed(math.Abs(n), int(d))
+	if negativeFixed(n, formatted) {
+		return "-" + formatted, nil
+	}
+	return formatted, nil
+}
+
+// builtinToPrecision formats a number with sig_figs significant digits:
+// to_precision(n, sig_figs). Like JavaScript's toPrecision(), very large and
+// very small numbers use exponent notation.
+func builtinToPrecision(evaluator *Evaluator, args map[string]any) (any, error) {
+	n, ok := GetArg(args, 0, "n").(float64)
+	if !ok {
+		return nil, fmt.Errorf("to_precision() requires a number as first argument")
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, fmt.Errorf("to_precision() requires a finite number")
+	}
+	s, ok := GetArg(args, 1, "sig_figs").(float64)
+	if !ok || s < 1 || s > 21 || !IsInteger(s) {
+		return nil, fmt.Errorf("to_precision() sig_figs must be an integer between 1 and 21")
+	}
+	sig := int(s)
+
+	// The exponent after rounding to sig digits, so 9.99 to 2 digits is 1.0e+01
+	abs := math.Abs(n)
+	exp := strconv.FormatFloat(abs, 'e', sig-1, 64)
+	e, _ := strconv.Atoi(exp[strings.IndexByte(exp, 'e')+1:])
+
+	formatted := exp
+	if e >= -6 && e < sig {
+		formatted = formatFixed(abs, sig-1-e)
+	}
+	if negativeFixed(n, formatted) {
+		return "-" + formatted, nil
+	}
+	return formatted, nil
+}
+
+// builtinWrapText wraps text on word boundaries: wrap_text(str, width [, indent])
+// Existing newlines are kept as paragraph breaks. indent (a number of spaces
+// or a string) starts every line after the first in a paragraph. Width is
+// counted in terminal columns, so color codes and wide characters line up.
+func builtinWrapText(evaluator *Evaluator, args map[string]any) (any, error) {
+	s, ok := GetArg(args, 0, "str").(string)
+	if !ok {
+		return nil, fmt.Errorf("wrap_text() requires a string as first argument")
+	}
+	width, ok := GetArg(args, 1, "width").(float64)
+	if !ok || width < 1 {
+		return nil, fmt.Errorf("wrap_text() requires a positive width as second argument")
+	}
+
+	indent := ""
+	switch v := GetArg(args, 2, "indent").(type) {
+	case nil:
+	case float64:
+		if v < 0 || !IsInteger(v) {
+			return nil, fmt.Errorf("wrap_text() indent must be a whole number of spaces or a string")
+		}
+		indent = strings.Repeat(" ", int(v))
+	case string:
+		indent = v
+	default:
+		return nil, fmt.Errorf("wrap_text() indent must be a whole number of spaces or a string")
+	}
+	if displayWidth(indent) >= int(width) {
+		return nil, fmt.Errorf("wrap_text() indent must be narrower than width")
+	}
+
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		lines = append(lines, wrapParagraph(para, int(width), indent)...)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// wrapParagraph fills one line of input into lines of at most width columns.
+// The paragraph's own leading whitespace is kept on its first line, and a
+// word longer than width gets a line to itself rather than being split.
+func wrapParagraph(para string, width int, indent string) []string {
+	text := strings.TrimLeft(para, " \t")
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := para[:len(para)-len(text)] + words[0]
+	lineWidth := displayWidth(line)
+	for _, word := range words[1:] {
+		wordWidth := displayWidth(word)
+		if lineWidth+1+wordWidth > width {
+			lines = append(lines, line)
+			line = indent + word
+			lineWidth = displayWidth(indent) + wordWidth
+			continue
+		}
+		line += " " + word
+		lineWidth += 1 + wordWidth
+	}
+	return append(lines, line)
+}
+
+// builtinDedent removes the leading whitespace every line shares: dedent(str)
+// Blank lines don't count toward the shared indent and come back empty.
+// Spaces and tabs must match exactly to count as shared.
+func builtinDedent(evaluator *Evaluator, args map[string]any) (any, error) {
+	s, ok := GetArg(args, 0, "str").(string)
+	if !ok {
+		return nil, fmt.Errorf("dedent() requires a string argument")
+	}
+
+	lines := strings.Split(s, "\n")
+	prefix := ""
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = lead, true
+			continue
+		}
+		for !strings.HasPrefix(lead, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[len(prefix):]
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// builtinHTMLEscape escapes <, >, &, ' and " so text can be placed in HTML
+// content or attribute values: html_escape(str)
+func builtinHTMLEscape(evaluator *Evaluator, args map[string]any) (any, error) {
+	s, ok := GetArg(args, 0, "str").(string)
+	if !ok {
+		return nil, fmt.Errorf("html_escape() requires a string argument")
+	}
+	return html.EscapeString(s), nil
+}
+
+// builtinHTMLUnescape decodes HTML entities such as &lt;, &#39; and &eacute;:
+// html_unescape(str)
+func builtinHTMLUnescape(evaluator *Evaluator, args map[string]any) (any, error) {
+	s, ok := GetArg(args, 0, "str").(string)
+	if !ok {
+		return nil, fmt.Errorf("html_unescape() requires a string argument")
+	}
+	return html.UnescapeString(s), nil
+}
+
+// displayWidth is the number of terminal columns s takes up, ignoring ANSI
+// escape codes: combining marks take none and East Asian wide characters and
+// emoji take two
+func displayWidth(s string) int {
+	s = color.Strip(s)
+	w := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Mn, r) || r == '\u200d':
+		case isWideRune(r):
+			w += 2
+		default:
+			w++
+		}
+	}
+	return w
+}
+
+func isWideRune(r rune) bool {
+	return (r >= 0x1100 && r <= 0x115f) || // Hangul Jamo
+		(r >= 0x2e80 && r <= 0xa4cf && r != 0x303f) || // CJK radicals through Yi
+		(r >= 0xac00 && r <= 0xd7a3) || // Hangul syllables
+		(r >= 0xf900 && r <= 0xfaff) || // CJK compatibility ideographs
+		(r >= 0xfe30 && r <= 0xfe4f) || // CJK compatibility forms
+		(r >= 0xff00 && r <= 0xff60) || (r >= 0xffe0 && r <= 0xffe6) || // fullwidth forms
+		(r >= 0x1f300 && r <= 0x1f64f) || (r >= 0x1f900 && r <= 0x1f9ff) || // emoji
+		(r >= 0x20000 && r <= 0x3fffd) // CJK extensions
+}
+
+// builtinStringBuilder builds a long string piece by piece in linear time
+// Usage: string_builder()
+// Returns an object with append(value [, ...]), which adds each argument
+// formatted as print() would, and build(), which returns everything appended
+// so far. Unlike s = s + piece in a loop, nothing is copied until build().
+func builtinStringBuilder(evaluator *Evaluator, args map[string]any) (any, error) {
+	var (
+		mu sync.Mutex
+		sb strings.Builder
+	)
+
+	appendFn := NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := 0; ; i++ {
+			val, ok := args[ArgKey(i)]
+			if !ok {
+				break
+			}
+			if str, ok := val.(string); ok {
+				sb.WriteString(str)
+			} else {
+				sb.WriteString(script.ValueForDisplay(InterfaceToValue(val)))
+			}
+		}
+		return nil, nil
+	})
+
+	buildFn := NewGoFunction(func(evaluator *Evaluator, args map[string]any) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return sb.String(), nil
+	})
+
+	return map[string]any{
+		"append": appendFn,
+		"build":  buildFn,
+	}, nil
+}
@@ -0,0 +1,204 @@
+package runtime
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/runtime/color"
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		in     string
+		width  float64
+		indent any
+		want   string
+	}{
+		{"the quick brown fox", 10, nil, "the quick\nbrown fox"},
+		{"one two\r\n\r\nthree   four", 20, nil, "one two\n\nthree four"},
+		{"  lead kept here", 9, nil, "  lead\nkept here"},
+		{"a longwordhere b", 4, nil, "a\nlongwordhere\nb"},
+		{"alpha beta gamma", 11, 2.0, "alpha beta\n  gamma"},
+		{"alpha beta gamma", 8, "> ", "alpha\n> beta\n> gamma"},
+		{"日本語 日本語", 8, nil, "日本語\n日本語"},
+		{color.Red + "red" + color.Reset + " fox", 7, nil, color.Red + "red" + color.Reset + " fox"},
+	}
+	for _, tt := range tests {
+		out, err := builtinWrapText(nil, map[string]any{"0": tt.in, "1": tt.width, "2": tt.indent})
+		if err != nil || out != tt.want {
+			t.Errorf("wrap_text(%q, %v, %v) = %q, %v; want %q", tt.in, tt.width, tt.indent, out, err, tt.want)
+		}
+	}
+
+	if _, err := builtinWrapText(nil, map[string]any{"0": "x", "1": 4.0, "2": 4.0}); err == nil {
+		t.Error("indent as wide as width should fail")
+	}
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"    a\n      b\n    c", "a\n  b\nc"},
+		{"\n    a\n\n      b\n", "\na\n\n  b\n"},
+		{"  a\n   \n  b", "a\n\nb"},
+		{"\ta\n\t\tb", "a\n\tb"},
+		{"  a\n\tb", "  a\n\tb"},
+		{"a\n  b", "a\n  b"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if out, err := builtinDedent(nil, map[string]any{"0": tt.in}); err != nil || out != tt.want {
+			t.Errorf("dedent(%q) = %q, %v; want %q", tt.in, out, err, tt.want)
+		}
+	}
+}
+
+func TestToFixedToPrecision(t *testing.T) {
+	fixed := []struct {
+		n, decimals float64
+		want        string
+	}{
+		{0.1 + 0.2, 2, "0.30"},
+		{3.14159, 2, "3.14"},
+		{2.5, 0, "3"},
+		{-1.5, 0, "-2"},
+		{1.005, 2, "1.00"},
+		{-0.001, 2, "0.00"},
+		{42, 3, "42.000"},
+	}
+	for _, tt := range fixed {
+		out, err := builtinToFixed(nil, map[string]any{"0": tt.n, "1": tt.decimals})
+		if err != nil || out != tt.want {
+			t.Errorf("to_fixed(%v, %v) = %q, %v; want %q", tt.n, tt.decimals, out, err, tt.want)
+		}
+	}
+
+	precision := []struct {
+		n, sig float64
+		want   string
+	}{
+		{3.14159, 3, "3.14"},
+		{0.000123456, 2, "0.00012"},
+		{1234.5, 6, "1234.50"},
+		{100, 3, "100"},
+		{9.99, 2, "10"},
+		{0, 3, "0.00"},
+		{-2.5, 1, "-3"},
+		{123456, 2, "1.2e+05"},
+		{99.5, 2, "1.0e+02"},
+		{1e-7, 3, "1.00e-07"},
+	}
+	for _, tt := range precision {
+		out, err := builtinToPrecision(nil, map[string]any{"0": tt.n, "1": tt.sig})
+		if err != nil || out != tt.want {
+			t.Errorf("to_precision(%v, %v) = %q, %v; want %q", tt.n, tt.sig, out, err, tt.want)
+		}
+	}
+
+	for _, args := range []map[string]any{
+		{"0": 1.0, "1": 2.5},
+		{"0": 1.0, "1": -1.0},
+		{"0": "1", "1": 2.0},
+		{"0": math.NaN(), "1": 2.0},
+	} {
+		if _, err := builtinToFixed(nil, args); err == nil {
+			t.Errorf("to_fixed(%v) should fail", args)
+		}
+	}
+	for _, args := range []map[string]any{
+		{"0": 1.0, "1": 0.0},
+		{"0": 1.0, "1": 22.0},
+		{"0": math.Inf(1), "1": 2.0},
+	} {
+		if _, err := builtinToPrecision(nil, args); err == nil {
+			t.Errorf("to_precision(%v) should fail", args)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`format_number(1234567.891, 2)`, "1,234,567.89"},
+		{`format_number(1234567.891)`, "1,234,567.891"},
+		{`format_number(999, 2)`, "999.00"},
+		{`format_number(-1234.5, 0)`, "-1,235"},
+		{`format_number(2.5, 0)`, "3"},
+		{`format_number(-999999.5, 0)`, "-1,000,000"},
+		{`format_number(-0.004, 2)`, "0.00"},
+		{`format_number(0)`, "0"},
+		{`format_number(100)`, "100"},
+		{`format_number(1000)`, "1,000"},
+		{`format_number(1e-7)`, "0.0000001"},
+		{`format_number(1e21)`, "1,000,000,000,000,000,000,000"},
+		{`format_number(1234567.891, 2, ".", ",")`, "1.234.567,89"},
+		{`format_number(1234567, thousands_sep = " ")`, "1 234 567"},
+		{`format_number(1234567, thousands_sep = "")`, "1234567"},
+		{`format_number(n = 1234.5, decimals = 2, thousands_sep = ".", decimal_sep = ",")`, "1.234,50"},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(" + tt.src + ")")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %q", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{
+		`format_number("1234")`,
+		`format_number(0/0)`,
+		`format_number(1/0)`,
+		`format_number(1, -1)`,
+		`format_number(1, 21)`,
+		`format_number(1, 1.5)`,
+		`format_number(1, 2, 3)`,
+		`format_number(1, decimal_sep = 4)`,
+	} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestHTMLEscape(t *testing.T) {
+	in := `<a href="x">Tom & Jerry's</a>`
+	escaped, err := builtinHTMLEscape(nil, map[string]any{"0": in})
+	if err != nil || escaped != "&lt;a href=&#34;x&#34;&gt;Tom &amp; Jerry&#39;s&lt;/a&gt;" {
+		t.Errorf("html_escape = %q, %v", escaped, err)
+	}
+	if out, err := builtinHTMLUnescape(nil, map[string]any{"0": escaped}); err != nil || out != in {
+		t.Errorf("html_unescape round trip = %q, %v", out, err)
+	}
+	if out, _ := builtinHTMLUnescape(nil, map[string]any{"0": "caf&eacute; &#x263A; &amp;amp;"}); out != "café ☺ &amp;" {
+		t.Errorf("html_unescape entities = %q", out)
+	}
+}
+
+func TestStringBuilder(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	got, err := script.NewInterpreter().ExecuteWithResult(`
+sb = string_builder()
+for i = 1, 3 do
+  sb.append("<li>", i, "</li>")
+end
+first = sb.build()
+sb.append("[", [1, "a"], nil, true, "]")
+exit(first, sb.build())
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{"<li>1</li><li>2</li><li>3</li>", `<li>1</li><li>2</li><li>3</li>[[1, "a"]niltrue]`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("string_builder built %#v, want %#v", got, want)
+	}
+}
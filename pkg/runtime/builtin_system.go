@@ -4,7 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	mathrand "math/rand"
 	"time"
+
+	"github.com/duso-org/duso/pkg/script"
 )
 
 // System functions
@@ -27,9 +30,10 @@ func builtinExit(evaluator *Evaluator, args map[string]any) (any, error) {
 }
 
 // builtinSleep pauses execution for the specified duration in seconds (default: 1)
+// Usage: sleep([seconds] [, jitter])
 func builtinSleep(evaluator *Evaluator, args map[string]any) (any, error) {
 	seconds := 1.0 // Default to 1 second
-	if arg, ok := args["0"]; ok {
+	if arg := GetArg(args, 0, "seconds"); arg != nil {
 		num, ok := arg.(float64)
 		if !ok {
 			return nil, fmt.Errorf("sleep() requires a number (seconds)")
@@ -39,40 +43,157 @@ func builtinSleep(evaluator *Evaluator, args map[string]any) (any, error) {
 		}
 		seconds = num
 	}
+	return sleepFor(evaluator, "sleep", seconds*float64(time.Second), GetArg(args, 1, "jitter"))
+}
+
+// builtinSleepMs pauses execution for the specified duration in milliseconds
+// Usage: sleep_ms(milliseconds [, jitter])
+func builtinSleepMs(evaluator *Evaluator, args map[string]any) (any, error) {
+	ms, ok := GetArg(args, 0, "milliseconds").(float64)
+	if !ok {
+		return nil, fmt.Errorf("sleep_ms() requires a number (milliseconds)")
+	}
+	if ms < 0 {
+		return nil, fmt.Errorf("sleep_ms() duration cannot be negative")
+	}
+	return sleepFor(evaluator, "sleep_ms", ms*float64(time.Millisecond), GetArg(args, 1, "jitter"))
+}
+
+// sleepFor waits for nanos nanoseconds, give or take a random fraction of up
+// to jitter (0 to 1) of it, so parallel retries don't all wake together
+func sleepFor(evaluator *Evaluator, name string, nanos float64, jitter any) (any, error) {
+	if jitter != nil {
+		j, ok := jitter.(float64)
+		if !ok || j < 0 || j > 1 {
+			return nil, fmt.Errorf("%s() jitter must be a number from 0 to 1", name)
+		}
+		nanos *= 1 + j*(2*mathrand.Float64()-1)
+	}
+
 	// Make sleep interruptible by listening for interrupt signal
 	select {
-	case <-time.After(time.Duration(seconds * float64(time.Second))):
+	case <-time.After(time.Duration(nanos)):
 		// Sleep completed normally
 	case <-interruptChan:
 		// Interrupted (e.g., Ctrl+C or systemctl stop)
 		return nil, fmt.Errorf("interrupted")
+	case <-evaluator.Context().Done():
+		// Cancelled by the embedder (Interpreter.ExecuteContext)
+		return nil, &script.CancelledError{Err: evaluator.Context().Err()}
 	}
 	return nil, nil
 }
 
-// builtinUUID generates a UUID v7 (RFC 9562)
-// UUID v7 is time-sorted with 48-bit Unix timestamp in milliseconds followed by random data
+// builtinUUID generates a UUID (RFC 9562)
+// Usage: uuid([version] [, format])
+// version is "v7" (default) or "v4". UUID v7 is time-sorted with a 48-bit
+// Unix timestamp in milliseconds followed by random data; v4 is all random.
+// format "compact" drops the dashes.
 func builtinUUID(evaluator *Evaluator, args map[string]any) (any, error) {
+	version := "v7"
+	if v, ok := GetArg(args, 0, "version").(string); ok {
+		version = v
+	} else if v := GetArg(args, 0, "version"); v != nil {
+		return nil, fmt.Errorf("uuid() version must be a string, \"v7\" or \"v4\"")
+	}
+	format := "standard"
+	if f, ok := GetArg(args, 1, "format").(string); ok {
+		format = f
+	} else if f := GetArg(args, 1, "format"); f != nil {
+		return nil, fmt.Errorf("uuid() format must be a string, \"standard\" or \"compact\"")
+	}
+	if format != "standard" && format != "compact" {
+		return nil, fmt.Errorf("uuid() unknown format '%s', expected \"standard\" or \"compact\"", format)
+	}
+
 	buf := make([]byte, 16)
+	switch version {
+	case "v7":
+		// 48-bit timestamp (Unix epoch in milliseconds)
+		binary.BigEndian.PutUint64(buf[0:8], uint64(time.Now().UnixMilli()))
 
-	// 48-bit timestamp (Unix epoch in milliseconds)
-	binary.BigEndian.PutUint64(buf[0:8], uint64(time.Now().UnixMilli()))
+		// Truncate timestamp to 6 bytes, shifting because PutUint64 writes 8 bytes
+		copy(buf[0:6], buf[2:8])
 
-	// Truncate timestamp to 6 bytes, shifting because PutUint64 writes 8 bytes
-	copy(buf[0:6], buf[2:8])
+		// 10 bytes random data
+		if _, err := rand.Read(buf[6:16]); err != nil {
+			return nil, fmt.Errorf("uuid() failed to generate random bytes: %v", err)
+		}
 
-	// 10 bytes random data
-	if _, err := rand.Read(buf[6:16]); err != nil {
-		return nil, fmt.Errorf("uuid() failed to generate random bytes: %v", err)
-	}
+		// Version 7: set version bits to 0111 in the 7th byte
+		buf[6] = (buf[6] & 0x0f) | 0x70
+	case "v4":
+		// 16 bytes random data, less the version and variant bits
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("uuid() failed to generate random bytes: %v", err)
+		}
 
-	// Version 7: set version bits to 0111 in the 7th byte
-	buf[6] = (buf[6] & 0x0f) | 0x70
+		// Version 4: set version bits to 0100 in the 7th byte
+		buf[6] = (buf[6] & 0x0f) | 0x40
+	default:
+		return nil, fmt.Errorf("uuid() unknown version '%s', expected \"v7\" or \"v4\"", version)
+	}
 
 	// Variant: set variant bits to 10 in the 9th byte
 	buf[8] = (buf[8] & 0x3f) | 0x80
 
+	if format == "compact" {
+		return fmt.Sprintf("%x", buf), nil
+	}
 	// Format as UUID string: xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
 	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16]), nil
 }
 
+// shortIDAlphabet is URL-safe, so IDs can go in paths and query strings as-is
+const shortIDAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz_-"
+
+// builtinShortID generates a short cryptographically random ID
+// Usage: short_id([length] [, alphabet])
+// length defaults to 12 and alphabet to A-Z, a-z, 0-9, _ and -. Every
+// character of the alphabet is equally likely.
+func builtinShortID(evaluator *Evaluator, args map[string]any) (any, error) {
+	length := 12
+	if l := GetArg(args, 0, "length"); l != nil {
+		n, ok := l.(float64)
+		if !ok || !IsInteger(n) || n < 1 || n > 1024 {
+			return nil, fmt.Errorf("short_id() length must be a whole number from 1 to 1024")
+		}
+		length = int(n)
+	}
+	alphabet := []rune(shortIDAlphabet)
+	if a := GetArg(args, 1, "alphabet"); a != nil {
+		s, ok := a.(string)
+		if !ok {
+			return nil, fmt.Errorf("short_id() alphabet must be a string")
+		}
+		alphabet = []rune(s)
+		if len(alphabet) < 2 || len(alphabet) > 256 {
+			return nil, fmt.Errorf("short_id() alphabet must have 2 to 256 characters")
+		}
+	}
+
+	// Mask random bytes down to the smallest power of two covering the
+	// alphabet and skip values past its end, so there's no modulo bias
+	mask := 1
+	for mask < len(alphabet) {
+		mask <<= 1
+	}
+	mask--
+
+	id := make([]rune, 0, length)
+	buf := make([]byte, length*2)
+	for len(id) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("short_id() failed to generate random bytes: %v", err)
+		}
+		for _, b := range buf {
+			if i := int(b) & mask; i < len(alphabet) {
+				id = append(id, alphabet[i])
+				if len(id) == length {
+					break
+				}
+			}
+		}
+	}
+	return string(id), nil
+}
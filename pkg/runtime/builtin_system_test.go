@@ -0,0 +1,157 @@
+package runtime
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// TestExecuteWithResult checks that exit() values reach the host, including
+// from inside a function and a try block.
+func TestExecuteWithResult(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		name   string
+		script string
+		want   []any
+	}{
+		{
+			name:   "object",
+			script: `config = {port = 8080, tags = ["a"]} exit(config)`,
+			want:   []any{map[string]any{"port": float64(8080), "tags": []any{"a"}}},
+		},
+		{
+			name:   "multiple values",
+			script: `exit(1, "two", nil)`,
+			want:   []any{float64(1), "two", nil},
+		},
+		{
+			name: "from a nested call",
+			script: `
+function finish(x)
+  try
+    exit(x * 2)
+  catch (e)
+    throw("exit should not be catchable")
+  end
+end
+finish(21)
+throw("unreachable")
+`,
+			want: []any{float64(42)},
+		},
+		{
+			name:   "no exit",
+			script: `x = 1`,
+			want:   []any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := script.NewInterpreter().ExecuteWithResult(tt.script)
+			if err != nil {
+				t.Fatalf("script failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := script.NewInterpreter().ExecuteWithResult(`throw("boom")`); err == nil {
+		t.Error("expected script error to be returned")
+	}
+}
+
+func TestUUID(t *testing.T) {
+	tests := []struct {
+		args    map[string]any
+		pattern string
+	}{
+		{map[string]any{}, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{map[string]any{"0": "v4"}, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{map[string]any{"format": "compact"}, `^[0-9a-f]{12}7[0-9a-f]{3}[89ab][0-9a-f]{15}$`},
+		{map[string]any{"0": "v4", "1": "compact"}, `^[0-9a-f]{12}4[0-9a-f]{3}[89ab][0-9a-f]{15}$`},
+	}
+	for _, tt := range tests {
+		got, err := builtinUUID(nil, tt.args)
+		if err != nil {
+			t.Fatalf("uuid(%v): %v", tt.args, err)
+		}
+		if !regexp.MustCompile(tt.pattern).MatchString(got.(string)) {
+			t.Errorf("uuid(%v) = %s, want match for %s", tt.args, got, tt.pattern)
+		}
+	}
+
+	for _, bad := range []map[string]any{{"0": "v1"}, {"format": "upper"}, {"0": float64(4)}} {
+		if _, err := builtinUUID(nil, bad); err == nil {
+			t.Errorf("uuid(%v) should fail", bad)
+		}
+	}
+}
+
+func TestShortID(t *testing.T) {
+	got, err := builtinShortID(nil, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9A-Za-z_-]{12}$`).MatchString(got.(string)) {
+		t.Errorf("short_id() = %q", got)
+	}
+
+	got, err = builtinShortID(nil, map[string]any{"0": float64(300), "1": "ab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[ab]{300}$`).MatchString(got.(string)) {
+		t.Errorf("short_id(300, \"ab\") = %q", got)
+	}
+
+	got, err = builtinShortID(nil, map[string]any{"length": float64(5), "alphabet": "αβγ"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[αβγ]{5}$`).MatchString(got.(string)) {
+		t.Errorf("short_id(5, \"αβγ\") = %q", got)
+	}
+
+	for _, bad := range []map[string]any{{"0": float64(0)}, {"0": 2.5}, {"0": "8"}, {"1": "a"}} {
+		if _, err := builtinShortID(nil, bad); err == nil {
+			t.Errorf("short_id(%v) should fail", bad)
+		}
+	}
+}
+
+func TestSleep(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		script   string
+		min, max time.Duration
+	}{
+		{`sleep(0.02)`, 20 * time.Millisecond, time.Second},
+		{`sleep_ms(20)`, 20 * time.Millisecond, time.Second},
+		{`sleep_ms(40, 0.5)`, 20 * time.Millisecond, time.Second},
+		{`sleep(0.04, jitter = 0.5)`, 20 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		if _, err := script.NewInterpreter().ExecuteWithResult(tt.script); err != nil {
+			t.Fatalf("%s: %v", tt.script, err)
+		}
+		if d := time.Since(start); d < tt.min || d > tt.max {
+			t.Errorf("%s took %v, want %v to %v", tt.script, d, tt.min, tt.max)
+		}
+	}
+
+	for _, bad := range []string{`sleep_ms()`, `sleep_ms(-1)`, `sleep(1, 2)`, `sleep_ms(1, "x")`} {
+		if _, err := script.NewInterpreter().ExecuteWithResult(bad); err == nil {
+			t.Errorf("%s should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// builtinTable renders rows as a bordered text table
+// Usage: table(rows [, headers])
+// rows is an array of objects or an array of arrays. For objects, headers
+// picks and orders the columns (default: every key, sorted); for arrays it
+// labels them. Numbers are right-aligned, everything else left-aligned.
+func builtinTable(evaluator *Evaluator, args map[string]any) (any, error) {
+	rowsVal := InterfaceToValue(GetArg(args, 0, "rows"))
+	if !rowsVal.IsArray() {
+		return nil, fmt.Errorf("table() requires an array of rows as first argument")
+	}
+	rows := rowsVal.AsArray()
+
+	var headers []string
+	if h := GetArg(args, 1, "headers"); h != nil {
+		hv := InterfaceToValue(h)
+		if !hv.IsArray() {
+			return nil, fmt.Errorf("table() headers must be an array")
+		}
+		for _, name := range hv.AsArray() {
+			headers = append(headers, tableCellText(name))
+		}
+	}
+
+	// Object rows take their columns from the headers or from their keys
+	objectRows := len(rows) > 0
+	for _, row := range rows {
+		if !row.IsObject() {
+			objectRows = false
+			break
+		}
+	}
+	keys := headers
+	if objectRows && keys == nil {
+		seen := map[string]bool{}
+		for _, row := range rows {
+			for k := range row.AsObject() {
+				if !seen[k] {
+					seen[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+		sort.Strings(keys)
+		headers = keys
+	}
+
+	cells := make([][]string, len(rows))
+	numeric := map[int]bool{}
+	for i, row := range rows {
+		var values []Value
+		switch {
+		case row.IsObject():
+			if !objectRows {
+				return nil, fmt.Errorf("table() rows must be all objects or all arrays")
+			}
+			obj := row.AsObject()
+			for _, k := range keys {
+				values = append(values, obj[k])
+			}
+		case row.IsArray():
+			values = row.AsArray()
+		default:
+			return nil, fmt.Errorf("table() row %d must be an object or array", i)
+		}
+		for col, v := range values {
+			cells[i] = append(cells[i], tableCellText(v))
+			if v.IsNumber() {
+				if _, seen := numeric[col]; !seen {
+					numeric[col] = true
+				}
+			} else if !v.IsNil() {
+				numeric[col] = false
+			}
+		}
+	}
+
+	// Column count and widths, in terminal columns with color codes removed
+	cols := len(headers)
+	for _, row := range cells {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return "", nil
+	}
+	widths := make([]int, cols)
+	for col, h := range headers {
+		widths[col] = displayWidth(h)
+	}
+	for _, row := range cells {
+		for col, c := range row {
+			widths[col] = max(widths[col], displayWidth(c))
+		}
+	}
+
+	var b strings.Builder
+	border := func(left, mid, right string) {
+		b.WriteString(left)
+		for col, w := range widths {
+			if col > 0 {
+				b.WriteString(mid)
+			}
+			b.WriteString(strings.Repeat("─", w+2))
+		}
+		b.WriteString(right + "\n")
+	}
+	line := func(row []string, alignNumbers bool) {
+		b.WriteString("│")
+		for col, w := range widths {
+			text := ""
+			if col < len(row) {
+				text = row[col]
+			}
+			pad := strings.Repeat(" ", w-displayWidth(text))
+			if alignNumbers && numeric[col] {
+				b.WriteString(" " + pad + text + " │")
+			} else {
+				b.WriteString(" " + text + pad + " │")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	border("┌", "┬", "┐")
+	if headers != nil {
+		line(headers, false)
+		border("├", "┼", "┤")
+	}
+	for _, row := range cells {
+		line(row, true)
+	}
+	border("└", "┴", "┘")
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// tableCellText is the display text of a cell, on one line
+func tableCellText(v Value) string {
+	if v.IsNil() {
+		return ""
+	}
+	s := script.ValueForDisplay(v)
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", " "), "\n", " ")
+}
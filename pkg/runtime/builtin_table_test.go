@@ -0,0 +1,42 @@
+package runtime
+
+import "testing"
+
+func TestTable(t *testing.T) {
+	rows := []any{
+		map[string]any{"name": "Zoë", "qty": 3.0},
+		map[string]any{"name": "東京", "qty": 120.0},
+	}
+	out, err := builtinTable(nil, map[string]any{"0": rows})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"┌──────┬─────┐\n" +
+		"│ name │ qty │\n" +
+		"├──────┼─────┤\n" +
+		"│ Zoë  │   3 │\n" +
+		"│ 東京 │ 120 │\n" +
+		"└──────┴─────┘"
+	if out != want {
+		t.Errorf("table =\n%s\nwant\n%s", out, want)
+	}
+
+	// Array rows without headers, ragged
+	out, err = builtinTable(nil, map[string]any{"0": []any{[]any{"a"}, []any{"b", "c"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "" +
+		"┌───┬───┐\n" +
+		"│ a │   │\n" +
+		"│ b │ c │\n" +
+		"└───┴───┘"
+	if out != want {
+		t.Errorf("table =\n%s\nwant\n%s", out, want)
+	}
+
+	if _, err := builtinTable(nil, map[string]any{"0": []any{"x"}}); err == nil {
+		t.Error("a string row should fail")
+	}
+}
@@ -1,15 +1,78 @@
 package runtime
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/duso-org/duso/pkg/script"
+)
 
 // builtinTemplate creates a reusable template function from a template string.
-// template(template_string) returns a function that evaluates the template with provided named args.
+// template(template_string [, partials]) returns a function that evaluates the
+// template with provided named args.
+//
+// Besides {{expr}} placeholders, templates support control tags:
+//
+//	{{for item in items}}...{{end}}
+//	{{if cond}}...{{elseif cond}}...{{else}}...{{end}}
+//	{{include "name"}}   (renders partials.name with the current variables)
+//	{{> name}}           (the same, also finding register_template() names)
+//
+// The control structure is parsed once, when template() is called.
 func builtinTemplate(evaluator *Evaluator, args map[string]any) (any, error) {
 	templateStr, ok := args["0"].(string)
 	if !ok {
 		return nil, fmt.Errorf("template() requires a string argument")
 	}
 
+	partialsArg, ok := args["partials"]
+	if !ok {
+		partialsArg = args["1"]
+	}
+	tmpl, err := compileTemplate(templateStr, partialsArg)
+	if err != nil {
+		return nil, fmt.Errorf("template(): %w", err)
+	}
+	return templateFunction(tmpl), nil
+}
+
+// builtinRegisterTemplate defines a named template that any template can
+// pull in with {{> name}} (or {{include "name"}}), looked up when the
+// including template renders.
+// register_template(name, template_string) returns the same function
+// template() would. Registering a name again replaces it.
+func builtinRegisterTemplate(evaluator *Evaluator, args map[string]any) (any, error) {
+	name, ok := args["0"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("register_template() requires a name as first argument")
+	}
+	templateStr, ok := args["1"].(string)
+	if !ok {
+		return nil, fmt.Errorf("register_template() requires a template string as second argument")
+	}
+
+	tmpl, err := compileTemplate(templateStr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("register_template(): %w", err)
+	}
+	registeredTemplates.Lock()
+	registeredTemplates.m[name] = tmpl.nodes
+	registeredTemplates.Unlock()
+	return templateFunction(tmpl), nil
+}
+
+// registeredTemplates holds register_template() definitions. They are
+// shared process-wide, so a server can register its layout fragments once
+// at startup and use them from every request handler.
+var registeredTemplates = struct {
+	sync.RWMutex
+	m map[string][]*tmplNode
+}{m: map[string][]*tmplNode{}}
+
+// templateFunction wraps a compiled template in the function that
+// template() and register_template() return
+func templateFunction(tmpl *compiledTemplate) Value {
 	// Return a function that evaluates the template with provided args
 	templateFn := func(templateEval *Evaluator, templateArgs map[string]any) (any, error) {
 		// Convert map[string]any to map[string]Value for bindings
@@ -24,14 +87,326 @@ func builtinTemplate(evaluator *Evaluator, args map[string]any) (any, error) {
 			bindings[key] = InterfaceToValue(val)
 		}
 
-		// Use public API to evaluate template
-		result, err := templateEval.EvaluateTemplate(templateStr, bindings)
-		if err != nil {
+		var out strings.Builder
+		if err := tmpl.render(templateEval, tmpl.nodes, bindings, &out, 0); err != nil {
 			return nil, fmt.Errorf("template evaluation error: %w", err)
 		}
+		return out.String(), nil
+	}
+
+	return NewGoFunction(templateFn)
+}
 
-		return result, nil
+// maxIncludeDepth bounds {{include}} nesting so a partial that includes
+// itself fails instead of recursing forever
+const maxIncludeDepth = 32
+
+type tmplKind int
+
+const (
+	tmplText tmplKind = iota
+	tmplFor
+	tmplIf
+	tmplInclude
+)
+
+// tmplNode is one piece of a compiled template
+type tmplNode struct {
+	kind     tmplKind
+	text     string        // text: literal text, may hold {{expr}} placeholders; include: partial name
+	varName  string        // for: loop variable
+	expr     string        // for: collection expression
+	body     []*tmplNode   // for: loop body
+	branches []*tmplBranch // if: branches in order, else last with cond ""
+}
+
+type tmplBranch struct {
+	cond string
+	body []*tmplNode
+}
+
+type compiledTemplate struct {
+	nodes    []*tmplNode
+	partials map[string][]*tmplNode
+}
+
+// compileTemplate parses the control tags of a template and its partials
+func compileTemplate(src string, partialsArg any) (*compiledTemplate, error) {
+	nodes, err := parseTemplateNodes(src)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &compiledTemplate{nodes: nodes, partials: map[string][]*tmplNode{}}
+
+	if partialsArg == nil {
+		return tmpl, nil
+	}
+	partials, ok := partialsArg.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("partials must be an object of template strings")
+	}
+	for name, p := range partials {
+		pstr, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("partial '%s' must be a string", name)
+		}
+		pnodes, err := parseTemplateNodes(pstr)
+		if err != nil {
+			return nil, fmt.Errorf("in partial '%s': %w", name, err)
+		}
+		tmpl.partials[name] = pnodes
 	}
+	return tmpl, nil
+}
 
-	return NewGoFunction(templateFn), nil
+// parseTemplateNodes splits a template into text and control nodes. Tags
+// that aren't control tags are left in the text for EvaluateTemplate. A
+// block tag alone on its line takes the whole line with it, so for/if/end
+// don't leave blank lines in the output.
+func parseTemplateNodes(src string) ([]*tmplNode, error) {
+	type frame struct {
+		node  *tmplNode
+		nodes *[]*tmplNode
+		tag   string
+	}
+	var root []*tmplNode
+	cur := &root
+	var stack []frame
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			*cur = append(*cur, &tmplNode{kind: tmplText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	pos := 0
+	for {
+		open := strings.Index(src[pos:], "{{")
+		if open < 0 {
+			text.WriteString(src[pos:])
+			break
+		}
+		open += pos
+		closeIdx := strings.Index(src[open+2:], "}}")
+		if closeIdx < 0 {
+			text.WriteString(src[pos:])
+			break
+		}
+		closeIdx += open + 2
+		tag := strings.TrimSpace(src[open+2 : closeIdx])
+		keyword, rest := splitTemplateTag(tag)
+		if keyword == "" {
+			// Ordinary {{expr}}: keep it for EvaluateTemplate
+			text.WriteString(src[pos : open+2])
+			pos = open + 2
+			continue
+		}
+
+		text.WriteString(src[pos:open])
+		pos = closeIdx + 2
+
+		// Standalone block tag: drop its indentation and line break
+		if indent, ok := standaloneTag(src[:open], src[pos:]); ok && keyword != "include" && keyword != ">" {
+			trimmed := text.String()
+			trimmed = trimmed[:len(trimmed)-min(indent, len(trimmed))]
+			text.Reset()
+			text.WriteString(trimmed)
+			if nl := strings.IndexByte(src[pos:], '\n'); nl >= 0 {
+				pos += nl + 1
+			} else {
+				pos = len(src)
+			}
+		}
+		flush()
+
+		switch keyword {
+		case "for":
+			varName, collection, ok := strings.Cut(rest, " in ")
+			varName = strings.TrimSpace(varName)
+			collection = strings.TrimSpace(collection)
+			if !ok || !isTemplateIdent(varName) || collection == "" {
+				return nil, fmt.Errorf("invalid tag {{%s}}, expected {{for name in expr}}", tag)
+			}
+			node := &tmplNode{kind: tmplFor, varName: varName, expr: collection}
+			*cur = append(*cur, node)
+			stack = append(stack, frame{node: node, nodes: cur, tag: tag})
+			cur = &node.body
+		case "if":
+			if rest == "" {
+				return nil, fmt.Errorf("invalid tag {{if}}, missing condition")
+			}
+			branch := &tmplBranch{cond: rest}
+			node := &tmplNode{kind: tmplIf, branches: []*tmplBranch{branch}}
+			*cur = append(*cur, node)
+			stack = append(stack, frame{node: node, nodes: cur, tag: tag})
+			cur = &branch.body
+		case "elseif", "else":
+			if len(stack) == 0 || stack[len(stack)-1].node.kind != tmplIf {
+				return nil, fmt.Errorf("{{%s}} without a matching {{if}}", tag)
+			}
+			node := stack[len(stack)-1].node
+			if last := node.branches[len(node.branches)-1]; last.cond == "" {
+				return nil, fmt.Errorf("{{%s}} after {{else}}", tag)
+			}
+			if keyword == "elseif" && rest == "" {
+				return nil, fmt.Errorf("invalid tag {{elseif}}, missing condition")
+			}
+			branch := &tmplBranch{cond: rest}
+			node.branches = append(node.branches, branch)
+			cur = &branch.body
+		case "end":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("{{end}} without a matching {{for}} or {{if}}")
+			}
+			cur = stack[len(stack)-1].nodes
+			stack = stack[:len(stack)-1]
+		case "include":
+			name := strings.TrimSpace(rest)
+			if len(name) < 2 || (name[0] != '"' && name[0] != '\'') || name[len(name)-1] != name[0] {
+				return nil, fmt.Errorf("invalid tag {{%s}}, expected {{include \"name\"}}", tag)
+			}
+			*cur = append(*cur, &tmplNode{kind: tmplInclude, text: name[1 : len(name)-1]})
+		case ">":
+			if !isTemplateIdent(rest) {
+				return nil, fmt.Errorf("invalid tag {{%s}}, expected {{> name}}", tag)
+			}
+			*cur = append(*cur, &tmplNode{kind: tmplInclude, text: rest})
+		}
+	}
+	flush()
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing {{end}} for {{%s}}", stack[len(stack)-1].tag)
+	}
+	return root, nil
+}
+
+// splitTemplateTag returns the control keyword of a tag and the rest of it,
+// or "" when the tag is an ordinary expression
+func splitTemplateTag(tag string) (string, string) {
+	if tag == "else" || tag == "end" {
+		return tag, ""
+	}
+	if rest, ok := strings.CutPrefix(tag, ">"); ok {
+		return ">", strings.TrimSpace(rest)
+	}
+	for _, kw := range []string{"for", "if", "elseif", "include"} {
+		if rest, ok := strings.CutPrefix(tag, kw); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return kw, strings.TrimSpace(rest)
+		}
+	}
+	return "", ""
+}
+
+// standaloneTag reports whether a tag sits alone on its line, given the
+// source before and after it, and returns the width of its indentation
+func standaloneTag(before, after string) (int, bool) {
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	if strings.TrimLeft(before[lineStart:], " \t") != "" {
+		return 0, false
+	}
+	lineEnd := strings.IndexByte(after, '\n')
+	if lineEnd < 0 {
+		lineEnd = len(after)
+	}
+	if strings.TrimRight(after[:lineEnd], " \t\r") != "" {
+		return 0, false
+	}
+	return len(before) - lineStart, true
+}
+
+func isTemplateIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// render writes nodes to out, evaluating them against scope
+func (t *compiledTemplate) render(e *Evaluator, nodes []*tmplNode, scope map[string]Value, out *strings.Builder, depth int) error {
+	for _, n := range nodes {
+		switch n.kind {
+		case tmplText:
+			if !strings.Contains(n.text, "{{") {
+				out.WriteString(n.text)
+				continue
+			}
+			s, err := e.EvaluateTemplate(n.text, scope)
+			if err != nil {
+				return err
+			}
+			out.WriteString(s)
+		case tmplFor:
+			coll, err := e.EvaluateExpression(n.expr, scope)
+			if err != nil {
+				return fmt.Errorf("{{for %s in %s}}: %w", n.varName, n.expr, err)
+			}
+			var items []Value
+			switch {
+			case coll.IsArray():
+				items = coll.AsArray()
+			case coll.IsObject():
+				// Keys in insertion order for ordered objects, otherwise
+				// sorted so output is deterministic
+				for _, k := range script.SortedKeys(coll.AsObject()) {
+					items = append(items, NewString(k))
+				}
+			case coll.IsNil():
+			default:
+				return fmt.Errorf("{{for %s in %s}}: cannot iterate over %s", n.varName, n.expr, coll.Type)
+			}
+			loopScope := make(map[string]Value, len(scope)+1)
+			for k, v := range scope {
+				loopScope[k] = v
+			}
+			for _, item := range items {
+				loopScope[n.varName] = item
+				if err := t.render(e, n.body, loopScope, out, depth); err != nil {
+					return err
+				}
+			}
+		case tmplIf:
+			for _, b := range n.branches {
+				if b.cond != "" {
+					cond, err := e.EvaluateExpression(b.cond, scope)
+					if err != nil {
+						return fmt.Errorf("{{if %s}}: %w", b.cond, err)
+					}
+					if !cond.IsTruthy() {
+						continue
+					}
+				}
+				if err := t.render(e, b.body, scope, out, depth); err != nil {
+					return err
+				}
+				break
+			}
+		case tmplInclude:
+			// The template's own partials win over registered templates
+			partial, ok := t.partials[n.text]
+			if !ok {
+				registeredTemplates.RLock()
+				partial, ok = registeredTemplates.m[n.text]
+				registeredTemplates.RUnlock()
+			}
+			if !ok {
+				return fmt.Errorf("{{include \"%s\"}}: no such partial or registered template", n.text)
+			}
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("{{include \"%s\"}}: includes nested more than %d deep", n.text, maxIncludeDepth)
+			}
+			if err := t.render(e, partial, scope, out, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,107 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestTemplateControlTags(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "for over array",
+			script: `t = template(raw "{{for x in xs}}[{{x}}]{{end}}") return t(xs = [1, 2, 3])`,
+			want:   "[1][2][3]",
+		},
+		{
+			name:   "for over object keys in sorted order",
+			script: `t = template(raw "{{for k in o}}{{k}}={{o[k]}};{{end}}") return t(o = {b = 2, a = 1})`,
+			want:   "a=1;b=2;",
+		},
+		{
+			name:   "for over ordered object keys in insertion order",
+			script: `t = template(raw "{{for k in o}}{{k}}={{o[k]}};{{end}}") o = ordered_object() o.z = 1 o.a = 2 return t(o = o)`,
+			want:   "z=1;a=2;",
+		},
+		{
+			name:   "if elseif else",
+			script: `t = template(raw "{{for n in ns}}{{if n > 1}}big{{elseif n == 1}}one{{else}}small{{end}} {{end}}") return t(ns = [0, 1, 2])`,
+			want:   "small one big ",
+		},
+		{
+			name:   "include sees loop variables",
+			script: `t = template(raw "{{for u in users}}{{include 'row'}}{{end}}", {row = raw "<{{u}}>"}) return t(users = ["a", "b"])`,
+			want:   "<a><b>",
+		},
+		{
+			name:   "standalone block tags drop their lines",
+			script: "t = template(raw \"\"\"\n{{for x in xs}}\n  - {{x}}\n{{end}}\ndone\"\"\") return t(xs = [1, 2])",
+			want:   "  - 1\n  - 2\ndone",
+		},
+		{
+			name:   "registered templates resolve at render time",
+			script: `page = template(raw "{{> tt_head}}|{{>tt_body}}") register_template("tt_head", raw "<{{title}}>") register_template("tt_body", raw "{{for x in xs}}{{x}}{{end}}") return page(title = "T", xs = [1, 2])`,
+			want:   "<T>|12",
+		},
+		{
+			name:   "own partials win over registered templates",
+			script: `register_template("tt_row", raw "registered") t = template(raw "{{> tt_row}}", {tt_row = raw "own"}) return t()`,
+			want:   "own",
+		},
+		{
+			name:   "register_template returns the template function",
+			script: `f = register_template("tt_greet", raw "hi {{name}}") return f(name = "ann")`,
+			want:   "hi ann",
+		},
+		{
+			name:   "undefined placeholders stay literal",
+			script: `t = template(raw "{{if true}}{{missing}}{{end}}") return t()`,
+			want:   "{{missing}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := script.NewInterpreter().ExecuteModule(tt.script)
+			if err != nil {
+				t.Fatalf("script failed: %v", err)
+			}
+			if got.AsString() != tt.want {
+				t.Errorf("got %q, want %q", got.AsString(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateControlTagErrors(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{`template(raw "{{for x in xs}}no end")`, "missing {{end}} for {{for x in xs}}"},
+		{`template(raw "{{end}}")`, "{{end}} without a matching"},
+		{`template(raw "{{else}}")`, "{{else}} without a matching {{if}}"},
+		{`template(raw "{{for 1 in xs}}{{end}}")`, "expected {{for name in expr}}"},
+		{`t = template(raw "{{include 'nope'}}") t()`, "no such partial"},
+		{`t = template(raw "{{include 'me'}}", {me = raw "{{include 'me'}}"}) t()`, "nested more than 32 deep"},
+		{`t = template(raw "{{for x in n}}{{end}}") t(n = 5)`, "cannot iterate over number"},
+		{`template(raw "{{> two words}}")`, "expected {{> name}}"},
+		{`register_template("", "x")`, "requires a name"},
+	}
+
+	for _, tt := range tests {
+		_, err := script.NewInterpreter().ExecuteModule(tt.script)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.script, tt.want, err)
+		}
+	}
+}
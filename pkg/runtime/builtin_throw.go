@@ -118,7 +118,7 @@ func builtinWatch(evaluator *Evaluator, args map[string]any) (any, error) {
 
 		// Check if value changed from cached
 		cachedVal, exists := watchCache[expr]
-		if !exists || !valuesEqualThrow(val, cachedVal) {
+		if !exists || !deepEqual(val, cachedVal) {
 			// Value changed or first time seeing it
 			watchCache[expr] = val
 			triggered = append(triggered, fmt.Sprintf("WATCH: %s = %v", expr, val.String()))
@@ -176,54 +176,6 @@ func formatArgsThrow(args map[string]any) string {
 	return strings.Join(parts, " ")
 }
 
-// valuesEqualThrow checks if two values are equal (for watch caching)
-func valuesEqualThrow(v1, v2 Value) bool {
-	if v1.Type != v2.Type {
-		return false
-	}
-
-	switch v1.Type {
-	case VAL_NIL:
-		return true
-	case VAL_NUMBER:
-		return v1.AsNumber() == v2.AsNumber()
-	case VAL_STRING:
-		return v1.AsString() == v2.AsString()
-	case VAL_BOOL:
-		return v1.AsBool() == v2.AsBool()
-	case VAL_ARRAY:
-		arr1 := v1.AsArray()
-		arr2 := v2.AsArray()
-		if len(arr1) != len(arr2) {
-			return false
-		}
-		for i := range arr1 {
-			if !valuesEqualThrow(arr1[i], arr2[i]) {
-				return false
-			}
-		}
-		return true
-	case VAL_OBJECT:
-		obj1 := v1.AsObject()
-		obj2 := v2.AsObject()
-		if len(obj1) != len(obj2) {
-			return false
-		}
-		for k, v := range obj1 {
-			v2Val, ok := obj2[k]
-			if !ok || !valuesEqualThrow(v, v2Val) {
-				return false
-			}
-		}
-		return true
-	case VAL_FUNCTION:
-		// Functions are compared by reference
-		return v1.Data == v2.Data
-	default:
-		return false
-	}
-}
-
 // builtinAssert checks a condition and throws an error if false
 func builtinAssert(evaluator *Evaluator, args map[string]any) (any, error) {
 	var condition any = false
@@ -1,7 +1,12 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/duso-org/duso/pkg/script"
@@ -66,31 +71,113 @@ func builtinType(evaluator *Evaluator, args map[string]any) (any, error) {
 
 // Type conversion functions
 
-// builtinToNumber converts a value to number
+// builtinToNumber converts a value to number.
+// Strings parse as floats by default; 0x/0b/0o prefixes select hex, binary,
+// or octal integers, and an optional base (2-36) parses integers in that radix.
+// With strict=true, unparseable input returns nil instead of 0.
 func builtinToNumber(evaluator *Evaluator, args map[string]any) (any, error) {
 	if arg, ok := args["0"]; ok {
+		base := 0
+		if b := GetArg(args, 1, "base"); b != nil {
+			n, ok := b.(float64)
+			if !ok || n != float64(int(n)) || n < 2 || n > 36 {
+				return nil, fmt.Errorf("tonumber() base must be an integer between 2 and 36")
+			}
+			base = int(n)
+		}
+		strict, _ := args["strict"].(bool)
+
 		switch v := arg.(type) {
 		case float64:
 			return v, nil
 		case string:
-			var n float64
-			_, err := fmt.Sscanf(v, "%f", &n)
-			if err != nil {
-				return 0.0, nil // Return 0 on parse error like Lua
+			if n, ok := parseNumberString(v, base, strict); ok {
+				return n, nil
 			}
-			return n, nil
+			if strict {
+				return nil, nil
+			}
+			return 0.0, nil // Return 0 on parse error like Lua
 		case bool:
 			if v {
 				return 1.0, nil
 			}
 			return 0.0, nil
 		default:
+			if strict {
+				return nil, nil
+			}
 			return 0.0, nil
 		}
 	}
 	return nil, fmt.Errorf("tonumber() requires an argument")
 }
 
+// parseNumberString parses s as a number. With base 0 it auto-detects
+// 0x/0b/0o integer prefixes and otherwise parses a float; with an explicit
+// base it parses an integer in that radix (a matching prefix is allowed).
+// Strict parsing rejects trailing garbage ("12abc") that the lenient
+// float scan accepts.
+func parseNumberString(s string, base int, strict bool) (float64, bool) {
+	s = strings.TrimSpace(s)
+
+	sign := ""
+	digits := s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+
+	prefixBase := 0
+	if len(digits) > 2 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			prefixBase = 16
+		case 'b', 'B':
+			prefixBase = 2
+		case 'o', 'O':
+			prefixBase = 8
+		}
+	}
+	if prefixBase != 0 && (base == 0 || base == prefixBase) {
+		base = prefixBase
+		digits = digits[2:]
+	}
+
+	if base == 0 && strict {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return 0, false
+		}
+		return n, true
+	}
+	if base == 0 {
+		var n float64
+		if _, err := fmt.Sscanf(s, "%f", &n); err != nil {
+			return 0, false
+		}
+		return n, true
+	}
+
+	// Numbers are float64, so magnitudes past uint64 are still representable
+	// (rounded); only ones past the float64 range are rejected
+	var n float64
+	if u, err := strconv.ParseUint(digits, base, 64); err == nil {
+		n = float64(u)
+	} else if errors.Is(err, strconv.ErrRange) {
+		b, _ := new(big.Int).SetString(digits, base)
+		n, _ = new(big.Float).SetInt(b).Float64()
+		if math.IsInf(n, 0) {
+			return 0, false
+		}
+	} else {
+		return 0, false
+	}
+	if sign == "-" {
+		n = -n
+	}
+	return n, true
+}
+
 // builtinToString converts a value to string
 func builtinToString(evaluator *Evaluator, args map[string]any) (any, error) {
 	if arg, ok := args["0"]; ok {
@@ -101,6 +188,37 @@ func builtinToString(evaluator *Evaluator, args map[string]any) (any, error) {
 	return nil, fmt.Errorf("tostring() requires an argument")
 }
 
+// builtinInspect renders a value as indented, multi-line text for debugging
+// Usage: inspect(value [, depth])
+// depth limits how many levels of arrays and objects are expanded.
+func builtinInspect(evaluator *Evaluator, args map[string]any) (any, error) {
+	val, ok := args["0"]
+	if !ok {
+		if val, ok = args["value"]; !ok {
+			return nil, fmt.Errorf("inspect() requires a value")
+		}
+	}
+	depth, err := inspectDepth(GetArg(args, 1, "depth"))
+	if err != nil {
+		return nil, err
+	}
+	return script.InspectValue(InterfaceToValue(val), depth), nil
+}
+
+// inspectDepth reads inspect()'s depth argument; 0 means no limit
+func inspectDepth(arg any) (int, error) {
+	switch d := arg.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if d < 1 || d != float64(int(d)) {
+			return 0, fmt.Errorf("inspect() depth must be a whole number greater than 0")
+		}
+		return int(d), nil
+	}
+	return 0, fmt.Errorf("inspect() depth must be a number")
+}
+
 // builtinToBool converts a value to boolean
 func builtinToBool(evaluator *Evaluator, args map[string]any) (any, error) {
 	if arg, ok := args["0"]; ok {
@@ -123,3 +241,78 @@ func builtinToBool(evaluator *Evaluator, args map[string]any) (any, error) {
 	}
 	return nil, fmt.Errorf("tobool() requires an argument")
 }
+
+// builtinAsArray wraps a value in an array unless it already is one.
+// nil becomes an empty array so optional "value or list" arguments work.
+func builtinAsArray(evaluator *Evaluator, args map[string]any) (any, error) {
+	arg, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("as_array() requires an argument")
+	}
+	switch v := arg.(type) {
+	case *[]Value:
+		return v, nil
+	case nil:
+		return NewArray([]Value{}), nil
+	default:
+		if err := evaluator.ChargeElements(1); err != nil {
+			return nil, err
+		}
+		return NewArray([]Value{InterfaceToValue(v)}), nil
+	}
+}
+
+// builtinAsObject returns objects unchanged, nil as an empty object, and
+// arrays as an object keyed by index ("0", "1", ...)
+func builtinAsObject(evaluator *Evaluator, args map[string]any) (any, error) {
+	arg, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("as_object() requires an argument")
+	}
+	switch v := arg.(type) {
+	case map[string]any:
+		return v, nil
+	case nil:
+		return NewObject(map[string]Value{}), nil
+	case *[]Value:
+		if err := evaluator.ChargeElements(len(*v)); err != nil {
+			return nil, err
+		}
+		obj := make(map[string]Value, len(*v))
+		for i, item := range *v {
+			obj[strconv.Itoa(i)] = item
+		}
+		return NewObject(obj), nil
+	default:
+		return nil, fmt.Errorf("as_object() cannot convert %s to object", InterfaceToValue(v).Type)
+	}
+}
+
+// builtinCoerce converts a value to the named type: coerce(value, type)
+// Type names match type(): "number", "string", "boolean", "array", "object".
+func builtinCoerce(evaluator *Evaluator, args map[string]any) (any, error) {
+	arg, ok := args["0"]
+	if !ok {
+		return nil, fmt.Errorf("coerce() requires a value as first argument")
+	}
+	typeName, ok := args["1"].(string)
+	if !ok {
+		return nil, fmt.Errorf("coerce() requires a type name string as second argument")
+	}
+
+	single := map[string]any{"0": arg}
+	switch typeName {
+	case "number":
+		return builtinToNumber(evaluator, single)
+	case "string":
+		return builtinToString(evaluator, single)
+	case "boolean", "bool":
+		return builtinToBool(evaluator, single)
+	case "array":
+		return builtinAsArray(evaluator, single)
+	case "object":
+		return builtinAsObject(evaluator, single)
+	default:
+		return nil, fmt.Errorf("coerce() unknown type %q (expected number, string, boolean, array, or object)", typeName)
+	}
+}
@@ -0,0 +1,136 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestToNumberBase(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`[tonumber("ff", 16), tonumber("FF", 16), tonumber("101", 2), tonumber("zz", 36), tonumber("17", 8)]`, `[255, 255, 5, 1295, 15]`},
+		{`[tonumber("-ff", 16), tonumber(" 1f ", 16), tonumber("ff", base=16)]`, `[-255, 31, 255]`},
+		{`[tonumber("0x1F"), tonumber("0XfF"), tonumber("0b101"), tonumber("0o17")]`, `[31, 255, 5, 15]`},
+		{`[tonumber("-0o17"), tonumber("+0b11"), tonumber("-0x10")]`, `[-15, 3, -16]`},
+		// A leading zero is decimal, not octal
+		{`[tonumber("017"), tonumber("0"), tonumber("1e3"), tonumber("3.14")]`, `[17, 0, 1000, 3.14]`},
+		// A prefix is allowed only when it matches the base
+		{`[tonumber("0xff", 16), tonumber("0b11", 2), tonumber("0x10", 8), tonumber("0b11", 16)]`, `[255, 3, 0, 2833]`},
+		// Digits outside the base, bare prefixes and fractions don't parse
+		{`[tonumber("12", 2), tonumber("0x"), tonumber("0xZZ"), tonumber("0b12"), tonumber("1.5", 10)]`, `[0, 0, 0, 0, 0]`},
+		{`[tonumber(true, 16), tonumber(5, 2)]`, `[1, 5]`},
+		// Past int64 and uint64 the value is rounded to a float64; past the
+		// float64 range it doesn't parse
+		{`[tonumber("9223372036854775808", 10), tonumber("0xffffffffffffffff"), tonumber("ffffffffffffffff", 16)]`, `[9.223372036854776e+18, 1.8446744073709552e+19, 1.8446744073709552e+19]`},
+		{`[tonumber("-0x10000000000000000"), tonumber("0xffffffffffffffff", strict=true), tonumber(repeat("f", 300), 16)]`, `[-1.8446744073709552e+19, 1.8446744073709552e+19, 0]`},
+		{`tonumber(repeat("f", 300), 16, strict=true)`, `nil`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{
+		`tonumber("10", 1)`,
+		`tonumber("10", 37)`,
+		`tonumber("10", 0)`,
+		`tonumber("10", 2.5)`,
+		`tonumber("10", "16")`,
+		`tonumber("10", -16)`,
+		`tonumber()`,
+	} {
+		if _, err := script.NewInterpreter().Execute(src); err == nil {
+			t.Errorf("%s should fail", src)
+		}
+	}
+}
+
+func TestToNumberStrict(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`[tonumber("0", strict=true), tonumber(" 42 ", strict=true), tonumber("-1.5", strict=true), tonumber("1e3", strict=true)]`, `[0, 42, -1.5, 1000]`},
+		{`[tonumber("abc", strict=true), tonumber("12abc", strict=true), tonumber("", strict=true), tonumber("NaN", strict=true), tonumber("inf", strict=true)]`, `[nil, nil, nil, nil, nil]`},
+		{`[tonumber(nil, strict=true), tonumber([1], strict=true), tonumber({a = 1}, strict=true)]`, `[nil, nil, nil]`},
+		{`[tonumber(7, strict=true), tonumber(true, strict=true), tonumber(false, strict=true), tonumber("0x1f", strict=true)]`, `[7, 1, 0, 31]`},
+		{`[tonumber("ff", 16, strict=true), tonumber("zz", 16, strict=true), tonumber("0x", strict=true)]`, `[255, nil, nil]`},
+		// Without strict the same inputs fall back to 0, and "12abc" keeps its prefix
+		{`[tonumber("abc"), tonumber("12abc"), tonumber(""), tonumber(nil), tonumber("zz", 16), tonumber("abc", strict=false)]`, `[0, 12, 0, 0, 0, 0]`},
+	}
+	for _, tt := range tests {
+		got, err := script.NewInterpreter().ExecuteWithResult("exit(tostring(" + tt.src + "))")
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestConversions runs as_array(), as_object() and coerce() as a script that
+// throws on a wrong result.
+func TestConversions(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	src := `
+list = [1, 2]
+same = as_array(list)
+push(same, 3)
+if len(list) != 3 then throw("as_array: an array should come back unchanged") end
+if tostring(as_array(5)) != "[5]" or len(as_array(nil)) != 0 then throw("as_array: wrap scalars, nil is empty") end
+wrapped = as_array({a = 1})
+if len(wrapped) != 1 or wrapped[0].a != 1 then throw("as_array: objects are wrapped, not converted") end
+if as_array("ab")[0] != "ab" then throw("as_array: strings are wrapped whole") end
+
+obj = {a = 1}
+same_obj = as_object(obj)
+if same_obj.a != 1 or len(keys(same_obj)) != 1 then throw("as_object: an object should come back unchanged") end
+if len(keys(as_object(nil))) != 0 then throw("as_object: nil is empty") end
+indexed = as_object(["x", "y"])
+if indexed["0"] != "x" or indexed["1"] != "y" or len(keys(indexed)) != 2 then throw("as_object: arrays key by index") end
+for bad in [5, "s", true] do
+  failed = false
+  try
+    as_object(bad)
+  catch (e)
+    failed = contains(tostring(e), "cannot convert")
+  end
+  if not failed then throw("as_object: " + tostring(bad) + " should be rejected") end
+end
+
+if coerce("42", "number") != 42 or coerce("abc", "number") != 0 then throw("coerce: number") end
+if coerce(42, "string") != "42" or coerce(nil, "string") != "nil" then throw("coerce: string") end
+if coerce(0, "boolean") != false or coerce("x", "bool") != true then throw("coerce: boolean") end
+if tostring(coerce(3, "array")) != "[3]" or coerce([1], "array")[0] != 1 then throw("coerce: array") end
+if coerce(["x"], "object")["0"] != "x" then throw("coerce: object") end
+if coerce("7", type(1)) != 7 or coerce(7, type("s")) != "7" then throw("coerce: type() names") end
+for args in [["x", "int"], ["x", nil], ["x", "Number"]] do
+  failed = false
+  try
+    coerce(args[0], args[1])
+  catch (e)
+    failed = true
+  end
+  if not failed then throw("coerce: " + tostring(args[1]) + " should be rejected") end
+end
+`
+	if _, err := script.NewInterpreter().Execute(src); err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// builtinParseQuery parses a URL query string into an object
+// Usage: parse_query(str)
+// A leading "?" or anything up to it (a full URL) is skipped. Values come
+// back the way request.query has them in an http_server handler: plain
+// numbers become numbers and repeated keys become arrays.
+func builtinParseQuery(evaluator *Evaluator, args map[string]any) (any, error) {
+	s, ok := GetArg(args, 0, "str").(string)
+	if !ok {
+		return nil, fmt.Errorf("parse_query() requires a string as first argument")
+	}
+	if _, after, found := strings.Cut(s, "?"); found {
+		s = after
+	}
+	s, _, _ = strings.Cut(s, "#")
+
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("parse_query() error: %v", err)
+	}
+	query := make(map[string]script.Value, len(values))
+	for k, vv := range values {
+		query[k] = queryValued(vv)
+	}
+	return chargedValue(evaluator, script.NewObject(query))
+}
+
+// builtinBuildQuery encodes an object as a URL query string
+// Usage: build_query(obj)
+// Keys are sorted so the same object always gives the same string. Arrays
+// repeat their key once per item and nil values are left out.
+func builtinBuildQuery(evaluator *Evaluator, args map[string]any) (any, error) {
+	obj := InterfaceToValue(GetArg(args, 0, "obj"))
+	if !obj.IsObject() {
+		return nil, fmt.Errorf("build_query() requires an object as first argument")
+	}
+
+	values := url.Values{}
+	for k, v := range obj.AsObject() {
+		items := []Value{v}
+		if v.IsArray() {
+			items = v.AsArray()
+		}
+		for _, item := range items {
+			if item.IsNil() {
+				continue
+			}
+			if item.IsObject() || item.IsArray() {
+				return nil, fmt.Errorf("build_query() value for '%s' must be a string, number, bool or array of those", k)
+			}
+			values.Add(k, script.ValueForDisplay(item))
+		}
+	}
+	return values.Encode(), nil
+}
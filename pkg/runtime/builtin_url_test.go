@@ -0,0 +1,44 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+func TestParseQuery(t *testing.T) {
+	out, err := builtinParseQuery(nil, map[string]any{"0": "https://x.test/p?a=1&b=hi+there&t=x&t=y&z=007#frag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := script.ValueForDisplay(out.(script.Value))
+	want := `{a=1, b="hi there", t=["x", "y"], z="007"}`
+	if got != want {
+		t.Errorf("parse_query = %s, want %s", got, want)
+	}
+
+	if _, err := builtinParseQuery(nil, map[string]any{"0": "a=%zz"}); err == nil {
+		t.Error("parse_query with a bad escape should fail")
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	obj := map[string]any{
+		"q":    "a&b c",
+		"n":    float64(2),
+		"tags": []any{"x", "y"},
+		"skip": nil,
+	}
+	out, err := builtinBuildQuery(nil, map[string]any{"0": obj})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "n=2&q=a%26b+c&tags=x&tags=y"; out != want {
+		t.Errorf("build_query = %q, want %q", out, want)
+	}
+
+	nested := map[string]any{"a": map[string]any{"b": "c"}}
+	if _, err := builtinBuildQuery(nil, map[string]any{"0": nested}); err == nil {
+		t.Error("build_query with a nested object should fail")
+	}
+}
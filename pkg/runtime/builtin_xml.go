@@ -0,0 +1,234 @@
+package runtime
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// XML maps to nested objects:
+//
+//	{name = "item", attrs = {id = "1"}, children = ["text", {name = ...}, ...]}
+//
+// children holds text (strings) and elements in document order, so mixed
+// content survives a round trip. Names keep their namespace prefix as
+// written ("soap:Body") and xmlns declarations stay in attrs; prefixes are
+// not resolved to URIs. Comments, processing instructions and the XML
+// declaration are dropped.
+
+// builtinParseXML parses an XML document into its root element
+func builtinParseXML(evaluator *Evaluator, args map[string]any) (any, error) {
+	s, ok := GetArg(args, 0, "str").(string)
+	if !ok {
+		return nil, fmt.Errorf("parse_xml() requires a string as first argument")
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(s))
+	fail := func(format string, a ...any) error {
+		line, _ := dec.InputPos()
+		return fmt.Errorf("parse_xml() error on line %d: %s", line, fmt.Sprintf(format, a...))
+	}
+
+	type element struct {
+		obj      map[string]any
+		children []any
+	}
+	var root map[string]any
+	var stack []*element
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse_xml() error: %v", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			attrs := make(map[string]any, len(t.Attr))
+			for _, a := range t.Attr {
+				attrs[xmlName(a.Name)] = a.Value
+			}
+			el := &element{obj: map[string]any{"name": xmlName(t.Name), "attrs": attrs}}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fail("more than one root element")
+				}
+				root = el.obj
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el.obj)
+			}
+			stack = append(stack, el)
+
+		case xml.EndElement:
+			name := xmlName(t.Name)
+			if len(stack) == 0 || stack[len(stack)-1].obj["name"] != name {
+				return nil, fail("unexpected </%s>", name)
+			}
+			el := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			el.obj["children"] = xmlTrimIndentation(el.children)
+
+		case xml.CharData:
+			text := string(t)
+			if len(stack) == 0 {
+				if strings.TrimSpace(text) != "" {
+					return nil, fail("text outside the root element")
+				}
+				continue
+			}
+			// Merge with preceding text, e.g. around a CDATA section
+			el := stack[len(stack)-1]
+			if n := len(el.children); n > 0 {
+				if prev, ok := el.children[n-1].(string); ok {
+					el.children[n-1] = prev + text
+					continue
+				}
+			}
+			el.children = append(el.children, text)
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fail("missing </%s>", stack[len(stack)-1].obj["name"])
+	}
+	if root == nil {
+		return nil, fmt.Errorf("parse_xml() error: no root element")
+	}
+	return chargedValue(evaluator, root)
+}
+
+// xmlTrimIndentation drops whitespace-only text from elements that contain
+// only elements, which is indentation from pretty-printing. Elements with
+// real text keep all of it, since spaces in mixed content are meaningful.
+func xmlTrimIndentation(children []any) []any {
+	for _, c := range children {
+		if s, ok := c.(string); ok && strings.TrimSpace(s) != "" {
+			return children
+		}
+	}
+	kept := []any{}
+	for _, c := range children {
+		if _, ok := c.(string); !ok {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func xmlName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// builtinFormatXML renders an element object as XML
+// Usage: format_xml(element [, indent])
+// With indent (a number of spaces or a string), elements that contain only
+// elements are laid out one per line; elements with text stay inline.
+func builtinFormatXML(evaluator *Evaluator, args map[string]any) (any, error) {
+	root := InterfaceToValue(GetArg(args, 0, "element"))
+	if !root.IsObject() {
+		return nil, fmt.Errorf("format_xml() requires an element object as first argument")
+	}
+
+	indent := ""
+	switch i := GetArg(args, 1, "indent").(type) {
+	case float64:
+		indent = strings.Repeat(" ", max(int(i), 0))
+	case string:
+		indent = i
+	}
+
+	var b strings.Builder
+	if err := writeXMLElement(&b, root, indent, 0); err != nil {
+		return nil, fmt.Errorf("format_xml(): %w", err)
+	}
+	return b.String(), nil
+}
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;",
+		"\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+)
+
+func writeXMLElement(b *strings.Builder, el Value, indent string, depth int) error {
+	obj := el.AsObject()
+	nameVal, ok := obj["name"]
+	if !ok || !nameVal.IsString() || !validXMLName(nameVal.AsString()) {
+		return fmt.Errorf("element needs a valid name, got %s", script.ValueForDisplay(nameVal))
+	}
+	name := nameVal.AsString()
+
+	b.WriteString("<" + name)
+	if attrs, ok := obj["attrs"]; ok && !attrs.IsNil() {
+		if !attrs.IsObject() {
+			return fmt.Errorf("attrs of <%s> must be an object", name)
+		}
+		attrMap := attrs.AsObject()
+		keys := make([]string, 0, len(attrMap))
+		for k := range attrMap {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !validXMLName(k) {
+				return fmt.Errorf("invalid attribute name '%s' on <%s>", k, name)
+			}
+			b.WriteString(fmt.Sprintf(` %s="%s"`, k, xmlAttrEscaper.Replace(script.ValueForDisplay(attrMap[k]))))
+		}
+	}
+
+	var children []Value
+	if c, ok := obj["children"]; ok && !c.IsNil() {
+		if !c.IsArray() {
+			return fmt.Errorf("children of <%s> must be an array", name)
+		}
+		children = c.AsArray()
+	}
+	if len(children) == 0 {
+		b.WriteString("/>")
+		return nil
+	}
+	b.WriteString(">")
+
+	// Only indent inside elements with no text, so mixed content is unchanged
+	layout := indent != ""
+	for _, c := range children {
+		if !c.IsObject() {
+			layout = false
+			break
+		}
+	}
+	for _, c := range children {
+		if layout {
+			b.WriteString("\n" + strings.Repeat(indent, depth+1))
+		}
+		if c.IsObject() {
+			if err := writeXMLElement(b, c, indent, depth+1); err != nil {
+				return err
+			}
+		} else if !c.IsNil() {
+			b.WriteString(xmlTextEscaper.Replace(script.ValueForDisplay(c)))
+		}
+	}
+	if layout {
+		b.WriteString("\n" + strings.Repeat(indent, depth))
+	}
+	b.WriteString("</" + name + ">")
+	return nil
+}
+
+// validXMLName rejects names that would break the markup. It is looser
+// than the XML spec, which also restricts the first character.
+func validXMLName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n<>&\"'/=")
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseXML(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<!-- comment -->
+<a:root xmlns:a="urn:x" id="1">
+  <p>Hi <b>there</b> &amp; <![CDATA[<bye>]]></p>
+  <empty/>
+</a:root>`
+	out, err := builtinParseXML(nil, map[string]any{"0": doc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":  "a:root",
+		"attrs": map[string]any{"xmlns:a": "urn:x", "id": "1"},
+		"children": []any{
+			map[string]any{"name": "p", "attrs": map[string]any{}, "children": []any{
+				"Hi ",
+				map[string]any{"name": "b", "attrs": map[string]any{}, "children": []any{"there"}},
+				" & <bye>",
+			}},
+			map[string]any{"name": "empty", "attrs": map[string]any{}, "children": []any{}},
+		},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("parse_xml =\n%#v\nwant\n%#v", out, want)
+	}
+
+	for _, bad := range []string{"", "<a>", "<a></b>", "<a/><b/>", "x<a/>", "<a>&nope;</a>"} {
+		if _, err := builtinParseXML(nil, map[string]any{"0": bad}); err == nil {
+			t.Errorf("parse_xml(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFormatXML(t *testing.T) {
+	doc := `<list kind="a&quot;b"><item n="1">x &lt; y</item><p>Hi <b>you</b></p><none/></list>`
+	parsed, err := builtinParseXML(nil, map[string]any{"0": doc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := builtinFormatXML(nil, map[string]any{"0": parsed})
+	if err != nil || out != doc {
+		t.Errorf("format_xml = %q, %v; want %q", out, err, doc)
+	}
+
+	out, err = builtinFormatXML(nil, map[string]any{"0": parsed, "1": 2.0})
+	want := strings.Join([]string{
+		`<list kind="a&quot;b">`,
+		`  <item n="1">x &lt; y</item>`,
+		`  <p>Hi <b>you</b></p>`,
+		`  <none/>`,
+		`</list>`,
+	}, "\n")
+	if err != nil || out != want {
+		t.Errorf("format_xml indented =\n%s\nwant\n%s (%v)", out, want, err)
+	}
+
+	if _, err := builtinFormatXML(nil, map[string]any{"0": map[string]any{"name": "bad name"}}); err == nil {
+		t.Error("format_xml should reject an invalid element name")
+	}
+}
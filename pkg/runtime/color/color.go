@@ -0,0 +1,137 @@
+// Package color holds the ANSI escape codes used for terminal output, so the
+// duso CLI and the script-facing colorize() builtin style text the same way.
+package color
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Attributes
+const (
+	Reset     = "\033[0m"
+	Bold      = "\033[1m"
+	Dim       = "\033[2m"
+	Italic    = "\033[3m"
+	Underline = "\033[4m"
+	Reverse   = "\033[7m"
+	Strike    = "\033[9m"
+)
+
+// Foreground colors
+const (
+	Black         = "\033[30m"
+	Red           = "\033[31m"
+	Green         = "\033[32m"
+	Yellow        = "\033[33m"
+	Blue          = "\033[34m"
+	Magenta       = "\033[35m"
+	Cyan          = "\033[36m"
+	White         = "\033[37m"
+	Gray          = "\033[90m"
+	BrightRed     = "\033[91m"
+	BrightGreen   = "\033[92m"
+	BrightYellow  = "\033[93m"
+	BrightBlue    = "\033[94m"
+	BrightMagenta = "\033[95m"
+	BrightCyan    = "\033[96m"
+	BrightWhite   = "\033[97m"
+)
+
+// Background colors
+const (
+	BgBlack       = "\033[40m"
+	BgRed         = "\033[41m"
+	BgGreen       = "\033[42m"
+	BgYellow      = "\033[43m"
+	BgBlue        = "\033[44m"
+	BgMagenta     = "\033[45m"
+	BgCyan        = "\033[46m"
+	BgWhite       = "\033[47m"
+	BgGray        = "\033[100m"
+	BgBrightWhite = "\033[107m"
+)
+
+// Names maps the style names scripts use to their escape codes
+var Names = map[string]string{
+	"reset":     Reset,
+	"bold":      Bold,
+	"dim":       Dim,
+	"italic":    Italic,
+	"underline": Underline,
+	"reverse":   Reverse,
+	"strike":    Strike,
+
+	"black":          Black,
+	"red":            Red,
+	"green":          Green,
+	"yellow":         Yellow,
+	"blue":           Blue,
+	"magenta":        Magenta,
+	"cyan":           Cyan,
+	"white":          White,
+	"gray":           Gray,
+	"bright_red":     BrightRed,
+	"bright_green":   BrightGreen,
+	"bright_yellow":  BrightYellow,
+	"bright_blue":    BrightBlue,
+	"bright_magenta": BrightMagenta,
+	"bright_cyan":    BrightCyan,
+	"bright_white":   BrightWhite,
+
+	"bg_black":        BgBlack,
+	"bg_red":          BgRed,
+	"bg_green":        BgGreen,
+	"bg_yellow":       BgYellow,
+	"bg_blue":         BgBlue,
+	"bg_magenta":      BgMagenta,
+	"bg_cyan":         BgCyan,
+	"bg_white":        BgWhite,
+	"bg_gray":         BgGray,
+	"bg_bright_white": BgBrightWhite,
+}
+
+// RGB returns a 24-bit foreground color code
+func RGB(r, g, b int) string {
+	return fmt.Sprintf("\033[38;2;%d;%d;%dm", r, g, b)
+}
+
+// BgRGB returns a 24-bit background color code
+func BgRGB(r, g, b int) string {
+	return fmt.Sprintf("\033[48;2;%d;%d;%dm", r, g, b)
+}
+
+// Codes turns a space-separated style such as "bold red" into its escape
+// codes
+func Codes(style string) (string, error) {
+	var b strings.Builder
+	for _, name := range strings.Fields(style) {
+		code, ok := Names[strings.ToLower(name)]
+		if !ok {
+			return "", fmt.Errorf("unknown color or style '%s'", name)
+		}
+		b.WriteString(code)
+	}
+	return b.String(), nil
+}
+
+// Wrap styles text and resets after it
+func Wrap(text, code string) string {
+	if code == "" {
+		return text
+	}
+	return code + text + Reset
+}
+
+// escapes matches CSI sequences (colors, cursor movement, clearing) and OSC
+// sequences (window titles, hyperlinks)
+var escapes = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// Strip removes ANSI escape sequences, leaving the text as it shows
+func Strip(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return escapes.ReplaceAllString(s, "")
+}
@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	utc := func(s string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		expr, from, want string
+	}{
+		{"* * * * *", "2026-03-10 12:30", "2026-03-10 12:31"},
+		{"*/15 * * * *", "2026-03-10 12:31", "2026-03-10 12:45"},
+		{"0 3 * * *", "2026-03-10 12:30", "2026-03-11 03:00"},
+		{"@daily", "2026-12-31 23:59", "2027-01-01 00:00"},
+		{"30 9 * * mon-fri", "2026-03-13 10:00", "2026-03-16 09:30"}, // Friday -> Monday
+		{"0 0 * * 7", "2026-03-10 00:00", "2026-03-15 00:00"},        // 7 is Sunday
+		{"0 0 1,15 * *", "2026-03-02 00:00", "2026-03-15 00:00"},
+		{"0 12 13 * fri", "2026-03-01 00:00", "2026-03-06 12:00"}, // either day field matches
+		{"0 0 29 feb *", "2026-01-01 00:00", "2028-02-29 00:00"},
+		{"5-10/5 8-18/5 * jan *", "2026-02-01 00:00", "2027-01-01 08:05"},
+	}
+	for _, tt := range tests {
+		sched, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := sched.next(utc(tt.from)); !got.Equal(utc(tt.want)) {
+			t.Errorf("%q after %s = %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "0 0 30 2 *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Errorf("parseCron(%q) should fail", bad)
+		}
+	}
+}
+
+// TestCronDST checks that times skipped or repeated by a DST change don't
+// stall or double-fire the schedule
+func TestCronDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	sched, _ := parseCron("30 2 * * *")
+	// 2:30 doesn't exist on 2026-03-08; the next real 2:30 is the 9th
+	got := sched.next(time.Date(2026, 3, 8, 1, 0, 0, 0, ny))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("next over spring-forward = %s, want %s", got, want)
+	}
+
+	hourly, _ := parseCron("0 * * * *")
+	from := time.Date(2026, 11, 1, 0, 30, 0, 0, ny)
+	var runs []time.Time
+	for at := from; len(runs) < 4; {
+		at = hourly.next(at)
+		runs = append(runs, at)
+	}
+	for i := 1; i < len(runs); i++ {
+		if d := runs[i].Sub(runs[i-1]); d != time.Hour {
+			t.Errorf("hourly runs over fall-back: %v then %v", runs[i-1], runs[i])
+		}
+	}
+}
@@ -3,6 +3,7 @@ package runtime
 import (
 	"container/heap"
 	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sync"
@@ -53,13 +54,14 @@ func (h *ExpiryHeap) Pop() any {
 
 // DatastoreValue represents an in-memory thread-safe key/value store
 // scoped to a specific namespace. Multiple scripts can access the same
-// store by using the same namespace. Optionally persists to JSON and/or WAL.
+// store by using the same namespace. Optionally persists to a gob or JSON
+// snapshot and/or WAL.
 type DatastoreValue struct {
 	namespace          string
 	data               map[string]any
 	dataMutex          sync.RWMutex
 	conditions         map[string]*sync.Cond // Per-key condition variables for wait operations
-	persistPath        string                // Optional: path to JSON file
+	persistPath        string                // Optional: path to snapshot file
 	persistInterval    time.Duration         // Optional: auto-save interval
 	ticker             *time.Ticker          // Auto-save ticker
 	stopTicker         chan bool              // Signal to stop ticker
@@ -79,18 +81,45 @@ type DatastoreValue struct {
 	walSyncInterval    time.Duration         // 0=sync every write, >0=batch writes
 	walSyncTicker      *time.Ticker          // Periodic WAL sync (if batching)
 	walStopSync        chan bool              // Signal to stop WAL sync ticker
+	backend            string                // Persistence format: "gob" (default), "json" or "sqlite"
+	saveDelay          time.Duration         // Autosave this long after a change (when no persist_interval)
+	saveTimer          *time.Timer           // Pending autosave, nil if none
+	saveTimerMutex     sync.Mutex             // Protect saveTimer
 }
 
+// datastoreBackends lists the accepted "backend" config values
+const datastoreBackends = `"gob", "json", "sqlite" or "memory"`
+
 // applyDatastoreConfig applies configuration to a datastore and triggers recovery.
 // IMPORTANT: Paths in config must be pre-resolved (caller is responsible).
-func applyDatastoreConfig(store *DatastoreValue, config map[string]any) {
+func applyDatastoreConfig(store *DatastoreValue, config map[string]any) error {
 	if config == nil {
-		return
+		return nil
+	}
+
+	backend := "gob"
+	if b, ok := config["backend"].(string); ok {
+		backend = b
+	}
+	switch backend {
+	case "gob", "json", "sqlite", "memory":
+		store.backend = backend
+	default:
+		return fmt.Errorf("unknown datastore backend %q (use %s)", backend, datastoreBackends)
 	}
 
 	// Apply config options - paths must already be resolved by caller
-	if persistPath, ok := config["persist"].(string); ok {
-		store.persistPath = persistPath
+	switch persist := config["persist"].(type) {
+	case string:
+		store.persistPath = persist
+	case bool:
+		if persist {
+			store.persistPath = defaultPersistPath(store.namespace, store.backend)
+		}
+	}
+	store.saveDelay = time.Second
+	if delay, ok := config["persist_delay"].(float64); ok && delay >= 0 {
+		store.saveDelay = time.Duration(delay * float64(time.Second))
 	}
 	if persistInterval, ok := config["persist_interval"]; ok {
 		if intervalSecs, ok := persistInterval.(float64); ok {
@@ -117,9 +146,19 @@ func applyDatastoreConfig(store *DatastoreValue, config map[string]any) {
 		}
 	}
 
-	// Step 1: Load persist if it exists
+	// The memory backend never touches disk, whatever else is configured
+	if store.backend == "memory" {
+		store.persistPath = ""
+		store.persistInterval = 0
+		store.walPath = ""
+	}
+
+	// Step 1: Load persist if it exists. A file that can't be read is an
+	// error rather than an empty store, which autosave would then write over
 	if store.persistPath != "" {
-		_ = store.loadFromDisk()
+		if err := store.loadFromDisk(); err != nil {
+			return err
+		}
 	}
 
 	// Step 2: Replay WAL if it exists
@@ -151,6 +190,23 @@ func applyDatastoreConfig(store *DatastoreValue, config map[string]any) {
 			}
 		}()
 	}
+	return nil
+}
+
+// defaultPersistPath names the file used for persist = true:
+// <namespace>.gob, <namespace>.json or <namespace>.sqlite in the current
+// directory, with characters that don't belong in a file name replaced by "_"
+func defaultPersistPath(namespace, backend string) string {
+	name := []byte(namespace)
+	for i, c := range name {
+		if !(c == '-' || c == '_' || c == '.' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || name[0] == '.' {
+		name = append([]byte("datastore"), name...)
+	}
+	return string(name) + "." + backend
 }
 
 // GetDatastore returns or creates a namespaced datastore with optional persistence config
@@ -215,6 +271,37 @@ func GetDatastoreCount() int {
 	return len(datastoreRegistry)
 }
 
+// FlushDatastores saves every persisted datastore with unsaved changes: a
+// pending autosave, or any store on a persist_interval schedule. Hosts call
+// it before exiting so the last second of writes isn't lost.
+func FlushDatastores() {
+	registryMutex.RLock()
+	stores := make([]*DatastoreValue, 0, len(datastoreRegistry))
+	for _, store := range datastoreRegistry {
+		stores = append(stores, store)
+	}
+	registryMutex.RUnlock()
+
+	for _, ds := range stores {
+		if ds.persistPath == "" {
+			continue
+		}
+		ds.saveTimerMutex.Lock()
+		pending := ds.saveTimer != nil
+		if pending {
+			ds.saveTimer.Stop()
+			ds.saveTimer = nil
+		}
+		ds.saveTimerMutex.Unlock()
+		if !pending && ds.persistInterval == 0 {
+			continue
+		}
+		if err := ds.saveToDisk(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+}
+
 // Set stores a value by key (thread-safe)
 func (ds *DatastoreValue) Set(key string, value any) error {
 	// Deep copy the value to prevent external mutations
@@ -232,7 +319,7 @@ func (ds *DatastoreValue) Set(key string, value any) error {
 	}
 
 	// Write to WAL before applying to memory (durability guarantee)
-	if err := ds.writeWAL(key, storedValue); err != nil {
+	if err := ds.recordWrite(key, storedValue); err != nil {
 		return err
 	}
 
@@ -277,7 +364,7 @@ func (ds *DatastoreValue) SetOnce(key string, value any) bool {
 	}
 
 	// Write to WAL before applying to memory
-	if err := ds.writeWAL(key, storedValue); err != nil {
+	if err := ds.recordWrite(key, storedValue); err != nil {
 		ds.dataMutex.Unlock()
 		return false // WAL write failed, don't apply
 	}
@@ -341,7 +428,7 @@ func (ds *DatastoreValue) Swap(key string, newValue any) (any, error) {
 	}
 
 	// Write to WAL before applying to memory
-	if err := ds.writeWAL(key, storedValue); err != nil {
+	if err := ds.recordWrite(key, storedValue); err != nil {
 		return nil, err
 	}
 
@@ -387,7 +474,7 @@ func (ds *DatastoreValue) Increment(key string, delta float64) (any, error) {
 	newValue := current + delta
 
 	// Write to WAL before applying to memory
-	if err := ds.writeWAL(key, newValue); err != nil {
+	if err := ds.recordWrite(key, newValue); err != nil {
 		return nil, err
 	}
 
@@ -426,7 +513,7 @@ func (ds *DatastoreValue) Push(key string, item any) (float64, error) {
 	}
 
 	// Write to WAL before applying to memory
-	if err := ds.writeWAL(key, newArr); err != nil {
+	if err := ds.recordWrite(key, newArr); err != nil {
 		return 0, err
 	}
 
@@ -466,7 +553,7 @@ func (ds *DatastoreValue) Shift(key string) (any, error) {
 		newArr := arr[1:]
 
 		// Write to WAL before applying to memory
-		if err := ds.writeWAL(key, newArr); err != nil {
+		if err := ds.recordWrite(key, newArr); err != nil {
 			return nil, err
 		}
 
@@ -507,7 +594,7 @@ func (ds *DatastoreValue) Pop(key string) (any, error) {
 		newArr := arr[:len(arr)-1]
 
 		// Write to WAL before applying to memory
-		if err := ds.writeWAL(key, newArr); err != nil {
+		if err := ds.recordWrite(key, newArr); err != nil {
 			return nil, err
 		}
 
@@ -680,7 +767,7 @@ func (ds *DatastoreValue) Unshift(key string, item any) (float64, error) {
 	}
 
 	// Write to WAL before applying to memory
-	if err := ds.writeWAL(key, newArr); err != nil {
+	if err := ds.recordWrite(key, newArr); err != nil {
 		return 0, err
 	}
 
@@ -722,6 +809,7 @@ func (ds *DatastoreValue) Rename(oldKey, newKey string) error {
 	// Move the value
 	ds.data[newKey] = oldValue
 	delete(ds.data, oldKey)
+	ds.scheduleSave()
 
 	// Move condition variable if it exists
 	if cond, exists := ds.conditions[oldKey]; exists {
@@ -785,6 +873,7 @@ func (ds *DatastoreValue) sweepExpiredKeys() {
 			delete(ds.data, entry.key)
 			delete(ds.expiryTimes, entry.key)
 			delete(ds.conditions, entry.key)
+			ds.scheduleSave()
 
 			// Notify any waiters that the key was deleted
 			// (they're already deleted from conditions, but this is for consistency)
@@ -978,7 +1067,7 @@ func (ds *DatastoreValue) Update(key string, updates any) (any, error) {
 	storedObj := DeepCopyAny(obj)
 
 	// Write to WAL before applying to memory
-	if err := ds.writeWAL(key, storedObj); err != nil {
+	if err := ds.recordWrite(key, storedObj); err != nil {
 		return nil, err
 	}
 
@@ -1026,7 +1115,7 @@ func deepMerge(dst, src map[string]any) {
 // Delete removes a key from the store and returns the deleted value (or nil if key didn't exist)
 func (ds *DatastoreValue) Delete(key string) (any, error) {
 	// Write nil to WAL to represent deletion
-	if err := ds.writeWAL(key, nil); err != nil {
+	if err := ds.recordWrite(key, nil); err != nil {
 		return nil, err
 	}
 
@@ -1059,11 +1148,12 @@ func (ds *DatastoreValue) Clear() error {
 	ds.conditions = make(map[string]*sync.Cond)
 	ds.expiryTimes = make(map[string]time.Time)
 	ds.expiryHeap = make(ExpiryHeap, 0)
+	ds.scheduleSave()
 
 	return nil
 }
 
-// Save explicitly saves the datastore to disk (JSON)
+// Save explicitly saves the datastore to disk
 func (ds *DatastoreValue) Save() error {
 	if ds.persistPath == "" {
 		return fmt.Errorf("datastore %q has no persist path configured", ds.namespace)
@@ -1071,7 +1161,7 @@ func (ds *DatastoreValue) Save() error {
 	return ds.saveToDisk()
 }
 
-// Load explicitly loads the datastore from disk (JSON)
+// Load explicitly loads the datastore from disk
 func (ds *DatastoreValue) Load() error {
 	if ds.persistPath == "" {
 		return fmt.Errorf("datastore %q has no persist path configured", ds.namespace)
@@ -1187,6 +1277,13 @@ func (ds *DatastoreValue) Count(evaluator *Evaluator, predicateFn Value) (float6
 
 // Shutdown stops the auto-save ticker and expiry ticker, and saves final state
 func (ds *DatastoreValue) Shutdown() error {
+	ds.saveTimerMutex.Lock()
+	if ds.saveTimer != nil {
+		ds.saveTimer.Stop()
+		ds.saveTimer = nil
+	}
+	ds.saveTimerMutex.Unlock()
+
 	if ds.ticker != nil {
 		ds.ticker.Stop()
 		select {
@@ -1223,7 +1320,7 @@ func (ds *DatastoreValue) Shutdown() error {
 	return nil
 }
 
-// saveToDisk serializes the datastore to a gob file and flushes to disk
+// saveToDisk serializes the datastore to its persist file and flushes to disk
 // After successful save, truncates the WAL (if configured)
 func (ds *DatastoreValue) saveToDisk() error {
 	if ds.persistPath == "" {
@@ -1244,36 +1341,66 @@ func (ds *DatastoreValue) saveToDisk() error {
 		}
 	}
 
-	// Open file for writing
-	file, err := os.OpenFile(ds.persistPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	var err error
+	if ds.backend == "sqlite" {
+		err = ds.writeSQLite()
+	} else {
+		err = ds.writeSnapshotFile()
+	}
+	if err != nil {
+		return err
+	}
+
+	// Truncate WAL after successful snapshot (it's captured in the snapshot now)
+	if ds.walPath != "" {
+		if err := ds.truncateWAL(); err != nil {
+			// Log but don't fail - snapshot succeeded even if WAL truncate failed
+			fmt.Fprintf(os.Stderr, "warning: failed to truncate WAL for %q: %v\n", ds.namespace, err)
+		}
+	}
+
+	return nil
+}
+
+// writeSnapshotFile writes the data to the persist file in the gob or JSON
+// format. Caller holds fileWriteMutex and dataMutex.
+func (ds *DatastoreValue) writeSnapshotFile() error {
+	// Write to a temp file and rename it over the old snapshot, so a crash
+	// mid-save (autosave runs often) never leaves a truncated file
+	tmpPath := ds.persistPath + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
 	}
-	defer file.Close()
+	defer os.Remove(tmpPath) // no-op once renamed
 
-	// Encode to gob
-	encoder := gob.NewEncoder(file)
-	if err := encoder.Encode(ds.data); err != nil {
+	if ds.backend == "json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(ds.data)
+	} else {
+		err = gob.NewEncoder(file).Encode(ds.data)
+	}
+	if err != nil {
+		file.Close()
 		return fmt.Errorf("failed to serialize datastore %q: %v", ds.namespace, err)
 	}
 
 	// Flush to disk to ensure data hits storage
 	if err := file.Sync(); err != nil {
+		file.Close()
 		return fmt.Errorf("failed to sync datastore %q to disk: %v", ds.namespace, err)
 	}
-
-	// Truncate WAL after successful snapshot (it's captured in the snapshot now)
-	if ds.walPath != "" {
-		if err := ds.truncateWAL(); err != nil {
-			// Log but don't fail - snapshot succeeded even if WAL truncate failed
-			fmt.Fprintf(os.Stderr, "warning: failed to truncate WAL for %q: %v\n", ds.namespace, err)
-		}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write datastore %q: %v", ds.namespace, err)
+	}
+	if err := os.Rename(tmpPath, ds.persistPath); err != nil {
+		return fmt.Errorf("failed to write datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
 	}
-
 	return nil
 }
 
-// loadFromDisk deserializes the datastore from gob file
+// loadFromDisk deserializes the datastore from its persist file
 func (ds *DatastoreValue) loadFromDisk() error {
 	if ds.persistPath == "" {
 		return nil // No persistence configured
@@ -1282,26 +1409,51 @@ func (ds *DatastoreValue) loadFromDisk() error {
 	ds.fileWriteMutex.Lock()
 	defer ds.fileWriteMutex.Unlock()
 
+	// Decode into a fresh map so a bad file leaves the data untouched, then
+	// merge the loaded keys in
+	var loaded map[string]any
+	var err error
+	if ds.backend == "sqlite" {
+		loaded, err = ds.readSQLite()
+	} else {
+		loaded, err = ds.readSnapshotFile()
+	}
+	if err != nil {
+		return err
+	}
+
+	ds.dataMutex.Lock()
+	defer ds.dataMutex.Unlock()
+	for k, v := range loaded {
+		ds.data[k] = v
+	}
+
+	return nil
+}
+
+// readSnapshotFile decodes the gob or JSON persist file, or returns nil if
+// it doesn't exist yet. Caller holds fileWriteMutex.
+func (ds *DatastoreValue) readSnapshotFile() (map[string]any, error) {
 	// Open file (fail silently if not exists)
 	file, err := os.Open(ds.persistPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil // File doesn't exist yet - OK
+			return nil, nil // File doesn't exist yet - OK
 		}
-		return fmt.Errorf("failed to read datastore %q from %q: %v", ds.namespace, ds.persistPath, err)
+		return nil, fmt.Errorf("failed to read datastore %q from %q: %v", ds.namespace, ds.persistPath, err)
 	}
 	defer file.Close()
 
-	ds.dataMutex.Lock()
-	defer ds.dataMutex.Unlock()
-
-	// Decode from gob
-	decoder := gob.NewDecoder(file)
-	if err := decoder.Decode(&ds.data); err != nil {
-		return fmt.Errorf("failed to deserialize datastore %q: %v", ds.namespace, err)
+	loaded := make(map[string]any)
+	if ds.backend == "json" {
+		err = json.NewDecoder(file).Decode(&loaded)
+	} else {
+		err = gob.NewDecoder(file).Decode(&loaded)
 	}
-
-	return nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize datastore %q: %v", ds.namespace, err)
+	}
+	return loaded, nil
 }
 
 // recoverFromWAL replays WAL entries, saves merged state, and truncates WAL
@@ -1420,6 +1572,36 @@ func (ds *DatastoreValue) replayWAL() error {
 	return nil
 }
 
+// recordWrite logs a change to the WAL and schedules an autosave
+// Caller must hold dataMutex if this is part of an atomic operation
+func (ds *DatastoreValue) recordWrite(key string, value any) error {
+	ds.scheduleSave()
+	return ds.writeWAL(key, value)
+}
+
+// scheduleSave arms a save saveDelay from now unless one is already
+// pending, so a burst of writes costs one snapshot. Stores with a
+// persist_interval keep their fixed schedule instead.
+func (ds *DatastoreValue) scheduleSave() {
+	if ds.persistPath == "" || ds.persistInterval > 0 {
+		return
+	}
+	ds.saveTimerMutex.Lock()
+	defer ds.saveTimerMutex.Unlock()
+	if ds.saveTimer != nil {
+		return
+	}
+	ds.saveTimer = time.AfterFunc(ds.saveDelay, func() {
+		defer core.RecoverPanic(fmt.Sprintf("datastore_autosave (namespace=%s)", ds.namespace))
+		ds.saveTimerMutex.Lock()
+		ds.saveTimer = nil
+		ds.saveTimerMutex.Unlock()
+		if err := ds.saveToDisk(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: datastore autosave failed: %v\n", err)
+		}
+	})
+}
+
 // writeWAL appends a key-value entry to the WAL file
 // Caller must hold dataMutex if this is part of an atomic operation
 func (ds *DatastoreValue) writeWAL(key string, value any) error {
@@ -1496,69 +1678,9 @@ func (ds *DatastoreValue) truncateWAL() error {
 	return nil
 }
 
-// valuesEqual compares two values for equality
-// Handles numeric comparisons (int/float) appropriately
+// valuesEqual compares two stored values structurally, as deep_equal() does
 func valuesEqual(a, b any) bool {
-	// Handle numeric comparisons
-	aFloat, aIsFloat := toFloat64(a)
-	bFloat, bIsFloat := toFloat64(b)
-
-	if aIsFloat && bIsFloat {
-		return aFloat == bFloat
-	}
-
-	// String comparison
-	if aStr, ok := a.(string); ok {
-		if bStr, ok := b.(string); ok {
-			return aStr == bStr
-		}
-	}
-
-	// Boolean comparison
-	if aBool, ok := a.(bool); ok {
-		if bBool, ok := b.(bool); ok {
-			return aBool == bBool
-		}
-	}
-
-	// Array comparison
-	if aArr, ok := a.([]any); ok {
-		if bArr, ok := b.([]any); ok {
-			if len(aArr) != len(bArr) {
-				return false
-			}
-			for i := range aArr {
-				if !valuesEqual(aArr[i], bArr[i]) {
-					return false
-				}
-			}
-			return true
-		}
-	}
-
-	// Object/map comparison - treat as equal (don't walk the tree)
-	// Maps can't be compared with ==, so we assume they're equal unless type mismatch
-	if _, ok := a.(map[string]any); ok {
-		_, ok := b.(map[string]any)
-		return ok // True if both are maps (equal), false if types differ
-	}
-
-	// Fall back to interface equality (for nil, etc.)
-	return a == b
-}
-
-// toFloat64 attempts to convert a value to float64
-func toFloat64(val any) (float64, bool) {
-	switch v := val.(type) {
-	case float64:
-		return v, true
-	case int:
-		return float64(v), true
-	case int64:
-		return float64(v), true
-	default:
-		return 0, false
-	}
+	return deepEqual(InterfaceToValue(a), InterfaceToValue(b))
 }
 
 // isArray checks if a value is an array/slice
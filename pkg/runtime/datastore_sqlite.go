@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// The sqlite backend keeps one row per key, with the value as JSON text, so
+// the file can be inspected with any SQLite tool:
+//
+//	SELECT key, json_extract(value, '$.status') FROM datastore
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS datastore (key TEXT PRIMARY KEY, value TEXT NOT NULL)`
+
+// writeSQLite replaces the rows in the persist database with the current
+// data. The whole snapshot is one transaction, so a crash mid-save leaves the
+// previous snapshot in place. Caller holds fileWriteMutex and dataMutex.
+func (ds *DatastoreValue) writeSQLite() error {
+	db, err := sql.Open("sqlite", ds.persistPath)
+	if err != nil {
+		return fmt.Errorf("failed to open datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to open datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
+	}
+	defer tx.Rollback() // no-op once committed
+
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("failed to write datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM datastore`); err != nil {
+		return fmt.Errorf("failed to write datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
+	}
+	insert, err := tx.Prepare(`INSERT INTO datastore (key, value) VALUES (?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to write datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
+	}
+	defer insert.Close()
+	for k, v := range ds.data {
+		value, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to serialize datastore %q: %v", ds.namespace, err)
+		}
+		if _, err := insert.Exec(k, string(value)); err != nil {
+			return fmt.Errorf("failed to write datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write datastore %q at %q: %v", ds.namespace, ds.persistPath, err)
+	}
+	return nil
+}
+
+// readSQLite returns the keys stored in the persist database, or nil if it
+// doesn't exist yet. Caller holds fileWriteMutex.
+func (ds *DatastoreValue) readSQLite() (map[string]any, error) {
+	if _, err := os.Stat(ds.persistPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // File doesn't exist yet - OK
+		}
+		return nil, fmt.Errorf("failed to read datastore %q from %q: %v", ds.namespace, ds.persistPath, err)
+	}
+
+	db, err := sql.Open("sqlite", ds.persistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read datastore %q from %q: %v", ds.namespace, ds.persistPath, err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT key, value FROM datastore`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize datastore %q: %v", ds.namespace, err)
+	}
+	defer rows.Close()
+
+	loaded := make(map[string]any)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to deserialize datastore %q: %v", ds.namespace, err)
+		}
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, fmt.Errorf("failed to deserialize datastore %q: key %q: %v", ds.namespace, key, err)
+		}
+		loaded[key] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to deserialize datastore %q: %v", ds.namespace, err)
+	}
+	return loaded, nil
+}
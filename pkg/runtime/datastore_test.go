@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDatastoreAutosaveJSON checks persist with the JSON backend saves
+// shortly after a change and loads again into a fresh store.
+func TestDatastoreAutosaveJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cfg := map[string]any{"persist": path, "backend": "json", "persist_delay": 0.05}
+
+	store := GetDatastore("test_autosave_json", nil)
+	if err := applyDatastoreConfig(store, cfg); err != nil {
+		t.Fatal(err)
+	}
+	store.Set("name", "duso")
+	if _, err := store.Increment("hits", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var data []byte
+	for time.Now().Before(deadline) {
+		if data, _ = os.ReadFile(path); strings.Contains(string(data), `"hits": 2`) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !strings.Contains(string(data), `"name": "duso"`) || !strings.Contains(string(data), `"hits": 2`) {
+		t.Fatalf("autosaved file = %q", data)
+	}
+
+	fresh := &DatastoreValue{namespace: "fresh", data: map[string]any{}}
+	if err := applyDatastoreConfig(fresh, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := fresh.Get("hits"); v != float64(2) {
+		t.Errorf("reloaded hits = %v", v)
+	}
+}
+
+// TestDatastoreSQLite checks the sqlite backend saves every key as a row and
+// loads them back into a fresh store, and that a file that isn't a datastore
+// database is refused.
+func TestDatastoreSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.sqlite")
+	cfg := map[string]any{"persist": path, "backend": "sqlite", "persist_delay": 60.0}
+
+	store := &DatastoreValue{namespace: "sqlite", data: map[string]any{}}
+	if err := applyDatastoreConfig(store, cfg); err != nil {
+		t.Fatal(err)
+	}
+	store.Set("name", "duso")
+	store.Set("job", map[string]any{"status": "done", "tags": []any{"a", "b"}})
+	if err := store.saveToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	store.Delete("name")
+	if err := store.saveToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := &DatastoreValue{namespace: "fresh", data: map[string]any{}}
+	if err := applyDatastoreConfig(fresh, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fresh.data["name"]; ok {
+		t.Errorf("deleted key was reloaded")
+	}
+	job, _ := fresh.Get("job")
+	if m, ok := job.(map[string]any); !ok || m["status"] != "done" || len(m["tags"].([]any)) != 2 {
+		t.Errorf("reloaded job = %v", job)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.sqlite")
+	if err := os.WriteFile(bad, []byte("not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := applyDatastoreConfig(&DatastoreValue{namespace: "bad", data: map[string]any{}}, map[string]any{"persist": bad, "backend": "sqlite"})
+	if err == nil {
+		t.Error("a file that isn't a database should be refused")
+	}
+}
+
+// TestDatastoreBackends checks backend validation and that the memory
+// backend ignores persistence settings.
+func TestDatastoreBackends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mem.gob")
+	store := &DatastoreValue{namespace: "mem", data: map[string]any{}}
+	if err := applyDatastoreConfig(store, map[string]any{"persist": path, "backend": "memory"}); err != nil {
+		t.Fatal(err)
+	}
+	if store.persistPath != "" {
+		t.Errorf("memory backend kept persist path %q", store.persistPath)
+	}
+
+	for _, backend := range []string{"redis", "SQLite"} {
+		err := applyDatastoreConfig(&DatastoreValue{data: map[string]any{}}, map[string]any{"backend": backend})
+		if err == nil {
+			t.Errorf("backend %q should be rejected", backend)
+		}
+	}
+
+	if got := defaultPersistPath("jobs/queue 1", "json"); got != "jobs_queue_1.json" {
+		t.Errorf("defaultPersistPath = %q", got)
+	}
+}
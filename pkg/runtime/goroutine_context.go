@@ -28,6 +28,8 @@ type RequestContext struct {
 	JWTSecret         string                   // JWT secret for HS256 signing/verification (HTTP context only)
 	RS256PrivateKey   string                   // PEM-encoded RSA private key for RS256 signing (HTTP context only)
 	RS256PublicKey    string                   // PEM-encoded RSA public key for RS256 verification (HTTP context only)
+	JWTIssuer         string                   // Expected JWT "iss" claim, empty to skip (HTTP context only)
+	JWTAudience       string                   // Expected JWT "aud" claim, empty to skip (HTTP context only)
 	CacheControl      string                   // Default Cache-Control header for response helpers (HTTP context only)
 	MaxBodySize       int64                    // Max request body size in bytes (HTTP context only)
 	MaxFormFields     int                      // Max form fields in multipart (HTTP context only)
@@ -0,0 +1,122 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPMetrics holds request counters for an HTTP server. Requests are
+// recorded once they finish; InFlight counts those still being handled.
+type HTTPMetrics struct {
+	mu            sync.Mutex
+	total         int64
+	byStatus      map[int]int64
+	totalDuration time.Duration
+	inFlight      atomic.Int64
+	started       time.Time
+}
+
+// NewHTTPMetrics creates an empty set of counters
+func NewHTTPMetrics() *HTTPMetrics {
+	return &HTTPMetrics{byStatus: make(map[int]int64), started: time.Now()}
+}
+
+// record counts a finished request
+func (m *HTTPMetrics) record(status int, duration time.Duration) {
+	m.mu.Lock()
+	m.total++
+	m.byStatus[status]++
+	m.totalDuration += duration
+	m.mu.Unlock()
+}
+
+// Snapshot returns the counters as a Duso-friendly object:
+// requests, status (counts keyed by code), in_flight, avg_latency_ms and
+// uptime in seconds.
+func (m *HTTPMetrics) Snapshot() map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := make(map[string]any, len(m.byStatus))
+	for code, n := range m.byStatus {
+		status[strconv.Itoa(code)] = float64(n)
+	}
+	avg := 0.0
+	if m.total > 0 {
+		avg = float64(m.totalDuration.Microseconds()) / float64(m.total) / 1000
+	}
+	return map[string]any{
+		"requests":       float64(m.total),
+		"status":         status,
+		"in_flight":      float64(m.inFlight.Load()),
+		"avg_latency_ms": avg,
+		"uptime":         time.Since(m.started).Seconds(),
+	}
+}
+
+// writePrometheus writes the counters in the Prometheus text exposition format
+func (m *HTTPMetrics) writePrometheus(w *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	codes := make([]int, 0, len(m.byStatus))
+	for code := range m.byStatus {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	w.WriteString("# HELP duso_http_requests_total Finished HTTP requests by status code.\n")
+	w.WriteString("# TYPE duso_http_requests_total counter\n")
+	for _, code := range codes {
+		fmt.Fprintf(w, "duso_http_requests_total{status=\"%d\"} %d\n", code, m.byStatus[code])
+	}
+	w.WriteString("# HELP duso_http_requests_in_flight HTTP requests currently being handled.\n")
+	w.WriteString("# TYPE duso_http_requests_in_flight gauge\n")
+	fmt.Fprintf(w, "duso_http_requests_in_flight %d\n", m.inFlight.Load())
+	w.WriteString("# HELP duso_http_request_duration_seconds Time spent handling HTTP requests.\n")
+	w.WriteString("# TYPE duso_http_request_duration_seconds summary\n")
+	fmt.Fprintf(w, "duso_http_request_duration_seconds_sum %g\n", m.totalDuration.Seconds())
+	fmt.Fprintf(w, "duso_http_request_duration_seconds_count %d\n", m.total)
+}
+
+// serveMetrics answers the metrics route: JSON by default, Prometheus text
+// for ?format=prometheus or when the client asks for text/plain (as
+// Prometheus scrapers do)
+func (s *HTTPServerValue) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		accept := r.Header.Get("Accept")
+		if strings.Contains(accept, "text/plain") || strings.Contains(accept, "openmetrics") {
+			format = "prometheus"
+		}
+	}
+
+	if format == "prometheus" {
+		var b strings.Builder
+		s.metrics.writePrometheus(&b)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(b.String()))
+		return
+	}
+
+	body, _ := json.Marshal(s.metrics.Snapshot())
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// finishRequest records a finished request in the metrics and access log
+func (s *HTTPServerValue) finishRequest(r *http.Request, statusCode int, bytesWritten int64, start time.Time) {
+	if s.metrics != nil {
+		s.metrics.record(statusCode, time.Since(start))
+	}
+	s.logAccessRequest(r, statusCode, bytesWritten, start)
+}
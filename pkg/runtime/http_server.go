@@ -4,15 +4,10 @@ import (
 	"bufio"
 	"compress/gzip"
 	"context"
-	"crypto"
-	"crypto/hmac"
-	"crypto/rsa"
 	"crypto/sha256"
-	"crypto/x509"
-	"encoding/base64"
 	"encoding/json"
-	"encoding/pem"
 	"fmt"
+	"html"
 	"io"
 	"mime"
 	"mime/multipart"
@@ -24,12 +19,16 @@ import (
 	"regexp"
 	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/duso-org/duso/pkg/core"
+	"github.com/duso-org/duso/pkg/runtime/jwt"
+	"github.com/duso-org/duso/pkg/runtime/markdown"
 	"github.com/duso-org/duso/pkg/script"
 	"golang.org/x/net/websocket"
 )
@@ -47,9 +46,11 @@ type CORSConfig struct {
 // JWTConfig holds JWT (JSON Web Token) settings
 type JWTConfig struct {
 	Enabled            bool
-	Secret             string // HS256 secret
-	RS256PrivateKey    string // PEM-encoded RSA private key for signing
-	RS256PublicKey     string // PEM-encoded RSA public key for verification
+	Secret             string // HMAC secret for HS256/HS384/HS512
+	RS256PrivateKey    string // PEM-encoded RSA or EC private key for RS*/ES* signing
+	RS256PublicKey     string // PEM-encoded RSA or EC public key for RS*/ES* verification
+	Issuer             string // Expected "iss" claim (unchecked if empty)
+	Audience           string // Expected "aud" claim (unchecked if empty)
 	Required           bool
 }
 
@@ -83,8 +84,14 @@ type HTTPServerValue struct {
 	MaxHeaders                int               // Max number of headers (default: 100)
 	MaxFormFields             int               // Max form fields in multipart (default: 1000)
 	IdleTimeout               time.Duration     // Idle connection timeout (default: 120s)
-	AccessLog                 bool              // Enable access logging to stderr (default: true)
+	AccessLog                 bool              // Enable access logging (default: true)
+	AccessLogFormat           string            // "plain" (Apache Combined Log Format) or "json" (default: "plain")
+	AccessLogWriter           io.Writer         // Access log sink (default: stderr)
 	StaticCacheControl        string            // Cache-Control header for static files (default: "public, max-age=3600")
+	RenderMarkdown            bool              // Render static .md files to HTML pages instead of sending raw markdown
+	MetricsPath               string            // Serve request metrics at this path (empty = off)
+	metrics                   *HTTPMetrics      // Request counters, always collected
+	shutdownHooks             []func() error    // Run in order once the server has shut down (see OnShutdown)
 	routes                    map[string]*Route // key: "METHOD /path"
 	sortedRouteKeys           []string          // Routes sorted by path length (descending)
 	routeMutex                sync.RWMutex
@@ -138,7 +145,8 @@ func (w *gzipResponseWriter) sendHeader() {
 func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 	if !w.decided {
 		w.decided = true
-		if len(b) >= gzipMinSize {
+		// A partial response's Content-Range counts uncompressed bytes
+		if len(b) >= gzipMinSize && w.status != http.StatusPartialContent {
 			w.Header().Set("Content-Encoding", "gzip")
 			w.Header().Del("Content-Length")
 			gz := gzipWriterPool.Get().(*gzip.Writer)
@@ -199,6 +207,7 @@ type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	bytesWritten int64
+	start time.Time // when the request arrived, for the access log duration
 }
 
 func (w *loggingResponseWriter) WriteHeader(statusCode int) {
@@ -232,9 +241,33 @@ type Route struct {
 	PathRegex   *regexp.Regexp     // Compiled regex for matching (nil if no params)
 	IsStatic    bool               // True if this is a static file route
 	StaticDir   string             // Directory to serve files from (for static routes)
+	StaticOpts  StaticOptions      // Per-route overrides of the server's static settings
 	IsWebSocket bool               // True if this is a WebSocket route (Method == "WS")
 }
 
+// StaticOptions overrides the server-wide static file settings for one route.
+// nil fields fall back to the server's DefaultFiles and ShowDirectoryListing.
+type StaticOptions struct {
+	DefaultFiles     []string // Default filenames to try; non-nil but empty disables them
+	DirectoryListing *bool    // Show directory listing when no default file found
+}
+
+// defaultFiles returns the default filenames to try for a static route
+func (s *HTTPServerValue) defaultFiles(route *Route) []string {
+	if route.StaticOpts.DefaultFiles != nil {
+		return route.StaticOpts.DefaultFiles
+	}
+	return s.DefaultFiles
+}
+
+// showDirectoryListing reports whether a static route lists directories
+func (s *HTTPServerValue) showDirectoryListing(route *Route) bool {
+	if route.StaticOpts.DirectoryListing != nil {
+		return *route.StaticOpts.DirectoryListing
+	}
+	return s.ShowDirectoryListing
+}
+
 // isTextMIME checks if a content type should be treated as text
 func isTextMIME(contentType string) bool {
 	textTypes := []string{
@@ -304,145 +337,15 @@ func processUploadedFile(fileHeader *multipart.FileHeader, maxSize int64) (map[s
 	return fileObj, nil
 }
 
-// base64urlEncode encodes data using base64url encoding (no padding)
-func base64urlEncode(data []byte) string {
-	encoded := base64.StdEncoding.EncodeToString(data)
-	// Remove padding and replace URL-unsafe characters
-	encoded = strings.TrimRight(encoded, "=")
-	encoded = strings.ReplaceAll(encoded, "+", "-")
-	encoded = strings.ReplaceAll(encoded, "/", "_")
-	return encoded
-}
-
-// base64urlDecode decodes base64url-encoded data (with or without padding)
-func base64urlDecode(data string) ([]byte, error) {
-	// Add padding if needed
-	switch len(data) % 4 {
-	case 1:
-		return nil, fmt.Errorf("illegal base64url string")
-	case 2:
-		data += "=="
-	case 3:
-		data += "="
-	}
-
-	// Replace URL-safe characters with standard base64
-	data = strings.ReplaceAll(data, "-", "+")
-	data = strings.ReplaceAll(data, "_", "/")
-
-	return base64.StdEncoding.DecodeString(data)
-}
-
 // verifyJWT verifies a JWT token and returns claims if valid
 func (s *HTTPServerValue) verifyJWT(tokenString string) (map[string]any, error) {
-	if !s.JWT.Enabled || s.JWT.Secret == "" {
+	if !s.JWT.Enabled || (s.JWT.Secret == "" && s.JWT.RS256PublicKey == "") {
 		return nil, fmt.Errorf("JWT not configured")
 	}
-
-	// Split token into three parts: header.payload.signature
-	parts := strings.Split(tokenString, ".")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid token format")
-	}
-
-	headerStr := parts[0]
-	payloadStr := parts[1]
-	signatureStr := parts[2]
-
-	// Decode and verify header
-	headerBytes, err := base64urlDecode(headerStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid header encoding: %w", err)
-	}
-
-	var header map[string]any
-	if err := json.Unmarshal(headerBytes, &header); err != nil {
-		return nil, fmt.Errorf("invalid header JSON: %w", err)
-	}
-
-	// Check algorithm and verify signature
-	alg, ok := header["alg"].(string)
-	if !ok {
-		return nil, fmt.Errorf("missing algorithm in token header")
-	}
-
-	// Decode the signature from the token
-	decodedSigBytes, err := base64urlDecode(signatureStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid signature encoding: %w", err)
-	}
-
-	switch alg {
-	case "HS256":
-		// Verify signature using HMAC-SHA256
-		if s.JWT.Secret == "" {
-			return nil, fmt.Errorf("HS256 secret not configured")
-		}
-		h := hmac.New(sha256.New, []byte(s.JWT.Secret))
-		h.Write([]byte(headerStr + "." + payloadStr))
-		expectedSigBytes := h.Sum(nil)
-
-		// Compare signatures using constant-time comparison
-		if !hmac.Equal(decodedSigBytes, expectedSigBytes) {
-			return nil, fmt.Errorf("invalid signature")
-		}
-
-	case "RS256":
-		// Verify signature using RSA-SHA256
-		if s.JWT.RS256PublicKey == "" {
-			return nil, fmt.Errorf("RS256 public key not configured")
-		}
-
-		// Parse public key from PEM
-		pubKeyBlock, _ := pem.Decode([]byte(s.JWT.RS256PublicKey))
-		if pubKeyBlock == nil {
-			return nil, fmt.Errorf("invalid RSA public key format")
-		}
-
-		pubKey, err := x509.ParsePKIXPublicKey(pubKeyBlock.Bytes)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse public key: %w", err)
-		}
-
-		rsaPublicKey, ok := pubKey.(*rsa.PublicKey)
-		if !ok {
-			return nil, fmt.Errorf("public key is not an RSA key")
-		}
-
-		// Hash the message
-		hash := sha256.Sum256([]byte(headerStr + "." + payloadStr))
-
-		// Verify the signature
-		if err := rsa.VerifyPKCS1v15(rsaPublicKey, crypto.SHA256, hash[:], decodedSigBytes); err != nil {
-			return nil, fmt.Errorf("invalid signature: %w", err)
-		}
-
-	default:
-		return nil, fmt.Errorf("unsupported algorithm: %s (supported: HS256, RS256)", alg)
-	}
-
-	// Decode payload
-	payloadBytes, err := base64urlDecode(payloadStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid payload encoding: %w", err)
-	}
-
-	var claims map[string]any
-	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
-		return nil, fmt.Errorf("invalid payload JSON: %w", err)
-	}
-
-	// Check expiration if present
-	if exp, ok := claims["exp"].(float64); ok {
-		if time.Now().Unix() > int64(exp) {
-			return nil, fmt.Errorf("token expired")
-		}
-	}
-
-	return claims, nil
+	return jwt.Verify(tokenString, jwt.Keys{Secret: s.JWT.Secret, PublicKey: s.JWT.RS256PublicKey}, jwt.Expect{Issuer: s.JWT.Issuer, Audience: s.JWT.Audience})
 }
 
-// buildSignJWTFunction creates a sign_jwt function bound to JWT config (HS256 secret and RS256 keys)
+// buildSignJWTFunction creates a sign_jwt function bound to JWT config (HMAC secret and RSA/EC private key)
 func buildSignJWTFunction(jwtConfig *JWTConfig) script.Value {
 	return script.NewGoFunction(func(evaluator *script.Evaluator, args map[string]any) (any, error) {
 		claims, ok := args["0"].(map[string]any)
@@ -486,73 +389,15 @@ func buildSignJWTFunction(jwtConfig *JWTConfig) script.Value {
 		}
 		tokenClaims["exp"] = float64(time.Now().Unix()) + expiresIn
 
-		// Build and encode header
-		header := map[string]string{
-			"alg": algorithm,
-			"typ": "JWT",
+		// Sign with specified algorithm, using the override key if provided
+		key := privateKey
+		if key == "" {
+			key = jwtConfig.RS256PrivateKey
 		}
-		headerJSON, _ := json.Marshal(header)
-		payloadJSON, _ := json.Marshal(tokenClaims)
-
-		headerB64 := base64urlEncode(headerJSON)
-		payloadB64 := base64urlEncode(payloadJSON)
-		message := headerB64 + "." + payloadB64
-
-		// Sign with specified algorithm
-		var signatureB64 string
-		switch algorithm {
-		case "HS256":
-			if jwtConfig.Secret == "" {
-				return nil, fmt.Errorf("HS256 secret not configured")
-			}
-			signature := hmac.New(sha256.New, []byte(jwtConfig.Secret))
-			signature.Write([]byte(message))
-			signatureB64 = base64urlEncode(signature.Sum(nil))
-
-		case "RS256":
-			// Use override key if provided, otherwise use configured key
-			key := privateKey
-			if key == "" {
-				key = jwtConfig.RS256PrivateKey
-			}
-			if key == "" {
-				return nil, fmt.Errorf("RS256 private key not configured")
-			}
-
-			// Parse private key from PEM
-			privKeyBlock, _ := pem.Decode([]byte(key))
-			if privKeyBlock == nil {
-				return nil, fmt.Errorf("invalid RSA private key format")
-			}
-
-			privKey, err := x509.ParsePKCS1PrivateKey(privKeyBlock.Bytes)
-			if err != nil {
-				// Try PKCS8 format
-				privKeyInterface, err := x509.ParsePKCS8PrivateKey(privKeyBlock.Bytes)
-				if err != nil {
-					return nil, fmt.Errorf("failed to parse private key: %w", err)
-				}
-				var ok bool
-				privKey, ok = privKeyInterface.(*rsa.PrivateKey)
-				if !ok {
-					return nil, fmt.Errorf("private key is not an RSA key")
-				}
-			}
-
-			// Hash and sign the message
-			hash := sha256.Sum256([]byte(message))
-			sig, err := rsa.SignPKCS1v15(nil, privKey, crypto.SHA256, hash[:])
-			if err != nil {
-				return nil, fmt.Errorf("failed to sign token: %w", err)
-			}
-			signatureB64 = base64urlEncode(sig)
-
-		default:
-			return nil, fmt.Errorf("unsupported algorithm: %s (supported: HS256, RS256)", algorithm)
+		token, err := jwt.Sign(tokenClaims, algorithm, jwt.Keys{Secret: jwtConfig.Secret, PrivateKey: key})
+		if err != nil {
+			return nil, fmt.Errorf("sign_jwt(): %w", err)
 		}
-
-		// Return token
-		token := message + "." + signatureB64
 		return token, nil
 	})
 }
@@ -575,30 +420,48 @@ func isValidHTTPMethod(method string) bool {
 	return validMethods[method]
 }
 
+// pathParamPattern finds :name single-segment and *name catch-all parameters
+var pathParamPattern = regexp.MustCompile(`([:*])([a-zA-Z_][a-zA-Z0-9_]*)`)
+
 // extractPathParams extracts parameter names and creates a regex pattern from a path like "/users/:id/tokens/:token"
+// A trailing "*name" segment captures the rest of the path, slashes included: "/files/*filepath"
 // Returns the parameter names and compiled regex pattern
 func extractPathParams(path string) ([]string, *regexp.Regexp, error) {
-	// Find all :paramName occurrences
-	paramPattern := regexp.MustCompile(`:([a-zA-Z_][a-zA-Z0-9_]*)`)
-	matches := paramPattern.FindAllStringSubmatch(path, -1)
+	// Find all :paramName and *paramName occurrences
+	matches := pathParamPattern.FindAllStringSubmatchIndex(path, -1)
 
 	if len(matches) == 0 {
 		// No parameters - return nil for regex
 		return nil, nil, nil
 	}
 
-	// Extract parameter names
+	// Convert path pattern to regex, extracting parameter names
+	// /users/:id/tokens/:token → ^/users/([^/]+)/tokens/([^/]+)$
+	// /files/*filepath         → ^/files(?:/(.*))?$
 	var paramNames []string
-	for _, match := range matches {
-		paramNames = append(paramNames, match[1])
+	var regexPattern strings.Builder
+	regexPattern.WriteString("^")
+	last := 0
+	for _, m := range matches {
+		kind, name := path[m[2]:m[3]], path[m[4]:m[5]]
+		paramNames = append(paramNames, name)
+		if kind == ":" {
+			regexPattern.WriteString(regexp.QuoteMeta(path[last:m[0]]))
+			regexPattern.WriteString(`([^/]+)`)
+		} else {
+			// Catch-all: must be a whole, final segment
+			if m[1] != len(path) || m[0] == 0 || path[m[0]-1] != '/' {
+				return nil, nil, fmt.Errorf("catch-all parameter *%s must be the last segment of the path", name)
+			}
+			regexPattern.WriteString(regexp.QuoteMeta(path[last : m[0]-1]))
+			regexPattern.WriteString(`(?:/(.*))?`)
+		}
+		last = m[1]
 	}
+	regexPattern.WriteString(regexp.QuoteMeta(path[last:]))
+	regexPattern.WriteString("$")
 
-	// Convert path pattern to regex
-	// /users/:id/tokens/:token → ^/users/([^/]+)/tokens/([^/]+)$
-	regexPattern := paramPattern.ReplaceAllString(regexp.QuoteMeta(path), `([^/]+)`)
-	regexPattern = "^" + regexPattern + "$"
-
-	regex, err := regexp.Compile(regexPattern)
+	regex, err := regexp.Compile(regexPattern.String())
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to compile path pattern regex: %w", err)
 	}
@@ -606,6 +469,16 @@ func extractPathParams(path string) ([]string, *regexp.Regexp, error) {
 	return paramNames, regex, nil
 }
 
+// isWildcardPath reports whether a route path matches by prefix: a trailing
+// "*" or a "*name" catch-all parameter
+func isWildcardPath(path string) bool {
+	if strings.HasSuffix(path, "*") {
+		return true
+	}
+	i := strings.LastIndex(path, "/*")
+	return i >= 0 && !strings.Contains(path[i+1:], "/")
+}
+
 // matchPathPattern matches a request path against a route pattern and extracts parameters
 // Returns the extracted parameters or nil if no match
 func matchPathPattern(route *Route, requestPath string) map[string]any {
@@ -631,9 +504,29 @@ func matchPathPattern(route *Route, requestPath string) map[string]any {
 	return params
 }
 
+// Reload drops the cached handler ASTs and required modules so the next
+// request re-reads them from disk. Relative paths are resolved against the
+// directory of the script that created the server; with no paths, everything
+// file-backed is reloaded. Safe to call while the server is running.
+func (s *HTTPServerValue) Reload(paths ...string) {
+	if s.Interpreter == nil {
+		return
+	}
+	scriptDir := core.Dir(s.Interpreter.GetFilePath())
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		resolved[i] = path
+		if !core.IsAbsoluteOrSpecial(path) {
+			resolved[i] = script.ResolveScriptPathFromDir(path, scriptDir)
+		}
+	}
+	s.Interpreter.InvalidateCache(resolved...)
+}
+
 // StaticRoute registers a static file route (thread-safe).
 // Serves files from staticDir for requests matching the path prefix.
-func (s *HTTPServerValue) StaticRoute(path, staticDir string) error {
+// opts overrides the server's default files and directory listing for this route.
+func (s *HTTPServerValue) StaticRoute(path, staticDir string, opts StaticOptions) error {
 	s.routeMutex.Lock()
 	defer s.routeMutex.Unlock()
 
@@ -657,6 +550,7 @@ func (s *HTTPServerValue) StaticRoute(path, staticDir string) error {
 			PathRegex:   nil,
 			IsStatic:    true,
 			StaticDir:   staticDir,
+			StaticOpts:  opts,
 		}
 	}
 
@@ -775,8 +669,8 @@ func (s *HTTPServerValue) rebuildSortedRoutes() {
 		pathI := strings.SplitN(keys[i], " ", 2)[1]
 		pathJ := strings.SplitN(keys[j], " ", 2)[1]
 
-		isWildcardI := strings.HasSuffix(pathI, "*")
-		isWildcardJ := strings.HasSuffix(pathJ, "*")
+		isWildcardI := isWildcardPath(pathI)
+		isWildcardJ := isWildcardPath(pathJ)
 
 		// Exact matches come before wildcards
 		if isWildcardI != isWildcardJ {
@@ -790,6 +684,27 @@ func (s *HTTPServerValue) rebuildSortedRoutes() {
 	s.sortedRouteKeys = keys
 }
 
+// routeMatchesPath reports whether route's path pattern matches path,
+// returning any extracted path parameters.
+func routeMatchesPath(route *Route, path string) (map[string]any, bool) {
+	if route.PathRegex != nil {
+		// Pattern matching with parameters
+		params := matchPathPattern(route, path)
+		return params, params != nil
+	}
+
+	// Wildcard vs exact matching (no parameters)
+	if strings.HasSuffix(route.Path, "*") {
+		// Wildcard route: /api* matches /api/, /api/v1, /api/v1/users, etc.
+		// A bare * is catch-all and always matches.
+		prefix := strings.TrimSuffix(route.Path, "*")
+		return nil, prefix == "" || strings.HasPrefix(path, prefix)
+	}
+
+	// Exact match only (no wildcard)
+	return nil, path == route.Path
+}
+
 // findMatchingRoute finds the best matching route using pattern matching.
 // Returns the route and extracted path parameters, or (nil, nil) if no match found.
 // Must be called with routeMutex held (RLock).
@@ -804,29 +719,9 @@ func (s *HTTPServerValue) findMatchingRoute(method, path string) (*Route, map[st
 		route := s.routes[routeKey]
 
 		// Check if path matches
-		var params map[string]any
-		if route.PathRegex != nil {
-			// Pattern matching with parameters
-			params = matchPathPattern(route, path)
-			if params == nil {
-				continue
-			}
-		} else {
-			// Wildcard vs exact matching (no parameters)
-			if strings.HasSuffix(route.Path, "*") {
-				// Wildcard route: /api* matches /api/, /api/v1, /api/v1/users, etc.
-				prefix := strings.TrimSuffix(route.Path, "*")
-				if prefix == "" {
-					// * is catch-all - always matches
-				} else if !strings.HasPrefix(path, prefix) {
-					continue
-				}
-			} else {
-				// Exact match only (no wildcard)
-				if path != route.Path {
-					continue
-				}
-			}
+		params, ok := routeMatchesPath(route, path)
+		if !ok {
+			continue
 		}
 
 		// Check if method matches (exact or wildcard)
@@ -849,36 +744,35 @@ func (s *HTTPServerValue) findMatchingRoute(method, path string) (*Route, map[st
 		}
 
 		// Check if path matches
-		var params map[string]any
-		if route.PathRegex != nil {
-			params = matchPathPattern(route, path)
-			if params == nil {
-				continue
-			}
-		} else {
-			// Wildcard vs exact matching (no parameters)
-			if strings.HasSuffix(route.Path, "*") {
-				// Wildcard route: /api* matches /api/, /api/v1, /api/v1/users, etc.
-				prefix := strings.TrimSuffix(route.Path, "*")
-				if prefix == "" {
-					// * is catch-all - always matches
-				} else if !strings.HasPrefix(path, prefix) {
-					continue
-				}
-			} else {
-				// Exact match only (no wildcard)
-				if path != route.Path {
-					continue
-				}
-			}
+		if params, ok := routeMatchesPath(route, path); ok {
+			return route, params
 		}
-
-		return route, params
 	}
 
 	return nil, nil
 }
 
+// allowedMethods lists, sorted, the HTTP methods registered for routes that
+// match path. Used to answer 405 Method Not Allowed when findMatchingRoute
+// finds nothing for the request's method. WebSocket routes are left out.
+// Must be called with routeMutex held (RLock).
+func (s *HTTPServerValue) allowedMethods(path string) []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, routeKey := range s.sortedRouteKeys {
+		parts := strings.SplitN(routeKey, " ", 2)
+		if len(parts) != 2 || parts[0] == "WS" || seen[parts[0]] {
+			continue
+		}
+		if _, ok := routeMatchesPath(s.routes[routeKey], path); ok {
+			seen[parts[0]] = true
+			methods = append(methods, parts[0])
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 // Start launches the HTTP server and blocks until the process receives a termination signal.
 // This allows the script to handle cleanup code after the server stops.
 // Returns an error if the server fails to bind to the port.
@@ -892,9 +786,13 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 		debug.SetGCPercent(400)
 	}
 
+	if s.metrics == nil {
+		s.metrics = NewHTTPMetrics()
+	}
+
 	// If no routes have been registered, default to serving static files from current directory
 	if len(s.routes) == 0 {
-		s.StaticRoute("/*", ".")
+		s.StaticRoute("/*", ".", StaticOptions{})
 	}
 
 	mux := http.NewServeMux()
@@ -902,13 +800,22 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 	// Register catch-all handler
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// Wrap response writer to capture status and bytes for logging
-		lw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		lw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK, start: time.Now()}
 		w = lw
 
+		s.metrics.inFlight.Add(1)
+		defer s.metrics.inFlight.Add(-1)
+
+		if s.MetricsPath != "" && r.URL.Path == s.MetricsPath && (r.Method == "GET" || r.Method == "HEAD") {
+			s.serveMetrics(w, r)
+			s.finishRequest(r, lw.statusCode, lw.bytesWritten, lw.start)
+			return
+		}
+
 		// Check Content-Length header against max body size limit (reject early before handler)
 		if s.MaxBodySize > 0 && r.ContentLength > s.MaxBodySize {
 			http.Error(w, "Payload Too Large", http.StatusRequestEntityTooLarge)
-			s.logAccessRequest(r, http.StatusRequestEntityTooLarge, 0)
+			s.finishRequest(r, http.StatusRequestEntityTooLarge, 0, lw.start)
 			return
 		}
 
@@ -920,7 +827,7 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 		// Enforce max header count limit
 		if s.MaxHeaders > 0 && len(r.Header) > s.MaxHeaders {
 			http.Error(w, "Too many headers", http.StatusRequestHeaderFieldsTooLarge)
-			s.logAccessRequest(r, http.StatusRequestHeaderFieldsTooLarge, lw.bytesWritten)
+			s.finishRequest(r, http.StatusRequestHeaderFieldsTooLarge, lw.bytesWritten, lw.start)
 			return
 		}
 
@@ -931,7 +838,7 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 				if err := r.ParseForm(); err == nil {
 					if len(r.Form) > s.MaxFormFields {
 						http.Error(w, "Too Many Form Fields", http.StatusBadRequest)
-						s.logAccessRequest(r, http.StatusBadRequest, lw.bytesWritten)
+						s.finishRequest(r, http.StatusBadRequest, lw.bytesWritten, lw.start)
 						return
 					}
 				}
@@ -943,7 +850,7 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 				if err := r.ParseMultipartForm(maxSize); err == nil {
 					if r.MultipartForm != nil && len(r.MultipartForm.Value) > s.MaxFormFields {
 						http.Error(w, "Too Many Form Fields", http.StatusBadRequest)
-						s.logAccessRequest(r, http.StatusBadRequest, lw.bytesWritten)
+						s.finishRequest(r, http.StatusBadRequest, lw.bytesWritten, lw.start)
 						return
 					}
 				}
@@ -1015,6 +922,10 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 		if route == nil && IsWebSocketUpgrade(r) {
 			wsRoute, wsPathParams = s.findMatchingRoute("WS", r.URL.Path)
 		}
+		var allowed []string
+		if route == nil && wsRoute == nil {
+			allowed = s.allowedMethods(r.URL.Path)
+		}
 		s.routeMutex.RUnlock()
 
 		// If we found a WS route for this upgrade request, use it
@@ -1024,8 +935,15 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 		}
 
 		if route == nil {
+			// The path exists but not for this method
+			if len(allowed) > 0 {
+				w.Header().Set("Allow", strings.Join(allowed, ", "))
+				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+				s.finishRequest(r, http.StatusMethodNotAllowed, lw.bytesWritten, lw.start)
+				return
+			}
 			http.NotFound(w, r)
-			s.logAccessRequest(r, http.StatusNotFound, lw.bytesWritten)
+			s.finishRequest(r, http.StatusNotFound, lw.bytesWritten, lw.start)
 			return
 		}
 
@@ -1039,7 +957,7 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 
 				if currentCount >= s.MaxWebSocketConnections {
 					http.Error(w, "Service Unavailable: WebSocket connection limit reached", http.StatusServiceUnavailable)
-					s.logAccessRequest(r, http.StatusServiceUnavailable, 0)
+					s.finishRequest(r, http.StatusServiceUnavailable, 0, lw.start)
 					return
 				}
 			}
@@ -1063,7 +981,7 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 				} else {
 					if !strings.HasPrefix(requestPath, prefix) {
 						http.NotFound(w, r)
-						s.logAccessRequest(r, http.StatusNotFound, lw.bytesWritten)
+						s.finishRequest(r, http.StatusNotFound, lw.bytesWritten, lw.start)
 						return
 					}
 					filePath = strings.TrimPrefix(requestPath, prefix)
@@ -1073,7 +991,7 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 			} else {
 				if !strings.HasPrefix(requestPath, route.Path) {
 					http.NotFound(w, r)
-					s.logAccessRequest(r, http.StatusNotFound, lw.bytesWritten)
+					s.finishRequest(r, http.StatusNotFound, lw.bytesWritten, lw.start)
 					return
 				}
 				filePath = strings.TrimPrefix(requestPath, route.Path)
@@ -1083,52 +1001,32 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 			fullPath := core.Join(route.StaticDir, filePath)
 
 			// 1. Try to serve as a file
-			if _, err := s.FileReader(fullPath); err == nil {
-				response := map[string]any{
-					"status":   200,
-					"filename": fullPath,
-				}
-				// Add Cache-Control header for static files
-				if s.StaticCacheControl != "" {
-					response["headers"] = map[string]any{
-						"Cache-Control": s.StaticCacheControl,
-					}
-				}
-				s.sendHTTPResponse(w, response, "")
-				s.logAccessRequest(r, lw.statusCode, lw.bytesWritten)
+			if content, err := s.FileReader(fullPath); err == nil {
+				s.serveStaticFile(w, r, fullPath, content)
+				s.finishRequest(r, lw.statusCode, lw.bytesWritten, lw.start)
 				return
 			}
 
 			// 2. Check if it's a directory
 			if s.DirReader == nil {
 				http.Error(w, "Server configuration error: DirReader not initialized for static file serving", 500)
-				s.logAccessRequest(r, http.StatusInternalServerError, lw.bytesWritten)
+				s.finishRequest(r, http.StatusInternalServerError, lw.bytesWritten, lw.start)
 				return
 			}
 			entries, err := s.DirReader(fullPath)
 			if err == nil && entries != nil {
 				// It's a directory - try default files in order
-				for _, defaultFile := range s.DefaultFiles {
+				for _, defaultFile := range s.defaultFiles(route) {
 					defaultPath := core.Join(fullPath, defaultFile)
-					if _, errFile := s.FileReader(defaultPath); errFile == nil {
-						response := map[string]any{
-							"status":   200,
-							"filename": defaultPath,
-						}
-						// Add Cache-Control header for static files
-						if s.StaticCacheControl != "" {
-							response["headers"] = map[string]any{
-								"Cache-Control": s.StaticCacheControl,
-							}
-						}
-						s.sendHTTPResponse(w, response, "")
-						s.logAccessRequest(r, lw.statusCode, lw.bytesWritten)
+					if content, errFile := s.FileReader(defaultPath); errFile == nil {
+						s.serveStaticFile(w, r, defaultPath, content)
+						s.finishRequest(r, lw.statusCode, lw.bytesWritten, lw.start)
 						return
 					}
 				}
 				// No default files found, show directory listing or 404
-				if s.ShowDirectoryListing {
-					html := fmt.Sprintf("<pre>Directory: %s\n\n", strings.ReplaceAll(requestPath, "<", "&lt;"))
+				if s.showDirectoryListing(route) {
+					listing := fmt.Sprintf("<pre>Directory: %s\n\n", html.EscapeString(requestPath))
 
 					for _, entryMap := range entries {
 						name, _ := entryMap["name"].(string)
@@ -1145,27 +1043,27 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 						if isDir {
 							path += "/"
 						}
-						html += fmt.Sprintf("<a href=\"%s\">%s</a>\n", path, name)
+						listing += fmt.Sprintf("<a href=\"%s\">%s</a>\n", html.EscapeString(path), html.EscapeString(name))
 					}
-					html += "</pre>"
+					listing += "</pre>"
 
 					w.Header().Set("Content-Type", "text/html; charset=utf-8")
 					if s.StaticCacheControl != "" {
 						w.Header().Set("Cache-Control", s.StaticCacheControl)
 					}
 					w.WriteHeader(200)
-					w.Write([]byte(html))
-					s.logAccessRequest(r, lw.statusCode, lw.bytesWritten)
+					w.Write([]byte(listing))
+					s.finishRequest(r, lw.statusCode, lw.bytesWritten, lw.start)
 					return
 				}
 				http.NotFound(w, r)
-				s.logAccessRequest(r, http.StatusNotFound, lw.bytesWritten)
+				s.finishRequest(r, http.StatusNotFound, lw.bytesWritten, lw.start)
 				return
 			}
 
 			// File not found and not a directory
 			http.NotFound(w, r)
-			s.logAccessRequest(r, http.StatusNotFound, lw.bytesWritten)
+			s.finishRequest(r, http.StatusNotFound, lw.bytesWritten, lw.start)
 			return
 		}
 
@@ -1173,7 +1071,7 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 		s.handleRequest(w, r, route, pathParams)
 
 		// Log request after handler completes
-		s.logAccessRequest(r, lw.statusCode, lw.bytesWritten)
+		s.finishRequest(r, lw.statusCode, lw.bytesWritten, lw.start)
 	})
 
 	s.server = &http.Server{
@@ -1213,6 +1111,8 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 		// Server started successfully, proceed to wait for signals
 		break
 	}
+	runningServers.Add(1)
+	defer runningServers.Add(-1)
 
 	// Set up signal handling - wait for Ctrl+C or termination signal
 	sigChan := make(chan os.Signal, 1)
@@ -1245,16 +1145,56 @@ func (s *HTTPServerValue) StartWithContext(procCtx context.Context) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := s.server.Shutdown(ctx); err != nil && err != context.Canceled {
+	shutdownErr := s.server.Shutdown(ctx)
+
+	// Stop set_interval()/set_timeout() jobs so none runs during or after the hooks
+	StopScheduledJobs()
+
+	// In-flight requests are done (or timed out), so hooks see final state
+	s.runShutdownHooks()
+
+	if shutdownErr != nil && shutdownErr != context.Canceled {
 		// If killed by kill(), don't report shutdown errors - just exit cleanly
 		if !wasKilled {
-			return fmt.Errorf("server shutdown error: %w", err)
+			return fmt.Errorf("server shutdown error: %w", shutdownErr)
 		}
 	}
 
 	return nil
 }
 
+// OnShutdown registers fn to run when the server stops, after in-flight
+// requests have finished. Hooks run in registration order; an error from one
+// is reported on stderr and doesn't stop the rest.
+func (s *HTTPServerValue) OnShutdown(fn func() error) {
+	s.routeMutex.Lock()
+	s.shutdownHooks = append(s.shutdownHooks, fn)
+	s.routeMutex.Unlock()
+}
+
+// runShutdownHooks runs the OnShutdown hooks
+func (s *HTTPServerValue) runShutdownHooks() {
+	s.routeMutex.RLock()
+	hooks := append([]func() error(nil), s.shutdownHooks...)
+	s.routeMutex.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(); err != nil {
+			fmt.Fprintf(os.Stderr, "on_shutdown: %v\n", err)
+		}
+	}
+}
+
+// runningServers counts servers blocked in StartWithContext
+var runningServers atomic.Int32
+
+// RunningHTTPServers reports how many servers are running. Hosts with their
+// own SIGINT/SIGTERM handling use it to leave the signal to the servers,
+// which shut down gracefully and run their on_shutdown hooks.
+func RunningHTTPServers() int {
+	return int(runningServers.Load())
+}
+
 // Start is a convenience method that calls StartWithContext(nil)
 // for compatibility with existing code
 func (s *HTTPServerValue) Start() error {
@@ -1263,9 +1203,6 @@ func (s *HTTPServerValue) Start() error {
 
 // handleRequest processes an incoming HTTP request
 func (s *HTTPServerValue) handleRequest(w http.ResponseWriter, r *http.Request, route *Route, pathParams map[string]any) {
-	// TODO: Increment HTTP request counter
-	// IncrementHTTPProcs() - metrics system disabled
-
 	// Create invocation frame for this HTTP route
 	// Note: For phase 1, we create script.InvocationFrame since that's what DebugEvent expects
 	frame := &script.InvocationFrame{
@@ -1292,6 +1229,8 @@ func (s *HTTPServerValue) handleRequest(w http.ResponseWriter, r *http.Request,
 		JWTSecret:       s.JWT.Secret,
 		RS256PrivateKey: s.JWT.RS256PrivateKey,
 		RS256PublicKey:  s.JWT.RS256PublicKey,
+		JWTIssuer:       s.JWT.Issuer,
+		JWTAudience:     s.JWT.Audience,
 		CacheControl:    s.CacheControl,
 		MaxBodySize:     s.MaxBodySize,
 		MaxFormFields:   s.MaxFormFields,
@@ -1456,6 +1395,9 @@ func (s *HTTPServerValue) handleRequest(w http.ResponseWriter, r *http.Request,
 
 // handleWebSocketRequest handles a WebSocket connection
 func (s *HTTPServerValue) handleWebSocketRequest(w http.ResponseWriter, r *http.Request, route *Route, pathParams map[string]any) {
+	// The access log records how long the connection stayed open
+	start := time.Now()
+
 	// Use websocket.Handler to perform the upgrade
 	// The handler function runs in its own goroutine for each connection
 	wsHandler := websocket.Handler(func(ws *websocket.Conn) {
@@ -1496,6 +1438,8 @@ func (s *HTTPServerValue) handleWebSocketRequest(w http.ResponseWriter, r *http.
 			JWTSecret:       s.JWT.Secret,
 			RS256PrivateKey: s.JWT.RS256PrivateKey,
 			RS256PublicKey:  s.JWT.RS256PublicKey,
+			JWTIssuer:       s.JWT.Issuer,
+			JWTAudience:     s.JWT.Audience,
 			CacheControl:    s.CacheControl,
 			MaxBodySize:     s.MaxBodySize,
 			MaxFormFields:   s.MaxFormFields,
@@ -1601,7 +1545,171 @@ func (s *HTTPServerValue) handleWebSocketRequest(w http.ResponseWriter, r *http.
 	wsHandler.ServeHTTP(w, r)
 
 	// Log the WebSocket connection
-	s.logAccessRequest(r, http.StatusSwitchingProtocols, 0)
+	s.finishRequest(r, http.StatusSwitchingProtocols, 0, start)
+}
+
+// isMarkdownFile reports whether a static file should be rendered when
+// RenderMarkdown is on
+func isMarkdownFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// serveStaticFile sends a file found by a static route. It tags the response
+// with an ETag (from the content) and Last-Modified (from FileStatter), and
+// answers a matching If-None-Match or If-Modified-Since with 304 Not Modified.
+func (s *HTTPServerValue) serveStaticFile(w http.ResponseWriter, r *http.Request, path string, content []byte) {
+	sum := sha256.Sum256(content)
+	etag := fmt.Sprintf("\"%x\"", sum[:12])
+	var modTime time.Time
+	if s.FileStatter != nil {
+		// Embedded files report a zero mtime, which isn't worth sending
+		if mtime := s.FileStatter(path); mtime > 0 {
+			modTime = time.Unix(mtime, 0)
+		}
+	}
+
+	w.Header().Set("ETag", etag)
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if s.StaticCacheControl != "" {
+		w.Header().Set("Cache-Control", s.StaticCacheControl)
+	}
+
+	if notModified(r, etag, modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if s.RenderMarkdown && isMarkdownFile(path) {
+		s.sendRenderedMarkdown(w, path, content)
+		return
+	}
+	w.Header().Set("Content-Type", getContentType(path))
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	// Byte ranges, so browsers can seek in audio and video
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && ifRangeMatches(r, etag, modTime) {
+		size := int64(len(content))
+		start, end, status := parseByteRange(rangeHeader, size)
+		switch status {
+		case http.StatusPartialContent:
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(content[start : end+1])
+			return
+		case http.StatusRequestedRangeNotSatisfiable:
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
+
+// parseByteRange parses a Range header against a file of size bytes and
+// returns the inclusive byte window. The status is 206 for a usable range,
+// 416 when the range lies outside the file, and 0 when the header should be
+// ignored and the whole file sent (malformed, or several ranges at once).
+func parseByteRange(header string, size int64) (int64, int64, int) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, 0
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, 0
+	}
+
+	if first == "" {
+		// Suffix range: the last N bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, 0
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, http.StatusRequestedRangeNotSatisfiable
+		}
+		return max(size-n, 0), size - 1, http.StatusPartialContent
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, 0
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, 0
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, http.StatusRequestedRangeNotSatisfiable
+	}
+	return start, end, http.StatusPartialContent
+}
+
+// ifRangeMatches reports whether a Range request should be honored: always
+// without If-Range, otherwise only if the validator still matches the file
+func ifRangeMatches(r *http.Request, etag string, modTime time.Time) bool {
+	ifRange := r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, "\"") {
+		return ifRange == etag
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && !modTime.IsZero() && modTime.Equal(t)
+}
+
+// notModified evaluates a request's conditional headers against the current
+// ETag and modification time. If-None-Match takes precedence over
+// If-Modified-Since, as RFC 9110 requires.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// Weak comparison: W/"x" matches "x"
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !modTime.After(t)
+		}
+	}
+	return false
+}
+
+// sendRenderedMarkdown responds with a markdown file rendered to a complete
+// HTML page (sanitized, as markdown_html() does by default)
+func (s *HTTPServerValue) sendRenderedMarkdown(w http.ResponseWriter, path string, content []byte) {
+	body := markdown.ToHTML(string(content), markdown.DefaultOptions())
+
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	page.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	page.WriteString("<title>" + html.EscapeString(title) + "</title>\n</head>\n<body>\n")
+	page.WriteString(body)
+	page.WriteString("</body>\n</html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if s.StaticCacheControl != "" {
+		w.Header().Set("Cache-Control", s.StaticCacheControl)
+	}
+	w.WriteHeader(200)
+	_, _ = w.Write([]byte(page.String()))
 }
 
 // getContentType returns the MIME type for a file based on extension
@@ -1819,6 +1927,35 @@ func multiValued(vv []string) script.Value {
 	return script.NewArray(arr)
 }
 
+// numericQueryPattern matches plain decimal numbers, leaving out forms such as
+// "1e3", "+5" or "NaN" that ParseFloat would also accept
+var numericQueryPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// queryParamValue converts one query parameter value, turning plain numbers
+// into Duso numbers. A number only replaces the string if it formats back to
+// exactly the same text, so "007", "1.50" and IDs too long for a float64
+// (e.g. "1234567890123456789") stay strings.
+func queryParamValue(v string) script.Value {
+	if numericQueryPattern.MatchString(v) {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && strconv.FormatFloat(n, 'f', -1, 64) == v {
+			return script.NewNumber(n)
+		}
+	}
+	return script.NewString(v)
+}
+
+// queryValued is multiValued for query parameters, with numeric coercion
+func queryValued(vv []string) script.Value {
+	if len(vv) == 1 {
+		return queryParamValue(vv[0])
+	}
+	arr := make([]script.Value, len(vv))
+	for i, v := range vv {
+		arr[i] = queryParamValue(v)
+	}
+	return script.NewArray(arr)
+}
+
 // GetRequest returns the request data for the context() builtin
 // For spawn/run contexts, returns the Data field as-is
 // For HTTP contexts, returns parsed HTTP request data.
@@ -1837,7 +1974,7 @@ func (rc *RequestContext) GetRequest() any {
 		query := make(map[string]script.Value)
 		if rc.Request.URL.RawQuery != "" {
 			for k, vv := range rc.Request.URL.Query() {
-				query[k] = multiValued(vv)
+				query[k] = queryValued(vv)
 			}
 		}
 
@@ -1915,6 +2052,16 @@ func (rc *RequestContext) GetRequest() any {
 			body = string(rc.bodyCache)
 		}
 
+		// Parse JSON bodies up front; a malformed body leaves json as nil
+		// and the handler can still inspect the raw body
+		jsonBody := script.NewNil()
+		if strings.Contains(contentType, "application/json") && len(body) > 0 {
+			var parsed any
+			if err := json.Unmarshal([]byte(body), &parsed); err == nil {
+				jsonBody = script.InterfaceToValue(jsonToValue(parsed))
+			}
+		}
+
 		result := map[string]script.Value{
 			"method":  script.NewString(rc.Request.Method),
 			"path":    script.NewString(rc.Request.URL.Path),
@@ -1922,6 +2069,7 @@ func (rc *RequestContext) GetRequest() any {
 			"query":   script.NewObject(query),
 			"form":    script.NewObject(formData),
 			"body":    script.NewString(body),
+			"json":    jsonBody,
 			"files":   script.InterfaceToValue(filesMap),
 		}
 
@@ -1948,6 +2096,8 @@ func (rc *RequestContext) GetRequest() any {
 			// Parse optional config overrides
 			publicKey := ""
 			secret := ""
+			expect := jwt.Expect{Issuer: rc.JWTIssuer, Audience: rc.JWTAudience}
+			throwErrors := false
 
 			if optArg, ok := args["0"].(map[string]any); ok {
 				if key, ok := optArg["public_key"].(string); ok {
@@ -1956,6 +2106,13 @@ func (rc *RequestContext) GetRequest() any {
 				if sec, ok := optArg["secret"].(string); ok {
 					secret = sec
 				}
+				if iss, ok := optArg["issuer"].(string); ok {
+					expect.Issuer = iss
+				}
+				if aud, ok := optArg["audience"].(string); ok {
+					expect.Audience = aud
+				}
+				throwErrors, _ = optArg["throw_errors"].(bool)
 			}
 
 			// Use provided values or fall back to defaults
@@ -1966,95 +2123,16 @@ func (rc *RequestContext) GetRequest() any {
 				secret = rc.JWTSecret
 			}
 
-			// Verify JWT token with config (inline verification logic)
-			parts := strings.Split(token, ".")
-			if len(parts) != 3 {
-				return nil, nil
-			}
-
-			headerStr := parts[0]
-			payloadStr := parts[1]
-			signatureStr := parts[2]
-
-			// Decode header
-			headerBytes, err := base64urlDecode(headerStr)
-			if err != nil {
-				return nil, nil
-			}
-
-			var header map[string]any
-			if err := json.Unmarshal(headerBytes, &header); err != nil {
-				return nil, nil
-			}
-
-			// Get algorithm from token
-			alg, ok := header["alg"].(string)
-			if !ok {
-				return nil, nil
-			}
-
-			// Decode signature
-			decodedSigBytes, err := base64urlDecode(signatureStr)
+			// Invalid, expired or unverifiable tokens all read as no token,
+			// unless the handler asked for the reason
+			claims, err := jwt.Verify(token, jwt.Keys{Secret: secret, PublicKey: publicKey}, expect)
 			if err != nil {
-				return nil, nil
-			}
-
-			// Verify signature based on algorithm
-			switch alg {
-			case "HS256":
-				if secret == "" {
-					return nil, nil
-				}
-				h := hmac.New(sha256.New, []byte(secret))
-				h.Write([]byte(headerStr + "." + payloadStr))
-				expectedSigBytes := h.Sum(nil)
-				if !hmac.Equal(decodedSigBytes, expectedSigBytes) {
-					return nil, nil
-				}
-
-			case "RS256":
-				if publicKey == "" {
-					return nil, nil
-				}
-				pubKeyBlock, _ := pem.Decode([]byte(publicKey))
-				if pubKeyBlock == nil {
-					return nil, nil
-				}
-				pubKeyInterface, err := x509.ParsePKIXPublicKey(pubKeyBlock.Bytes)
-				if err != nil {
-					return nil, nil
-				}
-				rsaPublicKey, ok := pubKeyInterface.(*rsa.PublicKey)
-				if !ok {
-					return nil, nil
+				if throwErrors {
+					return nil, fmt.Errorf("verify_jwt(): %w", err)
 				}
-				hash := sha256.Sum256([]byte(headerStr + "." + payloadStr))
-				if err := rsa.VerifyPKCS1v15(rsaPublicKey, crypto.SHA256, hash[:], decodedSigBytes); err != nil {
-					return nil, nil
-				}
-
-			default:
-				return nil, nil // Unsupported algorithm
-			}
-
-			// Decode and return claims
-			payloadBytes, err := base64urlDecode(payloadStr)
-			if err != nil {
 				return nil, nil
 			}
 
-			var claims map[string]any
-			if err := json.Unmarshal(payloadBytes, &claims); err != nil {
-				return nil, nil
-			}
-
-			// Check expiration
-			if exp, ok := claims["exp"].(float64); ok {
-				if time.Now().Unix() > int64(exp) {
-					return nil, nil
-				}
-			}
-
 			return claims, nil
 		})
 
@@ -2468,13 +2546,16 @@ func (rc *RequestContext) GetWSConnection() map[string]any {
 	return wsMethods
 }
 
-// logAccessRequest logs an HTTP request in Apache Combined Log Format to stderr
-// Format: remotehost rfc931 authuser [timestamp] "request line" http_status bytes_sent "referrer" "user-agent"
-// Truncates path if line would exceed 4KB (safe atomic write limit for stderr)
-func (s *HTTPServerValue) logAccessRequest(r *http.Request, statusCode int, bytesWritten int64) {
+// logAccessRequest writes one access log line for a finished request, in
+// Apache Combined Log Format with the duration appended, or as a JSON
+// object per line when AccessLogFormat is "json".
+// Plain format: remotehost rfc931 authuser [timestamp] "request line" http_status bytes_sent "referrer" "user-agent" duration
+// Truncates the request line if the line would exceed 4KB (safe atomic write limit for stderr)
+func (s *HTTPServerValue) logAccessRequest(r *http.Request, statusCode int, bytesWritten int64, start time.Time) {
 	if !s.AccessLog {
 		return
 	}
+	duration := time.Since(start)
 
 	// Get remote address
 	remoteAddr := r.RemoteAddr
@@ -2482,6 +2563,47 @@ func (s *HTTPServerValue) logAccessRequest(r *http.Request, statusCode int, byte
 		remoteAddr = remoteAddr[:idx] // Strip port
 	}
 
+	out := s.AccessLogWriter
+	if out == nil {
+		out = os.Stderr
+	}
+
+	if s.AccessLogFormat == "json" {
+		path := r.URL.RequestURI()
+		if len(path) > 2048 {
+			path = path[:2045] + "..."
+		}
+		entry := struct {
+			Time       string  `json:"time"`
+			Remote     string  `json:"remote"`
+			Method     string  `json:"method"`
+			Path       string  `json:"path"`
+			Proto      string  `json:"proto"`
+			Status     int     `json:"status"`
+			Bytes      int64   `json:"bytes"`
+			DurationMS float64 `json:"duration_ms"`
+			Referrer   string  `json:"referrer,omitempty"`
+			UserAgent  string  `json:"user_agent,omitempty"`
+		}{
+			Time:       start.Format(time.RFC3339Nano),
+			Remote:     remoteAddr,
+			Method:     r.Method,
+			Path:       path,
+			Proto:      r.Proto,
+			Status:     statusCode,
+			Bytes:      bytesWritten,
+			DurationMS: float64(duration.Microseconds()) / 1000,
+			Referrer:   r.Header.Get("Referer"),
+			UserAgent:  r.Header.Get("User-Agent"),
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		out.Write(append(line, '\n'))
+		return
+	}
+
 	// Build request line
 	requestLine := fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), r.Proto)
 
@@ -2497,10 +2619,11 @@ func (s *HTTPServerValue) logAccessRequest(r *http.Request, statusCode int, byte
 
 	// Format timestamp in Apache format: [DD/Mon/YYYY HH:MM:SS +0000]
 	timestamp := time.Now().Format("02/Jan/2006 15:04:05 -0700")
+	durationStr := fmt.Sprintf("%.3fms", float64(duration.Microseconds())/1000)
 
 	// Build initial log line (without request line, to measure length)
-	logPrefix := fmt.Sprintf("%s - - [%s] \"%s\" %d %d \"%s\" \"%s\"",
-		remoteAddr, timestamp, "", statusCode, bytesWritten, referrer, userAgent)
+	logPrefix := fmt.Sprintf("%s - - [%s] \"%s\" %d %d \"%s\" \"%s\" %s",
+		remoteAddr, timestamp, "", statusCode, bytesWritten, referrer, userAgent, durationStr)
 
 	// Calculate available space for request line (4KB - fixed overhead - margins)
 	maxLogLineLen := 4096
@@ -2513,9 +2636,9 @@ func (s *HTTPServerValue) logAccessRequest(r *http.Request, statusCode int, byte
 	}
 
 	// Build final log line
-	logLine := fmt.Sprintf("%s - - [%s] \"%s\" %d %d \"%s\" \"%s\"\n",
-		remoteAddr, timestamp, requestLine, statusCode, bytesWritten, referrer, userAgent)
+	logLine := fmt.Sprintf("%s - - [%s] \"%s\" %d %d \"%s\" \"%s\" %s\n",
+		remoteAddr, timestamp, requestLine, statusCode, bytesWritten, referrer, userAgent, durationStr)
 
-	// Write to stderr atomically (small writes are atomic)
-	os.Stderr.WriteString(logLine)
+	// Write to the sink atomically (small writes are atomic)
+	io.WriteString(out, logLine)
 }
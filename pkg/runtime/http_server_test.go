@@ -0,0 +1,375 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duso-org/duso/pkg/script"
+)
+
+// TestSendRenderedMarkdown checks the render_markdown static response: a
+// full, sanitized HTML page served as text/html.
+func TestSendRenderedMarkdown(t *testing.T) {
+	s := &HTTPServerValue{StaticCacheControl: "public, max-age=60"}
+	rec := httptest.NewRecorder()
+	s.sendRenderedMarkdown(rec, "/docs/guide.md", []byte("# Guide\n\nHi <b>there</b>\n"))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"<!DOCTYPE html>", "<title>guide</title>", `<h1 id="guide">Guide</h1>`, "<!-- raw HTML omitted -->"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<b>") {
+		t.Errorf("raw HTML was not sanitized:\n%s", body)
+	}
+
+	for path, want := range map[string]bool{"a.md": true, "B.MD": true, "c.markdown": true, "d.html": false, "md": false} {
+		if got := isMarkdownFile(path); got != want {
+			t.Errorf("isMarkdownFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// TestAllowedMethods checks the methods reported in the Allow header when a
+// path is registered but not for the request's method.
+func TestAllowedMethods(t *testing.T) {
+	s := &HTTPServerValue{}
+	for _, r := range []struct{ method, path string }{
+		{"GET", "/users/:id"},
+		{"DELETE", "/users/:id"},
+		{"POST", "/users"},
+		{"WS", "/users/:id"},
+	} {
+		if err := s.Route(r.method, r.path, "handler.du", nil); err != nil {
+			t.Fatalf("Route(%s %s): %v", r.method, r.path, err)
+		}
+	}
+
+	if route, _ := s.findMatchingRoute("PUT", "/users/42"); route != nil {
+		t.Fatalf("PUT /users/42 should not match, got %s %s", route.Method, route.Path)
+	}
+	if got := strings.Join(s.allowedMethods("/users/42"), ", "); got != "DELETE, GET" {
+		t.Errorf("Allow for /users/42 = %q, want \"DELETE, GET\"", got)
+	}
+	if got := s.allowedMethods("/nope"); len(got) != 0 {
+		t.Errorf("Allow for unknown path = %v, want none", got)
+	}
+}
+
+// TestCatchAllPathParams checks that a trailing *name segment captures the
+// rest of the path and sorts after more specific routes.
+func TestCatchAllPathParams(t *testing.T) {
+	s := &HTTPServerValue{}
+	for _, path := range []string{"/files/*filepath", "/files/readme", "/users/:id/files/*rest"} {
+		if err := s.Route("GET", path, "handler.du", nil); err != nil {
+			t.Fatalf("Route(%s): %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		path      string
+		wantRoute string
+		wantParam map[string]any
+	}{
+		{"/files/a/b/c.txt", "/files/*filepath", map[string]any{"filepath": "a/b/c.txt"}},
+		{"/files/", "/files/*filepath", map[string]any{"filepath": ""}},
+		{"/files", "/files/*filepath", map[string]any{"filepath": ""}},
+		{"/files/readme", "/files/readme", nil},
+		{"/users/7/files/x/y", "/users/:id/files/*rest", map[string]any{"id": "7", "rest": "x/y"}},
+		{"/filesystem", "", nil},
+	}
+	for _, tt := range tests {
+		route, params := s.findMatchingRoute("GET", tt.path)
+		if tt.wantRoute == "" {
+			if route != nil {
+				t.Errorf("%s: expected no match, got %s", tt.path, route.Path)
+			}
+			continue
+		}
+		if route == nil || route.Path != tt.wantRoute {
+			t.Errorf("%s: matched %v, want %s", tt.path, route, tt.wantRoute)
+			continue
+		}
+		for k, v := range tt.wantParam {
+			if params[k] != v {
+				t.Errorf("%s: param %s = %v, want %v", tt.path, k, params[k], v)
+			}
+		}
+	}
+
+	for _, bad := range []string{"/files/*path/more", "/files*path"} {
+		if _, _, err := extractPathParams(bad); err == nil {
+			t.Errorf("extractPathParams(%q): expected error", bad)
+		}
+	}
+}
+
+// TestGetRequestJSONAndQuery checks the parsed json body and the numeric
+// coercion of query parameters.
+func TestGetRequestJSONAndQuery(t *testing.T) {
+	r := httptest.NewRequest("POST", "/items?page=2&ratio=0.5&zip=01234&q=abc&id=3&id=x", strings.NewReader(`{"name": "widget", "tags": ["a"], "qty": 4}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req := (&RequestContext{Request: r}).GetRequest().(*script.ValueRef).Val.AsObject()
+
+	query := req["query"].AsObject()
+	if !query["page"].IsNumber() || query["page"].AsNumber() != 2 || query["ratio"].AsNumber() != 0.5 {
+		t.Errorf("numeric query params not coerced: %v", query)
+	}
+	if !query["zip"].IsString() || !query["q"].IsString() {
+		t.Errorf("non-canonical numbers and text should stay strings: %v", query)
+	}
+	for _, v := range []string{"1234567890123456789", "1.50", "-0.10", "1e3", "NaN"} {
+		if got := queryParamValue(v); !got.IsString() || got.AsString() != v {
+			t.Errorf("queryParamValue(%q) = %v, want the string kept", v, got)
+		}
+	}
+	if got := queryParamValue("9007199254740993"); !got.IsString() {
+		t.Errorf("an integer past 2^53 should stay a string, got %v", got)
+	}
+	if ids := query["id"].AsArray(); len(ids) != 2 || !ids[0].IsNumber() || !ids[1].IsString() {
+		t.Errorf("repeated params = %v", query["id"])
+	}
+
+	body := req["json"].AsObject()
+	if body["name"].AsString() != "widget" || body["qty"].AsNumber() != 4 || len(body["tags"].AsArray()) != 1 {
+		t.Errorf("json = %v", req["json"])
+	}
+	if req["body"].AsString() == "" {
+		t.Error("raw body should still be available")
+	}
+
+	for ct, payload := range map[string]string{"application/json": "{oops", "text/plain": `{"a": 1}`} {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(payload))
+		r.Header.Set("Content-Type", ct)
+		req := (&RequestContext{Request: r}).GetRequest().(*script.ValueRef).Val.AsObject()
+		if !req["json"].IsNil() {
+			t.Errorf("%s %q: json = %v, want nil", ct, payload, req["json"])
+		}
+	}
+}
+
+// TestStaticOptions checks per-route overrides of the server's default files
+// and directory listing.
+func TestStaticOptions(t *testing.T) {
+	listing := true
+	s := &HTTPServerValue{DefaultFiles: []string{"index.html"}}
+	s.StaticRoute("/docs/*", "/srv/docs", StaticOptions{DefaultFiles: []string{}, DirectoryListing: &listing})
+	s.StaticRoute("/app/*", "/srv/app", StaticOptions{})
+
+	docs, _ := s.findMatchingRoute("GET", "/docs/")
+	app, _ := s.findMatchingRoute("GET", "/app/")
+	if docs == nil || app == nil {
+		t.Fatal("static routes did not match")
+	}
+	if len(s.defaultFiles(docs)) != 0 || !s.showDirectoryListing(docs) {
+		t.Errorf("docs route: default files %v, listing %v", s.defaultFiles(docs), s.showDirectoryListing(docs))
+	}
+	if got := s.defaultFiles(app); len(got) != 1 || got[0] != "index.html" || s.showDirectoryListing(app) {
+		t.Errorf("app route should use server settings: default files %v, listing %v", got, s.showDirectoryListing(app))
+	}
+
+	for in, want := range map[any]string{"a.html, b.md": "a.html,b.md", "": "", nil: ""} {
+		if got := strings.Join(parseDefaultFiles(in), ","); got != want {
+			t.Errorf("parseDefaultFiles(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestServeStaticFileConditional checks ETag/Last-Modified and 304 answers
+// to conditional requests.
+func TestServeStaticFileConditional(t *testing.T) {
+	mtime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := &HTTPServerValue{FileStatter: func(string) int64 { return mtime.Unix() }}
+	content := []byte("body { color: red }")
+
+	rec := httptest.NewRecorder()
+	s.serveStaticFile(rec, httptest.NewRequest("GET", "/site.css", nil), "/srv/site.css", content)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != 200 || etag == "" || rec.Body.String() != string(content) {
+		t.Fatalf("first request: status %d, ETag %q, body %q", rec.Code, etag, rec.Body.String())
+	}
+	if lm := rec.Header().Get("Last-Modified"); lm != "Sun, 01 Mar 2026 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", lm)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/css; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"matching etag", "If-None-Match", etag, 304},
+		{"weak etag in a list", "If-None-Match", `"other", W/` + etag, 304},
+		{"stale etag", "If-None-Match", `"other"`, 200},
+		{"not modified since", "If-Modified-Since", mtime.Add(time.Hour).Format(http.TimeFormat), 304},
+		{"modified since", "If-Modified-Since", mtime.Add(-time.Hour).Format(http.TimeFormat), 200},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/site.css", nil)
+		r.Header.Set(tt.header, tt.value)
+		rec := httptest.NewRecorder()
+		s.serveStaticFile(rec, r, "/srv/site.css", content)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want == 304 && rec.Body.Len() != 0 {
+			t.Errorf("%s: 304 should have no body", tt.name)
+		}
+	}
+}
+
+// TestServeStaticFileRange checks 206 partial content for byte ranges.
+func TestServeStaticFileRange(t *testing.T) {
+	s := &HTTPServerValue{}
+	content := []byte("0123456789")
+
+	tests := []struct {
+		rangeHeader  string
+		wantStatus   int
+		wantBody     string
+		contentRange string
+	}{
+		{"bytes=2-5", 206, "2345", "bytes 2-5/10"},
+		{"bytes=7-", 206, "789", "bytes 7-9/10"},
+		{"bytes=-3", 206, "789", "bytes 7-9/10"},
+		{"bytes=8-100", 206, "89", "bytes 8-9/10"},
+		{"bytes=20-30", 416, "", "bytes */10"},
+		{"bytes=0-1,4-5", 200, "0123456789", ""},
+		{"items=0-1", 200, "0123456789", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/clip.mp4", nil)
+		r.Header.Set("Range", tt.rangeHeader)
+		rec := httptest.NewRecorder()
+		s.serveStaticFile(rec, r, "/srv/clip.mp4", content)
+		if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: status %d body %q, want %d %q", tt.rangeHeader, rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+		}
+		if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("%s: Content-Range %q, want %q", tt.rangeHeader, got, tt.contentRange)
+		}
+		if rec.Header().Get("Accept-Ranges") != "bytes" {
+			t.Errorf("%s: missing Accept-Ranges", tt.rangeHeader)
+		}
+	}
+
+	// A stale If-Range validator gets the whole file
+	r := httptest.NewRequest("GET", "/clip.mp4", nil)
+	r.Header.Set("Range", "bytes=0-1")
+	r.Header.Set("If-Range", `"stale"`)
+	rec := httptest.NewRecorder()
+	s.serveStaticFile(rec, r, "/srv/clip.mp4", content)
+	if rec.Code != 200 || rec.Body.String() != string(content) {
+		t.Errorf("stale If-Range: status %d body %q", rec.Code, rec.Body.String())
+	}
+}
+
+// TestLogAccessRequest checks both access log formats include the status
+// and duration and go to the configured sink.
+func TestLogAccessRequest(t *testing.T) {
+	var buf strings.Builder
+	s := &HTTPServerValue{AccessLog: true, AccessLogWriter: &buf}
+	r := httptest.NewRequest("GET", "/api/users?id=1", nil)
+	r.Header.Set("User-Agent", "test-agent")
+	start := time.Now().Add(-1500 * time.Microsecond)
+
+	s.logAccessRequest(r, 404, 12, start)
+	line := buf.String()
+	if !strings.Contains(line, `"GET /api/users?id=1 HTTP/1.1" 404 12 "-" "test-agent" `) || !strings.HasSuffix(line, "ms\n") {
+		t.Errorf("plain line = %q", line)
+	}
+
+	buf.Reset()
+	s.AccessLogFormat = "json"
+	s.logAccessRequest(r, 201, 3, start)
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &entry); err != nil {
+		t.Fatalf("json line %q: %v", buf.String(), err)
+	}
+	if entry["method"] != "GET" || entry["path"] != "/api/users?id=1" || entry["status"] != float64(201) || entry["user_agent"] != "test-agent" {
+		t.Errorf("json entry = %v", entry)
+	}
+	if d, _ := entry["duration_ms"].(float64); d < 1.5 {
+		t.Errorf("duration_ms = %v, want >= 1.5", entry["duration_ms"])
+	}
+	if _, ok := entry["referrer"]; ok {
+		t.Error("empty referrer should be omitted")
+	}
+
+	buf.Reset()
+	s.AccessLog = false
+	s.logAccessRequest(r, 200, 0, start)
+	if buf.Len() != 0 {
+		t.Errorf("disabled log wrote %q", buf.String())
+	}
+}
+
+// TestHTTPMetrics checks the counters behind the metrics route and both of
+// its output formats.
+func TestHTTPMetrics(t *testing.T) {
+	s := &HTTPServerValue{metrics: NewHTTPMetrics()}
+	start := time.Now().Add(-10 * time.Millisecond)
+	r := httptest.NewRequest("GET", "/x", nil)
+	s.finishRequest(r, 200, 0, start)
+	s.finishRequest(r, 200, 0, start)
+	s.finishRequest(r, 404, 0, start)
+	s.metrics.inFlight.Add(1)
+
+	snap := s.metrics.Snapshot()
+	status := snap["status"].(map[string]any)
+	if snap["requests"] != float64(3) || status["200"] != float64(2) || status["404"] != float64(1) || snap["in_flight"] != float64(1) {
+		t.Errorf("snapshot = %v", snap)
+	}
+	if avg := snap["avg_latency_ms"].(float64); avg < 10 {
+		t.Errorf("avg_latency_ms = %v, want >= 10", avg)
+	}
+
+	rec := httptest.NewRecorder()
+	s.serveMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("default Content-Type = %q", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	s.serveMetrics(rec, httptest.NewRequest("GET", "/metrics?format=prometheus", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`duso_http_requests_total{status="200"} 2`,
+		`duso_http_requests_total{status="404"} 1`,
+		"duso_http_requests_in_flight 1",
+		"duso_http_request_duration_seconds_count 3",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("prometheus output missing %q:\n%s", want, body)
+		}
+	}
+}
+
+// TestShutdownHooks checks OnShutdown hooks run in order and that a failing
+// hook doesn't stop the ones after it.
+func TestShutdownHooks(t *testing.T) {
+	s := &HTTPServerValue{}
+	var order []string
+	s.OnShutdown(func() error { order = append(order, "first"); return fmt.Errorf("flush failed") })
+	s.OnShutdown(func() error { order = append(order, "second"); return nil })
+
+	s.runShutdownHooks()
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("hooks ran as %v", order)
+	}
+}
@@ -0,0 +1,358 @@
+// Package jwt signs, verifies and decodes compact JSON Web Tokens. It backs
+// the HTTP server's verify_jwt() and sign_jwt() as well as the standalone
+// builtins of the same names. The algorithm comes from the token header: HS*
+// use the shared secret, RS* and ES* use PEM-encoded RSA or EC keys.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	_ "crypto/sha256" // SHA-256 for HS256/RS256/ES256
+	_ "crypto/sha512" // SHA-384 and SHA-512 for the *384/*512 variants
+)
+
+// Algorithms lists the supported "alg" values, for error messages
+const Algorithms = "HS256, HS384, HS512, RS256, RS384, RS512, ES256, ES384, ES512"
+
+// Claim validation failures, kept distinct so callers can tell a token that
+// needs refreshing from one meant for another service
+var (
+	ErrExpired       = errors.New("token expired")
+	ErrNotYetValid   = errors.New("token not yet valid")
+	ErrWrongIssuer   = errors.New("wrong issuer")
+	ErrWrongAudience = errors.New("wrong audience")
+)
+
+// Keys holds the key material for signing and verifying. Which field is used
+// depends on the algorithm: Secret for HS*, PrivateKey (signing) or
+// PublicKey (verifying) for RS* and ES*.
+type Keys struct {
+	Secret     string
+	PrivateKey string // PEM
+	PublicKey  string // PEM
+}
+
+// Expect lists the claims a token must carry. Empty fields aren't checked.
+type Expect struct {
+	Issuer   string // required "iss"
+	Audience string // must appear in "aud" (a string or an array of strings)
+}
+
+// Sign encodes claims as a token signed with alg. The claims are used as
+// given; callers add "exp" themselves.
+func Sign(claims map[string]any, alg string, keys Keys) (string, error) {
+	headerJSON, _ := json.Marshal(map[string]string{
+		"alg": alg,
+		"typ": "JWT",
+	})
+	payloadJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("invalid claims: %w", err)
+	}
+	message := encodeSegment(headerJSON) + "." + encodeSegment(payloadJSON)
+
+	sig, err := signMessage(alg, message, keys.Secret, keys.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return message + "." + encodeSegment(sig), nil
+}
+
+// Verify checks a token's signature and claims and returns the claims. The
+// algorithm is read from the token header; "none" and unknown algorithms are
+// rejected. The exp and nbf claims are always enforced, iss and aud when
+// expect asks for them.
+func Verify(token string, keys Keys, expect Expect) (map[string]any, error) {
+	header, claims, err := Decode(token)
+	if err != nil {
+		return nil, err
+	}
+	alg, ok := header["alg"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing algorithm in token header")
+	}
+
+	// Decode already checked there are three parts
+	last := strings.LastIndexByte(token, '.')
+	sig, err := decodeSegment(token[last+1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if err := verifySignature(alg, token[:last], sig, keys.Secret, keys.PublicKey); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateClaims(claims, expect, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Decode returns a token's header and claims without checking the signature
+// or any claim. Only use the result for display or routing, never to trust
+// the caller.
+func Decode(token string) (header, claims map[string]any, err error) {
+	// Split token into three parts: header.payload.signature
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("invalid token format")
+	}
+
+	headerBytes, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid header encoding: %w", err)
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, nil, fmt.Errorf("invalid header JSON: %w", err)
+	}
+
+	payloadBytes, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+		return nil, nil, fmt.Errorf("invalid payload JSON: %w", err)
+	}
+	return header, claims, nil
+}
+
+// ValidateClaims checks the registered time, issuer and audience claims
+func ValidateClaims(claims map[string]any, expect Expect, now time.Time) error {
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() > int64(exp) {
+		return ErrExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return ErrNotYetValid
+	}
+	if expect.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != expect.Issuer {
+			return ErrWrongIssuer
+		}
+	}
+	if expect.Audience != "" {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == expect.Audience
+		case []any:
+			for _, a := range aud {
+				if a == expect.Audience {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return ErrWrongAudience
+		}
+	}
+	return nil
+}
+
+// encodeSegment encodes data using base64url encoding (no padding)
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSegment decodes base64url-encoded data (with or without padding)
+func decodeSegment(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(data, "="))
+}
+
+// hashForAlg returns the hash for a JWT algorithm name, e.g. SHA-384 for "ES384"
+func hashForAlg(alg string) (crypto.Hash, bool) {
+	if len(alg) != 5 {
+		return 0, false
+	}
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, true
+	case "384":
+		return crypto.SHA384, true
+	case "512":
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// ecCurveForAlg returns the curve an ES* algorithm requires
+func ecCurveForAlg(alg string) elliptic.Curve {
+	switch alg {
+	case "ES256":
+		return elliptic.P256()
+	case "ES384":
+		return elliptic.P384()
+	case "ES512":
+		return elliptic.P521()
+	}
+	return nil
+}
+
+// parsePublicKey parses a PEM-encoded PKIX public key (RSA or EC)
+func parsePublicKey(keyPEM string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key format")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return key, nil
+}
+
+// parsePrivateKey parses a PEM-encoded private key in PKCS1, SEC1 (EC) or
+// PKCS8 form
+func parsePrivateKey(keyPEM string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key format")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// signMessage signs header.payload with the given algorithm
+func signMessage(alg, message, secret, privateKeyPEM string) ([]byte, error) {
+	hash, ok := hashForAlg(alg)
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm: %s (supported: %s)", alg, Algorithms)
+	}
+
+	switch alg[:2] {
+	case "HS":
+		if secret == "" {
+			return nil, fmt.Errorf("%s secret not configured", alg)
+		}
+		mac := hmac.New(hash.New, []byte(secret))
+		mac.Write([]byte(message))
+		return mac.Sum(nil), nil
+
+	case "RS", "ES":
+		if privateKeyPEM == "" {
+			return nil, fmt.Errorf("%s private key not configured", alg)
+		}
+		signer, err := parsePrivateKey(privateKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		h := hash.New()
+		h.Write([]byte(message))
+		digest := h.Sum(nil)
+
+		if alg[:2] == "RS" {
+			key, ok := signer.(*rsa.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("%s requires an RSA private key", alg)
+			}
+			return rsa.SignPKCS1v15(nil, key, hash, digest)
+		}
+
+		key, ok := signer.(*ecdsa.PrivateKey)
+		if !ok || key.Curve != ecCurveForAlg(alg) {
+			return nil, fmt.Errorf("%s requires an EC private key on curve %s", alg, ecCurveForAlg(alg).Params().Name)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+		// JWS wants r||s, each padded to the curve size, not DER
+		size := (key.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm: %s (supported: %s)", alg, Algorithms)
+}
+
+// verifySignature checks sig over header.payload for the given algorithm
+func verifySignature(alg, message string, sig []byte, secret, publicKeyPEM string) error {
+	hash, ok := hashForAlg(alg)
+	if !ok {
+		return fmt.Errorf("unsupported algorithm: %s (supported: %s)", alg, Algorithms)
+	}
+
+	switch alg[:2] {
+	case "HS":
+		if secret == "" {
+			return fmt.Errorf("%s secret not configured", alg)
+		}
+		mac := hmac.New(hash.New, []byte(secret))
+		mac.Write([]byte(message))
+		// Compare signatures using constant-time comparison
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+
+	case "RS", "ES":
+		if publicKeyPEM == "" {
+			return fmt.Errorf("%s public key not configured", alg)
+		}
+		pub, err := parsePublicKey(publicKeyPEM)
+		if err != nil {
+			return err
+		}
+		h := hash.New()
+		h.Write([]byte(message))
+		digest := h.Sum(nil)
+
+		if alg[:2] == "RS" {
+			key, ok := pub.(*rsa.PublicKey)
+			if !ok {
+				return fmt.Errorf("%s requires an RSA public key", alg)
+			}
+			if err := rsa.VerifyPKCS1v15(key, hash, digest, sig); err != nil {
+				return fmt.Errorf("invalid signature: %w", err)
+			}
+			return nil
+		}
+
+		key, ok := pub.(*ecdsa.PublicKey)
+		if !ok || key.Curve != ecCurveForAlg(alg) {
+			return fmt.Errorf("%s requires an EC public key on curve %s", alg, ecCurveForAlg(alg).Params().Name)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("invalid signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm: %s (supported: %s)", alg, Algorithms)
+}
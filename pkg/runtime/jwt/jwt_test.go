@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pemEncode(t *testing.T, blockType string, der []byte, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+// TestAlgorithms round-trips tokens through Sign and Verify for each
+// algorithm family and checks that mismatched keys are rejected.
+func TestAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	rsaPriv := pemEncode(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil)
+	rsaDer, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPub := pemEncode(t, "PUBLIC KEY", rsaDer, err)
+	p256Der, err := x509.MarshalECPrivateKey(p256)
+	p256Priv := pemEncode(t, "EC PRIVATE KEY", p256Der, err)
+	p256PubDer, err := x509.MarshalPKIXPublicKey(&p256.PublicKey)
+	p256Pub := pemEncode(t, "PUBLIC KEY", p256PubDer, err)
+	p384Der, err := x509.MarshalPKCS8PrivateKey(p384)
+	p384Priv := pemEncode(t, "PRIVATE KEY", p384Der, err)
+	p384PubDer, err := x509.MarshalPKIXPublicKey(&p384.PublicKey)
+	p384Pub := pemEncode(t, "PUBLIC KEY", p384PubDer, err)
+
+	tests := []struct {
+		alg, priv, pub string
+	}{
+		{"HS256", "", ""},
+		{"HS512", "", ""},
+		{"RS256", rsaPriv, rsaPub},
+		{"RS384", rsaPriv, rsaPub},
+		{"ES256", p256Priv, p256Pub},
+		{"ES384", p384Priv, p384Pub},
+	}
+	for _, tt := range tests {
+		token, err := Sign(map[string]any{"sub": "u1"}, tt.alg, Keys{Secret: "s3cret", PrivateKey: tt.priv})
+		if err != nil {
+			t.Fatalf("%s: sign: %v", tt.alg, err)
+		}
+		claims, err := Verify(token, Keys{Secret: "s3cret", PublicKey: tt.pub}, Expect{})
+		if err != nil || claims["sub"] != "u1" {
+			t.Errorf("%s: verify = %v, %v", tt.alg, claims, err)
+		}
+	}
+
+	// Tokens signed with one key family don't verify against another
+	token, _ := Sign(map[string]any{}, "ES256", Keys{PrivateKey: p256Priv})
+	if _, err := Verify(token, Keys{PublicKey: rsaPub}, Expect{}); err == nil || !strings.Contains(err.Error(), "requires an EC public key") {
+		t.Errorf("ES256 against an RSA key: %v", err)
+	}
+	if _, err := Verify(token, Keys{PublicKey: p384Pub}, Expect{}); err == nil {
+		t.Error("ES256 against a P-384 key should fail")
+	}
+
+	// alg "none" is never accepted
+	unsigned := encodeSegment([]byte(`{"alg":"none"}`)) + "." + encodeSegment([]byte(`{"sub":"x"}`)) + "."
+	if _, err := Verify(unsigned, Keys{Secret: "s3cret", PublicKey: rsaPub}, Expect{}); err == nil || !strings.Contains(err.Error(), "unsupported algorithm") {
+		t.Errorf("alg none: %v", err)
+	}
+
+	// Expired tokens fail
+	expired, _ := Sign(map[string]any{"exp": float64(time.Now().Unix() - 10)}, "HS256", Keys{Secret: "s3cret"})
+	if _, err := Verify(expired, Keys{Secret: "s3cret"}, Expect{}); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expired token: %v", err)
+	}
+}
+
+// TestValidateClaims checks the nbf, iss and aud checks and that each
+// failure is reported distinctly.
+func TestValidateClaims(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	at := func(offset int64) float64 { return float64(now.Unix() + offset) }
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		expect Expect
+		want   error
+	}{
+		{"valid", map[string]any{"exp": at(60), "nbf": at(-60)}, Expect{}, nil},
+		{"expired", map[string]any{"exp": at(-1)}, Expect{}, ErrExpired},
+		{"not yet valid", map[string]any{"nbf": at(30)}, Expect{}, ErrNotYetValid},
+		{"issuer match", map[string]any{"iss": "https://id.example.com"}, Expect{Issuer: "https://id.example.com"}, nil},
+		{"wrong issuer", map[string]any{"iss": "https://evil.example.com"}, Expect{Issuer: "https://id.example.com"}, ErrWrongIssuer},
+		{"missing issuer", map[string]any{}, Expect{Issuer: "https://id.example.com"}, ErrWrongIssuer},
+		{"audience string", map[string]any{"aud": "api"}, Expect{Audience: "api"}, nil},
+		{"audience array", map[string]any{"aud": []any{"web", "api"}}, Expect{Audience: "api"}, nil},
+		{"wrong audience", map[string]any{"aud": []any{"web"}}, Expect{Audience: "api"}, ErrWrongAudience},
+		{"audience unchecked", map[string]any{"aud": "web"}, Expect{}, nil},
+	}
+	for _, tt := range tests {
+		if err := ValidateClaims(tt.claims, tt.expect, now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestDecode reads claims without a key and rejects malformed tokens.
+func TestDecode(t *testing.T) {
+	token, err := Sign(map[string]any{"sub": "u1", "exp": float64(1)}, "HS256", Keys{Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, claims, err := Decode(token)
+	if err != nil || header["alg"] != "HS256" || claims["sub"] != "u1" {
+		t.Errorf("Decode = %v, %v, %v", header, claims, err)
+	}
+
+	for _, bad := range []string{"", "a.b", "a.b.c.d", "!!.e30.x", "e30.!!.x", "bm90IGpzb24.e30.x"} {
+		if _, _, err := Decode(bad); err == nil {
+			t.Errorf("Decode(%q) should fail", bad)
+		}
+	}
+}
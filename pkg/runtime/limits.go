@@ -0,0 +1,38 @@
+package runtime
+
+// Element limit helpers
+//
+// Builtins that return a new array or object charge its elements against the
+// evaluator's element limit (see script.Evaluator.SetElementLimit), so a
+// script can't get around the limit by having a builtin allocate for it.
+// Builtins that build a flat result call evaluator.ChargeElements with its
+// length before allocating; these cover copies and nested results.
+
+// chargeCopy charges the elements a copy of v will hold: every nested one
+// for a deep copy, the top level for a shallow one
+func chargeCopy(evaluator *Evaluator, v Value, deep bool) error {
+	if deep {
+		return evaluator.ChargeValue(v)
+	}
+	switch v.Type {
+	case VAL_ARRAY:
+		return evaluator.ChargeElements(len(v.AsArray()))
+	case VAL_OBJECT:
+		return evaluator.ChargeElements(len(v.AsObject()))
+	}
+	return nil
+}
+
+// chargedValue charges every element of a freshly built result (parsed
+// JSON, XML, ...). Without a limit the result is returned as is; with one it
+// is converted to a Value for counting, and that Value is returned.
+func chargedValue(evaluator *Evaluator, result any) (any, error) {
+	if evaluator == nil || evaluator.ElementLimit() <= 0 {
+		return result, nil
+	}
+	v := InterfaceToValue(result)
+	if err := evaluator.ChargeValue(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -12,6 +12,8 @@
 // There is no AST visitor; renderers walk Block / Inline structs directly.
 package markdown
 
+import "github.com/duso-org/duso/pkg/runtime/color"
+
 // Options controls HTML rendering. Defaults match the previous goldmark setup.
 type Options struct {
 	Tables        bool
@@ -21,6 +23,9 @@ type Options struct {
 	Smartquotes   bool
 	HeadingIDs    bool
 	CodeLanguage  bool
+	// Sanitize omits raw HTML and blanks javascript:/vbscript:/file:/data:
+	// link targets, so untrusted markdown can't inject script into a page.
+	Sanitize bool
 }
 
 // DefaultOptions returns the defaults used by markdown_html() in duso.
@@ -33,6 +38,7 @@ func DefaultOptions() Options {
 		Smartquotes:   true,
 		HeadingIDs:    true,
 		CodeLanguage:  true,
+		Sanitize:      true,
 	}
 }
 
@@ -56,23 +62,23 @@ type Theme struct {
 // DefaultTheme matches duso's default_ansi_theme().
 func DefaultTheme() *Theme {
 	return &Theme{
-		H1:         "\033[33m\033[1m\033[4m",
-		H2:         "\033[37m\033[1m\033[4m",
-		H3:         "\033[37m\033[4m",
-		H4:         "\033[37m\033[4m",
-		H5:         "\033[37m\033[4m",
-		H6:         "\033[37m\033[4m",
-		CodeBlock:  "\033[96m",
-		CodeInline: "\033[96m",
-		Blockquote: "\033[90m",
+		H1:         color.Yellow + color.Bold + color.Underline,
+		H2:         color.White + color.Bold + color.Underline,
+		H3:         color.White + color.Underline,
+		H4:         color.White + color.Underline,
+		H5:         color.White + color.Underline,
+		H6:         color.White + color.Underline,
+		CodeBlock:  color.BrightCyan,
+		CodeInline: color.BrightCyan,
+		Blockquote: color.Gray,
 		ListItem:   "",
-		Bold:       "\033[1m",
-		Italic:     "\033[3m",
-		Strike:     "\033[9m",
-		Highlight:  "\033[43m\033[30m",
-		Link:       "\033[93m\033[1m\033[4m",
-		HR:         "\033[90m",
-		Reset:      "\033[0m",
+		Bold:       color.Bold,
+		Italic:     color.Italic,
+		Strike:     color.Strike,
+		Highlight:  color.BgYellow + color.Black,
+		Link:       color.BrightYellow + color.Bold + color.Underline,
+		HR:         color.Gray,
+		Reset:      color.Reset,
 	}
 }
 
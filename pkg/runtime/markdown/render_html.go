@@ -100,6 +100,10 @@ func (r *htmlRenderer) renderBlock(b *Block) {
 		escapeHTML(&r.b, b.Text)
 		r.b.WriteString("</code></pre>\n")
 	case BlockHTMLBlock:
+		if r.opts.Sanitize {
+			r.b.WriteString(rawHTMLOmitted + "\n")
+			return
+		}
 		r.b.WriteString(b.Text)
 	case BlockThematicBreak:
 		r.b.WriteString("<hr />\n")
@@ -240,7 +244,7 @@ func (r *htmlRenderer) renderInline(n *Inline) {
 		r.b.WriteString("</code>")
 	case InlineLink:
 		r.b.WriteString(`<a href="`)
-		percentEncode(&r.b, n.URL)
+		r.writeURL(n.URL)
 		r.b.WriteByte('"')
 		if n.Title != "" {
 			r.b.WriteString(` title="`)
@@ -252,7 +256,7 @@ func (r *htmlRenderer) renderInline(n *Inline) {
 		r.b.WriteString("</a>")
 	case InlineImage:
 		r.b.WriteString(`<img src="`)
-		percentEncode(&r.b, n.URL)
+		r.writeURL(n.URL)
 		r.b.WriteString(`" alt="`)
 		var alt strings.Builder
 		collectAltText(&alt, n.Children)
@@ -266,15 +270,47 @@ func (r *htmlRenderer) renderInline(n *Inline) {
 		r.b.WriteString(" />")
 	case InlineAutolink:
 		r.b.WriteString(`<a href="`)
-		percentEncode(&r.b, n.URL)
+		r.writeURL(n.URL)
 		r.b.WriteString(`">`)
 		escapeHTML(&r.b, n.Text)
 		r.b.WriteString("</a>")
 	case InlineRawHTML:
+		if r.opts.Sanitize {
+			r.b.WriteString(rawHTMLOmitted)
+			return
+		}
 		r.b.WriteString(n.Text)
 	}
 }
 
+// rawHTMLOmitted replaces raw HTML when sanitizing (matches goldmark)
+const rawHTMLOmitted = "<!-- raw HTML omitted -->"
+
+// writeURL writes a link or image target, blanking dangerous ones when
+// sanitizing
+func (r *htmlRenderer) writeURL(url string) {
+	if r.opts.Sanitize && isDangerousURL(url) {
+		return
+	}
+	percentEncode(&r.b, url)
+}
+
+// isDangerousURL reports whether url uses a scheme that can run script.
+// data: URLs are allowed only for common image types.
+func isDangerousURL(url string) bool {
+	u := strings.ToLower(strings.TrimSpace(url))
+	if strings.HasPrefix(u, "data:") {
+		for _, t := range []string{"image/png", "image/gif", "image/jpeg", "image/webp"} {
+			if strings.HasPrefix(u[5:], t) {
+				return false
+			}
+		}
+		return true
+	}
+	return strings.HasPrefix(u, "javascript:") || strings.HasPrefix(u, "vbscript:") ||
+		strings.HasPrefix(u, "file:")
+}
+
 func collectAltText(b *strings.Builder, nodes []*Inline) {
 	for _, n := range nodes {
 		switch n.Kind {
@@ -0,0 +1,56 @@
+package markdown
+
+import "testing"
+
+// TestSanitize checks that raw HTML and script URLs are dropped when
+// Options.Sanitize is set (the markdown_html() default) and kept otherwise.
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		safe string
+		raw  string
+	}{
+		{
+			name: "html block",
+			src:  "<script>alert(1)</script>\n",
+			safe: "<!-- raw HTML omitted -->\n",
+			raw:  "<script>alert(1)</script>\n",
+		},
+		{
+			name: "inline html",
+			src:  "hi <img src=x onerror=alert(1)> there",
+			safe: "<p>hi <!-- raw HTML omitted --> there</p>\n",
+			raw:  "<p>hi <img src=x onerror=alert(1)> there</p>\n",
+		},
+		{
+			name: "javascript link",
+			src:  "[click](javascript:alert(1))",
+			safe: "<p><a href=\"\">click</a></p>\n",
+			raw:  "<p><a href=\"javascript:alert(1)\">click</a></p>\n",
+		},
+		{
+			name: "data image is allowed",
+			src:  "![dot](data:image/png;base64,AAAA)",
+			safe: "<p><img src=\"data:image/png;base64,AAAA\" alt=\"dot\" /></p>\n",
+			raw:  "<p><img src=\"data:image/png;base64,AAAA\" alt=\"dot\" /></p>\n",
+		},
+		{
+			name: "ordinary link",
+			src:  "[docs](/docs/index.md)",
+			safe: "<p><a href=\"/docs/index.md\">docs</a></p>\n",
+			raw:  "<p><a href=\"/docs/index.md\">docs</a></p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.src, Options{Sanitize: true}); got != tt.safe {
+				t.Errorf("sanitized:\n got %q\nwant %q", got, tt.safe)
+			}
+			if got := ToHTML(tt.src, Options{}); got != tt.raw {
+				t.Errorf("unsanitized:\n got %q\nwant %q", got, tt.raw)
+			}
+		})
+	}
+}
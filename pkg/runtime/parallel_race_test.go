@@ -1,8 +1,11 @@
 package runtime
 
 import (
+	"context"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/duso-org/duso/pkg/script"
 )
@@ -58,3 +61,193 @@ end
 		t.Fatalf("parallel script failed: %v", err)
 	}
 }
+
+// TestParallelInheritsStepLimit checks that a step-limited script can't
+// escape its budget by running the work in a parallel() branch: the branch
+// fails, and since it spent the budget the script it belongs to fails too
+func TestParallelInheritsStepLimit(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+	interp := script.NewInterpreter()
+	interp.SetStepLimit(100000)
+
+	src := `
+results = parallel([
+  function()
+    while true do end
+  end
+])
+throw("the parent should have run out of steps, got " + type(results[0]))
+`
+	_, err := interp.Execute(src)
+	if err == nil || !strings.Contains(err.Error(), "step limit of 100000 exceeded") {
+		t.Fatalf("expected the step limit error, got %v", err)
+	}
+}
+
+// TestSpawnSharesStepBudget checks that a spawned script draws on its
+// parent's remaining step budget rather than getting a fresh one. The child
+// fits the limit on its own, but not in what's left after the parent's loop.
+func TestSpawnSharesStepBudget(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+	prev := globalInterpreter
+	defer SetInterpreter(prev)
+
+	// About 5 steps per iteration: 10000 for the child, 15000 for the parent
+	src := `
+store = datastore("spawn_budget_test")
+store.clear()
+child = parse("
+  store = datastore(\"spawn_budget_test\")
+  store.set(\"started\", true)
+  m = 0 while m < 2000 do m = m + 1 end
+  store.set(\"finished\", true)
+")
+if burn then
+  n = 0 while n < 3000 do n = n + 1 end
+end
+exit(spawn(child, io = {out = false, err = false, exit = false}))
+`
+	for _, burn := range []bool{false, true} {
+		interp := script.NewInterpreter()
+		SetInterpreter(interp)
+		interp.SetStepLimit(20000)
+		interp.GetEvaluator().GetEnv().Define("burn", script.NewBool(burn))
+
+		got, err := interp.ExecuteWithResult(src)
+		if err != nil || len(got) != 1 {
+			t.Fatalf("burn=%v: spawn failed: %v, %v", burn, got, err)
+		}
+		pid, _ := got[0].(float64)
+		waitForProc(t, int64(pid))
+
+		finished, err := interp.ExecuteWithResult(`exit(datastore("spawn_budget_test").get("finished") == true)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := !burn; len(finished) != 1 || finished[0] != want {
+			t.Errorf("burn=%v: child finished = %v, want %v", burn, finished, want)
+		}
+	}
+
+	// run() shares the budget the same way
+	interp := script.NewInterpreter()
+	SetInterpreter(interp)
+	interp.SetStepLimit(20000)
+	_, err := interp.Execute(`
+n = 0 while n < 3000 do n = n + 1 end
+run(parse("m = 0 while m < 2000 do m = m + 1 end"))
+done = true
+`)
+	if err == nil || !strings.Contains(err.Error(), "step limit of 20000 exceeded") {
+		t.Fatalf("run(): expected the step limit error, got %v", err)
+	}
+}
+
+// waitForProc waits for a spawned script to exit
+func waitForProc(t *testing.T, pid int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		procMutex.Lock()
+		_, running := spawnedProcs[pid]
+		procMutex.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("spawned process %d still running", pid)
+}
+
+// TestElementLimitBuiltins checks that builtins which add elements or return
+// new arrays and objects count against an interpreter's element limit,
+// including from a parallel() branch
+func TestElementLimitBuiltins(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	for _, src := range []string{
+		`a = [] while true do push(a, 1) end`,
+		`a = range(1, 1000000000)`,
+		`r = parallel([function() return range(1, 20000) end])
+		 throw(r[0])`,
+		`parts = split(repeat("x,", 3000000), ",")`,
+		`a = [1] for i = 1, 20 do a = deep_copy([a, a]) end`,
+		`a = range(1, 6000) b = map(a, function(x) return x end)`,
+		`o = {} for i = 1, 6000 do o["k" + i] = i end k = keys(o)`,
+		`a = parse_json("[" + repeat("[1, 2], ", 5000) + "1]")`,
+		`a = [1, 2, 3] while true do a = concat(a, a) end`,
+	} {
+		interp := script.NewInterpreter()
+		interp.SetElementLimit(10000)
+		_, err := interp.Execute(src)
+		if err == nil || !strings.Contains(err.Error(), "element limit of 10000 exceeded") {
+			t.Fatalf("%s: expected element limit error, got %v", src, err)
+		}
+	}
+
+	interp := script.NewInterpreter()
+	interp.SetElementLimit(10000)
+	if _, err := interp.Execute(`
+		parts = split("a,b,c", ",")
+		copy = deep_copy({list = parts, nested = [[1], [2]]})
+		doubled = map(range(1, 100), function(x) return x * 2 end)
+		data = parse_json("{\"a\": [1, 2, 3]}")
+	`); err != nil {
+		t.Fatalf("small builtin results should fit the limit: %v", err)
+	}
+}
+
+// TestExecuteContextCancelsBuiltins checks that cancellation reaches a
+// blocking sleep() and loops running in parallel() branches
+func TestExecuteContextCancelsBuiltins(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+
+	for _, src := range []string{
+		`sleep(10)`,
+		`r = parallel([function() while true do end end])
+		 throw(r[0])`,
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		_, err := script.NewInterpreter().ExecuteContext(ctx, src)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), "context deadline exceeded") {
+			t.Fatalf("%s: expected cancellation, got %v", src, err)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("%s: cancellation took %v", src, time.Since(start))
+		}
+	}
+}
+
+// TestCaptureOutput checks that print() goes to the interpreter's
+// OutputWriter, including from parallel() branches and ExecuteModule
+func TestCaptureOutput(t *testing.T) {
+	registerOnce.Do(RegisterBuiltins)
+	interp := script.NewInterpreter()
+
+	out, err := interp.CaptureOutput(func() error {
+		_, err := interp.Execute(`
+print("hello", 42)
+parallel([function() print("from branch") end])
+`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+	if out != "hello 42\nfrom branch\n" {
+		t.Errorf("captured %q", out)
+	}
+
+	out, _ = interp.CaptureOutput(func() error {
+		_, err := interp.ExecuteModule(`print("module") return 1`)
+		return err
+	})
+	if out != "module\n" {
+		t.Errorf("captured %q from ExecuteModule", out)
+	}
+	if interp.OutputWriter != nil {
+		t.Error("CaptureOutput should restore the previous OutputWriter")
+	}
+}
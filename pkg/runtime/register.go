@@ -35,6 +35,10 @@ func RegisterBuiltins() {
 
 	// Data operations
 	RegisterBuiltin("deep_copy", builtinDeepCopy)
+	RegisterBuiltin("shallow_copy", builtinShallowCopy)
+	RegisterBuiltin("deep_equal", builtinDeepEqual)
+	RegisterBuiltin("freeze", builtinFreeze)
+	RegisterBuiltin("is_frozen", builtinIsFrozen)
 
 	// JSON operations
 	RegisterBuiltin("parse_json", builtinParseJSON)
@@ -44,10 +48,18 @@ func RegisterBuiltins() {
 	RegisterBuiltin("parse_csv", builtinParseCSV)
 	RegisterBuiltin("format_csv", builtinFormatCSV)
 
+	// XML operations
+	RegisterBuiltin("parse_xml", builtinParseXML)
+	RegisterBuiltin("format_xml", builtinFormatXML)
+
 	// Base64 operations
 	RegisterBuiltin("encode_base64", builtinEncodeBase64)
 	RegisterBuiltin("decode_base64", builtinDecodeBase64)
 
+	// URL operations
+	RegisterBuiltin("parse_query", builtinParseQuery)
+	RegisterBuiltin("build_query", builtinBuildQuery)
+
 	// Hash operations
 	RegisterBuiltin("hash", builtinHash)
 
@@ -71,6 +83,11 @@ func RegisterBuiltins() {
 	// HMAC operations
 	RegisterBuiltin("hmac", builtinHMAC)
 
+	// JWT operations
+	RegisterBuiltin("sign_jwt", builtinSignJWT)
+	RegisterBuiltin("verify_jwt", builtinVerifyJWT)
+	RegisterBuiltin("decode_jwt", builtinDecodeJWT)
+
 	// WebSocket operations
 	RegisterBuiltin("websocket", builtinWebSocket)
 	RegisterBuiltin("send_websocket", builtinSendWebSocket)
@@ -83,6 +100,14 @@ func RegisterBuiltins() {
 	RegisterBuiltin("trim", builtinTrim)
 	RegisterBuiltin("pad_left", builtinPadLeft)
 	RegisterBuiltin("pad_right", builtinPadRight)
+	RegisterBuiltin("format_number", builtinFormatNumber)
+	RegisterBuiltin("to_fixed", builtinToFixed)
+	RegisterBuiltin("to_precision", builtinToPrecision)
+	RegisterBuiltin("wrap_text", builtinWrapText)
+	RegisterBuiltin("dedent", builtinDedent)
+	RegisterBuiltin("html_escape", builtinHTMLEscape)
+	RegisterBuiltin("html_unescape", builtinHTMLUnescape)
+	RegisterBuiltin("string_builder", builtinStringBuilder)
 
 	// Math operations - basic
 	RegisterBuiltin("floor", builtinFloor)
@@ -93,7 +118,16 @@ func RegisterBuiltins() {
 	RegisterBuiltin("max", builtinMax)
 	RegisterBuiltin("sqrt", builtinSqrt)
 	RegisterBuiltin("pow", builtinPow)
+	RegisterBuiltin("div", builtinDiv)
+	RegisterBuiltin("mod", builtinMod)
 	RegisterBuiltin("clamp", builtinClamp)
+	RegisterBuiltin("clamp01", builtinClamp01)
+	RegisterBuiltin("lerp", builtinLerp)
+	RegisterBuiltin("inv_lerp", builtinInvLerp)
+	RegisterBuiltin("remap", builtinRemap)
+	RegisterBuiltin("is_nan", builtinIsNaN)
+	RegisterBuiltin("is_finite", builtinIsFinite)
+	RegisterBuiltin("is_infinite", builtinIsInfinite)
 
 	// Math operations - trigonometric
 	RegisterBuiltin("sin", builtinSin)
@@ -103,6 +137,10 @@ func RegisterBuiltins() {
 	RegisterBuiltin("acos", builtinAcos)
 	RegisterBuiltin("atan", builtinAtan)
 	RegisterBuiltin("atan2", builtinAtan2)
+	RegisterBuiltin("to_radians", builtinToRadians)
+	RegisterBuiltin("to_degrees", builtinToDegrees)
+	RegisterBuiltin("sin_deg", builtinSinDeg)
+	RegisterBuiltin("cos_deg", builtinCosDeg)
 
 	// Math operations - exponential and logarithmic
 	RegisterBuiltin("exp", builtinExp)
@@ -111,6 +149,14 @@ func RegisterBuiltins() {
 	RegisterBuiltin("pi", builtinPi)
 	RegisterBuiltin("random", builtinRandom)
 
+	// Math operations - bitwise
+	RegisterBuiltin("bit_and", builtinBitAnd)
+	RegisterBuiltin("bit_or", builtinBitOr)
+	RegisterBuiltin("bit_xor", builtinBitXor)
+	RegisterBuiltin("bit_not", builtinBitNot)
+	RegisterBuiltin("shift_left", builtinShiftLeft)
+	RegisterBuiltin("shift_right", builtinShiftRight)
+
 	// Math operations - special functions
 	RegisterBuiltin("fibonacci", builtinFibonacci)
 
@@ -126,7 +172,11 @@ func RegisterBuiltins() {
 	RegisterBuiltin("type", builtinType)
 	RegisterBuiltin("tonumber", builtinToNumber)
 	RegisterBuiltin("tostring", builtinToString)
+	RegisterBuiltin("inspect", builtinInspect)
 	RegisterBuiltin("tobool", builtinToBool)
+	RegisterBuiltin("as_array", builtinAsArray)
+	RegisterBuiltin("as_object", builtinAsObject)
+	RegisterBuiltin("coerce", builtinCoerce)
 
 	// Code operations
 	RegisterBuiltin("parse", builtinParse)
@@ -134,19 +184,45 @@ func RegisterBuiltins() {
 	// Array/Object operations
 	RegisterBuiltin("keys", builtinKeys)
 	RegisterBuiltin("values", builtinValues)
+	RegisterBuiltin("ordered_object", builtinOrderedObject)
 	RegisterBuiltin("push", builtinPush)
 	RegisterBuiltin("pop", builtinPop)
 	RegisterBuiltin("shift", builtinShift)
 	RegisterBuiltin("unshift", builtinUnshift)
+	RegisterBuiltin("insert", builtinInsert)
+	RegisterBuiltin("remove_at", builtinRemoveAt)
+	RegisterBuiltin("splice", builtinSplice)
 	RegisterBuiltin("split", builtinSplit)
 	RegisterBuiltin("join", builtinJoin)
+	RegisterBuiltin("lines", builtinLines)
+	RegisterBuiltin("unlines", builtinUnlines)
 	RegisterBuiltin("range", builtinRange)
+	RegisterBuiltin("take", builtinTake)
+	RegisterBuiltin("drop", builtinDrop)
+	RegisterBuiltin("fill", builtinFill)
+	RegisterBuiltin("concat", builtinConcat)
+	RegisterBuiltin("window", builtinWindow)
+	RegisterBuiltin("set", builtinSet)
 
 	// Functional operations
 	RegisterBuiltin("map", builtinMap)
+	RegisterBuiltin("flat_map", builtinFlatMap)
+	RegisterBuiltin("map_values", builtinMapValues)
+	RegisterBuiltin("map_keys", builtinMapKeys)
 	RegisterBuiltin("filter", builtinFilter)
+	RegisterBuiltin("partition", builtinPartition)
+	RegisterBuiltin("filter_object", builtinFilterObject)
 	RegisterBuiltin("reduce", builtinReduce)
+	RegisterBuiltin("scan", builtinScan)
 	RegisterBuiltin("sort", builtinSort)
+	RegisterBuiltin("min_by", builtinMinBy)
+	RegisterBuiltin("max_by", builtinMaxBy)
+	RegisterBuiltin("any", builtinAny)
+	RegisterBuiltin("all", builtinAll)
+	RegisterBuiltin("take_while", builtinTakeWhile)
+	RegisterBuiltin("drop_while", builtinDropWhile)
+	RegisterBuiltin("debounce", builtinDebounce)
+	RegisterBuiltin("throttle", builtinThrottle)
 
 	// Regex operations
 	RegisterBuiltin("toregex", builtinToRegex)
@@ -158,16 +234,29 @@ func RegisterBuiltins() {
 
 	// Template operations
 	RegisterBuiltin("template", builtinTemplate)
+	RegisterBuiltin("register_template", builtinRegisterTemplate)
 
 	// Markdown operations
 	RegisterBuiltin("markdown_html", builtinMarkdownHTML)
 	RegisterBuiltin("markdown_ansi", builtinMarkdownANSI)
 	RegisterBuiltin("markdown_text", builtinMarkdownText)
 
+	// Terminal output operations
+	RegisterBuiltin("colorize", builtinColorize)
+	RegisterBuiltin("strip_ansi", builtinStripANSI)
+	RegisterBuiltin("table", builtinTable)
+
 	// System operations
 	RegisterBuiltin("exit", builtinExit)
 	RegisterBuiltin("sleep", builtinSleep)
+	RegisterBuiltin("sleep_ms", builtinSleepMs)
 	RegisterBuiltin("uuid", builtinUUID)
+	RegisterBuiltin("short_id", builtinShortID)
+
+	// Scheduling operations
+	RegisterBuiltin("set_interval", builtinSetInterval)
+	RegisterBuiltin("set_timeout", builtinSetTimeout)
+	RegisterBuiltin("cron", builtinCron)
 
 	// HTTP operations
 	RegisterBuiltin("fetch", builtinFetch)
@@ -54,6 +54,9 @@ var (
 var (
 	IsInteger = core.IsInteger
 	DeepCopyAny = script.DeepCopyAny
+	Freeze = script.Freeze
+	IsFrozen = script.IsFrozen
+	IsFrozenArray = script.IsFrozenArray
 )
 
 // Exception types
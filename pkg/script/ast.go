@@ -19,6 +19,11 @@
 // - Support for all language constructs
 package script
 
+import (
+	"regexp"
+	"sync/atomic"
+)
+
 // Node is the interface that all AST nodes must implement
 type Node interface {
 	node()
@@ -73,8 +78,9 @@ func (s *ForStatement) node() {}
 
 // Parameter represents a function parameter with optional default value
 type Parameter struct {
-	Name    string // Parameter name
-	Default Node   // Default value expression (nil if no default)
+	Name     string // Parameter name
+	Default  Node   // Default value expression (nil if no default)
+	Variadic bool   // true for a "...rest" parameter (always last, collects remaining positional args)
 }
 
 type FunctionDef struct {
@@ -120,6 +126,7 @@ type AssignStatement struct {
 	Target           Node // Can be Identifier, IndexExpr, or PropertyAccess
 	Value            Node
 	IsVarDeclaration bool // true if "var x = ..." syntax
+	IsConst          bool // true if "const x = ..." syntax (also sets IsVarDeclaration)
 }
 
 func (s *AssignStatement) node() {}
@@ -170,21 +177,27 @@ type UnaryExpr struct {
 func (e *UnaryExpr) node() {}
 
 type CallExpr struct {
-	Pos        Position
-	Func       Node
-	Arguments  []Node
-	NamedArgs  map[string]Node // For function(name = value) style calls
-	cachedFunc Value            // Lazy-loaded function reference (avoid repeated lookups)
-	cached     bool             // Flag indicating cachedFunc is valid
-	cachedFast GoFunctionFast   // Fast-path builtin, set when the name resolves to the registry (not an env shadow)
+	Pos       Position
+	Func      Node
+	Arguments []Node
+	NamedArgs map[string]Node              // For function(name = value) style calls
+	resolved  atomic.Pointer[resolvedCall] // Builtin the callee resolved to; set once, read by every evaluator sharing the AST
+}
+
+// resolvedCall is a CallExpr's cached builtin. It's never modified after
+// being stored, so concurrent executions of a cached script can share it.
+type resolvedCall struct {
+	fn   Value          // The GoFunction the name resolved to (avoids repeated lookups)
+	fast GoFunctionFast // Fast-path builtin, set when the name resolves to the registry (not an env shadow)
 }
 
 func (e *CallExpr) node() {}
 
 type IndexExpr struct {
-	Pos    Position
-	Object Node
-	Index  Node
+	Pos      Position
+	Object   Node
+	Index    Node
+	Optional bool // a?.[i]: ends the enclosing OptionalChain when Object is nil
 }
 
 func (e *IndexExpr) node() {}
@@ -193,14 +206,25 @@ type PropertyAccess struct {
 	Pos      Position
 	Object   Node
 	Property string
+	Optional bool // a?.b: ends the enclosing OptionalChain when Object is nil
 }
 
 func (e *PropertyAccess) node() {}
 
-type Identifier struct {
+// OptionalChain is a postfix chain with at least one ?. link, such as
+// a?.b.c(). If an optional link's object is nil, the whole chain is nil.
+type OptionalChain struct {
 	Pos  Position
-	Name string
-	slot uint8 // resolver-assigned param slot + 1; 0 = dynamic lookup (see resolver.go)
+	Expr Node
+}
+
+func (e *OptionalChain) node() {}
+
+type Identifier struct {
+	Pos   Position
+	Name  string
+	slot  uint8 // resolver-assigned param slot + 1; 0 = dynamic lookup (see resolver.go)
+	local uint8 // resolver-predicted local slot + 1, used only while the slot holds Name; 0 = none
 }
 
 func (e *Identifier) node() {}
@@ -220,7 +244,9 @@ type StringLiteral struct {
 func (l *StringLiteral) node() {}
 
 type RegexLiteral struct {
-	Pattern string
+	Pattern    string
+	compiled   *regexp.Regexp // Compiled by the parser, so each evaluation doesn't recompile
+	compileErr error          // Reported when the literal is evaluated, as before
 }
 
 func (l *RegexLiteral) node() {}
@@ -255,8 +281,15 @@ func (l *ObjectLiteral) node()    {}
 func (c *ComputedKeyPair) node() {}
 
 type TemplateLiteral struct {
-	Pos   Position
-	Parts []Node // Alternating TextPart and expression nodes
+	Pos          Position
+	Parts        []Node                 // Alternating TextPart and expression nodes
+	placeholders []*templatePlaceholder // Indexed like Parts, nil for text
+}
+
+// templatePlaceholder holds what evaluating a {{ }} needs besides its node
+type templatePlaceholder struct {
+	source string      // Text between the braces, written back when a variable is undefined
+	format *formatSpec // From {{expr:spec}}, or nil
 }
 
 type TextPart struct {
@@ -264,6 +297,7 @@ type TextPart struct {
 }
 
 type FunctionExpr struct {
+	Pos        Position
 	Parameters []*Parameter
 	Body       []Node
 	noCapture  bool // resolver-proven: body creates no closures, so call envs can be pooled (see resolver.go)
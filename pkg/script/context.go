@@ -1,5 +1,34 @@
 package script
 
+import "sync/atomic"
+
+// DefaultMaxCallDepth is the default limit on nested script function calls.
+// Every Duso call nests several Go frames, so unbounded recursion would hit
+// Go's stack limit and kill the whole process with a fatal stack overflow;
+// the limit turns it into an ordinary (catchable) Duso error well before that.
+const DefaultMaxCallDepth = 50000
+
+var maxCallDepth atomic.Int64
+
+func init() {
+	maxCallDepth.Store(DefaultMaxCallDepth)
+}
+
+// SetMaxCallDepth sets the process-wide limit on nested script function
+// calls. n <= 0 restores DefaultMaxCallDepth. Raising it well beyond the
+// default may also require a larger Go stack (runtime/debug.SetMaxStack).
+func SetMaxCallDepth(n int) {
+	if n <= 0 {
+		n = DefaultMaxCallDepth
+	}
+	maxCallDepth.Store(int64(n))
+}
+
+// MaxCallDepth returns the current limit on nested script function calls
+func MaxCallDepth() int {
+	return int(maxCallDepth.Load())
+}
+
 // CallFrame represents a function call in the execution stack
 type CallFrame struct {
 	FunctionName string
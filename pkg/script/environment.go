@@ -28,12 +28,15 @@
 // is only ever touched by one goroutine, with one exception: parallel() branches
 // read parent scopes concurrently. That is safe because parallel() blocks the
 // parent goroutine on wg.Wait() (no writer exists while readers run), and branch
-// writes stop at the branch's own function env via isParallelContext. Anything
-// that would share an env tree across goroutines in a new way must revisit this.
+// writes stop at the branch's own function env via isParallelContext. Functions
+// that run in the background while their caller keeps going (debounce() and the
+// like) don't share the tree at all: they get a Snapshot. Anything that would
+// share an env tree across goroutines in a new way must revisit this.
 package script
 
 import (
 	"fmt"
+	"maps"
 )
 
 // smallScopeSize is the number of variable slots stored inline in an Environment.
@@ -55,6 +58,7 @@ type Environment struct {
 	// instead of a map to save memory (~250 bytes per env) while keeping lookups fast
 	paramFlags uint64          // Bit flags for common parameter names (n, x, y, i, j, k, etc.)
 	parameters map[string]bool // Map for uncommon parameter names (lazy allocated)
+	constants  map[string]bool // Names declared with const in this scope (lazy allocated)
 
 	isParallelContext bool // If true, assignments don't walk up to parent scope (for parallel() blocks)
 }
@@ -198,33 +202,48 @@ func (e *Environment) Define(name string, value Value) {
 
 // Get retrieves a variable, walking up the parent chain if necessary
 func (e *Environment) Get(name string) (Value, error) {
-	// Check if accessing "self" directly
-	if name == "self" && !e.self.IsNil() {
-		return e.self, nil
-	}
-
-	if val, ok := e.lookupLocal(name); ok {
+	if val, ok := e.lookup(name); ok {
 		return val, nil
 	}
+	return NewNil(), fmt.Errorf("undefined variable: %s", name)
+}
 
-	// If self exists and is an object, check its properties
-	if !e.self.IsNil() && e.self.IsObject() {
-		objMap := e.self.AsObject()
-		if val, ok := objMap[name]; ok {
-			return val, nil
+// lookup is Get without building an error for a missing name, for callers
+// that fall back to the builtin registry
+func (e *Environment) lookup(name string) (Value, bool) {
+	for env := e; env != nil; env = env.parent {
+		// Check if accessing "self" directly
+		if name == "self" && !env.self.IsNil() {
+			return env.self, true
 		}
-	}
 
-	if e.parent != nil {
-		return e.parent.Get(name)
+		if val, ok := env.lookupLocal(name); ok {
+			return val, true
+		}
+
+		// If self exists and is an object, check its properties
+		if !env.self.IsNil() && env.self.IsObject() {
+			objMap := env.self.AsObject()
+			if val, ok := objMap[name]; ok {
+				return val, true
+			}
+		}
 	}
+	return Value{}, false
+}
 
-	return NewNil(), fmt.Errorf("undefined variable: %s", name)
+// has reports whether name is bound anywhere in the scope chain
+func (e *Environment) has(name string) bool {
+	_, ok := e.lookup(name)
+	return ok
 }
 
 // Set updates a variable, checking self properties first, then walking up the parent chain
 // Parallel context blocks assignment walk-up to parent: parent scope becomes read-only
 func (e *Environment) Set(name string, value Value) error {
+	if e.constants != nil && e.constants[name] {
+		return fmt.Errorf("cannot assign to constant '%s'", name)
+	}
 	if e.updateLocal(name, value) {
 		return nil
 	}
@@ -289,8 +308,93 @@ func (e *Environment) IsParameter(name string) bool {
 	return e.parameters[name]
 }
 
+// DefineConst creates a variable in the current scope that Set refuses to reassign.
+// Re-running the same declaration (e.g. in a loop body) simply rebinds it.
+func (e *Environment) DefineConst(name string, value Value) {
+	e.Define(name, value)
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[name] = true
+}
+
+// IsConst checks if a name was declared with const in this scope
+func (e *Environment) IsConst(name string) bool {
+	return e.constants != nil && e.constants[name]
+}
+
 // SetParallelContext marks this environment as part of a parallel() block
 // When true, assignments don't walk up to parent scope (parent scope is read-only)
 func (e *Environment) SetParallelContext(isParallel bool) {
 	e.isParallelContext = isParallel
 }
+
+// Snapshot copies this scope chain so another goroutine can read it while
+// the owner keeps running. Variables are copied, so later assignments on
+// either side aren't seen by the other; arrays and objects are still shared.
+// Script functions held in the copied scopes are re-pointed at the copies,
+// so calling a helper defined alongside doesn't reach the live scopes either.
+func (e *Environment) Snapshot() *Environment {
+	top, _ := snapshotScopes(e)
+	return top
+}
+
+// SnapshotFunction returns fn closing over a Snapshot of its scopes, ready to
+// be called from another goroutine. Go functions are returned as-is.
+func SnapshotFunction(fn Value) Value {
+	scriptFn, ok := fn.Data.(*ScriptFunction)
+	if !ok || scriptFn.Closure == nil {
+		return fn
+	}
+	_, copies := snapshotScopes(scriptFn.Closure)
+	return repointClosure(fn, copies)
+}
+
+// snapshotScopes copies e and its ancestors, returning the copy of e and
+// the original-to-copy mapping
+func snapshotScopes(e *Environment) (*Environment, map[*Environment]*Environment) {
+	copies := make(map[*Environment]*Environment)
+	top := e.snapshot(copies)
+	for _, c := range copies {
+		for i := 0; i < c.n; i++ {
+			c.vals[i] = repointClosure(c.vals[i], copies)
+		}
+		for name, v := range c.overflow {
+			c.overflow[name] = repointClosure(v, copies)
+		}
+	}
+	return top, copies
+}
+
+func (e *Environment) snapshot(copies map[*Environment]*Environment) *Environment {
+	if e == nil {
+		return nil
+	}
+	if c, ok := copies[e]; ok {
+		return c
+	}
+	c := *e
+	copies[e] = &c
+	c.overflow = maps.Clone(e.overflow)
+	c.parameters = maps.Clone(e.parameters)
+	c.constants = maps.Clone(e.constants)
+	c.parent = e.parent.snapshot(copies)
+	c.fnScope = e.fnScope.snapshot(copies) // itself or an ancestor, already copied
+	return &c
+}
+
+// repointClosure returns a script function closing over a copied scope as a
+// copy closing over the copy; other values are returned as-is
+func repointClosure(v Value, copies map[*Environment]*Environment) Value {
+	fn, ok := v.Data.(*ScriptFunction)
+	if !ok {
+		return v
+	}
+	closure, ok := copies[fn.Closure]
+	if !ok {
+		return v
+	}
+	copied := *fn
+	copied.Closure = closure
+	return Value{Type: v.Type, Data: &copied}
+}
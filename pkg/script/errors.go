@@ -44,28 +44,45 @@ func (e *DusoError) Error() string {
 	// Add call stack if present
 	if len(e.CallStack) > 0 {
 		buf.WriteString("\n\nCall stack:")
-		// Print in reverse order (most recent call last)
-		for i := len(e.CallStack) - 1; i >= 0; i-- {
-			frame := e.CallStack[i]
-			buf.WriteString("\n  at ")
-			buf.WriteString(frame.FunctionName)
-			buf.WriteString(" (")
-			if frame.FilePath != "" {
-				buf.WriteString(frame.FilePath)
-				buf.WriteByte(':')
-			}
-			buf.WriteString(strconv.Itoa(frame.Position.Line))
-			if frame.Position.Column > 0 {
-				buf.WriteByte(':')
-				buf.WriteString(strconv.Itoa(frame.Position.Column))
-			}
-			buf.WriteByte(')')
-		}
+		writeCallStack(&buf, e.CallStack)
 	}
 
 	return buf.String()
 }
 
+// maxPrintedFrames caps how many call frames an error message lists; runaway
+// recursion can leave tens of thousands of identical frames on the stack
+const maxPrintedFrames = 40
+
+// writeCallStack writes one "at fn (file:line:col)" line per frame, most
+// recent call first. Long stacks keep both ends and elide the middle.
+func writeCallStack(buf *strings.Builder, stack []CallFrame) {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if len(stack) > maxPrintedFrames && i == len(stack)-maxPrintedFrames/2 {
+			skipped := len(stack) - maxPrintedFrames
+			buf.WriteString("\n  ... ")
+			buf.WriteString(strconv.Itoa(skipped))
+			buf.WriteString(" more frames ...")
+			i -= skipped - 1
+			continue
+		}
+		frame := stack[i]
+		buf.WriteString("\n  at ")
+		buf.WriteString(frame.FunctionName)
+		buf.WriteString(" (")
+		if frame.FilePath != "" {
+			buf.WriteString(frame.FilePath)
+			buf.WriteByte(':')
+		}
+		buf.WriteString(strconv.Itoa(frame.Position.Line))
+		if frame.Position.Column > 0 {
+			buf.WriteByte(':')
+			buf.WriteString(strconv.Itoa(frame.Position.Column))
+		}
+		buf.WriteByte(')')
+	}
+}
+
 // ReturnValue is used to signal a return from a function
 type ReturnValue struct {
 	Value Value
@@ -78,10 +95,18 @@ func (e *ReturnValue) Error() string {
 // Shared control-flow signals: these carry no data, so the evaluator reuses
 // singletons instead of allocating one per break/continue
 var (
-	errBreak    = &BreakIteration{}
-	errContinue = &ContinueIteration{}
+	errBreak       = &BreakIteration{}
+	errContinue    = &ContinueIteration{}
+	errOptionalNil = &optionalNil{}
 )
 
+// optionalNil is used to end an OptionalChain at a nil ?. link
+type optionalNil struct{}
+
+func (e *optionalNil) Error() string {
+	return "optional chain reached nil"
+}
+
 // BreakIteration is used to signal a break from a loop (for future use)
 type BreakIteration struct{}
 
@@ -105,6 +130,22 @@ func (e *ExitExecution) Error() string {
 	return "exit"
 }
 
+// CancelledError signals that execution was stopped because the context
+// passed to ExecuteContext was cancelled or timed out. Like exit() it is not
+// catchable by try/catch. Unwrap exposes the context error, so callers can
+// test errors.Is(err, context.DeadlineExceeded).
+type CancelledError struct {
+	Err error
+}
+
+func (e *CancelledError) Error() string {
+	return "execution cancelled: " + e.Err.Error()
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
 // BreakpointError signals debug breakpoint hit and captures call stack for display
 type BreakpointError struct {
 	FilePath  string
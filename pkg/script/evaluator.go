@@ -20,7 +20,10 @@ package script
 // They are registered separately via pkg/cli or by embedded applications.
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"regexp"
 	"strconv"
 	"strings"
@@ -44,6 +47,47 @@ type Evaluator struct {
 	// Per-evaluator = single goroutine, so no locking. See resolver.go.
 	envPool  []*Environment
 	retScratch ReturnValue // reused for return unwinding (consumed synchronously, like errBreak/errContinue)
+
+	callDepth int   // nested script function calls, checked against maxCallDepth()
+	depthErr  error // the last call-depth overflow, passed through builtins unwrapped
+
+	// Sandboxing limits set by embedders (see limits.go). limited is the
+	// only thing Eval's hot path reads while no limit or trace is configured.
+	limited      bool
+	stepLimit    int64   // max Eval steps; 0 = unlimited
+	elementLimit int64   // max array/object elements added; 0 = unlimited
+	usage        *budget // steps and elements counted against the limits
+	maxDepth     int     // per-evaluator call depth limit; 0 = process-wide MaxCallDepth
+
+	cancelCtx context.Context // from ExecuteContext; nil when not cancellable
+	cancelErr error           // set once cancellation is seen (sticky)
+	ticks     uint32          // Eval count between cancellation checks
+
+	// Execution trace (see trace.go); nil when not tracing
+	trace     io.Writer
+	traceFile string // file and line last logged
+	traceLine int
+
+	// Call profile (see profile.go); nil when not profiling
+	profile       *Profile
+	profileStack  []profileFrame
+	profileActive map[string]int // calls in progress per name, for recursion
+}
+
+// enterCall counts a nested script function call, failing once the call
+// depth limit is reached. Every successful enterCall is paired with exitCall.
+func (e *Evaluator) enterCall(callPos Position) error {
+	if limit := e.maxCallDepth(); e.callDepth >= limit {
+		e.depthErr = e.newError(fmt.Sprintf("maximum call depth of %d exceeded (infinite recursion?)", limit), callPos)
+		return e.depthErr
+	}
+	e.callDepth++
+	return nil
+}
+
+// exitCall undoes enterCall
+func (e *Evaluator) exitCall() {
+	e.callDepth--
 }
 
 // getCallEnv returns a reset pooled environment (or a fresh one) set up as a
@@ -85,6 +129,26 @@ func (e *Evaluator) getLoopEnv(parent *Environment) *Environment {
 	return NewChildEnvironment(parent)
 }
 
+// slotRef returns the function-scope storage a resolver-annotated identifier
+// refers to, or nil if it has to be looked up by name. A parameter slot is
+// always bound; a local's predicted slot is used only once it holds the name
+// (the declaration may not have run yet, or another binding may sit there).
+func (e *Evaluator) slotRef(id *Identifier) *Value {
+	fs := e.env.fnScope
+	if fs == nil {
+		return nil
+	}
+	if id.slot != 0 {
+		return &fs.vals[id.slot-1]
+	}
+	if id.local != 0 {
+		if i := int(id.local - 1); i < fs.n && fs.names[i] == id.Name {
+			return &fs.vals[i]
+		}
+	}
+	return nil
+}
+
 // SetReqCtx attaches the per-execution request context to this evaluator.
 // Evaluators are per-execution (HTTP handler, spawn, run), so this is safe
 // to read without locking from builtins invoked during that execution.
@@ -149,6 +213,7 @@ func NewEvaluator() *Evaluator {
 		goObjects:   make(map[string]map[string]GoFunction),
 		ctx:         NewExecContext("<stdin>"),
 		watchCache:  make(map[string]Value),
+		usage:       &budget{},
 	}
 
 	return evaluator
@@ -247,6 +312,8 @@ func (e *Evaluator) wrapError(err error, node Node) error {
 		pos = n.Pos
 	case *PropertyAccess:
 		pos = n.Pos
+	case *OptionalChain:
+		pos = n.Pos
 	case *Identifier:
 		pos = n.Pos
 	case *TernaryExpr:
@@ -277,6 +344,14 @@ func (e *Evaluator) wrapError(err error, node Node) error {
 
 // Eval evaluates a node
 func (e *Evaluator) Eval(node Node) (Value, error) {
+	if e.limited {
+		if err := e.checkLimits(); err != nil {
+			return NewNil(), err
+		}
+		if e.trace != nil {
+			e.traceNode(node)
+		}
+	}
 	switch n := node.(type) {
 	case *Program:
 		return e.evalProgram(n)
@@ -314,18 +389,17 @@ func (e *Evaluator) Eval(node Node) (Value, error) {
 		return e.evalIndexExpr(n)
 	case *PropertyAccess:
 		return e.evalPropertyAccess(n)
+	case *OptionalChain:
+		return e.evalOptionalChain(n)
 	case *Identifier:
-		// Slot fast path: resolver-proven parameter reference — read the
-		// function scope's inline storage directly (see resolver.go)
-		if n.slot != 0 {
-			if fs := e.env.fnScope; fs != nil {
-				return fs.vals[n.slot-1], nil
-			}
+		// Slot fast path: resolver-proven parameter or local reference — read
+		// the function scope's inline storage directly (see resolver.go)
+		if ref := e.slotRef(n); ref != nil {
+			return *ref, nil
 		}
 
 		// Check environment first (user-defined variables and functions can shadow builtins)
-		val, err := e.env.Get(n.Name)
-		if err == nil {
+		if val, ok := e.env.lookup(n.Name); ok {
 			return val, nil
 		}
 
@@ -335,13 +409,18 @@ func (e *Evaluator) Eval(node Node) (Value, error) {
 		}
 
 		// Not found
-		return val, e.wrapError(err, n)
+		_, err := e.env.Get(n.Name)
+		return NewNil(), e.wrapError(err, n)
 	case *NumberLiteral:
 		return NewNumber(n.Value), nil
 	case *StringLiteral:
 		return NewString(n.Value), nil
 	case *RegexLiteral:
-		compiled, err := regexp.Compile(n.Pattern)
+		compiled, err := n.compiled, n.compileErr
+		if compiled == nil && err == nil {
+			// Built outside the parser
+			compiled, err = regexp.Compile(n.Pattern)
+		}
 		if err != nil {
 			return NewNil(), e.wrapError(fmt.Errorf("invalid regex pattern: %v", err), n)
 		}
@@ -534,15 +613,15 @@ func (e *Evaluator) evalForStatement(stmt *ForStatement) (Value, error) {
 			return NewNil(), e.newError("for loop step cannot be zero", stmt.Pos)
 		}
 
-		// Create child scope (pooled when the body provably creates no closures)
+		// Create child scope (pooled when the body provably creates no
+		// closures; otherwise a fresh one per iteration, see iterationEnv)
 		var loopEnv *Environment
 		if stmt.noCapture {
 			loopEnv = e.getLoopEnv(e.env)
-		} else {
-			loopEnv = NewChildEnvironment(e.env)
 		}
 
 		for i := start; (step > 0 && i <= end) || (step < 0 && i >= end); i += step {
+			loopEnv = e.iterationEnv(stmt, loopEnv)
 			loopEnv.Define(stmt.Var, NewNumber(float64(i)))
 
 			// Temporarily switch environment
@@ -582,13 +661,12 @@ func (e *Evaluator) evalForStatement(stmt *ForStatement) (Value, error) {
 		var loopEnv *Environment
 		if stmt.noCapture {
 			loopEnv = e.getLoopEnv(e.env)
-		} else {
-			loopEnv = NewChildEnvironment(e.env)
 		}
 
 		if iterVal.IsArray() {
 			arr := iterVal.AsArray()
 			for _, item := range arr {
+				loopEnv = e.iterationEnv(stmt, loopEnv)
 				loopEnv.Define(stmt.Var, item)
 
 				prevEnv := e.env
@@ -610,8 +688,17 @@ func (e *Evaluator) evalForStatement(stmt *ForStatement) (Value, error) {
 				result = val
 			}
 		} else if iterVal.IsObject() {
+			// Ordered objects iterate in insertion order; others as the map does
 			objMap := iterVal.AsObject()
-			for key := range objMap {
+			keys, ordered := OrderedKeys(objMap)
+			if !ordered {
+				keys = make([]string, 0, len(objMap))
+				for key := range objMap {
+					keys = append(keys, key)
+				}
+			}
+			for _, key := range keys {
+				loopEnv = e.iterationEnv(stmt, loopEnv)
 				loopEnv.Define(stmt.Var, NewString(key))
 
 				prevEnv := e.env
@@ -642,10 +729,23 @@ func (e *Evaluator) evalForStatement(stmt *ForStatement) (Value, error) {
 	return result, nil
 }
 
+// iterationEnv returns the scope for the next iteration of a for loop. A body
+// that creates closures gets a fresh scope every iteration, so each closure
+// captures its own loop variable (and body locals) rather than sharing one
+// binding that ends up holding the last value. Capture-free bodies keep
+// reusing the pooled env.
+func (e *Evaluator) iterationEnv(stmt *ForStatement, loopEnv *Environment) *Environment {
+	if stmt.noCapture {
+		return loopEnv
+	}
+	return NewChildEnvironment(e.env)
+}
+
 func (e *Evaluator) evalFunctionDef(stmt *FunctionDef) (Value, error) {
 	fn := &ScriptFunction{
 		Name:       stmt.Name,
 		FilePath:   e.ctx.FilePath,
+		Pos:        stmt.Pos,
 		Parameters: stmt.Parameters,
 		Body:       stmt.Body,
 		Closure:    e.env,
@@ -673,6 +773,9 @@ func (e *Evaluator) evalTryStatement(stmt *TryStatement) (Value, error) {
 		if _, ok := err.(*ContinueIteration); ok {
 			return NewNil(), err
 		}
+		if _, ok := err.(*CancelledError); ok {
+			return NewNil(), err
+		}
 
 		// Execute catch block
 		catchEnv := NewChildEnvironment(e.env)
@@ -730,18 +833,30 @@ func (e *Evaluator) evalAssignStatement(stmt *AssignStatement) (Value, error) {
 	switch target := stmt.Target.(type) {
 	case *Identifier:
 		if stmt.IsVarDeclaration {
+			keyword := "var"
+			if stmt.IsConst {
+				keyword = "const"
+			}
 			// var declaration: check if trying to shadow a function parameter
 			if e.env.IsParameter(target.Name) {
-				return NewNil(), e.newError(fmt.Sprintf("cannot use 'var' to declare function parameter '%s'; use '%s = value' instead", target.Name, target.Name), stmt.Pos)
+				return NewNil(), e.newError(fmt.Sprintf("cannot use '%s' to declare function parameter '%s'; use '%s = value' instead", keyword, target.Name, target.Name), stmt.Pos)
 			}
 			// var declaration: always create local variable
-			e.env.Define(target.Name, value)
-		} else if target.slot != 0 && e.env.fnScope != nil {
-			// Slot fast path: local param wins over self/parent for writes too
-			e.env.fnScope.vals[target.slot-1] = value
+			if stmt.IsConst {
+				e.env.DefineConst(target.Name, value)
+			} else if e.env.IsConst(target.Name) {
+				return NewNil(), e.newError(fmt.Sprintf("cannot redeclare constant '%s' with 'var'", target.Name), stmt.Pos)
+			} else {
+				e.env.Define(target.Name, value)
+			}
+		} else if ref := e.slotRef(target); ref != nil {
+			// Slot fast path: a local wins over self/parent for writes too
+			*ref = value
 		} else {
 			// regular assignment: reach through scope chain or create locally at boundary
-			e.env.Set(target.Name, value)
+			if err := e.env.Set(target.Name, value); err != nil {
+				return NewNil(), e.newError(err.Error(), stmt.Pos)
+			}
 		}
 		return value, nil
 	case *IndexExpr:
@@ -762,8 +877,8 @@ func (e *Evaluator) evalCompoundAssignStatement(stmt *CompoundAssignStatement) (
 
 	switch target := stmt.Target.(type) {
 	case *Identifier:
-		if target.slot != 0 && e.env.fnScope != nil {
-			currentVal = e.env.fnScope.vals[target.slot-1]
+		if ref := e.slotRef(target); ref != nil {
+			currentVal = *ref
 		} else {
 			currentVal, err = e.env.Get(target.Name)
 			if err != nil {
@@ -835,7 +950,7 @@ func (e *Evaluator) evalCompoundAssignStatement(stmt *CompoundAssignStatement) (
 		if !currentVal.IsNumber() || !rightVal.IsNumber() {
 			return NewNil(), e.newError("invalid operands for %=", stmt.Pos)
 		}
-		if rightVal.AsNumber() == 0 {
+		if int64(rightVal.AsNumber()) == 0 {
 			return NewNil(), e.newError("modulo by zero", stmt.Pos)
 		}
 		result = NewNumber(float64(int64(currentVal.AsNumber()) % int64(rightVal.AsNumber())))
@@ -846,11 +961,13 @@ func (e *Evaluator) evalCompoundAssignStatement(stmt *CompoundAssignStatement) (
 	// Assign the result, reusing the already-evaluated target components
 	switch target := stmt.Target.(type) {
 	case *Identifier:
-		if target.slot != 0 && e.env.fnScope != nil {
-			e.env.fnScope.vals[target.slot-1] = result
+		if ref := e.slotRef(target); ref != nil {
+			*ref = result
 			return result, nil
 		}
-		e.env.Set(target.Name, result)
+		if err := e.env.Set(target.Name, result); err != nil {
+			return NewNil(), e.newError(err.Error(), stmt.Pos)
+		}
 		return result, nil
 	case *IndexExpr:
 		return e.writeIndexed(idxObj, idxVal, result)
@@ -879,8 +996,8 @@ func (e *Evaluator) applyIncrement(targetNode Node, isIncrement bool) (Value, er
 
 	switch target := targetNode.(type) {
 	case *Identifier:
-		if target.slot != 0 && e.env.fnScope != nil {
-			currentVal = e.env.fnScope.vals[target.slot-1]
+		if ref := e.slotRef(target); ref != nil {
+			currentVal = *ref
 		} else {
 			currentVal, err = e.env.Get(target.Name)
 			if err != nil {
@@ -928,10 +1045,10 @@ func (e *Evaluator) applyIncrement(targetNode Node, isIncrement bool) (Value, er
 	// Assign the new value, reusing the already-evaluated target components
 	switch target := targetNode.(type) {
 	case *Identifier:
-		if target.slot != 0 && e.env.fnScope != nil {
-			e.env.fnScope.vals[target.slot-1] = newVal
-		} else {
-			e.env.Set(target.Name, newVal)
+		if ref := e.slotRef(target); ref != nil {
+			*ref = newVal
+		} else if err := e.env.Set(target.Name, newVal); err != nil {
+			return NewNil(), e.wrapError(err, target)
 		}
 	case *IndexExpr:
 		if _, err := e.writeIndexed(idxObj, idxVal, newVal); err != nil {
@@ -1000,7 +1117,8 @@ func (e *Evaluator) evalBinaryExpr(expr *BinaryExpr) (Value, error) {
 			}
 			return NewNumber(leftVal / rightVal), nil
 		case TOK_PERCENT:
-			if rightVal == 0 {
+			// % truncates both operands first, so 5 % 0.5 is also modulo by zero
+			if int64(rightVal) == 0 {
 				return NewNil(), e.newError("modulo by zero", expr.Pos)
 			}
 			return NewNumber(float64(int64(leftVal) % int64(rightVal))), nil
@@ -1046,10 +1164,10 @@ func (e *Evaluator) evalBinaryExpr(expr *BinaryExpr) (Value, error) {
 
 	case TOK_PERCENT:
 		if left.IsNumber() && right.IsNumber() {
-			if right.AsNumber() == 0 {
+			if int64(right.AsNumber()) == 0 {
 				return NewNil(), e.newError("modulo by zero", expr.Pos)
 			}
-			// Go's % operator for floats
+			// Truncate to integers, then Go's %: the sign follows the left operand
 			return NewNumber(float64(int64(left.AsNumber()) % int64(right.AsNumber()))), nil
 		}
 		return NewNil(), e.newError("cannot modulo non-numbers", expr.Pos)
@@ -1196,36 +1314,35 @@ func (e *Evaluator) evalCallExpr(expr *CallExpr) (Value, error) {
 		}
 	}
 
-	// Check cache first to avoid repeated lookups in tight loops (critical for push() in array building)
+	// Check cache first to avoid repeated lookups in tight loops (critical for push() in array building).
+	// The cache lives in the AST, which cached scripts share between concurrent
+	// executions (e.g. HTTP handlers), so it's published atomically and never modified.
 	var fn Value
+	var fast GoFunctionFast
 	var err error
-	if expr.cached {
-		fn = expr.cachedFunc
+	ident, isIdent := expr.Func.(*Identifier)
+	if resolved := expr.resolved.Load(); resolved != nil && !e.env.has(ident.Name) {
+		fn, fast = resolved.fn, resolved.fast
 	} else {
 		fn, err = e.Eval(expr.Func)
 		if err != nil {
 			return NewNil(), err
 		}
 
-		// Cache only builtins (GoFunctions), not user-defined functions (which may be parameters)
-		// This avoids caching parameter values across multiple calls to the same CallExpr
-		if ident, ok := expr.Func.(*Identifier); ok && fn.IsFunction() {
-			// Only cache if it's a GoFunction (builtin), not a ScriptFunction (user-defined)
+		// Cache only names that resolved through the builtin registry. A
+		// variable holding a GoFunction (a parameter, a loop variable, p =
+		// print) may hold a different builtin next time, and a name bound
+		// later shadows the builtin, so hits re-check the scope chain.
+		if isIdent && !e.env.has(ident.Name) {
 			if _, isGo := fn.Data.(GoFunction); isGo {
-				expr.cachedFunc = fn
-				expr.cached = true
-				// Enable the []Value fast path only when the name resolved through
-				// the builtin registry, not an env variable that holds a GoFunction
-				// (e.g. p = print) — those may point at a different builtin
-				if _, envErr := e.env.Get(ident.Name); envErr != nil {
-					expr.cachedFast = e.fastBuiltins[ident.Name]
-				}
+				fast = e.fastBuiltins[ident.Name]
+				expr.resolved.Store(&resolvedCall{fn: fn, fast: fast})
 			}
 		}
 	}
 
 	// Fast-path builtin call: positional args only, no map[string]any marshalling
-	if expr.cachedFast != nil && len(expr.NamedArgs) == 0 && !isMethodCall {
+	if fast != nil && len(expr.NamedArgs) == 0 && !isMethodCall {
 		fastArgs := make([]Value, len(expr.Arguments))
 		for i, argNode := range expr.Arguments {
 			val, err := e.Eval(argNode)
@@ -1234,7 +1351,13 @@ func (e *Evaluator) evalCallExpr(expr *CallExpr) (Value, error) {
 			}
 			fastArgs[i] = val
 		}
-		result, err := expr.cachedFast(e, fastArgs)
+		if e.profile != nil {
+			e.profileEnter(e.extractFunctionName(expr.Func))
+		}
+		result, err := fast(e, fastArgs)
+		if e.profile != nil {
+			e.profileExit()
+		}
 		if err != nil {
 			return NewNil(), e.wrapGoFunctionError(err, expr.Pos)
 		}
@@ -1243,20 +1366,20 @@ func (e *Evaluator) evalCallExpr(expr *CallExpr) (Value, error) {
 
 	// Handle callable objects
 	if fn.IsObject() {
-		return e.callObject(fn, expr.Arguments, expr.NamedArgs)
+		return e.callObject(fn, expr.Arguments, expr.NamedArgs, expr.Pos)
 	}
 
 	// Handle callable arrays
 	if fn.IsArray() {
 		if len(expr.NamedArgs) > 0 {
-			return NewNil(), fmt.Errorf("arrays can only be called with positional arguments")
+			return NewNil(), e.newError("arrays can only be called with positional arguments", expr.Pos)
 		}
 		return e.callArray(fn, expr.Arguments)
 	}
 
 	if !fn.IsFunction() {
 		fnName := e.extractFunctionName(expr.Func)
-		return NewNil(), fmt.Errorf("cannot call non-function: %s (got %s)", fnName, fn.Type.String())
+		return NewNil(), e.newError(fmt.Sprintf("cannot call non-function: %s (got %s)", fnName, fn.Type.String()), expr.Pos)
 	}
 
 	// Handle script functions
@@ -1266,13 +1389,18 @@ func (e *Evaluator) evalCallExpr(expr *CallExpr) (Value, error) {
 
 	// Handle Go functions
 	if goFn, ok := fn.Data.(GoFunction); ok {
-		return e.callGoFunction(goFn, expr.Arguments, expr.NamedArgs, expr.Pos)
+		return e.callGoFunction(goFn, expr)
 	}
 
-	return NewNil(), fmt.Errorf("invalid function type")
+	return NewNil(), e.newError("invalid function type", expr.Pos)
 }
 
-func (e *Evaluator) callScriptFunction(fn *ScriptFunction, args []Node, namedArgs map[string]Node, receiver Value, isMethodCall bool, callPos Position) (Value, error) {
+func (e *Evaluator) callScriptFunction(fn *ScriptFunction, args []Node, namedArgs map[string]Node, receiver Value, isMethodCall bool, callPos Position) (result Value, err error) {
+	if err := e.enterCall(callPos); err != nil {
+		return NewNil(), err
+	}
+	defer e.exitCall()
+
 	// Push call frame for stack trace using the function's defined file path
 	e.ctx.PushCall(fn.Name, fn.FilePath, callPos)
 	defer e.ctx.PopCall()
@@ -1309,7 +1437,7 @@ func (e *Evaluator) callScriptFunction(fn *ScriptFunction, args []Node, namedArg
 	fnEnv.paramFlags = fn.paramFlags
 	fnEnv.parameters = fn.paramMap
 
-	if len(namedArgs) == 0 && len(args) == len(fn.Parameters) && len(args) <= smallScopeSize {
+	if len(namedArgs) == 0 && len(args) == len(fn.Parameters) && len(args) <= smallScopeSize && !fn.variadic {
 		// Fast path: every parameter supplied positionally — fill the inline
 		// slots directly in declaration order (the invariant slot reads rely
 		// on) with no default evaluation or name-scan Defines
@@ -1323,54 +1451,72 @@ func (e *Evaluator) callScriptFunction(fn *ScriptFunction, args []Node, namedArg
 		}
 		fnEnv.n = len(args)
 	} else {
-		// Define all parameters; defaults are only evaluated for parameters
-		// no argument supplies (positional or named)
-		for i, param := range fn.Parameters {
-			supplied := i < len(args)
-			if !supplied && namedArgs != nil {
-				_, supplied = namedArgs[param.Name]
-			}
-			defaultVal := NewNil()
-			if !supplied && param.Default != nil {
-				// Evaluate default in the closure environment (not the function env)
-				prevEnv := e.env
-				e.env = fn.Closure
-				val, err := e.Eval(param.Default)
-				e.env = prevEnv
+		// Evaluate the supplied arguments in the caller's scope first; a rest
+		// parameter collects the positionals past the fixed parameters
+		supplied := make([]Value, len(fn.Parameters))
+		have := make([]bool, len(fn.Parameters))
+		var rest []Value
+		for i, argNode := range args {
+			if fn.variadic && i >= len(fn.Parameters)-1 {
+				val, err := e.Eval(argNode)
 				if err != nil {
 					return NewNil(), err
 				}
-				defaultVal = val
-			}
-			fnEnv.Define(param.Name, defaultVal)
-		}
-
-		// Evaluate positional arguments
-		for i, argNode := range args {
-			if i < len(fn.Parameters) {
+				rest = append(rest, val)
+			} else if i < len(fn.Parameters) {
 				val, err := e.Eval(argNode)
 				if err != nil {
 					return NewNil(), err
 				}
-				fnEnv.Define(fn.Parameters[i].Name, val)
+				supplied[i], have[i] = val, true
 			}
 		}
-
-		// Evaluate named arguments
+		if rest != nil {
+			last := len(fn.Parameters) - 1
+			supplied[last], have[last] = NewArray(rest), true
+		}
+		var extra map[string]Value
 		for name, argNode := range namedArgs {
 			val, err := e.Eval(argNode)
 			if err != nil {
 				return NewNil(), err
 			}
+			if idx := fn.paramIndex(name); idx >= 0 {
+				supplied[idx], have[idx] = val, true
+				continue
+			}
+			if extra == nil {
+				extra = make(map[string]Value)
+			}
+			extra[name] = val
+		}
+
+		// Bind parameters in declaration order (the invariant slot reads rely
+		// on). Defaults run in the function env, so they can refer to the
+		// parameters before them, and only when no argument was supplied.
+		if err := e.bindParams(fn, fnEnv, supplied, have); err != nil {
+			return NewNil(), err
+		}
+		// Named arguments that match no parameter become locals, after the
+		// parameters so their slots stay put
+		for name, val := range extra {
 			fnEnv.Define(name, val)
 		}
 	}
 
+	if e.trace != nil {
+		e.traceCall(fn, fnEnv)
+		defer func() { e.traceReturn(fn, result, err) }()
+	}
+	if e.profile != nil {
+		e.profileEnter(profileName(fn))
+		defer e.profileExit()
+	}
+
 	// Execute function body
 	prevEnv := e.env
 	e.env = fnEnv
 
-	var result Value
 	for i, stmt := range fn.Body {
 		// Detect bare identifiers used as statements (likely typos like "print x" instead of "print(x)")
 		// But allow them as the last statement (implicit return)
@@ -1436,12 +1582,39 @@ func (e *Evaluator) callScriptFunction(fn *ScriptFunction, args []Node, namedArg
 	return result, nil
 }
 
-func (e *Evaluator) callGoFunction(goFn GoFunction, args []Node, namedArgs map[string]Node, callPos Position) (Value, error) {
+// bindParams defines fn's parameters in fnEnv in declaration order. Supplied
+// values are bound as-is; the rest get their default (evaluated in fnEnv, so
+// earlier parameters are visible), an empty array for a rest parameter, or nil.
+func (e *Evaluator) bindParams(fn *ScriptFunction, fnEnv *Environment, supplied []Value, have []bool) error {
+	for i, param := range fn.Parameters {
+		if have[i] {
+			fnEnv.Define(param.Name, supplied[i])
+			continue
+		}
+		val := NewNil()
+		if param.Variadic {
+			val = NewArray([]Value{})
+		} else if param.Default != nil {
+			prevEnv := e.env
+			e.env = fnEnv
+			v, err := e.Eval(param.Default)
+			e.env = prevEnv
+			if err != nil {
+				return err
+			}
+			val = v
+		}
+		fnEnv.Define(param.Name, val)
+	}
+	return nil
+}
+
+func (e *Evaluator) callGoFunction(goFn GoFunction, expr *CallExpr) (Value, error) {
 	// Build argument map
 	argMap := make(map[string]any)
 
 	// Add positional arguments with numeric keys
-	for i, argNode := range args {
+	for i, argNode := range expr.Arguments {
 		val, err := e.Eval(argNode)
 		if err != nil {
 			return NewNil(), err
@@ -1450,7 +1623,7 @@ func (e *Evaluator) callGoFunction(goFn GoFunction, args []Node, namedArgs map[s
 	}
 
 	// Add named arguments
-	for name, argNode := range namedArgs {
+	for name, argNode := range expr.NamedArgs {
 		val, err := e.Eval(argNode)
 		if err != nil {
 			return NewNil(), err
@@ -1459,9 +1632,15 @@ func (e *Evaluator) callGoFunction(goFn GoFunction, args []Node, namedArgs map[s
 	}
 
 	// Call the function, passing evaluator as first parameter
+	if e.profile != nil {
+		e.profileEnter(e.extractFunctionName(expr.Func))
+	}
 	result, err := goFn(e, argMap)
+	if e.profile != nil {
+		e.profileExit()
+	}
 	if err != nil {
-		return NewNil(), e.wrapGoFunctionError(err, callPos)
+		return NewNil(), e.wrapGoFunctionError(err, expr.Pos)
 	}
 
 	// Convert result back to script value
@@ -1471,6 +1650,16 @@ func (e *Evaluator) callGoFunction(goFn GoFunction, args []Node, namedArgs map[s
 // wrapGoFunctionError adds position/file info to errors returned by builtins,
 // leaving control-flow errors (exit, return, breakpoint) untouched
 func (e *Evaluator) wrapGoFunctionError(err error, callPos Position) error {
+	// A call-depth overflow unwinds through every builtin callback level
+	// (map, filter, ...); pass it through unchanged rather than re-wrapping
+	// it, and re-copying the whole call stack, once per level
+	if e.depthErr != nil && errors.Is(err, e.depthErr) {
+		return e.depthErr
+	}
+	var cancelled *CancelledError
+	if errors.As(err, &cancelled) {
+		return cancelled
+	}
 	// Add position info to DusoError if not already present
 	if dusoErr, ok := err.(*DusoError); ok {
 		if dusoErr.Position == (Position{}) {
@@ -1494,22 +1683,26 @@ func (e *Evaluator) wrapGoFunctionError(err error, callPos Position) error {
 	}
 	// Wrap plain errors (from builtins) in a DusoError with position info
 	filePath := ""
+	var stack []CallFrame
 	if e.ctx != nil {
 		filePath = e.ctx.FilePath
+		stack = make([]CallFrame, len(e.ctx.CallStack))
+		copy(stack, e.ctx.CallStack)
 	}
 	return &DusoError{
-		Message:  err.Error(),
-		Position: callPos,
-		FilePath: filePath,
+		Message:   err.Error(),
+		Position:  callPos,
+		FilePath:  filePath,
+		CallStack: stack,
 	}
 }
 
-func (e *Evaluator) callObject(obj Value, args []Node, namedArgs map[string]Node) (Value, error) {
+func (e *Evaluator) callObject(obj Value, args []Node, namedArgs map[string]Node, callPos Position) (Value, error) {
 	// Objects are callable as constructors - they create a new object (copy) with optional overrides
 	// Objects can only be called with named arguments (named argument syntax)
 
 	if len(args) > 0 {
-		return NewNil(), fmt.Errorf("objects can only be called with named arguments")
+		return NewNil(), e.newError("objects can only be called with named arguments", callPos)
 	}
 
 	// Get the object map
@@ -1520,6 +1713,7 @@ func (e *Evaluator) callObject(obj Value, args []Node, namedArgs map[string]Node
 	for k, v := range objMap {
 		newObj[k] = v
 	}
+	CopyOrder(objMap, newObj)
 
 	// Create a temporary environment where named arguments can reference each other
 	// Start with current environment but add a child so we can track new definitions
@@ -1534,7 +1728,7 @@ func (e *Evaluator) callObject(obj Value, args []Node, namedArgs map[string]Node
 			e.env = prevEnv
 			return NewNil(), err
 		}
-		newObj[name] = val
+		setObjectKey(newObj, name, val)
 		// Add to environment so subsequent arguments can reference it
 		tmpEnv.Define(name, val)
 	}
@@ -1571,6 +1765,9 @@ func (e *Evaluator) evalIndexExpr(expr *IndexExpr) (Value, error) {
 	if err != nil {
 		return NewNil(), err
 	}
+	if expr.Optional && obj.IsNil() {
+		return NewNil(), errOptionalNil
+	}
 
 	index, err := e.Eval(expr.Index)
 	if err != nil {
@@ -1649,6 +1846,9 @@ func (e *Evaluator) writeIndexed(obj, index, value Value) (Value, error) {
 	if !obj.IsArray() && !obj.IsObject() {
 		return NewNil(), fmt.Errorf("cannot assign to index of %v", obj.Type)
 	}
+	if IsFrozen(obj) {
+		return NewNil(), fmt.Errorf("cannot modify frozen %v", obj.Type)
+	}
 
 	if obj.IsArray() {
 		if !index.IsNumber() {
@@ -1664,6 +1864,9 @@ func (e *Evaluator) writeIndexed(obj, index, value Value) (Value, error) {
 		}
 		// Grow array if necessary (sparse arrays)
 		if idx >= len(*arrPtr) {
+			if err := e.ChargeElements(idx + 1 - len(*arrPtr)); err != nil {
+				return NewNil(), err
+			}
 			// Append nils to reach the desired index
 			for len(*arrPtr) <= idx {
 				*arrPtr = append(*arrPtr, NewNil())
@@ -1676,7 +1879,10 @@ func (e *Evaluator) writeIndexed(obj, index, value Value) (Value, error) {
 	if obj.IsObject() {
 		key := index.String()
 		objMap := obj.AsObject()
-		objMap[key] = value
+		if err := e.chargeNewKey(objMap, key); err != nil {
+			return NewNil(), err
+		}
+		setObjectKey(objMap, key, value)
 		return value, nil
 	}
 
@@ -1688,10 +1894,23 @@ func (e *Evaluator) evalPropertyAccess(expr *PropertyAccess) (Value, error) {
 	if err != nil {
 		return NewNil(), err
 	}
+	if expr.Optional && obj.IsNil() {
+		return NewNil(), errOptionalNil
+	}
 
 	return e.readProperty(obj, expr.Property)
 }
 
+// evalOptionalChain evaluates a chain such as a?.b.c(), which is nil if a
+// ?. link is reached with a nil object. Links after it aren't evaluated.
+func (e *Evaluator) evalOptionalChain(expr *OptionalChain) (Value, error) {
+	val, err := e.Eval(expr.Expr)
+	if err == errOptionalNil {
+		return NewNil(), nil
+	}
+	return val, err
+}
+
 // readProperty reads obj.property from an already-evaluated object (see evalPropertyAccess)
 func (e *Evaluator) readProperty(obj Value, property string) (Value, error) {
 	// Handle code values
@@ -1762,9 +1981,15 @@ func (e *Evaluator) writeProperty(obj Value, property string, value Value) (Valu
 	if !obj.IsObject() {
 		return NewNil(), fmt.Errorf("cannot assign property of %v", obj.Type)
 	}
+	if IsFrozen(obj) {
+		return NewNil(), fmt.Errorf("cannot modify frozen %v", obj.Type)
+	}
 
 	objMap := obj.AsObject()
-	objMap[property] = value
+	if err := e.chargeNewKey(objMap, property); err != nil {
+		return NewNil(), err
+	}
+	setObjectKey(objMap, property, value)
 	return value, nil
 }
 
@@ -1777,6 +2002,9 @@ func (e *Evaluator) evalArrayLiteral(lit *ArrayLiteral) (Value, error) {
 		}
 		elements = append(elements, elem)
 	}
+	if err := e.ChargeElements(len(elements)); err != nil {
+		return NewNil(), err
+	}
 	return NewArray(elements), nil
 }
 
@@ -1805,21 +2033,34 @@ func (e *Evaluator) evalObjectLiteral(lit *ObjectLiteral) (Value, error) {
 		obj[keyVal.String()] = val
 	}
 
+	if err := e.ChargeElements(len(obj)); err != nil {
+		return NewNil(), err
+	}
 	return NewObject(obj), nil
 }
 
 func (e *Evaluator) evalTemplateLiteral(lit *TemplateLiteral) (Value, error) {
 	var result strings.Builder
-	for _, part := range lit.Parts {
+	for i, part := range lit.Parts {
 		if tp, ok := part.(*TextPart); ok {
 			result.WriteString(tp.Value)
 		} else {
+			var placeholder *templatePlaceholder
+			if i < len(lit.placeholders) {
+				placeholder = lit.placeholders[i]
+			}
+
 			// Evaluate the expression
 			val, err := e.Eval(part)
 			if err != nil {
-				// Check if this is an undefined variable error - if so, render as literal {{varname}}
+				// Check if this is an undefined variable error - if so, render the
+				// placeholder literally so template() can fill it in later
 				if dusoErr, ok := err.(*DusoError); ok {
 					if msg, ok := dusoErr.Message.(string); ok && strings.Contains(msg, "undefined variable:") {
+						if placeholder != nil {
+							result.WriteString("{{" + placeholder.source + "}}")
+							continue
+						}
 						// Extract variable name from error message
 						parts := strings.Split(msg, "undefined variable:")
 						if len(parts) == 2 {
@@ -1836,6 +2077,14 @@ func (e *Evaluator) evalTemplateLiteral(lit *TemplateLiteral) (Value, error) {
 				// For non-Duso errors, wrap with template position
 				return NewNil(), e.wrapError(err, lit)
 			}
+			if placeholder != nil && placeholder.format != nil {
+				s, err := placeholder.format.format(val)
+				if err != nil {
+					return NewNil(), e.wrapError(err, lit)
+				}
+				result.WriteString(s)
+				continue
+			}
 			// Convert to string using ValueForDisplay for proper formatting
 			result.WriteString(ValueForDisplay(val))
 		}
@@ -1873,6 +2122,8 @@ func (e *Evaluator) evalFunctionExpr(expr *FunctionExpr) (Value, error) {
 		Parameters: expr.Parameters,
 		Body:       expr.Body,
 		Closure:    e.env,
+		FilePath:   e.ctx.FilePath,
+		Pos:        expr.Pos,
 		poolable:   expr.noCapture,
 	}
 	fn.initParams()
@@ -1902,20 +2153,32 @@ func (e *Evaluator) valuesEqual(a, b Value) bool {
 
 // CallFunction calls a Duso function with the given arguments
 // This delegates to callScriptFunction or callGoFunction based on the function type
-func (e *Evaluator) CallFunction(fn Value, args map[string]Value) (Value, error) {
+func (e *Evaluator) CallFunction(fn Value, args map[string]Value) (result Value, err error) {
 	if !fn.IsFunction() {
 		return NewNil(), fmt.Errorf("cannot call non-function (got %s)", fn.Type.String())
 	}
 
 	// Handle script functions
 	if scriptFn, ok := fn.Data.(*ScriptFunction); ok {
+		if err := e.enterCall(NoPos); err != nil {
+			return NewNil(), err
+		}
+		defer e.exitCall()
+
+		// Report errors against the file the function was defined in
+		if scriptFn.FilePath != "" {
+			prevFilePath := e.ctx.FilePath
+			e.ctx.FilePath = scriptFn.FilePath
+			defer func() { e.ctx.FilePath = prevFilePath }()
+		}
+
 		// Convert map[string]Value to positional and named args
 		// The map format should have numeric keys for positional args
 		var positionalArgs []Value
 		namedArgs := make(map[string]Value)
 
-		// First, collect positional arguments
-		for i := 0; i < len(scriptFn.Parameters); i++ {
+		// First, collect positional arguments (all of them for a rest parameter)
+		for i := 0; i < len(scriptFn.Parameters) || scriptFn.variadic; i++ {
 			if val, exists := args[ArgKey(i)]; exists {
 				positionalArgs = append(positionalArgs, val)
 			} else {
@@ -1939,45 +2202,51 @@ func (e *Evaluator) CallFunction(fn Value, args map[string]Value) (Value, error)
 			fnEnv.SetParallelContext(true)
 		}
 
-		// Define all parameters; defaults only evaluated when no argument supplies the value
-		for i, param := range scriptFn.Parameters {
-			supplied := i < len(positionalArgs)
-			if !supplied {
-				_, supplied = namedArgs[param.Name]
+		// Match the supplied values to parameters; a rest parameter takes
+		// the positional remainder
+		supplied := make([]Value, len(scriptFn.Parameters))
+		have := make([]bool, len(scriptFn.Parameters))
+		for i, val := range positionalArgs {
+			if scriptFn.variadic && i >= len(scriptFn.Parameters)-1 {
+				restIdx := len(scriptFn.Parameters) - 1
+				supplied[restIdx] = NewArray(append([]Value(nil), positionalArgs[restIdx:]...))
+				have[restIdx] = true
+				break
 			}
-			defaultVal := NewNil()
-			if !supplied && param.Default != nil {
-				// Evaluate default in the closure environment
-				prevEnv := e.env
-				e.env = scriptFn.Closure
-				val, err := e.Eval(param.Default)
-				e.env = prevEnv
-				if err != nil {
-					return NewNil(), err
-				}
-				defaultVal = val
+			if i < len(scriptFn.Parameters) {
+				supplied[i], have[i] = val, true
 			}
-			fnEnv.Define(param.Name, defaultVal)
-			fnEnv.MarkParameter(param.Name)
 		}
-
-		// Apply positional arguments
-		for i, val := range positionalArgs {
-			if i < len(scriptFn.Parameters) {
-				fnEnv.Define(scriptFn.Parameters[i].Name, val)
+		for name, val := range namedArgs {
+			if idx := scriptFn.paramIndex(name); idx >= 0 {
+				supplied[idx], have[idx] = val, true
 			}
 		}
 
-		// Apply named arguments
+		if err := e.bindParams(scriptFn, fnEnv, supplied, have); err != nil {
+			return NewNil(), err
+		}
 		for name, val := range namedArgs {
-			fnEnv.Define(name, val)
+			if scriptFn.paramIndex(name) < 0 {
+				fnEnv.Define(name, val)
+			}
+		}
+		for _, param := range scriptFn.Parameters {
+			fnEnv.MarkParameter(param.Name)
+		}
+		if e.trace != nil {
+			e.traceCall(scriptFn, fnEnv)
+			defer func() { e.traceReturn(scriptFn, result, err) }()
+		}
+		if e.profile != nil {
+			e.profileEnter(profileName(scriptFn))
+			defer e.profileExit()
 		}
 
 		// Execute function body
 		prevEnv := e.env
 		e.env = fnEnv
 
-		var result Value
 		for _, stmt := range scriptFn.Body {
 			val, err := e.Eval(stmt)
 			if returnVal, ok := err.(*ReturnValue); ok {
@@ -2014,68 +2283,25 @@ func (e *Evaluator) CallFunction(fn Value, args map[string]Value) (Value, error)
 }
 
 // EvalTemplateLiteral evaluates a template string with embedded expressions
+// in the current scope, the same way a string literal in a script is
 func (e *Evaluator) EvalTemplateLiteral(template string) (string, error) {
-	// Parse the template string to extract text and expression parts
-	// Find all {{...}} patterns
-	var result strings.Builder
-	i := 0
-	for i < len(template) {
-		// Find next template expression
-		start := strings.Index(template[i:], "{{")
-		if start == -1 {
-			// No more expressions, append remaining text
-			result.WriteString(template[i:])
-			break
-		}
-
-		// Append text before expression
-		result.WriteString(template[i : i+start])
-
-		// Find closing }}
-		exprStart := i + start + 2
-		end := strings.Index(template[exprStart:], "}}")
-		if end == -1 {
-			// No closing }}, treat {{ as literal
-			result.WriteString("{{")
-			i = exprStart
-			continue
-		}
-
-		// Extract and evaluate expression
-		exprStr := template[exprStart : exprStart+end]
-
-		// Parse and evaluate the expression
-		lexer := NewLexer(exprStr)
-		tokens, err := lexer.Tokenize()
-		if err != nil {
-			return "", fmt.Errorf("template expression error: %w", err)
-		}
-		parser := NewParser(tokens)
-		exprNode, err := parser.parseExpression()
-		if err != nil {
-			return "", fmt.Errorf("template expression error: %w", err)
-		}
+	parser := &Parser{filePath: "<template>"}
+	node, err := parser.ParseTemplateString(template, NoPos)
+	if err != nil {
+		return "", fmt.Errorf("template expression error: %w", err)
+	}
 
-		val, err := e.Eval(exprNode)
+	switch n := node.(type) {
+	case *TemplateLiteral:
+		result, err := e.evalTemplateLiteral(n)
 		if err != nil {
-			// For undefined variables, render as literal {{varname}}
-			if dusoErr, ok := err.(*DusoError); ok {
-				if msg, ok := dusoErr.Message.(string); ok && strings.Contains(msg, "undefined variable:") {
-					result.WriteString("{{" + exprStr + "}}")
-				} else {
-					return "", err
-				}
-			} else {
-				return "", err
-			}
-		} else {
-			result.WriteString(val.String())
+			return "", err
 		}
-
-		i = exprStart + end + 2
+		return result.AsString(), nil
+	case *StringLiteral:
+		return n.Value, nil
 	}
-
-	return result.String(), nil
+	return "", nil
 }
 
 // GetEnvironment returns the current evaluation environment
@@ -2153,3 +2379,22 @@ func (e *Evaluator) EvaluateTemplate(templateStr string, bindings map[string]Val
 
 	return result.AsString(), nil
 }
+
+// EvaluateExpression evaluates a single Duso expression with the provided
+// variable bindings, like EvaluateTemplate does for {{ }} placeholders.
+func (e *Evaluator) EvaluateExpression(exprStr string, bindings map[string]Value) (Value, error) {
+	node, err := e.ParseExpression(exprStr)
+	if err != nil {
+		return NewNil(), err
+	}
+
+	exprEnv := NewEnvironment()
+	for key, val := range bindings {
+		exprEnv.Define(key, val)
+	}
+	prevEnv := e.env
+	e.env = exprEnv
+	defer func() { e.env = prevEnv }()
+
+	return e.Eval(node)
+}
@@ -1,7 +1,14 @@
 package script
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // TestClosureCapture tests variable scoping and closure capture
@@ -381,6 +388,82 @@ func TestForLoops(t *testing.T) {
 	}
 }
 
+// TestLoopClosureCapture tests that closures created in a loop body capture
+// that iteration's binding rather than the loop variable's final value
+func TestLoopClosureCapture(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name: "numeric for",
+			script: `
+				fs = {}
+				for i = 0, 2 do
+					fs[i] = function() return i * 10 end
+				end
+				return [fs[0](), fs[1](), fs[2]()]
+			`,
+			expected: "[0, 10, 20]",
+		},
+		{
+			name: "array iteration with body local",
+			script: `
+				fs = {}
+				n = 0
+				for item in ["a", "b", "c"] do
+					var tagged = item + "!"
+					fs[n] = function() return item + tagged end
+					n = n + 1
+				end
+				return [fs[0](), fs[1](), fs[2]()]
+			`,
+			expected: `["aa!", "bb!", "cc!"]`,
+		},
+		{
+			name: "object iteration",
+			script: `
+				fs = {}
+				for key in {x = 1, y = 2} do
+					fs[key] = function() return key end
+				end
+				return [fs.x(), fs.y()]
+			`,
+			expected: `["x", "y"]`,
+		},
+		{
+			name: "nested loops",
+			script: `
+				fs = {}
+				for i = 0, 1 do
+					for j = 0, 1 do
+						fs[i * 2 + j] = function() return [i, j] end
+					end
+				end
+				return [fs[0](), fs[1](), fs[2](), fs[3]()]
+			`,
+			expected: "[[0, 0], [0, 1], [1, 0], [1, 1]]",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewInterpreter().ExecuteModule(tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got.String())
+			}
+		})
+	}
+}
+
 // TestWhileLoops tests while loop control flow
 func TestWhileLoops(t *testing.T) {
 	t.Parallel()
@@ -1213,3 +1296,751 @@ func TestStringTemplates(t *testing.T) {
 		})
 	}
 }
+
+// TestStringInterpolation tests how {{ }} in string literals renders
+func TestStringInterpolation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"double quotes", `name = "Ann" return "hi {{name}}"`, "hi Ann"},
+		{"single quotes", `name = "Ann" return 'hi {{name}}'`, "hi Ann"},
+		{"raw opts out", `name = "Ann" return raw "hi {{name}}"`, "hi {{name}}"},
+		{"escaped braces", `name = "Ann" return "\{{name}} {{name}}"`, "{{name}} Ann"},
+		{"escaped backslash before braces", `name = "Ann" return "\\{{name}}"`, `\Ann`},
+		{"undefined variable kept verbatim", `return "{{upper(who)}} {{who.name}} {{n:03d}}"`, "{{upper(who)}} {{who.name}} {{n:03d}}"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewInterpreter().ExecuteModule(tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.AsString() != tt.want {
+				t.Errorf("got %q, want %q", got.AsString(), tt.want)
+			}
+		})
+	}
+}
+
+// TestStepLimit tests that a step limit stops runaway scripts
+func TestStepLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{name: "infinite while", script: `while true do end`},
+		{name: "caught and retried", script: `
+			while true do
+				try
+					x = 1
+				catch (e)
+				end
+			end
+		`},
+		{name: "recursion", script: `
+			function f(n) return f(n + 1) end
+			f(0)
+		`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			interp := NewInterpreter()
+			interp.SetStepLimit(10000)
+			interp.SetMaxCallDepth(1000000)
+			_, err := interp.Execute(tt.script)
+			if err == nil || !strings.Contains(err.Error(), "step limit of 10000 exceeded") {
+				t.Fatalf("expected step limit error, got %v", err)
+			}
+		})
+	}
+
+	// The budget applies to each Execute call, not the interpreter's lifetime
+	interp := NewInterpreter()
+	interp.SetStepLimit(1000)
+	for i := 0; i < 3; i++ {
+		if _, err := interp.Execute(`for i = 1, 50 do x = i end`); err != nil {
+			t.Fatalf("run %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+// TestInheritLimitsSharesBudget tests that evaluators created with
+// InheritLimits draw on their parent's budget instead of a fresh one
+func TestInheritLimitsSharesBudget(t *testing.T) {
+	t.Parallel()
+
+	parent := NewEvaluator()
+	parent.SetElementLimit(1000)
+	if err := parent.ChargeElements(600); err != nil {
+		t.Fatal(err)
+	}
+	child := NewEvaluator()
+	child.InheritLimits(parent)
+	if err := child.ChargeElements(500); err == nil {
+		t.Fatal("child should only have 400 elements left")
+	}
+
+	// Concurrent children sharing the budget can't overspend it together
+	var wg sync.WaitGroup
+	var charged atomic.Int64
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			branch := NewEvaluator()
+			branch.InheritLimits(child)
+			for i := 0; i < 100; i++ {
+				if branch.ChargeElements(1) == nil {
+					charged.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if charged.Load() != 400 {
+		t.Fatalf("branches charged %d elements, want the 400 left", charged.Load())
+	}
+	if err := parent.ChargeElements(1); err == nil {
+		t.Fatal("parent's budget should be spent")
+	}
+
+	// Steps taken by a child count against the parent's step limit
+	interp := NewInterpreter()
+	interp.SetStepLimit(100000)
+	stepChild := NewEvaluator()
+	stepChild.InheritLimits(interp.GetEvaluator())
+	tokens, err := NewLexer(`for i = 1, 100 do x = i end`).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stepChild.Eval(prog); err != nil {
+		t.Fatal(err)
+	}
+	if interp.GetEvaluator().Steps() == 0 || interp.GetEvaluator().Steps() != stepChild.Steps() {
+		t.Fatalf("parent counted %d steps, child %d", interp.GetEvaluator().Steps(), stepChild.Steps())
+	}
+}
+
+// TestEvaluatorMaxCallDepth tests the per-interpreter call depth limit
+func TestEvaluatorMaxCallDepth(t *testing.T) {
+	t.Parallel()
+
+	interp := NewInterpreter()
+	interp.SetMaxCallDepth(100)
+	_, err := interp.Execute(`
+		function f(n) if n == 0 then return 0 end return f(n - 1) end
+		f(99)
+		f(100)
+	`)
+	if err == nil || !strings.Contains(err.Error(), "maximum call depth of 100 exceeded") {
+		t.Fatalf("expected call depth error, got %v", err)
+	}
+}
+
+// TestElementLimit tests that an element limit stops scripts from growing containers without bound
+func TestElementLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		script      string
+		expectError bool
+	}{
+		{name: "sparse array growth", script: `a = [] a[100000000] = 1`, expectError: true},
+		{name: "object keys in a loop", script: `o = {} i = 0 while true do o[i] = i i = i + 1 end`, expectError: true},
+		{name: "literals in a loop", script: `while true do x = [1, 2, 3] end`, expectError: true},
+		{name: "within budget", script: `a = [1, 2, 3] a[5] = 6 o = {x = 1} o.y = 2 o.x = 3`, expectError: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			interp := NewInterpreter()
+			interp.SetElementLimit(1000)
+			_, err := interp.Execute(tt.script)
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "element limit of 1000 exceeded") {
+					t.Fatalf("expected element limit error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestExecuteContext tests cancelling a running script through its context
+func TestExecuteContext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{name: "tight loop", script: `while true do end`},
+		{name: "loop that catches errors", script: `
+			while true do
+				try
+					x = 1
+				catch (e)
+				end
+			end
+		`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, err := NewInterpreter().ExecuteContext(ctx, tt.script)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected deadline exceeded, got %v", err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewInterpreter().ExecuteContext(ctx, `x = 1`); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected already-cancelled context to fail, got %v", err)
+	}
+
+	// A finished run doesn't leave the context attached to the interpreter
+	interp := NewInterpreter()
+	ctx, cancel = context.WithCancel(context.Background())
+	if _, err := interp.ExecuteContext(ctx, `x = 1`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+	if _, err := interp.Execute(`for i = 1, 10000 do x = i end`); err != nil {
+		t.Fatalf("unexpected error after cancel: %v", err)
+	}
+}
+
+func TestRegisterModule(t *testing.T) {
+	t.Parallel()
+
+	interp := NewInterpreter()
+	err := interp.RegisterModule("db", map[string]GoFunction{
+		"query": func(evaluator *Evaluator, args map[string]any) (any, error) {
+			return "rows for " + args["0"].(string), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterModule: %v", err)
+	}
+
+	got, err := interp.ExecuteModule(`
+		db = require("db")
+		return db.query("users")
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.String() != "rows for users" {
+		t.Errorf("got %q, want %q", got.String(), "rows for users")
+	}
+
+	_, err = interp.Execute(`require("nope")`)
+	if err == nil || !strings.Contains(err.Error(), "cannot find module 'nope'") {
+		t.Errorf("expected missing module error, got %v", err)
+	}
+
+	if err := interp.RegisterModule("", nil); err == nil {
+		t.Error("expected error for empty module name")
+	}
+}
+
+func TestGoFunctionReturnsMethods(t *testing.T) {
+	t.Parallel()
+
+	// Functions returned inside a map use the same signature as
+	// RegisterFunction and become callable methods on the object
+	interp := NewInterpreter()
+	interp.RegisterFunction("counter", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		n := 0.0
+		var inc GoFunction = func(evaluator *Evaluator, args map[string]any) (any, error) {
+			n++
+			return n, nil
+		}
+		return map[string]any{
+			"inc": inc,
+			"get": func(evaluator *Evaluator, args map[string]any) (any, error) {
+				return n, nil
+			},
+		}, nil
+	})
+
+	got, err := interp.ExecuteModule(`
+		c = counter()
+		c.inc()
+		c.inc()
+		return c.get()
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.String() != "2" {
+		t.Errorf("got %s, want 2", got.String())
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	t.Parallel()
+
+	// The mtime never changes, so only an explicit invalidation reloads
+	sources := map[string]string{"/app/a.du": `return 1`, "/app/b.du": `return "b"`}
+	loads := map[string]int{}
+	interp := NewInterpreter()
+	interp.ScriptLoader = func(path string) ([]byte, error) {
+		loads[path]++
+		return []byte(sources[path]), nil
+	}
+	interp.FileStatter = func(path string) int64 { return 100 }
+	interp.CacheProgram("<inline-1>", &Program{})
+
+	parseAll := func() {
+		for path := range sources {
+			if _, err := interp.ParseScript(path); err != nil {
+				t.Fatalf("ParseScript(%s): %v", path, err)
+			}
+		}
+	}
+
+	parseAll()
+	parseAll()
+	if loads["/app/a.du"] != 1 || loads["/app/b.du"] != 1 {
+		t.Fatalf("expected cached parses, got loads %v", loads)
+	}
+
+	interp.InvalidateCache("/app/a.du")
+	parseAll()
+	if loads["/app/a.du"] != 2 || loads["/app/b.du"] != 1 {
+		t.Errorf("only a.du should reload, got loads %v", loads)
+	}
+
+	interp.InvalidateCache()
+	parseAll()
+	if loads["/app/a.du"] != 3 || loads["/app/b.du"] != 2 {
+		t.Errorf("everything should reload, got loads %v", loads)
+	}
+	if _, err := interp.ParseScript("<inline-1>"); err != nil {
+		t.Errorf("inline program should survive invalidation: %v", err)
+	}
+}
+
+// TestSharedProgram runs one parsed program on many goroutines at once, as
+// HTTP handlers do with the parse cache. Run with -race: the per-node caches
+// filled in during evaluation are shared by every execution.
+func TestSharedProgram(t *testing.T) {
+	t.Parallel()
+
+	interp := NewInterpreter()
+	interp.RegisterFunction("add", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		return args["0"].(float64) + args["1"].(float64), nil
+	})
+	interp.RegisterFunction("check", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		if args["0"] != float64(5050) {
+			return nil, fmt.Errorf("total is %v", args["0"])
+		}
+		return nil, nil
+	})
+	interp.ScriptLoader = func(path string) ([]byte, error) {
+		return []byte(`
+			pattern = ~[0-9]+~
+			total = 0
+			for i = 1, 100 do
+				total = add(total, i)
+			end
+			check(total)
+		`), nil
+	}
+	program, err := interp.ParseScript("/app/handler.du")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const runs = 8
+	results := make(chan *ScriptExecutionResult, runs)
+	for i := 0; i < runs; i++ {
+		go func() {
+			results <- ExecuteScript(program, interp, nil, nil, context.Background())
+		}()
+	}
+	for i := 0; i < runs; i++ {
+		if result := <-results; result.Error != nil {
+			t.Errorf("run failed: %v", result.Error)
+		}
+	}
+}
+
+// slotTestInterpreter has up() and low(), two builtins a call site can
+// resolve to, and check(got, want), which fails the script on a mismatch
+func slotTestInterpreter() *Interpreter {
+	interp := NewInterpreter()
+	interp.RegisterFunction("up", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		return strings.ToUpper(args["0"].(string)), nil
+	})
+	interp.RegisterFunction("low", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		return strings.ToLower(args["0"].(string)), nil
+	})
+	interp.RegisterFunction("check", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		if args["0"] != args["1"] {
+			return nil, fmt.Errorf("got %v, want %v", args["0"], args["1"])
+		}
+		return nil, nil
+	})
+	return interp
+}
+
+// TestCallSiteCache checks that a call site only keeps the builtin a name
+// resolved to through the registry, never one held by a variable
+func TestCallSiteCache(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "loop variable holding builtins",
+			script: `
+				got = ""
+				for f in [up, low] do
+					got = got + f("aB")
+				end
+				check(got, "ABab")
+			`,
+		},
+		{
+			name: "parameter holding builtins",
+			script: `
+				function apply(f, s)
+					return f(s)
+				end
+				check(apply(up, "aB"), "AB")
+				check(apply(low, "aB"), "ab")
+			`,
+		},
+		{
+			name: "builtin shadowed after the first call",
+			script: `
+				function twice(s)
+					return s + s
+				end
+				got = []
+				for i = 1, 2 do
+					got[i - 1] = up("aB")
+					up = twice
+				end
+				check(got[0], "AB")
+				check(got[1], "aBaB")
+			`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := slotTestInterpreter().Execute(tt.script); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestLocalSlots covers function locals read and written through their
+// predicted slots, and the cases where the slot can't be trusted
+func TestLocalSlots(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "locals read and written in loops",
+			script: `
+				function sum(n)
+					var total = 0
+					var count = 0
+					for i = 1, n do
+						total = total + i
+						count++
+					end
+					total += 0
+					return total + count
+				end
+				check(sum(10), 65)
+				check(sum(3), 9)
+			`,
+		},
+		{
+			name: "read before the declaration sees the global",
+			script: `
+				x = "global"
+				function f()
+					var before = x
+					var x = "local"
+					return before + " " + x
+				end
+				check(f(), "global local")
+				check(x, "global")
+			`,
+		},
+		{
+			name: "extra named arguments take the slot first",
+			script: `
+				function f(a)
+					var b = "local"
+					return b
+				end
+				check(f(a = 1, z = 2), "local")
+				check(f(1), "local")
+			`,
+		},
+		{
+			name: "local function definitions",
+			script: `
+				function outer(n)
+					function double(x)
+						return x * 2
+					end
+					return double(n)
+				end
+				check(outer(4), 8)
+			`,
+		},
+		{
+			name: "local also bound in a loop body",
+			script: `
+				function f()
+					var v = "outer"
+					for v in ["a", "b"] do
+					end
+					return v
+				end
+				check(f(), "outer")
+			`,
+		},
+		{
+			name: "const local stays read-only",
+			script: `
+				function f()
+					const limit = 3
+					limit = 4
+				end
+				f()
+			`,
+		},
+		{
+			name: "method locals win over self properties",
+			script: `
+				obj = {name = "self", get = function()
+					var name = "local"
+					return name
+				end}
+				check(obj.get(), "local")
+				check(obj.name, "self")
+			`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := slotTestInterpreter().Execute(tt.script)
+			if tt.name == "const local stays read-only" {
+				if err == nil || !strings.Contains(err.Error(), "cannot assign to constant 'limit'") {
+					t.Fatalf("expected constant error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestEnvironmentSnapshot checks that a snapshot and its original scopes don't
+// see each other's assignments, including through functions defined
+// alongside, while arrays stay shared.
+func TestEnvironmentSnapshot(t *testing.T) {
+	t.Parallel()
+
+	interp := NewInterpreter()
+	if _, err := interp.Execute(`
+		a = 1 b = 2 c = 3 d = 4 e = 5 f = 6 g = 7
+		items = [1]
+		function count() return g end
+		function scheduled() return count() end
+	`); err != nil {
+		t.Fatal(err)
+	}
+	eval := interp.GetEvaluator()
+	fn, _ := eval.GetEnv().Get("scheduled")
+	snapFn := SnapshotFunction(fn)
+	snapEnv := eval.GetEnv().Snapshot()
+
+	if _, err := interp.Execute(`g = 70 h = 8 items[0] = 9`); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewEvaluator().CallFunction(snapFn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AsNumber() != 7 {
+		t.Errorf("snapshot function saw g = %v, want 7", got.AsNumber())
+	}
+	if g, _ := snapEnv.Get("g"); g.AsNumber() != 7 {
+		t.Errorf("snapshot scope has g = %v, want 7", g.AsNumber())
+	}
+	if _, err := snapEnv.Get("h"); err == nil {
+		t.Error("snapshot scope sees a variable defined after it was taken")
+	}
+	if items, _ := snapEnv.Get("items"); items.AsArray()[0].AsNumber() != 9 {
+		t.Errorf("snapshot items = %v, want the shared array", items.AsArray())
+	}
+
+	if err := snapEnv.Set("a", NewNumber(100)); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := eval.GetEnv().Get("a"); a.AsNumber() != 1 {
+		t.Errorf("assignment in snapshot reached the original: a = %v", a.AsNumber())
+	}
+}
+
+// TestTrace checks the lines, calls and returns SetTrace logs
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	interp := NewInterpreter()
+	interp.SetFilePath("t.du")
+	interp.SetTrace(&out)
+	if _, err := interp.Execute("function add(a, b)\n  return a + b\nend\nx = add(1, \"2\")\ny = x\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"[trace] t.du:1",
+		"[trace] t.du:4",
+		"[trace]   call add(a = 1, b = \"2\")",
+		"[trace]   t.du:2",
+		"[trace]   return add: \"12\"",
+		"[trace] t.du:5",
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("trace:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	interp.SetTrace(nil)
+	out.Reset()
+	if _, err := interp.Execute("z = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("trace written after SetTrace(nil): %q", out.String())
+	}
+}
+
+// TestProfile checks call counts per function and that recursive calls
+// aren't counted twice in a function's total time
+func TestProfile(t *testing.T) {
+	t.Parallel()
+
+	profile := NewProfile()
+	interp := NewInterpreter()
+	interp.SetProfile(profile)
+	interp.RegisterFunction("tick", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		time.Sleep(time.Millisecond)
+		return nil, nil
+	})
+	if _, err := interp.Execute(`
+		function fib(n)
+			if n < 2 then return n end
+			return fib(n - 1) + fib(n - 2)
+		end
+		function step()
+			tick()
+			return fib(10)
+		end
+		step()
+		step()
+		apply = function(f) return f() end
+		apply(function() return 1 end)
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make(map[string]ProfileEntry)
+	for _, entry := range profile.Entries() {
+		entries[entry.Name] = entry
+	}
+	for name, calls := range map[string]int64{"step": 2, "tick": 2, "fib": 354, "function (<stdin>:12)": 1, "function (<stdin>:13)": 1} {
+		if entries[name].Calls != calls {
+			t.Errorf("%s: %d calls, want %d", name, entries[name].Calls, calls)
+		}
+	}
+	if fib := entries["fib"]; fib.Total != fib.Self {
+		t.Errorf("fib total %v != self %v; recursive calls counted twice", fib.Total, fib.Self)
+	}
+	if step := entries["step"]; step.Total < entries["fib"].Total+entries["tick"].Total || step.Self > step.Total {
+		t.Errorf("step times don't add up: %+v", step)
+	}
+	if entries["tick"].Total < 2*time.Millisecond {
+		t.Errorf("tick total %v, want at least 2ms", entries["tick"].Total)
+	}
+}
+
+// TestNestedExecuteKeepsRequestContext checks that an Execute or
+// ExecuteModule run from inside a Go function restores the caller's request
+// context instead of clearing it
+func TestNestedExecuteKeepsRequestContext(t *testing.T) {
+	outer := NewInterpreter()
+	var during, after *RequestContext
+	outer.RegisterFunction("nested", func(evaluator *Evaluator, args map[string]any) (any, error) {
+		gid := GetGoroutineID()
+		during, _ = GetRequestContext(gid)
+		if _, err := NewInterpreter().ExecuteModule(`return 1`); err != nil {
+			return nil, err
+		}
+		if _, err := NewInterpreter().Execute(`x = 1`); err != nil {
+			return nil, err
+		}
+		after, _ = GetRequestContext(gid)
+		return nil, nil
+	})
+	if _, err := outer.Execute(`nested()`); err != nil {
+		t.Fatal(err)
+	}
+	if during == nil || after != during {
+		t.Errorf("request context after nested runs = %p, want the caller's %p", after, during)
+	}
+	if _, ok := GetRequestContext(GetGoroutineID()); ok {
+		t.Error("the outermost Execute should leave no request context behind")
+	}
+}
@@ -47,6 +47,12 @@ func ExecuteScript(
 		for name, fn := range parentEval.GetGoFunctions() {
 			childEval.RegisterFunction(name, fn)
 		}
+		if childEval.Trace() == nil {
+			childEval.SetTrace(parentEval.Trace())
+		}
+		if childEval.Profile() == nil {
+			childEval.SetProfile(parentEval.Profile())
+		}
 	}
 
 	// Execute statements one-by-one so breakpoints can pause and resume mid-execution
@@ -228,6 +234,27 @@ func SetRequestContextWithData(gid uint64, ctx *RequestContext, spawnedData any)
 	requestContexts[gid] = ctx
 }
 
+// swapRequestContext installs ctx as the goroutine's request context and
+// returns a function that puts back the one it replaced, so an Execute or
+// ExecuteModule nested inside a running script or handler on the same
+// goroutine doesn't wipe its caller's context
+func swapRequestContext(gid uint64, ctx *RequestContext) (restore func()) {
+	contextMutex.Lock()
+	prev, had := requestContexts[gid]
+	requestContexts[gid] = ctx
+	contextMutex.Unlock()
+
+	return func() {
+		contextMutex.Lock()
+		defer contextMutex.Unlock()
+		if had {
+			requestContexts[gid] = prev
+		} else {
+			delete(requestContexts, gid)
+		}
+	}
+}
+
 // GetRequestContext retrieves a request context from goroutine-local storage
 func GetRequestContext(gid uint64) (*RequestContext, bool) {
 	contextMutex.RLock()
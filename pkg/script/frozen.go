@@ -0,0 +1,86 @@
+// frozen.go - Immutable arrays and objects
+//
+// Arrays and objects are reference types, so a frozen flag can't live in the
+// Value struct itself (copies of a Value share the same underlying container).
+// Instead, frozen containers are recorded in a side table (see identity.go)
+// keyed by the *[]Value pointer for arrays and the map pointer for objects.
+// The evaluator's assignment paths and the in-place array builtins (push,
+// pop, ...) consult it before mutating; the lookup takes no lock, and a
+// frozen container that's no longer reachable drops out of the table.
+package script
+
+// frozenSet records which containers are frozen
+var frozenSet sideTable[struct{}]
+
+// Freeze returns a frozen copy of an array or object. Assigning to an index or
+// property of the copy, or pushing/popping it, fails with an error. With deep
+// set, nested arrays and objects are copied and frozen too; otherwise they are
+// shared with the original and stay mutable. Other values are returned as-is
+// since they are already immutable.
+func Freeze(v Value, deep bool) Value {
+	var frozen Value
+	switch v.Type {
+	case VAL_ARRAY:
+		arr := v.AsArray()
+		newArr := make([]Value, len(arr))
+		for i, elem := range arr {
+			if deep {
+				elem = Freeze(elem, true)
+			}
+			newArr[i] = elem
+		}
+		frozen = NewArray(newArr)
+	case VAL_OBJECT:
+		obj := v.AsObject()
+		newObj := make(map[string]Value, len(obj))
+		for k, val := range obj {
+			if deep {
+				val = Freeze(val, true)
+			}
+			newObj[k] = val
+		}
+		CopyOrder(obj, newObj)
+		frozen = NewObject(newObj)
+	default:
+		return v
+	}
+
+	id, _ := valueID(frozen)
+	frozenSet.store(id, struct{}{})
+	return frozen
+}
+
+// IsFrozen reports whether v is a frozen array or object
+func IsFrozen(v Value) bool {
+	if frozenSet.empty() {
+		return false
+	}
+	id, ok := valueID(v)
+	if !ok {
+		return false
+	}
+	_, frozen := frozenSet.load(id)
+	return frozen
+}
+
+// copyFrozen freezes dst if src is a frozen object. The conversions between
+// objects and Go maps call it, so a frozen object passed to a builtin as a
+// map (or returned by one) is still frozen on the other side.
+func copyFrozen[V, W any](src map[string]V, dst map[string]W) {
+	if frozenSet.empty() {
+		return
+	}
+	if _, frozen := frozenSet.load(mapID(src)); frozen {
+		frozenSet.store(mapID(dst), struct{}{})
+	}
+}
+
+// IsFrozenArray reports whether the array behind arrPtr is frozen.
+// Builtins that mutate arrays in place receive the pointer directly.
+func IsFrozenArray(arrPtr *[]Value) bool {
+	if frozenSet.empty() || arrPtr == nil {
+		return false
+	}
+	_, frozen := frozenSet.load(arrayID(arrPtr))
+	return frozen
+}
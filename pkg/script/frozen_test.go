@@ -0,0 +1,44 @@
+package script
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFreeze(t *testing.T) {
+	arr := NewArray([]Value{NewNumber(1), NewArray([]Value{NewNumber(2)})})
+	frozen := Freeze(arr, false)
+	if !IsFrozen(frozen) || !IsFrozenArray(frozen.AsArrayPtr()) {
+		t.Fatal("Freeze should register the copy")
+	}
+	if IsFrozen(arr) || IsFrozen(frozen.AsArray()[1]) {
+		t.Fatal("the original and shallow-frozen elements should stay mutable")
+	}
+	if deep := Freeze(arr, true); !IsFrozen(deep.AsArray()[1]) {
+		t.Fatal("a deep freeze should freeze nested arrays")
+	}
+
+	obj := NewObject(map[string]Value{"a": NewNumber(1)})
+	if frozenObj := Freeze(obj, false); !IsFrozen(frozenObj) || IsFrozen(obj) {
+		t.Fatal("Freeze should register only the object copy")
+	}
+	if IsFrozen(NewObject(map[string]Value{})) || IsFrozenArray(nil) {
+		t.Fatal("new containers should not be frozen")
+	}
+}
+
+func TestFrozenContainersAreForgotten(t *testing.T) {
+	before := frozenSet.count.Load()
+	for i := 0; i < 100; i++ {
+		Freeze(NewArray([]Value{NewNumber(float64(i))}), false)
+		Freeze(NewObject(map[string]Value{"a": NewNumber(float64(i))}), false)
+	}
+	for i := 0; i < 20 && frozenSet.count.Load() > before; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond) // cleanups run on their own goroutine
+	}
+	if n := frozenSet.count.Load(); n > before {
+		t.Errorf("%d frozen containers still registered after GC", n-before)
+	}
+}
@@ -0,0 +1,109 @@
+// identity.go - Per-container side tables
+//
+// A Value holding an array or object is just a pointer to the container (the
+// *[]Value or the map), and copies of the Value share it, so state that
+// belongs to one container - whether it's frozen, an ordered object's key
+// order - is kept in a side table keyed by the container's address.
+//
+// Entries hold their container weakly, a cleanup removes them once it's
+// collected, and a lookup checks the weak pointer so an entry for a dead
+// container is never mistaken for a new one at the same address. Lookups are
+// lock-free (sync.Map), since the evaluator checks the tables on every
+// container write once anything has been registered.
+package script
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+	"weak"
+)
+
+// sideTable maps live containers to a value of type T
+type sideTable[T any] struct {
+	entries sync.Map     // uintptr -> *sideEntry[T]
+	count   atomic.Int64 // lets lookups skip the map while the table is empty
+}
+
+// sideEntry is one container's value in a sideTable
+type sideEntry[T any] struct {
+	obj weak.Pointer[byte] // the container, to tell a live entry from a reused address
+	val T
+}
+
+// containerRef is the identity of an array or object: a pointer to it (for
+// the weak reference and cleanup) and its address (for the table key)
+type containerRef struct {
+	ptr  *byte
+	addr uintptr
+}
+
+// mapID returns the identity of a map
+func mapID[V any](obj map[string]V) containerRef {
+	rv := reflect.ValueOf(obj)
+	return containerRef{ptr: (*byte)(rv.UnsafePointer()), addr: rv.Pointer()}
+}
+
+// arrayID returns the identity of an array
+func arrayID(arrPtr *[]Value) containerRef {
+	return containerRef{ptr: (*byte)(unsafe.Pointer(arrPtr)), addr: uintptr(unsafe.Pointer(arrPtr))}
+}
+
+// valueID returns the identity of an array or object value
+func valueID(v Value) (containerRef, bool) {
+	switch v.Type {
+	case VAL_ARRAY:
+		if p, ok := v.Data.(*[]Value); ok && p != nil {
+			return arrayID(p), true
+		}
+	case VAL_OBJECT:
+		if m, ok := v.Data.(map[string]Value); ok && m != nil {
+			return mapID(m), true
+		}
+	}
+	return containerRef{}, false
+}
+
+// empty reports whether nothing is registered, without touching the map
+func (t *sideTable[T]) empty() bool {
+	return t.count.Load() == 0
+}
+
+// load returns the value registered for id, if its container is still the
+// one registered
+func (t *sideTable[T]) load(id containerRef) (T, bool) {
+	var zero T
+	if id.ptr == nil || t.empty() {
+		return zero, false
+	}
+	e, ok := t.entries.Load(id.addr)
+	if !ok {
+		return zero, false
+	}
+	entry := e.(*sideEntry[T])
+	if entry.obj.Value() != id.ptr {
+		return zero, false
+	}
+	return entry.val, true
+}
+
+// store registers val for id, replacing any earlier value
+func (t *sideTable[T]) store(id containerRef, val T) {
+	if id.ptr == nil {
+		return
+	}
+	entry := &sideEntry[T]{obj: weak.Make(id.ptr), val: val}
+	if _, replaced := t.entries.Swap(id.addr, entry); !replaced {
+		t.count.Add(1)
+	}
+
+	runtime.AddCleanup(id.ptr, func(addr uintptr) {
+		// The address may already belong to a newer container
+		e, ok := t.entries.Load(addr)
+		if ok && e.(*sideEntry[T]).obj.Value() == nil && t.entries.CompareAndDelete(addr, e) {
+			t.count.Add(-1)
+		}
+	}, id.addr)
+}
@@ -1,6 +1,8 @@
 package script
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -334,6 +336,11 @@ func TestArithmeticErrors(t *testing.T) {
 			script:      `result = 10 % 0`,
 			expectError: true,
 		},
+		{
+			name:        "modulo by a fraction that truncates to zero",
+			script:      `result = 10 % 0.5`,
+			expectError: true,
+		},
 		{
 			name:        "unary minus on string",
 			script:      `x = "hello"; result = -x`,
@@ -568,3 +575,88 @@ func TestInvalidOperations(t *testing.T) {
 		})
 	}
 }
+
+// TestCallDepthLimit tests that runaway recursion is a catchable error, not a Go stack overflow
+func TestCallDepthLimit(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewInterpreter().Execute(`
+		function loop(n) return loop(n + 1) end
+		loop(0)
+	`)
+	if err == nil || !strings.Contains(err.Error(), "maximum call depth") {
+		t.Fatalf("expected call depth error, got %v", err)
+	}
+	if strings.Count(err.Error(), "\n  at ") > maxPrintedFrames {
+		t.Fatalf("expected call stack to be elided, got %d frames", strings.Count(err.Error(), "\n  at "))
+	}
+
+	got, err := NewInterpreter().ExecuteModule(`
+		function loop(n) return loop(n + 1) end
+		function depth(n) if n == 0 then return 0 end return 1 + depth(n - 1) end
+		caught = false
+		try
+			loop(0)
+		catch (e)
+			caught = true
+		end
+		return [caught, depth(1000)]
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.String() != "[true, 1000]" {
+		t.Fatalf("expected [true, 1000], got %s", got.String())
+	}
+}
+
+// TestCallErrorPositions checks that errors raised while making a call, by
+// the evaluator or by a Go function, point at the call site in the right
+// file and carry the script functions that led there
+func TestCallErrorPositions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+		line   int
+		frames int
+	}{
+		{name: "non-function", script: "x = 5\nx()", line: 2},
+		{name: "missing method", script: "o = {}\no.foo()", line: 2},
+		{name: "array with named args", script: "a = [1]\na(x = 1)", line: 2},
+		{name: "object with positional args", script: "o = {}\no(1)", line: 2},
+		{name: "go function", script: "function f()\n  fail()\nend\nf()", line: 2, frames: 1},
+		{name: "function expression", script: "f = function()\n  fail()\nend\nf()", line: 2, frames: 1},
+		{name: "callback", script: "each(function()\n  fail()\nend)", line: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			interp := NewInterpreter()
+			interp.SetFilePath("t.du")
+			interp.RegisterFunction("fail", func(evaluator *Evaluator, args map[string]any) (any, error) {
+				return nil, errors.New("fail() failed")
+			})
+			interp.RegisterFunction("each", func(evaluator *Evaluator, args map[string]any) (any, error) {
+				return evaluator.CallFunction(interfaceToValue(args["0"]), nil)
+			})
+			_, err := interp.Execute(tt.script)
+			dusoErr, ok := err.(*DusoError)
+			if !ok {
+				t.Fatalf("expected a DusoError, got %T: %v", err, err)
+			}
+			if dusoErr.FilePath != "t.du" {
+				t.Errorf("expected error in t.du, got %q", dusoErr.FilePath)
+			}
+			if dusoErr.Position.Line != tt.line {
+				t.Errorf("expected error on line %d, got %d", tt.line, dusoErr.Position.Line)
+			}
+			if len(dusoErr.CallStack) != tt.frames {
+				t.Errorf("expected %d call frames, got %d", tt.frames, len(dusoErr.CallStack))
+			}
+		})
+	}
+}
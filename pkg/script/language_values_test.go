@@ -1,6 +1,7 @@
 package script
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -763,3 +764,74 @@ func TestValueStringRepresentation(t *testing.T) {
 		})
 	}
 }
+
+// TestInspectValue checks inspect()'s layout, depth limit and cycle marker
+func TestInspectValue(t *testing.T) {
+	t.Parallel()
+
+	interp := NewInterpreter()
+	if _, err := interp.Execute(`
+		function greet(name, ...rest) return name end
+		small = {b = [1, "two"], a = nil, "odd key" = true, f = greet, g = function() end}
+		big = {host = "localhost", options = {ssl = true, pool = 10, timeout = 30, retries = 3}, port = 5432}
+		loop = {name = "root"}
+		loop.self = loop
+		list = [1]
+		list[0] = list
+	`); err != nil {
+		t.Fatal(err)
+	}
+	get := func(name string) Value {
+		val, err := interp.GetEvaluator().GetEnv().Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return val
+	}
+
+	tests := []struct {
+		name  string
+		value Value
+		depth int
+		want  string
+	}{
+		{"scalar", NewString("a\nb"), 0, `"a\nb"`},
+		{"functions", get("small"), 0, "{\n  a = nil,\n  b = [1, \"two\"],\n  f = <function greet(name, ...rest)>,\n  g = <function()>,\n  \"odd key\" = true\n}"},
+		{"multi-line", get("big"), 0, "{\n  host = \"localhost\",\n  options = {pool = 10, retries = 3, ssl = true, timeout = 30},\n  port = 5432\n}"},
+		{"depth", get("big"), 1, `{host = "localhost", options = {... 4 keys}, port = 5432}`},
+		{"circular object", get("loop"), 0, `{name = "root", self = [Circular]}`},
+		{"circular array", get("list"), 0, `[[Circular]]`},
+	}
+	for _, tt := range tests {
+		if got := InspectValue(tt.value, tt.depth); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestCircularValues checks that conversions and tostring() stop at an
+// object that contains itself instead of recursing forever
+func TestCircularValues(t *testing.T) {
+	t.Parallel()
+
+	obj := map[string]Value{"name": NewString("root")}
+	node := NewObject(obj)
+	obj["self"] = node
+
+	if got := ValueToDusoString(node); got != `{name="root", self=[Circular]}` {
+		t.Errorf("ValueToDusoString = %s", got)
+	}
+
+	// The Go map keeps the cycle, and converting back restores it
+	goMap, ok := ValueToInterface(node).(map[string]any)
+	if !ok {
+		t.Fatalf("ValueToInterface returned %T", ValueToInterface(node))
+	}
+	if self, ok := goMap["self"].(map[string]any); !ok || reflect.ValueOf(self).Pointer() != reflect.ValueOf(goMap).Pointer() {
+		t.Errorf("converted self should be the converted map itself, got %v", goMap["self"])
+	}
+	back := InterfaceToValue(goMap).AsObject()
+	if self := back["self"].AsObject(); reflect.ValueOf(self).Pointer() != reflect.ValueOf(back).Pointer() {
+		t.Error("converting back should restore the cycle")
+	}
+}
@@ -493,12 +493,25 @@ func (l *Lexer) NextToken() Token {
 			value := "0" + l.source[start : l.pos-1]
 			return Token{Type: TOK_NUMBER, Value: value, Line: line, Column: column}
 		}
+		// Rest parameter marker: function f(a, ...rest)
+		if l.peekChar() == '.' && l.peekChar2() == '.' {
+			l.readChar()
+			l.readChar()
+			l.readChar()
+			return Token{Type: TOK_ELLIPSIS, Value: "...", Line: line, Column: column}
+		}
 		l.readChar()
 		return Token{Type: TOK_DOT, Value: ".", Line: line, Column: column}
 	case ':':
 		l.readChar()
 		return Token{Type: TOK_COLON, Value: ":", Line: line, Column: column}
 	case '?':
+		// Optional chaining a?.b, unless it's a ternary with a float: x ?.5 : 1
+		if l.peekChar() == '.' && !unicode.IsDigit(l.peekChar2()) {
+			l.readChar()
+			l.readChar()
+			return Token{Type: TOK_QUESTIONDOT, Value: "?.", Line: line, Column: column}
+		}
 		l.readChar()
 		return Token{Type: TOK_QUESTION, Value: "?", Line: line, Column: column}
 	case '"', '\'':
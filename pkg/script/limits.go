@@ -0,0 +1,253 @@
+// limits.go - Execution limits for sandboxed scripts
+//
+// Embedders that run untrusted (or just buggy) scripts need a way to stop a
+// `while true do end` or a runaway recursion without taking the host down.
+// An evaluator can carry a step limit (the number of nodes Eval may visit),
+// its own call depth limit, and an element limit (how many array/object
+// elements the script may add, so it can't allocate its way to an OOM). All
+// are off by default, so the CLI and ordinary embedding pay only a single
+// bool or int test per Eval and per container write.
+//
+// Cancellation works the same way: ExecuteContext attaches a context and Eval
+// polls it every few hundred steps, so a cancelled or timed-out context stops
+// even a tight loop that never calls a builtin.
+//
+// Evaluators created on behalf of a limited one (parallel() branches,
+// spawn(), run(), modules) copy its limits with InheritLimits and share its
+// budget, so a script can't escape the limits by moving work elsewhere: steps
+// and elements a child uses come out of what the parent has left. The shared
+// counters are atomic because parallel branches and spawned scripts run on
+// their own goroutines.
+package script
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// budget is the usage counted against an evaluator's step and element
+// limits, shared with the evaluators that inherit them
+type budget struct {
+	steps    atomic.Int64
+	elements atomic.Int64
+}
+
+// SetStepLimit caps the number of evaluation steps (AST nodes visited) the
+// evaluator may perform; n <= 0 removes the limit. The step count restarts
+// from zero, so a limit applies to the work done after it's set.
+func (e *Evaluator) SetStepLimit(n int64) {
+	if n < 0 {
+		n = 0
+	}
+	e.stepLimit = n
+	e.usage.steps.Store(0)
+	e.updateLimited()
+}
+
+// StepLimit returns the configured step limit (0 = unlimited)
+func (e *Evaluator) StepLimit() int64 {
+	return e.stepLimit
+}
+
+// Steps returns the number of evaluation steps counted against the step
+// limit, including steps taken by evaluators sharing this one's budget
+func (e *Evaluator) Steps() int64 {
+	return e.usage.steps.Load()
+}
+
+// ResetSteps restarts step counting, giving the evaluator (and any sharing
+// its budget) a fresh step budget
+func (e *Evaluator) ResetSteps() {
+	e.usage.steps.Store(0)
+}
+
+// SetMaxCallDepth overrides the process-wide MaxCallDepth for this
+// evaluator; n <= 0 goes back to the process-wide limit.
+func (e *Evaluator) SetMaxCallDepth(n int) {
+	if n < 0 {
+		n = 0
+	}
+	e.maxDepth = n
+}
+
+// maxCallDepth returns the call depth limit in effect for this evaluator
+func (e *Evaluator) maxCallDepth() int {
+	if e.maxDepth > 0 {
+		return e.maxDepth
+	}
+	return MaxCallDepth()
+}
+
+// SetElementLimit caps the total number of elements the script may add to
+// arrays and objects (literals, index/property assignment, push, unshift, and
+// every builtin that returns a new array or object); n <= 0 removes the limit. The count only grows - removing
+// elements doesn't give budget back - and restarts when the limit is set.
+func (e *Evaluator) SetElementLimit(n int64) {
+	if n < 0 {
+		n = 0
+	}
+	e.elementLimit = n
+	e.usage.elements.Store(0)
+}
+
+// ElementLimit returns the configured element limit (0 = unlimited)
+func (e *Evaluator) ElementLimit() int64 {
+	return e.elementLimit
+}
+
+// ChargeElements counts n new array/object elements against the element
+// limit, failing (without counting them) if they'd exceed it. Builtins that
+// grow containers call it before allocating; a nil evaluator (a builtin
+// called directly from Go) has no limit.
+func (e *Evaluator) ChargeElements(n int) error {
+	if e == nil || e.elementLimit <= 0 || n <= 0 {
+		return nil
+	}
+	return e.chargeCounted(int64(n))
+}
+
+// chargeCounted adds n to the shared element count, backing it out again if
+// that goes over the limit. Adding first keeps two goroutines sharing the
+// budget from both passing a check that only one of them fits.
+func (e *Evaluator) chargeCounted(n int64) error {
+	if e.usage.elements.Add(n) > e.elementLimit {
+		e.usage.elements.Add(-n)
+		return fmt.Errorf("element limit of %d exceeded", e.elementLimit)
+	}
+	return nil
+}
+
+// ChargeValue charges every array/object element nested anywhere in v, for
+// builtins that build a whole structure at once (deep_copy, parse_json, ...).
+// Counting stops once it passes the remaining budget, so a huge or cyclic
+// structure fails quickly instead of being walked in full.
+func (e *Evaluator) ChargeValue(v Value) error {
+	if e == nil || e.elementLimit <= 0 {
+		return nil
+	}
+	remaining := e.elementLimit - e.usage.elements.Load()
+	n := countElements(v, remaining)
+	if n > remaining {
+		return fmt.Errorf("element limit of %d exceeded", e.elementLimit)
+	}
+	return e.chargeCounted(n)
+}
+
+// countElements counts the elements nested in v, giving up once the count
+// passes max
+func countElements(v Value, max int64) int64 {
+	var n int64
+	switch v.Type {
+	case VAL_ARRAY:
+		for _, item := range v.AsArray() {
+			n++
+			if n > max {
+				return n
+			}
+			n += countElements(item, max-n)
+		}
+	case VAL_OBJECT:
+		for _, item := range v.AsObject() {
+			n++
+			if n > max {
+				return n
+			}
+			n += countElements(item, max-n)
+		}
+	}
+	return n
+}
+
+// chargeNewKey charges one element if key is new to obj
+func (e *Evaluator) chargeNewKey(obj map[string]Value, key string) error {
+	if e.elementLimit <= 0 {
+		return nil
+	}
+	if _, exists := obj[key]; exists {
+		return nil
+	}
+	return e.ChargeElements(1)
+}
+
+// resetUsage gives the evaluator a fresh step and element budget (called at
+// the start of each top-level Execute). It's a new budget rather than zeroed
+// counters, so scripts spawned by an earlier Execute that are still running
+// keep counting against the budget they started with.
+func (e *Evaluator) resetUsage() {
+	e.usage = &budget{}
+}
+
+// InheritLimits copies parent's limits onto e and makes e share parent's
+// budget, for evaluators that run work on behalf of parent (parallel
+// branches, spawned and run scripts). A trace writer and profile carry over
+// the same way.
+func (e *Evaluator) InheritLimits(parent *Evaluator) {
+	if parent == nil {
+		return
+	}
+	e.SetTrace(parent.trace)
+	e.SetProfile(parent.profile)
+	e.stepLimit = parent.stepLimit
+	e.maxDepth = parent.maxDepth
+	e.elementLimit = parent.elementLimit
+	e.usage = parent.usage
+	e.updateLimited()
+}
+
+// cancelCheckInterval is how many Evals pass between context polls; ctx.Err
+// takes a lock, so checking on every node would be measurable
+const cancelCheckInterval = 256
+
+// SetContext makes evaluation stop with a *CancelledError once ctx is done.
+// nil (or a context that can never be cancelled) turns cancellation off.
+func (e *Evaluator) SetContext(ctx context.Context) {
+	if ctx != nil && ctx.Done() == nil {
+		ctx = nil
+	}
+	e.cancelCtx = ctx
+	e.cancelErr = nil
+	e.ticks = 0
+	e.updateLimited()
+}
+
+// Context returns the context attached with SetContext, or
+// context.Background() if there is none. Blocking builtins (sleep, ...)
+// select on its Done channel so cancellation doesn't wait for them.
+func (e *Evaluator) Context() context.Context {
+	if e.cancelCtx == nil {
+		return context.Background()
+	}
+	return e.cancelCtx
+}
+
+// updateLimited recomputes the flag Eval checks before calling checkLimits
+// (and traceNode, see trace.go)
+func (e *Evaluator) updateLimited() {
+	e.limited = e.stepLimit > 0 || e.cancelCtx != nil || e.trace != nil
+}
+
+// checkLimits counts one evaluation step and fails once the budget is spent
+// or the context is cancelled. Both failures are sticky: the step count keeps
+// rising past the limit and cancelErr stays set, so every later Eval fails
+// too and a try/catch in the script can't swallow the error and keep running.
+func (e *Evaluator) checkLimits() error {
+	if e.cancelCtx != nil {
+		if e.cancelErr != nil {
+			return e.cancelErr
+		}
+		e.ticks++
+		if e.ticks%cancelCheckInterval == 0 {
+			if err := e.cancelCtx.Err(); err != nil {
+				e.cancelErr = &CancelledError{Err: err}
+				return e.cancelErr
+			}
+		}
+	}
+	if e.stepLimit > 0 {
+		if e.usage.steps.Add(1) > e.stepLimit {
+			return e.newError(fmt.Sprintf("step limit of %d exceeded", e.stepLimit), NoPos)
+		}
+	}
+	return nil
+}
@@ -167,6 +167,8 @@ func (a *LintAnalyzer) walkNode(node Node) {
 		a.walkNode(n.Index)
 	case *PropertyAccess:
 		a.walkNode(n.Object)
+	case *OptionalChain:
+		a.walkNode(n.Expr)
 	case *ArrayLiteral:
 		for _, elem := range n.Elements {
 			a.walkNode(elem)
@@ -534,6 +536,8 @@ func (a *LintAnalyzer) getNodePos(node Node) Position {
 		return n.Pos
 	case *PropertyAccess:
 		return n.Pos
+	case *OptionalChain:
+		return n.Pos
 	case *Identifier:
 		return n.Pos
 	case *TemplateLiteral:
@@ -0,0 +1,88 @@
+// literal.go - Reading Duso literals without running them
+//
+// ParseObjectLiteral turns the inside of an object literal, such as the
+// `port=8080, name="api"` passed to duso -config, straight into values. It
+// only lexes and parses: there's no interpreter, environment or statement
+// evaluation, so anything that isn't a literal is an error rather than code
+// that runs.
+package script
+
+import "fmt"
+
+// ParseObjectLiteral parses the key = value pairs of an object literal,
+// without the braces. Values may be numbers (including negative ones),
+// strings, booleans, nil, and arrays and objects of those.
+func ParseObjectLiteral(source string) (map[string]Value, error) {
+	tokens, err := NewLexer("{" + source + "}").Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	p := NewParser(tokens)
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := node.(*ObjectLiteral); !ok || p.current().Type != TOK_EOF {
+		return nil, fmt.Errorf("expected key = value pairs")
+	}
+
+	val, err := literalValue(node)
+	if err != nil {
+		return nil, err
+	}
+	return val.AsObject(), nil
+}
+
+// literalValue converts a literal node to its value
+func literalValue(node Node) (Value, error) {
+	switch n := node.(type) {
+	case *NumberLiteral:
+		return NewNumber(n.Value), nil
+	case *StringLiteral:
+		return NewString(n.Value), nil
+	case *TemplateLiteral:
+		// A string containing {{ }}: evaluate it as a script would, without
+		// any variables in scope
+		return NewEvaluator().Eval(n)
+	case *BoolLiteral:
+		return NewBool(n.Value), nil
+	case *NilLiteral:
+		return NewNil(), nil
+	case *UnaryExpr:
+		if num, ok := n.Operand.(*NumberLiteral); ok && n.Op == TOK_MINUS {
+			return NewNumber(-num.Value), nil
+		}
+	case *ArrayLiteral:
+		elements := make([]Value, len(n.Elements))
+		for i, elem := range n.Elements {
+			val, err := literalValue(elem)
+			if err != nil {
+				return NewNil(), err
+			}
+			elements[i] = val
+		}
+		return NewArray(elements), nil
+	case *ObjectLiteral:
+		obj := make(map[string]Value, len(n.StaticPairs)+len(n.ComputedPairs))
+		for key, valueNode := range n.StaticPairs {
+			val, err := literalValue(valueNode)
+			if err != nil {
+				return NewNil(), err
+			}
+			obj[key] = val
+		}
+		for _, pair := range n.ComputedPairs {
+			key, err := literalValue(pair.KeyExpr)
+			if err != nil {
+				return NewNil(), err
+			}
+			val, err := literalValue(pair.ValueExpr)
+			if err != nil {
+				return NewNil(), err
+			}
+			obj[key.String()] = val
+		}
+		return NewObject(obj), nil
+	}
+	return NewNil(), fmt.Errorf("only literal values are allowed (numbers, strings, booleans, nil, arrays and objects)")
+}
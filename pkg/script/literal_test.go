@@ -0,0 +1,42 @@
+package script
+
+import "testing"
+
+func TestParseObjectLiteral(t *testing.T) {
+	t.Parallel()
+
+	obj, err := ParseObjectLiteral(`port=8080, name="api", debug=true, retries=-2, none=nil, tags=["a", "b"], db={host="x"}, ["odd key"]=1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"port":    "8080",
+		"name":    `"api"`,
+		"debug":   "true",
+		"retries": "-2",
+		"none":    "nil",
+		"tags":    `["a", "b"]`,
+		"db":      `{host="x"}`,
+		"odd key": "1",
+	}
+	if len(obj) != len(want) {
+		t.Errorf("got %d keys, want %d", len(obj), len(want))
+	}
+	for key, expected := range want {
+		if got := ValueToDusoString(obj[key]); got != expected {
+			t.Errorf("%s = %s, want %s", key, got, expected)
+		}
+	}
+
+	for _, source := range []string{
+		`port=8000+1`,
+		`mode=debug`,
+		`f=print("x")`,
+		`a=1}, b={`,
+		`port 8080`,
+	} {
+		if _, err := ParseObjectLiteral(source); err == nil {
+			t.Errorf("ParseObjectLiteral(%q) should fail", source)
+		}
+	}
+}
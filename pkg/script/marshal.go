@@ -0,0 +1,645 @@
+// marshal.go - Typed helpers for Go functions exposed to Duso
+//
+// Go functions receive their arguments as map[string]any: positional args
+// under "0", "1", ... and named args under their names. Arrays arrive as
+// *[]Value and objects as map[string]any. Args wraps that map with typed
+// accessors that return descriptive errors instead of panicking on a bad
+// type assertion, and Marshal/Unmarshal convert between Duso values and Go
+// structs using `duso:"name"` struct tags (falling back to `json` tags).
+package script
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Args wraps a Go function's argument map with typed accessors.
+//
+// Each accessor takes a key that is either an int (positional argument) or a
+// string (named argument):
+//
+//	a := script.Args(args)
+//	name, err := a.String(0)
+//	limit, err := a.Number("limit")
+type Args map[string]any
+
+// lookup resolves an int or string key to its argument value
+func (a Args) lookup(key any) (any, bool) {
+	switch k := key.(type) {
+	case int:
+		v, ok := a[ArgKey(k)]
+		return v, ok
+	case string:
+		v, ok := a[k]
+		return v, ok
+	default:
+		return nil, false
+	}
+}
+
+// argName describes a key for error messages
+func argName(key any) string {
+	if i, ok := key.(int); ok {
+		return fmt.Sprintf("argument %d", i+1)
+	}
+	return fmt.Sprintf("argument '%v'", key)
+}
+
+// typeOfArg returns the Duso type name of a Go-side argument value
+func typeOfArg(v any) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case float64, int, int64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case *[]Value, []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case *ValueRef:
+		return v.(*ValueRef).Val.Type.String()
+	case Value:
+		return v.(Value).Type.String()
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// get returns the argument for key, or an error if it is missing or nil
+func (a Args) get(key any) (any, error) {
+	v, ok := a.lookup(key)
+	if !ok || v == nil {
+		return nil, fmt.Errorf("missing %s", argName(key))
+	}
+	return v, nil
+}
+
+func (a Args) typeError(key any, want string, got any) error {
+	return fmt.Errorf("%s must be %s, got %s", argName(key), want, typeOfArg(got))
+}
+
+// Has reports whether the argument was supplied (and isn't nil)
+func (a Args) Has(key any) bool {
+	v, ok := a.lookup(key)
+	return ok && v != nil
+}
+
+// Value returns the raw argument, or nil if it wasn't supplied
+func (a Args) Value(key any) any {
+	v, _ := a.lookup(key)
+	return v
+}
+
+// String returns a string argument
+func (a Args) String(key any) (string, error) {
+	v, err := a.get(key)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", a.typeError(key, "a string", v)
+	}
+	return s, nil
+}
+
+// Number returns a numeric argument
+func (a Args) Number(key any) (float64, error) {
+	v, err := a.get(key)
+	if err != nil {
+		return 0, err
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	}
+	return 0, a.typeError(key, "a number", v)
+}
+
+// Int returns a numeric argument that must be a whole number
+func (a Args) Int(key any) (int, error) {
+	n, err := a.Number(key)
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("%s must be an integer, got %v", argName(key), n)
+	}
+	return int(n), nil
+}
+
+// Bool returns a boolean argument
+func (a Args) Bool(key any) (bool, error) {
+	v, err := a.get(key)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, a.typeError(key, "a bool", v)
+	}
+	return b, nil
+}
+
+// Array returns an array argument as a Go slice (a copy; changes to it are
+// not seen by the script)
+func (a Args) Array(key any) ([]any, error) {
+	v, err := a.get(key)
+	if err != nil {
+		return nil, err
+	}
+	switch arr := v.(type) {
+	case *[]Value, []any:
+		return DeepCopyAny(arr).([]any), nil
+	}
+	return nil, a.typeError(key, "an array", v)
+}
+
+// Object returns an object argument
+func (a Args) Object(key any) (map[string]any, error) {
+	v, err := a.get(key)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, a.typeError(key, "an object", v)
+	}
+	return obj, nil
+}
+
+// Unmarshal decodes the argument into out (see Unmarshal)
+func (a Args) Unmarshal(key any, out any) error {
+	v, err := a.get(key)
+	if err != nil {
+		return err
+	}
+	if err := Unmarshal(v, out); err != nil {
+		return fmt.Errorf("%s: %v", argName(key), err)
+	}
+	return nil
+}
+
+// Marshal converts a Go value into the plain form Duso understands
+// (float64, string, bool, []any, map[string]any), ready to return from a Go
+// function or pass to SetVariable. Structs become objects keyed by their
+// `duso` (or `json`) tag, or the field name when untagged; a tag of "-"
+// skips the field and ",omitempty" drops zero values. Values pass through
+// unchanged and GoFunctions become callable functions. Fields of embedded
+// structs are promoted as encoding/json does, and a value that contains
+// itself through a pointer, map or slice is an error.
+func Marshal(v any) (any, error) {
+	return marshalValue(reflect.ValueOf(v), "", make(map[marshalRef]bool))
+}
+
+// marshalRef identifies a pointer, map or slice being marshaled, to detect
+// cycles. Slices also need their length, since a slice and its prefix share
+// a pointer.
+type marshalRef struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enterRef records a pointer, map or slice on the current path, reporting
+// false if it's already there
+func enterRef(rv reflect.Value, seen map[marshalRef]bool) (marshalRef, bool) {
+	ref := marshalRef{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		ref.len = rv.Len()
+	}
+	if seen[ref] {
+		return ref, false
+	}
+	seen[ref] = true
+	return ref, true
+}
+
+func marshalValue(rv reflect.Value, path string, seen map[marshalRef]bool) (any, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	switch x := rv.Interface().(type) {
+	case Value, *ValueRef, *[]Value:
+		return x, nil
+	case GoFunction:
+		return NewGoFunction(x), nil
+	case func(*Evaluator, map[string]any) (any, error):
+		return NewGoFunction(x), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Kind() == reflect.Pointer {
+			ref, ok := enterRef(rv, seen)
+			if !ok {
+				return nil, fmt.Errorf("%sencountered a cycle via %s", pathPrefix(path), rv.Type())
+			}
+			defer delete(seen, ref)
+		}
+		return marshalValue(rv.Elem(), path, seen)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			if rv.IsNil() {
+				return nil, nil
+			}
+			if rv.Len() > 0 {
+				ref, ok := enterRef(rv, seen)
+				if !ok {
+					return nil, fmt.Errorf("%sencountered a cycle via %s", pathPrefix(path), rv.Type())
+				}
+				defer delete(seen, ref)
+			}
+		}
+		arr := make([]any, rv.Len())
+		for i := range arr {
+			item, err := marshalValue(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), seen)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = item
+		}
+		return arr, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%scannot marshal map with %s keys", pathPrefix(path), rv.Type().Key())
+		}
+		if rv.IsNil() {
+			return nil, nil
+		}
+		ref, ok := enterRef(rv, seen)
+		if !ok {
+			return nil, fmt.Errorf("%sencountered a cycle via %s", pathPrefix(path), rv.Type())
+		}
+		defer delete(seen, ref)
+		obj := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			item, err := marshalValue(iter.Value(), joinPath(path, key), seen)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = item
+		}
+		return obj, nil
+	case reflect.Struct:
+		obj := make(map[string]any)
+		for _, f := range structFields(rv.Type()) {
+			fv, ok := fieldByIndex(rv, f.index, false)
+			if !ok || f.omitEmpty && fv.IsZero() {
+				continue
+			}
+			item, err := marshalValue(fv, joinPath(path, f.name), seen)
+			if err != nil {
+				return nil, err
+			}
+			obj[f.name] = item
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("%scannot marshal %s", pathPrefix(path), rv.Type())
+}
+
+// Unmarshal decodes a Duso value into the Go value out points to. The input
+// may be a Value or any form a Go function receives in its args (so
+// Unmarshal(args["0"], &cfg) works). Objects decode into structs (matching
+// fields as Marshal names them, case-insensitively) or string-keyed maps,
+// arrays into slices or arrays, and numbers into any numeric type; a number
+// with a fractional part won't decode into an integer field. Keys with no
+// matching struct field are ignored.
+func Unmarshal(v any, out any) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("Unmarshal requires a non-nil pointer, got %T", out)
+	}
+	return unmarshalValue(v, rv.Elem(), "")
+}
+
+var valueType = reflect.TypeOf(Value{})
+
+func unmarshalValue(v any, rv reflect.Value, path string) error {
+	// Values decode through their Go form, except into a Value target
+	if val, ok := v.(Value); ok {
+		if rv.Type() == valueType {
+			rv.Set(reflect.ValueOf(val))
+			return nil
+		}
+		v = ValueToInterface(val)
+	}
+	if rv.Type() == valueType {
+		rv.Set(reflect.ValueOf(InterfaceToValue(v)))
+		return nil
+	}
+
+	if v == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			break
+		}
+		rv.Set(reflect.ValueOf(DeepCopyAny(v)))
+		return nil
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalValue(v, rv.Elem(), path)
+	case reflect.Bool:
+		if b, ok := v.(bool); ok {
+			rv.SetBool(b)
+			return nil
+		}
+	case reflect.String:
+		if s, ok := v.(string); ok {
+			rv.SetString(s)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := toFloat(v); ok {
+			rv.SetFloat(n)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := toFloat(v)
+		if !ok {
+			break
+		}
+		if n != math.Trunc(n) || math.IsInf(n, 0) {
+			return fmt.Errorf("%scannot unmarshal %v into %s", pathPrefix(path), n, rv.Type())
+		}
+		if rv.CanInt() {
+			if rv.OverflowInt(int64(n)) {
+				return fmt.Errorf("%s%v overflows %s", pathPrefix(path), n, rv.Type())
+			}
+			rv.SetInt(int64(n))
+		} else {
+			if n < 0 || rv.OverflowUint(uint64(n)) {
+				return fmt.Errorf("%s%v overflows %s", pathPrefix(path), n, rv.Type())
+			}
+			rv.SetUint(uint64(n))
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		items, ok := toSlice(v)
+		if !ok {
+			break
+		}
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), len(items), len(items)))
+		} else if len(items) > rv.Len() {
+			return fmt.Errorf("%scannot unmarshal %d elements into %s", pathPrefix(path), len(items), rv.Type())
+		}
+		for i, item := range items {
+			if err := unmarshalValue(item, rv.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(obj)))
+		}
+		for key, item := range obj {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := unmarshalValue(item, elem, joinPath(path, key)); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), elem)
+		}
+		return nil
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			break
+		}
+		fields := structFields(rv.Type())
+		for key, item := range obj {
+			f := matchField(fields, key)
+			if f == nil {
+				continue
+			}
+			fv, ok := fieldByIndex(rv, f.index, true)
+			if !ok {
+				return fmt.Errorf("%scannot set %s through an unexported embedded pointer", pathPrefix(path), f.name)
+			}
+			if err := unmarshalValue(item, fv, joinPath(path, f.name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%scannot unmarshal %s into %s", pathPrefix(path), typeOfArg(v), rv.Type())
+}
+
+// fieldInfo describes how a struct field maps to an object key
+type fieldInfo struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+}
+
+// structFields lists the exported fields of t with their object keys,
+// including fields promoted from embedded structs. As with encoding/json, a
+// shallower field hides deeper ones with the same name; at the same depth a
+// tagged field wins, and otherwise the name is ambiguous and left out.
+func structFields(t reflect.Type) []fieldInfo {
+	var all []fieldInfo
+	collectFields(t, nil, make(map[reflect.Type]bool), &all)
+
+	byName := make(map[string][]fieldInfo)
+	var names []string
+	for _, f := range all {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	fields := make([]fieldInfo, 0, len(names))
+	for _, name := range names {
+		if f, ok := dominantField(byName[name]); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// collectFields appends the fields of t, walking into embedded structs
+func collectFields(t reflect.Type, index []int, visiting map[reflect.Type]bool, out *[]fieldInfo) {
+	if visiting[t] {
+		return // embedded through a pointer to itself
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("duso")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if sf.Anonymous {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if name == "" && ft.Kind() == reflect.Struct {
+				collectFields(ft, fieldIndex, visiting, out)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = sf.Name
+		}
+		*out = append(*out, fieldInfo{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: strings.Contains(opts, "omitempty"),
+			tagged:    tagged,
+		})
+	}
+}
+
+// dominantField picks the field a name refers to among those sharing it
+func dominantField(fields []fieldInfo) (fieldInfo, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields {
+		depth = min(depth, len(f.index))
+	}
+	var found []fieldInfo
+	for _, f := range fields {
+		if len(f.index) == depth {
+			found = append(found, f)
+		}
+	}
+	if len(found) > 1 {
+		var tagged []fieldInfo
+		for _, f := range found {
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		found = tagged
+	}
+	if len(found) != 1 {
+		return fieldInfo{}, false
+	}
+	return found[0], true
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex for promoted fields. Reading
+// (alloc false) reports false at a nil embedded pointer; writing allocates
+// it, reporting false if the pointer is unexported and can't be set.
+func fieldByIndex(rv reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !alloc || !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// matchField finds the field for an object key, preferring an exact match
+func matchField(fields []fieldInfo, key string) *fieldInfo {
+	for i := range fields {
+		if fields[i].name == key {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, key) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toSlice(v any) ([]any, bool) {
+	switch arr := v.(type) {
+	case []any:
+		return arr, true
+	case *[]Value:
+		items := make([]any, len(*arr))
+		for i, item := range *arr {
+			items[i] = ValueToInterface(item)
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathPrefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + ": "
+}
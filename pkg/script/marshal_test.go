@@ -0,0 +1,229 @@
+package script
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArgsAccessors(t *testing.T) {
+	t.Parallel()
+
+	arr := []Value{NewNumber(1), NewString("two")}
+	args := Args{
+		"0":     "hello",
+		"1":     float64(3),
+		"limit": float64(2.5),
+		"flag":  true,
+		"items": &arr,
+		"opts":  map[string]any{"x": float64(1)},
+	}
+
+	if s, err := args.String(0); err != nil || s != "hello" {
+		t.Errorf("String(0) = %q, %v", s, err)
+	}
+	if n, err := args.Int(1); err != nil || n != 3 {
+		t.Errorf("Int(1) = %d, %v", n, err)
+	}
+	if n, err := args.Number("limit"); err != nil || n != 2.5 {
+		t.Errorf("Number(limit) = %v, %v", n, err)
+	}
+	if b, err := args.Bool("flag"); err != nil || !b {
+		t.Errorf("Bool(flag) = %v, %v", b, err)
+	}
+	if items, err := args.Array("items"); err != nil || len(items) != 2 || items[1] != "two" {
+		t.Errorf("Array(items) = %v, %v", items, err)
+	}
+	if opts, err := args.Object("opts"); err != nil || opts["x"] != float64(1) {
+		t.Errorf("Object(opts) = %v, %v", opts, err)
+	}
+
+	errTests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"missing positional", func() error { _, err := args.String(5); return err }, "missing argument 6"},
+		{"missing named", func() error { _, err := args.Number("nope"); return err }, "missing argument 'nope'"},
+		{"wrong type", func() error { _, err := args.Number(0); return err }, "argument 1 must be a number, got string"},
+		{"not an integer", func() error { _, err := args.Int("limit"); return err }, "must be an integer"},
+		{"array is not object", func() error { _, err := args.Object("items"); return err }, "must be an object, got array"},
+	}
+	for _, tt := range errTests {
+		err := tt.call()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+type testServer struct {
+	Host    string            `duso:"host"`
+	Port    int               `duso:"port"`
+	Tags    []string          `json:"tags"`
+	Limits  map[string]uint16 `duso:"limits,omitempty"`
+	Debug   *bool             `duso:"debug,omitempty"`
+	Secret  string            `duso:"-"`
+	Backend *testServer       `duso:"backend,omitempty"`
+}
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	debug := true
+	in := testServer{
+		Host:    "localhost",
+		Port:    8080,
+		Tags:    []string{"a", "b"},
+		Debug:   &debug,
+		Secret:  "hidden",
+		Backend: &testServer{Host: "db", Port: 5432},
+	}
+
+	m, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	obj := m.(map[string]any)
+	if _, ok := obj["Secret"]; ok {
+		t.Error("field tagged \"-\" should be skipped")
+	}
+	if _, ok := obj["limits"]; ok {
+		t.Error("empty omitempty field should be dropped")
+	}
+	if obj["port"] != float64(8080) {
+		t.Errorf("port = %v, want 8080", obj["port"])
+	}
+
+	var out testServer
+	if err := Unmarshal(InterfaceToValue(m), &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if out.Host != "localhost" || out.Port != 8080 || len(out.Tags) != 2 || out.Debug == nil || !*out.Debug {
+		t.Errorf("round trip mismatch: %+v", out)
+	}
+	if out.Backend == nil || out.Backend.Port != 5432 {
+		t.Errorf("nested struct not decoded: %+v", out.Backend)
+	}
+}
+
+func TestUnmarshalFromScript(t *testing.T) {
+	t.Parallel()
+
+	got, err := NewInterpreter().ExecuteModule(`return {host = "example.com", PORT = 443, limits = {conns = 10}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cfg testServer
+	if err := Unmarshal(got, &cfg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if cfg.Host != "example.com" || cfg.Port != 443 || cfg.Limits["conns"] != 10 {
+		t.Errorf("unexpected result: %+v", cfg)
+	}
+
+	errTests := []struct {
+		src  string
+		want string
+	}{
+		{`return {port = "80"}`, "port: cannot unmarshal string into int"},
+		{`return {port = 1.5}`, "port: cannot unmarshal 1.5 into int"},
+		{`return {limits = {conns = -1}}`, "limits.conns: -1 overflows uint16"},
+	}
+	for _, tt := range errTests {
+		v, err := NewInterpreter().ExecuteModule(tt.src)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var cfg testServer
+		err = Unmarshal(v, &cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.src, tt.want, err)
+		}
+	}
+
+	if err := Unmarshal(got, cfg); err == nil {
+		t.Error("expected error for non-pointer target")
+	}
+}
+
+type testNode struct {
+	Name string
+	Next *testNode `duso:"next,omitempty"`
+}
+
+func TestMarshalCycle(t *testing.T) {
+	t.Parallel()
+
+	n := &testNode{Name: "a"}
+	n.Next = &testNode{Name: "b", Next: n}
+	if _, err := Marshal(n); err == nil || !strings.Contains(err.Error(), "next.next: encountered a cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+
+	m := map[string]any{}
+	m["self"] = m
+	if _, err := Marshal(m); err == nil {
+		t.Error("expected a cycle error for a map containing itself")
+	}
+
+	// The same pointer twice, without a cycle, is fine
+	shared := &testNode{Name: "shared"}
+	if _, err := Marshal([]*testNode{shared, shared}); err != nil {
+		t.Errorf("shared pointer: %v", err)
+	}
+}
+
+type testInner struct {
+	A    int
+	Name string `duso:"name"`
+}
+
+type TestExported struct {
+	C int
+}
+
+type testOuter struct {
+	testInner
+	*TestExported
+	B    string
+	Name string
+}
+
+func TestMarshalEmbedded(t *testing.T) {
+	t.Parallel()
+
+	out, err := Marshal(testOuter{testInner: testInner{A: 1, Name: "in"}, B: "x", Name: "out"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := out.(map[string]any)
+	if obj["A"] != float64(1) || obj["name"] != "in" || obj["B"] != "x" || obj["Name"] != "out" {
+		t.Errorf("promoted fields missing: %v", obj)
+	}
+	if _, ok := obj["C"]; ok {
+		t.Errorf("a nil embedded pointer's fields should be left out: %v", obj)
+	}
+
+	var back testOuter
+	if err := Unmarshal(map[string]any{"A": float64(2), "C": float64(3), "B": "y"}, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.A != 2 || back.B != "y" || back.TestExported == nil || back.C != 3 {
+		t.Errorf("unmarshal into promoted fields: %+v", back)
+	}
+
+	// Same name at the same depth with no tag to break the tie: neither is used
+	type left struct{ X int }
+	type right struct{ X int }
+	type both struct {
+		left
+		right
+	}
+	out, err = Marshal(both{left{1}, right{2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.(map[string]any)["X"]; ok {
+		t.Errorf("ambiguous field should be dropped: %v", out)
+	}
+}
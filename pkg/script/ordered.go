@@ -0,0 +1,152 @@
+// ordered.go - Insertion-ordered objects
+//
+// Objects are Go maps, which iterate in random order. An ordered object is an
+// ordinary object whose key order is also recorded in a side table (see
+// identity.go), like a frozen container: keys(), values(), for loops,
+// format_json() and tostring() then follow the order its keys were set in.
+// Assignments in the evaluator append new keys, and copies made by builtins
+// (including the map[string]any conversion for Go functions) carry the order
+// over.
+//
+// Finding an object's order takes no lock, and each order has its own lock,
+// so ordered objects don't serialize writes to other objects. Entries hold
+// their map weakly: an ordered object that's no longer reachable drops out of
+// the table, so they can be created freely, e.g. one per HTTP response.
+package script
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+// objectOrder is the key order of one ordered object
+type objectOrder struct {
+	mu   sync.RWMutex
+	keys []string
+	has  map[string]bool
+}
+
+// orderedSet records the key order of each ordered object
+var orderedSet sideTable[*objectOrder]
+
+// lookupOrder returns the order of obj, or nil if it isn't ordered
+func lookupOrder[V any](obj map[string]V) *objectOrder {
+	if orderedSet.empty() || obj == nil {
+		return nil
+	}
+	order, _ := orderedSet.load(mapID(obj))
+	return order
+}
+
+// setOrder records keys as the order of obj, replacing any earlier order
+func setOrder[V any](obj map[string]V, keys []string) {
+	if obj == nil {
+		return
+	}
+	order := &objectOrder{has: make(map[string]bool, len(keys))}
+	for _, k := range keys {
+		if !order.has[k] {
+			order.has[k] = true
+			order.keys = append(order.keys, k)
+		}
+	}
+	orderedSet.store(mapID(obj), order)
+}
+
+// NewOrderedObject makes obj an ordered object with its keys in the order
+// of keys. Keys of obj missing from keys are listed after them, sorted.
+func NewOrderedObject(obj map[string]Value, keys []string) Value {
+	setOrder(obj, keys)
+	return NewObject(obj)
+}
+
+// IsOrdered reports whether v is an ordered object
+func IsOrdered(v Value) bool {
+	return v.IsObject() && lookupOrder(v.AsObject()) != nil
+}
+
+// OrderedKeys returns obj's keys in insertion order, or false if obj isn't
+// an ordered object
+func OrderedKeys[V any](obj map[string]V) ([]string, bool) {
+	order := lookupOrder(obj)
+	if order == nil {
+		return nil, false
+	}
+	order.mu.RLock()
+	keys := make([]string, 0, len(obj))
+	for _, k := range order.keys {
+		// Skip keys deleted since they were set
+		if _, ok := obj[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	order.mu.RUnlock()
+	return appendMissingKeys(obj, keys), true
+}
+
+// SortedKeys returns obj's keys in insertion order for an ordered object,
+// sorted otherwise, for output that should be the same every run
+func SortedKeys[V any](obj map[string]V) []string {
+	if keys, ok := OrderedKeys(obj); ok {
+		return keys
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CopyOrder makes dst ordered like src, if src is an ordered object. It's
+// for builtins that copy an object key by key.
+func CopyOrder[V, W any](src map[string]V, dst map[string]W) {
+	if keys, ok := OrderedKeys(src); ok {
+		setOrder(dst, keys)
+	}
+}
+
+// setObjectKey assigns obj[key] = value and, if obj is an ordered object and
+// key is new to it, adds key to the end of its order. A key that was deleted
+// and is set again moves to the end, as if it had never been there.
+func setObjectKey(obj map[string]Value, key string, value Value) {
+	if orderedSet.empty() {
+		obj[key] = value
+		return
+	}
+	_, existed := obj[key]
+	obj[key] = value
+	if existed {
+		return
+	}
+	if order := lookupOrder(obj); order != nil {
+		order.mu.Lock()
+		if order.has[key] {
+			order.keys = slices.DeleteFunc(order.keys, func(k string) bool { return k == key })
+		}
+		order.has[key] = true
+		order.keys = append(order.keys, key)
+		order.mu.Unlock()
+	}
+}
+
+// appendMissingKeys adds the keys of obj that aren't in keys, sorted. keys
+// must all be keys of obj, without duplicates.
+func appendMissingKeys[V any](obj map[string]V, keys []string) []string {
+	if len(keys) == len(obj) {
+		return keys
+	}
+	listed := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		listed[k] = true
+	}
+	var missing []string
+	for k := range obj {
+		if !listed[k] {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+	return append(keys, missing...)
+}
@@ -0,0 +1,91 @@
+package script
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrderedKeys(t *testing.T) {
+	obj := map[string]Value{"b": NewNumber(1), "a": NewNumber(2), "c": NewNumber(3)}
+	if _, ok := OrderedKeys(obj); ok {
+		t.Fatal("a plain object should not be ordered")
+	}
+	ordered := NewOrderedObject(obj, []string{"c", "a"})
+	if !IsOrdered(ordered) {
+		t.Fatal("NewOrderedObject should register the object")
+	}
+	// Keys left out of the order follow, sorted
+	if keys, _ := OrderedKeys(obj); !reflect.DeepEqual(keys, []string{"c", "a", "b"}) {
+		t.Errorf("OrderedKeys = %v", keys)
+	}
+
+	setObjectKey(obj, "a", NewNumber(5)) // already listed
+	setObjectKey(obj, "z", NewNil())
+	delete(obj, "c")
+	if keys, _ := OrderedKeys(obj); !reflect.DeepEqual(keys, []string{"a", "z", "b"}) {
+		t.Errorf("after changes OrderedKeys = %v", keys)
+	}
+	// A deleted key that's set again goes to the end
+	setObjectKey(obj, "c", NewNumber(3))
+	if keys, _ := OrderedKeys(obj); !reflect.DeepEqual(keys, []string{"a", "z", "c", "b"}) {
+		t.Errorf("after re-adding c OrderedKeys = %v", keys)
+	}
+	delete(obj, "c")
+
+	// Conversion for Go functions keeps the order both ways
+	back := InterfaceToValue(ValueToInterface(ordered))
+	if keys := SortedKeys(back.AsObject()); !reflect.DeepEqual(keys, []string{"a", "z", "b"}) {
+		t.Errorf("converted keys = %v", keys)
+	}
+	if keys := SortedKeys(map[string]int{"y": 1, "x": 2}); !reflect.DeepEqual(keys, []string{"x", "y"}) {
+		t.Errorf("SortedKeys of a plain map = %v", keys)
+	}
+}
+
+func TestOrderedObjectsAreForgotten(t *testing.T) {
+	before := orderedSet.count.Load()
+	for i := 0; i < 100; i++ {
+		NewOrderedObject(map[string]Value{"a": NewNumber(float64(i))}, []string{"a"})
+	}
+	for i := 0; i < 20 && orderedSet.count.Load() > before; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond) // cleanups run on their own goroutine
+	}
+	if n := orderedSet.count.Load(); n > before {
+		t.Errorf("%d ordered objects still registered after GC", n-before)
+	}
+}
+
+// Writes to different ordered objects and reads of their order may run on
+// different goroutines, e.g. parallel() branches. Run with -race to verify.
+func TestOrderedConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			obj := map[string]Value{}
+			NewOrderedObject(obj, nil)
+			plain := map[string]Value{}
+			for i := 0; i < 200; i++ {
+				key := string(rune('a' + i%26))
+				setObjectKey(obj, key, NewNumber(float64(i)))
+				setObjectKey(plain, key, NewNumber(float64(i)))
+				if _, ok := OrderedKeys(obj); !ok {
+					t.Error("object lost its order")
+					return
+				}
+			}
+			if keys, _ := OrderedKeys(obj); len(keys) != 26 || keys[0] != "a" || keys[25] != "z" {
+				t.Errorf("OrderedKeys = %v", keys)
+			}
+			if IsOrdered(NewObject(plain)) {
+				t.Error("a plain object should not be ordered")
+			}
+		}()
+	}
+	wg.Wait()
+}
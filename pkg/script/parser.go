@@ -15,6 +15,7 @@ package script
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -199,7 +200,7 @@ func (p *Parser) parseStatement() (Node, error) {
 		pos := Position{Line: p.current().Line, Column: p.current().Column}
 		p.advance()
 		return &ContinueStatement{Pos: pos}, nil
-	case TOK_VAR:
+	case TOK_VAR, TOK_CONST:
 		return p.parseVarDeclaration()
 	default:
 		// Try to parse as assignment or expression statement
@@ -214,6 +215,13 @@ func (p *Parser) parseStatement() (Node, error) {
 			exprPos = id.Pos
 		}
 
+		// a?.b = 1 has nothing to assign to when a is nil
+		if chain, ok := expr.(*OptionalChain); ok {
+			if t := p.current().Type; t == TOK_ASSIGN || p.isCompoundAssign(t) || t == TOK_INCREMENT || t == TOK_DECREMENT {
+				return nil, p.parseError("cannot assign to an optional chain (?.)", chain.Pos)
+			}
+		}
+
 		// Check if it's an assignment
 		if p.current().Type == TOK_ASSIGN {
 			// Validate that simple identifier targets aren't reserved names
@@ -444,7 +452,17 @@ func (p *Parser) parseForStatement() (*ForStatement, error) {
 // Advances past closing paren
 func (p *Parser) parseParameters() ([]*Parameter, error) {
 	var params []*Parameter
-	for p.current().Type == TOK_IDENT {
+	for p.current().Type == TOK_IDENT || p.current().Type == TOK_ELLIPSIS {
+		variadic := false
+		if p.current().Type == TOK_ELLIPSIS {
+			variadic = true
+			p.advance()
+			if p.current().Type != TOK_IDENT {
+				pos := Position{Line: p.current().Line, Column: p.current().Column}
+				return nil, p.parseError("expected parameter name after '...'", pos)
+			}
+		}
+
 		paramName := p.current().Value
 		paramPos := Position{Line: p.current().Line, Column: p.current().Column}
 
@@ -457,6 +475,9 @@ func (p *Parser) parseParameters() ([]*Parameter, error) {
 
 		var defaultExpr Node = nil
 		if p.current().Type == TOK_ASSIGN {
+			if variadic {
+				return nil, p.parseError(fmt.Sprintf("rest parameter '%s' cannot have a default value", paramName), paramPos)
+			}
 			p.advance()
 			expr, err := p.parseExpression()
 			if err != nil {
@@ -465,11 +486,14 @@ func (p *Parser) parseParameters() ([]*Parameter, error) {
 			defaultExpr = expr
 		}
 
-		params = append(params, &Parameter{Name: paramName, Default: defaultExpr})
+		params = append(params, &Parameter{Name: paramName, Default: defaultExpr, Variadic: variadic})
 
 		if p.current().Type == TOK_COMMA {
 			p.advance()
 		}
+		if variadic && p.current().Type != TOK_RPAREN {
+			return nil, p.parseError(fmt.Sprintf("rest parameter '%s' must be the last parameter", paramName), paramPos)
+		}
 	}
 
 	if err := p.expect(TOK_RPAREN); err != nil {
@@ -518,6 +542,7 @@ func (p *Parser) parseFunctionDef() (*FunctionDef, error) {
 }
 
 func (p *Parser) parseFunctionExpr() (*FunctionExpr, error) {
+	funcPos := Position{Line: p.current().Line, Column: p.current().Column}
 	p.advance() // skip "function"
 
 	if err := p.expect(TOK_LPAREN); err != nil {
@@ -538,7 +563,7 @@ func (p *Parser) parseFunctionExpr() (*FunctionExpr, error) {
 		return nil, err
 	}
 
-	return &FunctionExpr{Parameters: params, Body: body}, nil
+	return &FunctionExpr{Pos: funcPos, Parameters: params, Body: body}, nil
 }
 
 func (p *Parser) parseTryStatement() (*TryStatement, error) {
@@ -607,14 +632,17 @@ func (p *Parser) parseReturnStatement() (*ReturnStatement, error) {
 	return &ReturnStatement{Pos: startPos, Value: value}, nil
 }
 
+// parseVarDeclaration parses "var name = value" and "const name = value"
 func (p *Parser) parseVarDeclaration() (*AssignStatement, error) {
 	startPos := Position{Line: p.current().Line, Column: p.current().Column}
-	p.advance() // skip "var"
+	keyword := p.current().Value
+	isConst := p.current().Type == TOK_CONST
+	p.advance() // skip "var" / "const"
 
 	// Expect an identifier
 	if p.current().Type != TOK_IDENT {
 		pos := Position{Line: p.current().Line, Column: p.current().Column}
-		return nil, p.parseError("expected identifier after 'var'", pos)
+		return nil, p.parseError(fmt.Sprintf("expected identifier after '%s'", keyword), pos)
 	}
 	name := p.current().Value
 	identPos := Position{Line: p.current().Line, Column: p.current().Column}
@@ -644,6 +672,7 @@ func (p *Parser) parseVarDeclaration() (*AssignStatement, error) {
 		Target:           &Identifier{Pos: identPos, Name: name},
 		Value:            value,
 		IsVarDeclaration: true,
+		IsConst:          isConst,
 	}, nil
 }
 
@@ -872,10 +901,12 @@ func (p *Parser) parseUnary() (Node, error) {
 }
 
 func (p *Parser) parsePostfix() (Node, error) {
+	start := Position{Line: p.current().Line, Column: p.current().Column}
 	expr, err := p.parsePrimary()
 	if err != nil {
 		return nil, err
 	}
+	optional := false
 
 	for {
 		var err error
@@ -910,7 +941,33 @@ func (p *Parser) parsePostfix() (Node, error) {
 				expr = &PropertyAccess{Pos: pos, Object: expr, Property: propName}
 			}
 
+		case TOK_QUESTIONDOT:
+			// Optional chaining: a?.b or a?.[i]
+			pos = Position{Line: p.current().Line, Column: p.current().Column}
+			p.advance()
+			optional = true
+			switch p.current().Type {
+			case TOK_IDENT:
+				expr = &PropertyAccess{Pos: pos, Object: expr, Property: p.current().Value, Optional: true}
+				p.advance()
+			case TOK_LBRACKET:
+				p.advance()
+				var index Node
+				index, err = p.parseExpression()
+				if err == nil {
+					err = p.expect(TOK_RBRACKET)
+					if err == nil {
+						expr = &IndexExpr{Pos: pos, Object: expr, Index: index, Optional: true}
+					}
+				}
+			default:
+				err = p.parseError("expected a property name or [ after ?.", pos)
+			}
+
 		default:
+			if optional {
+				return &OptionalChain{Pos: start, Expr: expr}, nil
+			}
 			return expr, nil
 		}
 
@@ -999,7 +1056,8 @@ func (p *Parser) parsePrimary() (Node, error) {
 		// Tilde strings ~pattern~ create regex values
 		pattern := p.current().Value
 		p.advance()
-		return &RegexLiteral{Pattern: pattern}, nil
+		compiled, err := regexp.Compile(pattern)
+		return &RegexLiteral{Pattern: pattern, compiled: compiled, compileErr: err}, nil
 
 	case TOK_TRUE:
 		p.advance()
@@ -1142,12 +1200,13 @@ func (p *Parser) parsePrimary() (Node, error) {
 // ParseTemplateString parses a template string containing {{ }} expressions
 func (p *Parser) ParseTemplateString(template string, pos Position) (Node, error) {
 	var parts []Node
+	var placeholders []*templatePlaceholder
 
 	// Split template by {{ and }}
 	i := 0
 	for i < len(template) {
 		// Find next template expression
-		start := strings.Index(template[i:], "{{")
+		start := findTemplateOpen(template[i:])
 		if start == -1 {
 			// No more templates - add remaining text (unescaped)
 			if i < len(template) {
@@ -1165,12 +1224,22 @@ func (p *Parser) ParseTemplateString(template string, pos Position) (Node, error
 		exprStart := i + start + 2
 		end := strings.Index(template[exprStart:], "}}")
 		if end == -1 {
-			pos := Position{Line: 1, Column: exprStart}
 			return nil, p.parseError("unclosed {{ in template string", pos)
 		}
 
 		// Extract and parse expression (raw, no unescaping for expressions)
 		exprStr := template[exprStart : exprStart+end]
+		placeholder := &templatePlaceholder{source: exprStr}
+
+		// {{expr:spec}} formats the value, e.g. {{price:.2f}}
+		if expr, spec, ok := splitFormatSpec(exprStr); ok {
+			f, err := parseFormatSpec(strings.TrimSpace(spec))
+			if err != nil {
+				errPos := Position{Line: pos.Line, Column: pos.Column}
+				return nil, p.parseError(fmt.Sprintf("error in template expression: %v", err), errPos)
+			}
+			exprStr, placeholder.format = expr, f
+		}
 
 		// Calculate correct starting position for expression within template
 		linesBeforeExpr := 0
@@ -1205,6 +1274,9 @@ func (p *Parser) ParseTemplateString(template string, pos Position) (Node, error
 			return nil, p.parseError(fmt.Sprintf("error in template expression: %v", err), errPos)
 		}
 
+		// Keep placeholders indexed like parts
+		placeholders = append(placeholders, make([]*templatePlaceholder, len(parts)-len(placeholders))...)
+		placeholders = append(placeholders, placeholder)
 		parts = append(parts, expr)
 
 		// Move past }}
@@ -1222,5 +1294,26 @@ func (p *Parser) ParseTemplateString(template string, pos Position) (Node, error
 		}
 	}
 
-	return &TemplateLiteral{Pos: pos, Parts: parts}, nil
+	return &TemplateLiteral{Pos: pos, Parts: parts, placeholders: placeholders}, nil
+}
+
+// findTemplateOpen returns the index of the first {{ in s that isn't
+// escaped as \{{, or -1
+func findTemplateOpen(s string) int {
+	offset := 0
+	for {
+		idx := strings.Index(s[offset:], "{{")
+		if idx == -1 {
+			return -1
+		}
+		idx += offset
+		backslashes := 0
+		for j := idx - 1; j >= 0 && s[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			return idx
+		}
+		offset = idx + 2
+	}
 }
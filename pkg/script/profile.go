@@ -0,0 +1,126 @@
+// profile.go - Flat function profile (duso -profile)
+//
+// With a Profile attached, the evaluator times every call to a script
+// function or builtin and adds it to a per-function entry: how many calls,
+// the total time spent in them, and the self time (total minus the calls
+// they made in turn). A recursive function's total counts only its
+// outermost calls, so it isn't inflated by its own nesting.
+//
+// Evaluators created on behalf of a profiled one (parallel() branches,
+// spawn(), run()) record into the same Profile, so times from concurrent
+// work can add up to more than the wall time.
+package script
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Profile collects per-function call counts and times. It is safe for
+// concurrent use.
+type Profile struct {
+	mu      sync.Mutex
+	entries map[string]*ProfileEntry
+}
+
+// ProfileEntry is the profile of one function
+type ProfileEntry struct {
+	Name  string
+	Calls int64
+	Total time.Duration // time in calls, counting only outermost recursive calls
+	Self  time.Duration // time in calls, minus the calls they made
+}
+
+// profileFrame is a call in progress on one evaluator
+type profileFrame struct {
+	name     string
+	start    time.Time
+	children time.Duration // time spent in calls made from this one
+}
+
+// NewProfile creates an empty profile
+func NewProfile() *Profile {
+	return &Profile{entries: make(map[string]*ProfileEntry)}
+}
+
+// Entries returns a copy of the profile, the most total time first
+func (p *Profile) Entries() []ProfileEntry {
+	p.mu.Lock()
+	entries := make([]ProfileEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, *entry)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Total != entries[j].Total {
+			return entries[i].Total > entries[j].Total
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+func (p *Profile) record(name string, elapsed, self time.Duration, outermost bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry := p.entries[name]
+	if entry == nil {
+		entry = &ProfileEntry{Name: name}
+		p.entries[name] = entry
+	}
+	entry.Calls++
+	entry.Self += self
+	if outermost {
+		entry.Total += elapsed
+	}
+}
+
+// SetProfile makes the evaluator record its calls in p; nil turns profiling
+// off
+func (e *Evaluator) SetProfile(p *Profile) {
+	e.profile = p
+	e.profileStack = nil
+	e.profileActive = nil
+	if p != nil {
+		e.profileActive = make(map[string]int)
+	}
+}
+
+// Profile returns the profile set with SetProfile, or nil
+func (e *Evaluator) Profile() *Profile {
+	return e.profile
+}
+
+// profileEnter starts timing a call to name; every profileEnter is paired
+// with a profileExit
+func (e *Evaluator) profileEnter(name string) {
+	e.profileStack = append(e.profileStack, profileFrame{name: name, start: time.Now()})
+	e.profileActive[name]++
+}
+
+// profileExit records the call started by the last profileEnter
+func (e *Evaluator) profileExit() {
+	n := len(e.profileStack) - 1
+	frame := e.profileStack[n]
+	e.profileStack = e.profileStack[:n]
+	elapsed := time.Since(frame.start)
+
+	e.profileActive[frame.name]--
+	outermost := e.profileActive[frame.name] == 0
+	if n > 0 {
+		e.profileStack[n-1].children += elapsed
+	}
+	e.profile.record(frame.name, elapsed, elapsed-frame.children, outermost)
+}
+
+// profileName names a script function in the profile. Anonymous functions
+// are told apart by where they're defined.
+func profileName(fn *ScriptFunction) string {
+	if fn.Name != "" {
+		return fn.Name
+	}
+	return fmt.Sprintf("function (%s:%d)", fn.FilePath, fn.Pos.Line)
+}
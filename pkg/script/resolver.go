@@ -1,4 +1,4 @@
-// resolver.go - Compile-time slot resolution for function parameters
+// resolver.go - Compile-time slot resolution for function parameters and locals
 //
 // After parsing, resolveProgram walks the AST and annotates Identifier nodes
 // that provably refer to a parameter of the enclosing function with a slot
@@ -7,6 +7,13 @@
 // annotated identifier reads e.env.fnScope.vals[slot] directly instead of
 // walking the scope chain with string compares.
 //
+// Locals declared at the top level of a function body (var declarations and
+// function definitions) are bound in the function environment right after the
+// parameters, so they get a predicted slot too. Unlike a parameter's, a
+// local's slot is only a prediction - the local may not be declared yet, or
+// extra named arguments may have taken the slot first - so the evaluator
+// checks the name stored there before using it (see Evaluator.slotRef).
+//
 // CORE LANGUAGE COMPONENT: This is part of the minimal core runtime.
 //
 // The resolver is deliberately conservative: it annotates a use only when the
@@ -15,20 +22,26 @@
 // (see Environment.Get/Set). Anything ambiguous stays un-annotated and takes
 // the existing name-based path. Punt rules:
 //
-//   - only parameters are slotted; they are bound before the body runs no
-//     matter how they were supplied (positional, named, or default)
+//   - only parameters and top-level locals are slotted; parameters are bound
+//     before the body runs no matter how they were supplied (positional,
+//     named, or default)
 //   - a parameter shadowed anywhere in the function body — by a var
 //     declaration, a for-loop variable, a catch variable, or a nested
 //     function definition name — is not slotted at all
+//   - a local that is also bound in a nested block (if, loop, try or catch
+//     body), or declared with const anywhere, is not slotted: an inner scope
+//     could hold its own binding, and const writes must be refused by Set
 //   - identifiers inside named-argument expressions are never slotted:
 //     object constructors evaluate them in a temp scope where earlier named
 //     args are visible (see callObject)
-//   - parameter default expressions are never slotted (evaluated in the
-//     closure environment, not the function environment)
-//   - nested function bodies get their own scope; outer parameters are not
-//     slottable inside them (closure capture stays name-based)
-//   - a parameter named "self" is never slotted (Get special-cases the name)
-//   - only the first smallScopeSize parameters get slots (inline storage)
+//   - parameter default expressions are never slotted (they run in the
+//     function environment while parameters are still being bound, so
+//     later slots are not filled yet)
+//   - nested function bodies get their own scope; outer parameters and
+//     locals are not slottable inside them (closure capture stays name-based)
+//   - a name "self" is never slotted (Get special-cases the name)
+//   - only the first smallScopeSize parameters and locals get slots (inline
+//     storage)
 package script
 
 // resolveProgram annotates parameter references in all function bodies.
@@ -42,6 +55,20 @@ type resolver struct {
 	// slottable params of the innermost function being walked: name -> slot+1
 	// (0 means dynamic); nil outside any function body
 	slots map[string]uint8
+	// predicted slots of its top-level locals: name -> slot+1
+	locals map[string]uint8
+}
+
+// defaultsContainFunction reports whether any parameter default defines a
+// function. Defaults are evaluated in the call env, so a closure created there
+// captures it just like one created in the body.
+func defaultsContainFunction(params []*Parameter) bool {
+	for _, p := range params {
+		if p.Default != nil && containsFunction([]Node{p.Default}) {
+			return true
+		}
+	}
+	return false
 }
 
 // containsFunction reports whether any node in the tree defines a function.
@@ -119,6 +146,10 @@ func containsFunction(nodes []Node) bool {
 			if containsFunction([]Node{x.Object}) {
 				return true
 			}
+		case *OptionalChain:
+			if containsFunction([]Node{x.Expr}) {
+				return true
+			}
 		case *ArrayLiteral:
 			if containsFunction(x.Elements) {
 				return true
@@ -160,10 +191,67 @@ func (r *resolver) resolveFunction(params []*Parameter, body []Node) {
 		slots[p.Name] = uint8(i + 1)
 	}
 
-	prev := r.slots
-	r.slots = slots
+	prev, prevLocals := r.slots, r.locals
+	r.slots, r.locals = slots, resolveLocals(params, body)
 	r.walkAll(body)
-	r.slots = prev
+	r.slots, r.locals = prev, prevLocals
+}
+
+// resolveLocals predicts the slots of the locals a function body declares at
+// its top level. They're bound in the function env in the order their first
+// declarations run, after the parameters.
+func resolveLocals(params []*Parameter, body []Node) map[string]uint8 {
+	if len(params) >= smallScopeSize {
+		return nil
+	}
+	isParam := make(map[string]bool, len(params))
+	for _, p := range params {
+		isParam[p.Name] = true
+	}
+	unslottable := map[string]bool{"self": true}
+	for _, n := range body {
+		// Bindings in nested blocks, found the same way as parameter shadows
+		switch n.(type) {
+		case *IfStatement, *WhileStatement, *ForStatement, *TryStatement:
+			collectShadows([]Node{n}, unslottable)
+		}
+	}
+
+	locals := make(map[string]uint8)
+	next := len(params)
+	for _, n := range body {
+		var name string
+		switch s := n.(type) {
+		case *FunctionDef:
+			name = s.Name
+		case *AssignStatement:
+			id, ok := s.Target.(*Identifier)
+			if !ok || !s.IsVarDeclaration {
+				continue
+			}
+			name = id.Name
+			if s.IsConst {
+				unslottable[name] = true
+			}
+		default:
+			continue
+		}
+		if _, seen := locals[name]; seen || isParam[name] {
+			// Rebinding updates the existing slot
+			continue
+		}
+		if next >= smallScopeSize {
+			break
+		}
+		next++
+		locals[name] = uint8(next)
+	}
+	for name := range locals {
+		if unslottable[name] {
+			delete(locals, name)
+		}
+	}
+	return locals
 }
 
 // collectShadows records every name a function body could bind in the
@@ -212,6 +300,9 @@ func (r *resolver) walk(n Node) {
 		if r.slots != nil {
 			x.slot = r.slots[x.Name]
 		}
+		if x.slot == 0 && r.locals != nil {
+			x.local = r.locals[x.Name]
+		}
 	case *IfStatement:
 		r.walk(x.Condition)
 		r.walkAll(x.Then)
@@ -238,10 +329,10 @@ func (r *resolver) walk(n Node) {
 		}
 		r.walkAll(x.Body)
 	case *FunctionDef:
-		x.noCapture = !containsFunction(x.Body)
+		x.noCapture = !containsFunction(x.Body) && !defaultsContainFunction(x.Parameters)
 		r.resolveFunction(x.Parameters, x.Body)
 	case *FunctionExpr:
-		x.noCapture = !containsFunction(x.Body)
+		x.noCapture = !containsFunction(x.Body) && !defaultsContainFunction(x.Parameters)
 		r.resolveFunction(x.Parameters, x.Body)
 	case *TryStatement:
 		r.walkAll(x.Block)
@@ -274,6 +365,8 @@ func (r *resolver) walk(n Node) {
 		r.walk(x.Index)
 	case *PropertyAccess:
 		r.walk(x.Object)
+	case *OptionalChain:
+		r.walk(x.Expr)
 	case *ArrayLiteral:
 		r.walkAll(x.Elements)
 	case *ObjectLiteral:
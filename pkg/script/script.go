@@ -1,7 +1,10 @@
 package script
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"sync"
@@ -89,6 +92,8 @@ type Interpreter struct {
 	debugHandler    DebugHandler                // Handler for debug events (set by CLI or other integrations)
 	debugHandlerMu  sync.Mutex                  // Protects debugHandler
 	debugSessionMu  sync.Mutex                  // Serializes debug REPL access (only one session at a time)
+	goModules       map[string]map[string]GoFunction // Go-backed modules for require(), keyed by module name
+	goModulesMu     sync.RWMutex                // Protects goModules
 
 	// I/O routing configuration (optional, set at spawn/run time)
 	IOConfig *IOConfig // If set, print/error/exit route to datastore instead of default handlers
@@ -129,6 +134,44 @@ func (i *Interpreter) GetScriptDir() string {
 	return i.scriptDir
 }
 
+// SetStepLimit caps how many evaluation steps (AST nodes visited) each
+// Execute or ExecuteModule call may take before it fails with a "step limit"
+// error; n <= 0 removes the limit. Use it to stop runaway loops in untrusted
+// scripts. Steps taken by parallel(), spawn() and run() work started by the
+// script count against the same budget.
+func (i *Interpreter) SetStepLimit(n int64) {
+	i.GetEvaluator().SetStepLimit(n)
+}
+
+// SetElementLimit caps how many elements each Execute or ExecuteModule call
+// may add to arrays and objects before failing with an "element limit"
+// error; n <= 0 removes the limit. It keeps an untrusted script from
+// allocating a giant array and exhausting the host's memory. Like the step
+// limit, parallel(), spawn() and run() work shares the script's budget.
+func (i *Interpreter) SetElementLimit(n int64) {
+	i.GetEvaluator().SetElementLimit(n)
+}
+
+// SetMaxCallDepth caps nested function calls for this interpreter's scripts,
+// overriding the process-wide MaxCallDepth; n <= 0 restores it.
+func (i *Interpreter) SetMaxCallDepth(n int) {
+	i.GetEvaluator().SetMaxCallDepth(n)
+}
+
+// SetTrace logs the script's execution to w as it runs: each line reached and
+// each function call with its arguments and return value (see trace.go).
+// nil turns tracing off. parallel(), spawn() and run() work is traced too.
+func (i *Interpreter) SetTrace(w io.Writer) {
+	i.GetEvaluator().SetTrace(w)
+}
+
+// SetProfile records the time spent in each function the script calls in p
+// (see profile.go); nil turns profiling off. parallel(), spawn() and run()
+// work records into p too.
+func (i *Interpreter) SetProfile(p *Profile) {
+	i.GetEvaluator().SetProfile(p)
+}
+
 // RegisterFunction registers a custom Go function callable from Duso scripts.
 //
 // This is how embedded applications extend Duso with domain-specific functionality.
@@ -168,11 +211,86 @@ func (i *Interpreter) RegisterObject(name string, methods map[string]GoFunction)
 	return nil
 }
 
+// RegisterModule registers a Go-backed module that scripts load with
+// require(name), e.g. RegisterModule("db", ...) makes require("db").query(...)
+// call your Go function. Registered modules take precedence over script
+// files of the same name. Each require() returns a fresh object, so one
+// script can't change what another script sees.
+func (i *Interpreter) RegisterModule(name string, functions map[string]GoFunction) error {
+	if name == "" {
+		return fmt.Errorf("module name cannot be empty")
+	}
+	exports := make(map[string]GoFunction, len(functions))
+	for fnName, fn := range functions {
+		exports[fnName] = fn
+	}
+
+	i.goModulesMu.Lock()
+	if i.goModules == nil {
+		i.goModules = make(map[string]map[string]GoFunction)
+	}
+	i.goModules[name] = exports
+	i.goModulesMu.Unlock()
+
+	// Core interpreters have no require() builtin (it lives in pkg/cli), so
+	// provide one that resolves registered modules and defers to the builtin
+	// for everything else.
+	i.GetEvaluator().RegisterFunction("require", i.requireModule)
+	return nil
+}
+
+// LookupModule returns a fresh exports object for a module registered with
+// RegisterModule.
+func (i *Interpreter) LookupModule(name string) (Value, bool) {
+	i.goModulesMu.RLock()
+	functions, ok := i.goModules[name]
+	i.goModulesMu.RUnlock()
+	if !ok {
+		return NewNil(), false
+	}
+	exports := make(map[string]Value, len(functions))
+	for fnName, fn := range functions {
+		exports[fnName] = NewGoFunction(fn)
+	}
+	return NewObject(exports), true
+}
+
+// requireModule is the require() installed by RegisterModule
+func (i *Interpreter) requireModule(evaluator *Evaluator, args map[string]any) (any, error) {
+	name, ok := args["0"].(string)
+	if !ok {
+		if n, ok := args["filename"].(string); ok {
+			name = n
+		}
+	}
+	if exports, ok := i.LookupModule(name); ok {
+		return exports, nil
+	}
+	if builtin, ok := evaluator.builtins["require"]; ok {
+		return builtin(evaluator, args)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("require() requires a filename argument")
+	}
+	return nil, fmt.Errorf("cannot find module '%s'", name)
+}
+
 // Execute executes script source code
 func (i *Interpreter) Execute(source string) (string, error) {
+	return i.ExecuteContext(context.Background(), source)
+}
+
+// ExecuteContext executes script source code, stopping with a
+// *CancelledError (which wraps ctx.Err()) once ctx is cancelled or its
+// deadline passes. The script can't catch the cancellation with try/catch.
+// parallel() branches and run() scripts started by it are cancelled too.
+func (i *Interpreter) ExecuteContext(ctx context.Context, source string) (string, error) {
 	if i.evaluator == nil {
 		i.evaluator = NewEvaluator()
 	}
+	if err := ctx.Err(); err != nil {
+		return "", &CancelledError{Err: err}
+	}
 
 	// Tokenize
 	lexer := NewLexer(source)
@@ -198,15 +316,18 @@ func (i *Interpreter) Execute(source string) (string, error) {
 		Col:      1,
 		Reason:   "main",
 	}
-	ctx := &RequestContext{
-		Frame:       frame,
-		Interpreter: i,
-		Evaluator:   i.evaluator,
+	reqCtx := &RequestContext{
+		Frame:        frame,
+		Interpreter:  i,
+		Evaluator:    i.evaluator,
+		OutputWriter: i.OutputWriter,
 	}
-	SetRequestContextWithData(gid, ctx, nil)
-	defer ClearRequestContext(gid)
+	defer swapRequestContext(gid, reqCtx)()
 
 	// Evaluate
+	i.evaluator.resetUsage()
+	i.evaluator.SetContext(ctx)
+	defer i.evaluator.SetContext(nil)
 	_, err = i.evaluator.Eval(program)
 	if err != nil {
 		return "", err
@@ -215,6 +336,32 @@ func (i *Interpreter) Execute(source string) (string, error) {
 	return "", nil
 }
 
+// ExecuteWithResult executes script source code and returns the values the
+// script passed to exit(), converted to plain Go values (float64, string,
+// bool, []any, map[string]any). A script that finishes without calling
+// exit() returns no values. Decode a value into a struct with Unmarshal:
+//
+//	values, err := interp.ExecuteWithResult(`exit({port = 8080})`)
+//	var cfg Config
+//	err = script.Unmarshal(values[0], &cfg)
+func (i *Interpreter) ExecuteWithResult(source string) ([]any, error) {
+	return i.ExecuteWithResultContext(context.Background(), source)
+}
+
+// ExecuteWithResultContext is ExecuteWithResult with cancellation, as with
+// ExecuteContext.
+func (i *Interpreter) ExecuteWithResultContext(ctx context.Context, source string) ([]any, error) {
+	_, err := i.ExecuteContext(ctx, source)
+	var exit *ExitExecution
+	if errors.As(err, &exit) {
+		return exit.Values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []any{}, nil
+}
+
 // ExecuteNode executes a single AST node.
 // Used by debugger for statement-by-statement execution.
 // Maintains evaluator state between calls.
@@ -381,6 +528,39 @@ func (i *Interpreter) GetDebugSessionMutex() *sync.Mutex {
 	return &i.debugSessionMu
 }
 
+// CaptureOutput runs fn with print() output redirected into a buffer and
+// returns everything printed, along with fn's error. Typical use:
+//
+//	out, err := interp.CaptureOutput(func() error {
+//		_, err := interp.Execute(source)
+//		return err
+//	})
+//
+// Output from parallel() branches is captured too; spawn()ed scripts that
+// outlive fn are not. The previous OutputWriter is restored afterwards.
+//
+// CaptureOutput swaps the interpreter's OutputWriter field, so it isn't safe
+// to call while another goroutine runs a script on the same Interpreter:
+// that script's output could land in the capture, or the swap could race
+// with its read of OutputWriter. Give each goroutine its own Interpreter.
+func (i *Interpreter) CaptureOutput(fn func() error) (string, error) {
+	var mu sync.Mutex
+	var buf strings.Builder
+	prev := i.OutputWriter
+	i.OutputWriter = func(msg string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		buf.WriteString(msg)
+		return nil
+	}
+	defer func() { i.OutputWriter = prev }()
+
+	err := fn()
+	mu.Lock()
+	defer mu.Unlock()
+	return buf.String(), err
+}
+
 // ExecuteModule executes script source in an isolated module scope and returns the result value.
 // This is used by require() to load modules in isolation. The module's variables
 // don't leak into the caller's scope. The last expression value (or explicit return) is the export.
@@ -403,7 +583,17 @@ func (i *Interpreter) ExecuteModule(source string) (Value, error) {
 		return NewNil(), err
 	}
 
+	// Register a request context so builtins see this interpreter's
+	// OutputWriter, as they do under Execute
+	defer swapRequestContext(GetGoroutineID(), &RequestContext{
+		Frame:        &InvocationFrame{Filename: i.GetFilePath(), Line: 1, Col: 1, Reason: "main"},
+		Interpreter:  i,
+		Evaluator:    i.evaluator,
+		OutputWriter: i.OutputWriter,
+	})()
+
 	// Evaluate in isolated scope
+	i.evaluator.resetUsage()
 	return i.evaluator.EvalModule(program)
 }
 
@@ -418,6 +608,7 @@ func (i *Interpreter) ExecuteModuleProgram(program *Program) (Value, error) {
 	// Always create a fresh evaluator for the module (not cached)
 	// This prevents race conditions when multiple goroutines execute modules concurrently
 	moduleEval := NewEvaluator()
+	moduleEval.InheritLimits(i.evaluator)
 	return moduleEval.EvalModule(program)
 }
 
@@ -572,6 +763,37 @@ func (i *Interpreter) CacheProgram(key string, program *Program) {
 	}
 }
 
+// InvalidateCache drops cached ASTs and module results so the next
+// ParseScript, ParseScriptFile or require() reloads them from disk, even if
+// the file's mtime hasn't moved on. With no paths, every file-backed entry is
+// dropped; inline code registered with CacheProgram is kept since it has no
+// file to reload from.
+func (i *Interpreter) InvalidateCache(paths ...string) {
+	i.parseMutex.Lock()
+	if len(paths) == 0 {
+		for key := range i.parseCache {
+			if !strings.HasPrefix(key, "<inline-") {
+				delete(i.parseCache, key)
+			}
+		}
+	} else {
+		for _, path := range paths {
+			delete(i.parseCache, path)
+		}
+	}
+	i.parseMutex.Unlock()
+
+	i.moduleCacheMu.Lock()
+	if len(paths) == 0 {
+		i.moduleCache = nil
+	} else {
+		for _, path := range paths {
+			delete(i.moduleCache, path)
+		}
+	}
+	i.moduleCacheMu.Unlock()
+}
+
 // GetModuleCache retrieves a cached module value by absolute path with mtime validation.
 // Returns (value, mtime, found). Caller should validate mtime if caching should expire.
 // Used by require() to implement module caching with file change detection.
@@ -685,23 +907,7 @@ func FormatErrorWithStack(err *DusoError) string {
 	// Add call stack if present
 	if len(err.CallStack) > 0 {
 		buf.WriteString("\n\nCall stack:")
-		// Print in reverse order (most recent call last)
-		for i := len(err.CallStack) - 1; i >= 0; i-- {
-			frame := err.CallStack[i]
-			buf.WriteString("\n  at ")
-			buf.WriteString(frame.FunctionName)
-			buf.WriteString(" (")
-			if frame.FilePath != "" {
-				buf.WriteString(frame.FilePath)
-				buf.WriteByte(':')
-			}
-			buf.WriteString(strconv.Itoa(frame.Position.Line))
-			if frame.Position.Column > 0 {
-				buf.WriteByte(':')
-				buf.WriteString(strconv.Itoa(frame.Position.Column))
-			}
-			buf.WriteByte(')')
-		}
+		writeCallStack(&buf, err.CallStack)
 	}
 
 	return buf.String()
@@ -297,6 +297,353 @@ func TestFunctionDefaults(t *testing.T) {
 	}
 }
 
+// TestDefaultsSeeEarlierParameters tests that defaults are evaluated in the call scope
+func TestDefaultsSeeEarlierParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name: "default references earlier parameter",
+			script: `
+				function f(a, b = a + 1) return [a, b] end
+				return [f(1), f(1, 5), f(a = 10)]
+			`,
+			expected: "[[1, 2], [1, 5], [10, 11]]",
+		},
+		{
+			name: "chained defaults",
+			script: `
+				function f(a, b = a * 2, c = a + b) return c end
+				return [f(1), f(1, c = 0), f(b = 10, a = 1)]
+			`,
+			expected: "[3, 0, 11]",
+		},
+		{
+			name: "default not evaluated when supplied",
+			script: `
+				calls = [0]
+				function tick() calls[0] = calls[0] + 1 return calls[0] end
+				function f(x = tick()) return x end
+				f(1)
+				f(x = 2)
+				return [f(), calls[0]]
+			`,
+			expected: "[1, 1]",
+		},
+		{
+			name: "recursive default",
+			script: `
+				function sum(n, acc = n) if n <= 1 then return acc end return sum(n - 1, acc + n - 1) end
+				return sum(4)
+			`,
+			expected: "10",
+		},
+		{
+			name: "closure created in default",
+			script: `
+				function mk(n, get = function() return n end) return get end
+				a = mk(1)
+				b = mk(2)
+				return [a(), b()]
+			`,
+			expected: "[1, 2]",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewInterpreter().ExecuteModule(tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got.String())
+			}
+		})
+	}
+}
+
+// TestNamedArguments tests binding call arguments to user function parameters by name
+func TestNamedArguments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name: "named only, out of order",
+			script: `
+				function f(a, b, c) return [a, b, c] end
+				return f(c = 3, a = 1, b = 2)
+			`,
+			expected: "[1, 2, 3]",
+		},
+		{
+			name: "mixed positional and named",
+			script: `
+				function f(a, b = 10, c = 20) return [a, b, c] end
+				return f(1, c = 3)
+			`,
+			expected: "[1, 10, 3]",
+		},
+		{
+			name: "named wins over positional",
+			script: `
+				function f(a, b) return [a, b] end
+				return f(1, 2, a = 9)
+			`,
+			expected: "[9, 2]",
+		},
+		{
+			name: "unknown name becomes a local",
+			script: `
+				function f(a) return [a, extra] end
+				return f(1, extra = 2)
+			`,
+			expected: "[1, 2]",
+		},
+		{
+			name: "function expression and method",
+			script: `
+				f = function(x, y) return x - y end
+				obj = {scale = function(n, by = 1) return n * by end}
+				return [f(y = 1, x = 5), obj.scale(by = 3, n = 2)]
+			`,
+			expected: "[4, 6]",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewInterpreter().ExecuteModule(tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got.String())
+			}
+		})
+	}
+
+	// Go callers may pass keys beyond the parameters, as at a script call site
+	fn, err := NewInterpreter().ExecuteModule(`return function(a) return [a, extra] end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewEvaluator().CallFunction(fn, map[string]Value{"0": NewNumber(1), "extra": NewString("x")})
+	if err != nil || got.String() != `[1, "x"]` {
+		t.Fatalf("CallFunction with an extra key = %v, %v", got, err)
+	}
+}
+
+// TestVariadicParameters tests "...rest" parameters collecting extra arguments
+func TestVariadicParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name: "rest collects remaining arguments",
+			script: `
+				function f(a, ...rest) return [a, rest] end
+				return [f(1), f(1, 2), f(1, 2, 3)]
+			`,
+			expected: "[[1, []], [1, [2]], [1, [2, 3]]]",
+		},
+		{
+			name: "rest as only parameter",
+			script: `
+				fn = function(...all) return all end
+				return [fn(), fn("a", "b")]
+			`,
+			expected: `[[], ["a", "b"]]`,
+		},
+		{
+			name: "rest supplied by name",
+			script: `
+				function f(a, ...rest) return rest end
+				return f(1, rest = [7, 8])
+			`,
+			expected: "[7, 8]",
+		},
+		{
+			name: "rest with defaults before it",
+			script: `
+				function f(a, b = 10, ...rest) return [a, b, rest] end
+				return [f(1), f(1, 2, 3)]
+			`,
+			expected: "[[1, 10, []], [1, 2, [3]]]",
+		},
+		{
+			name: "fresh rest array per call",
+			script: `
+				function f(...rest)
+					rest[0] = "changed"
+					return rest
+				end
+				f()
+				return f()
+			`,
+			expected: `["changed"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewInterpreter().ExecuteModule(tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got.String())
+			}
+		})
+	}
+}
+
+// TestVariadicParameterErrors tests invalid rest parameter declarations
+func TestVariadicParameterErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{name: "rest not last", script: `function f(...rest, a) end`},
+		{name: "rest with default", script: `function f(...rest = []) end`},
+		{name: "missing rest name", script: `function f(...) end`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewInterpreter().Execute(tt.script); err == nil {
+				t.Fatalf("expected parse error")
+			}
+		})
+	}
+}
+
+// TestOptionalChaining tests ?. links ending a chain with nil
+func TestOptionalChaining(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name: "present links",
+			script: `
+				data = {user = {name = "ada", tags = ["x"]}}
+				return [data?.user?.name, data.user?.tags?.[0]]
+			`,
+			expected: `["ada", "x"]`,
+		},
+		{
+			name: "nil link ends the chain",
+			script: `
+				data = {}
+				return [data.user?.name, data.user?.name.first, data.user?.tags[0], data.user?.greet()]
+			`,
+			expected: "[nil, nil, nil, nil]",
+		},
+		{
+			name: "method call through ?.",
+			script: `
+				obj = {name = "ada", greet = function() return "hi " + self.name end}
+				return obj?.greet()
+			`,
+			expected: "hi ada",
+		},
+		{
+			name: "index not evaluated after nil",
+			script: `
+				calls = [0]
+				function tick() calls[0] = calls[0] + 1 return 0 end
+				a = nil
+				b = a?.[tick()]
+				return [b, calls[0]]
+			`,
+			expected: "[nil, 0]",
+		},
+		{
+			name: "chain as an argument",
+			script: `
+				function first(items) return items[0] end
+				a = nil
+				return [first(a?.items or [0]), a?.x == nil]
+			`,
+			expected: "[0, true]",
+		},
+		{
+			name: "ternary with a float is not a chain",
+			script: `
+				return true ?.5 : 1
+			`,
+			expected: "0.5",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewInterpreter().ExecuteModule(tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got.String())
+			}
+		})
+	}
+}
+
+// TestOptionalChainingErrors tests that ?. only guards against nil
+func TestOptionalChainingErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{name: "assign through ?.", script: `a = {} a?.b = 1`},
+		{name: "compound assign through ?.", script: `a = {b = 1} a?.b += 1`},
+		{name: "increment through ?.", script: `a = {b = 1} a?.b++`},
+		{name: "missing property name", script: `a = {} x = a?.`},
+		{name: "out of bounds index", script: `a = [1] x = a?.[5]`},
+		{name: "non-nil link still indexes", script: `a = {b = nil} x = a?.b[0]`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewInterpreter().Execute(tt.script); err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
+
 // TestVarKeyword tests local variable declarations with var keyword
 func TestVarKeyword(t *testing.T) {
 	t.Parallel()
@@ -362,6 +709,96 @@ func TestVarKeyword(t *testing.T) {
 	}
 }
 
+// TestConstKeyword tests const declarations and reassignment errors
+func TestConstKeyword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		script      string
+		expectError bool
+	}{
+		{
+			name:   "const can be read",
+			script: `const MAX = 10
+				if MAX != 10 then throw("wrong value") end`,
+		},
+		{
+			name:        "assignment to const",
+			script:      `const MAX = 10
+				MAX = 5`,
+			expectError: true,
+		},
+		{
+			name:        "compound assignment to const",
+			script:      `const MAX = 10
+				MAX += 1`,
+			expectError: true,
+		},
+		{
+			name:        "increment of const",
+			script:      `const MAX = 10
+				MAX++`,
+			expectError: true,
+		},
+		{
+			name:        "var redeclaring const in same scope",
+			script:      `const MAX = 10
+				var MAX = 5`,
+			expectError: true,
+		},
+		{
+			name:        "assignment to outer const from function",
+			script:      `const MAX = 10
+				function f() MAX = 5 end
+				f()`,
+			expectError: true,
+		},
+		{
+			name: "var shadows const in inner function",
+			script: `const MAX = 10
+				function f()
+					var MAX = 5
+					MAX = 6
+					return MAX
+				end
+				if f() != 6 or MAX != 10 then throw("shadowing failed") end`,
+		},
+		{
+			name: "const in loop body rebinds each iteration",
+			script: `total = 0
+				for i = 1, 3 do
+					const sq = i * i
+					total += sq
+				end
+				if total != 14 then throw("wrong total") end`,
+		},
+		{
+			name: "const object contents stay mutable",
+			script: `const cfg = {a = 1}
+				cfg.a = 2
+				if cfg.a != 2 then throw("property not updated") end`,
+		},
+		{
+			name:        "const as function parameter",
+			script:      `function f(n) const n = 1 end
+				f(2)`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewInterpreter().Execute(tt.script)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error=%v, got error=%v", tt.expectError, err)
+			}
+		})
+	}
+}
+
 // TestStringOperations tests string concatenation and operations
 func TestStringOperations(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,263 @@
+package script
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format specifiers in template placeholders, such as {{price:.2f}} or
+// {{name:>10}}. The spec follows Python's format mini-language:
+//
+//	[[fill]align][+][0][width][,][.precision][type]
+//
+// align is < (left), > (right) or ^ (center). + shows the sign of positive
+// numbers, 0 pads numbers with zeros after the sign, and , groups
+// thousands. type is one of:
+//
+//	s  display form, as {{expr}} prints it (precision truncates)
+//	d  whole number
+//	f  fixed point, precision decimals (default 6)
+//	e  exponent notation
+//	%  percentage: the number times 100, fixed point, then %
+//	x X o b  hex, upper-case hex, octal, binary
+//
+// Without a type, numbers take precision as decimal places and everything
+// else is shown as-is. Numbers align right by default, other values left.
+
+// formatSpec is a parsed placeholder format
+type formatSpec struct {
+	text      string
+	fill      rune
+	align     byte // '<', '>', '^', or 0 for the default
+	plus      bool
+	zero      bool
+	width     int
+	group     bool
+	precision int // -1 when not given
+	verb      byte
+}
+
+// splitFormatSpec splits "expr:spec" at the first colon outside strings
+// and brackets that doesn't belong to a ternary
+func splitFormatSpec(expr string) (string, string, bool) {
+	depth, ternaries := 0, 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if quote != 0 {
+			if c == '\\' && quote != '~' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'', '~':
+			quote = c
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				ternaries++
+			}
+		case ':':
+			if depth > 0 {
+				continue
+			}
+			if ternaries > 0 {
+				ternaries--
+				continue
+			}
+			return expr[:i], expr[i+1:], true
+		}
+	}
+	return expr, "", false
+}
+
+func isFormatAlign(r rune) bool {
+	return r == '<' || r == '>' || r == '^'
+}
+
+// parseFormatSpec parses the part of a placeholder after the colon
+func parseFormatSpec(spec string) (*formatSpec, error) {
+	f := &formatSpec{text: spec, fill: ' ', precision: -1}
+	r := []rune(spec)
+	i := 0
+
+	if len(r) >= 2 && isFormatAlign(r[1]) {
+		f.fill, f.align = r[0], byte(r[1])
+		i = 2
+	} else if len(r) >= 1 && isFormatAlign(r[0]) {
+		f.align = byte(r[0])
+		i = 1
+	}
+	if i < len(r) && r[i] == '+' {
+		f.plus = true
+		i++
+	}
+	if i < len(r) && r[i] == '0' {
+		f.zero = true
+		i++
+	}
+	digits := func() (int, bool) {
+		start := i
+		for i < len(r) && r[i] >= '0' && r[i] <= '9' {
+			i++
+		}
+		n, err := strconv.Atoi(string(r[start:i]))
+		return n, err == nil
+	}
+	if n, ok := digits(); ok {
+		f.width = n
+	}
+	if i < len(r) && r[i] == ',' {
+		f.group = true
+		i++
+	}
+	if i < len(r) && r[i] == '.' {
+		i++
+		n, ok := digits()
+		if !ok {
+			return nil, fmt.Errorf("invalid format '%s': expected digits after '.'", spec)
+		}
+		f.precision = n
+	}
+	if i < len(r) && strings.ContainsRune("sdfe%xXob", r[i]) {
+		f.verb = byte(r[i])
+		i++
+	}
+	if i != len(r) {
+		return nil, fmt.Errorf("invalid format '%s'", spec)
+	}
+	return f, nil
+}
+
+// format renders a value according to the spec
+func (f *formatSpec) format(val Value) (string, error) {
+	if f.verb == 's' || (f.verb == 0 && !val.IsNumber()) {
+		s := ValueForDisplay(val)
+		if f.precision >= 0 && utf8.RuneCountInString(s) > f.precision {
+			s = string([]rune(s)[:f.precision])
+		}
+		return f.pad(s, "", false), nil
+	}
+	if !val.IsNumber() {
+		return "", fmt.Errorf("format '%s' requires a number, got %s", f.text, val.Type)
+	}
+
+	n := val.AsNumber()
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return f.pad(ValueForDisplay(val), "", true), nil
+	}
+	digits := f.formatDigits(math.Abs(n))
+
+	// No "-0.00" when rounding reaches zero
+	sign := ""
+	if n < 0 && strings.Trim(digits, "0.e+-%") != "" {
+		sign = "-"
+	} else if f.plus {
+		sign = "+"
+	}
+	return f.pad(digits, sign, true), nil
+}
+
+// formatDigits formats a non-negative number without its sign
+func (f *formatSpec) formatDigits(a float64) string {
+	prec := f.precision
+	switch f.verb {
+	case 'd':
+		return groupThousands(strconv.FormatFloat(math.Round(a), 'f', 0, 64), f.group)
+	case 'x', 'X', 'o', 'b':
+		base := map[byte]int{'x': 16, 'X': 16, 'o': 8, 'b': 2}[f.verb]
+		s := strconv.FormatUint(uint64(math.Round(a)), base)
+		if f.verb == 'X' {
+			s = strings.ToUpper(s)
+		}
+		return s
+	case 'e':
+		if prec < 0 {
+			prec = 6
+		}
+		return strconv.FormatFloat(a, 'e', prec, 64)
+	case '%':
+		if prec < 0 {
+			prec = 6
+		}
+		return formatFixed(a*100, prec, f.group) + "%"
+	case 'f':
+		if prec < 0 {
+			prec = 6
+		}
+	}
+	return formatFixed(a, prec, f.group)
+}
+
+// formatFixed formats with prec decimals (-1 for the shortest exact form),
+// rounding half away from zero like round()
+func formatFixed(a float64, prec int, group bool) string {
+	if prec >= 0 {
+		scale := math.Pow(10, float64(prec))
+		if a*scale < 1<<53 {
+			a = math.Round(a*scale) / scale
+		}
+	}
+	s := strconv.FormatFloat(a, 'f', prec, 64)
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	intPart = groupThousands(intPart, group)
+	if hasFrac {
+		return intPart + "." + frac
+	}
+	return intPart
+}
+
+func groupThousands(digits string, group bool) string {
+	if !group || len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+// pad fills s out to the spec's width. Numbers with the 0 flag and no
+// explicit alignment are zero-padded between the sign and the digits.
+func (f *formatSpec) pad(s, sign string, numeric bool) string {
+	n := f.width - utf8.RuneCountInString(sign+s)
+	if n <= 0 {
+		return sign + s
+	}
+	if f.zero && f.align == 0 {
+		if numeric {
+			return sign + strings.Repeat("0", n) + s
+		}
+		return s + strings.Repeat("0", n)
+	}
+
+	fill := string(f.fill)
+	align := f.align
+	if align == 0 {
+		align = '<'
+		if numeric {
+			align = '>'
+		}
+	}
+	s = sign + s
+	switch align {
+	case '>':
+		return strings.Repeat(fill, n) + s
+	case '^':
+		return strings.Repeat(fill, n/2) + s + strings.Repeat(fill, n-n/2)
+	}
+	return s + strings.Repeat(fill, n)
+}
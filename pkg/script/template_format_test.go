@@ -0,0 +1,53 @@
+package script
+
+import "testing"
+
+func TestTemplateFormatSpecs(t *testing.T) {
+	t.Parallel()
+
+	bindings := map[string]Value{
+		"price": NewNumber(1234.5),
+		"name":  NewString("Zoë"),
+		"n":     NewNumber(-7),
+		"ok":    NewBool(true),
+	}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{{price}}", "1234.5"},
+		{"{{price:.2f}}", "1234.50"},
+		{"{{price:,.2f}}", "1,234.50"},
+		{"[{{price:>10.1f}}]", "[    1234.5]"},
+		{"{{price:010.1f}}", "00001234.5"},
+		{"{{n:05d}}", "-0007"},
+		{"{{n:+d}} {{price:+.0f}}", "-7 +1235"},
+		{"{{0.125:.2f}} {{-0.001:.2f}}", "0.13 0.00"},
+		{"{{0.256:.1%}}", "25.6%"},
+		{"{{255:x}} {{255:X}} {{8:o}} {{5:04b}}", "ff FF 10 0101"},
+		{"{{12345.678:.2e}}", "1.23e+04"},
+		{"[{{name:>6}}] [{{name:<6}}] [{{name:^7}}]", "[   Zoë] [Zoë   ] [  Zoë  ]"},
+		{"[{{name:*^7}}] [{{name:.2}}] [{{ok:>5}}]", "[**Zoë**] [Zo] [ true]"},
+		{"[{{n:4}}] [{{price:s}}]", "[  -7] [1234.5]"},
+		{"{{ok ? 'yes' : 'no'}}", "yes"},
+		{"[{{ok ? 'yes' : 'no':>4}}] [{{(ok ? 1 : 2):03d}}]", "[ yes] [001]"},
+		{"{{'a:b'}} {{[1, 2][0]:>2}}", "a:b  1"},
+		{"{{missing:>5}}", "{{missing:>5}}"},
+	}
+	for _, tt := range tests {
+		got, err := NewEvaluator().EvaluateTemplate(tt.template, bindings)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"{{price:zz}}", "{{price:.}}", "{{name:.2f}}", "{{name:d}}"} {
+		if _, err := NewEvaluator().EvaluateTemplate(bad, bindings); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
@@ -39,6 +39,7 @@ const (
 	TOK_OR
 	TOK_NOT
 	TOK_VAR
+	TOK_CONST
 	TOK_RAW
 
 	// Operators
@@ -71,8 +72,10 @@ const (
 	TOK_RBRACE
 	TOK_COMMA
 	TOK_DOT
+	TOK_ELLIPSIS
 	TOK_COLON
 	TOK_QUESTION
+	TOK_QUESTIONDOT
 )
 
 var tokenNames = map[TokenType]string{
@@ -105,6 +108,7 @@ var tokenNames = map[TokenType]string{
 	TOK_OR:        "OR",
 	TOK_NOT:       "NOT",
 	TOK_VAR:       "VAR",
+	TOK_CONST:     "CONST",
 	TOK_RAW:       "RAW",
 	TOK_PLUS:      "+",
 	TOK_MINUS:     "-",
@@ -133,8 +137,10 @@ var tokenNames = map[TokenType]string{
 	TOK_RBRACE:    "}",
 	TOK_COMMA:     ",",
 	TOK_DOT:       ".",
+	TOK_ELLIPSIS:  "...",
 	TOK_COLON:     ":",
 	TOK_QUESTION:  "?",
+	TOK_QUESTIONDOT: "?.",
 }
 
 // String returns a human-readable name for the TokenType
@@ -184,6 +190,7 @@ var keywords = map[string]TokenType{
 	"or":        TOK_OR,
 	"not":       TOK_NOT,
 	"var":       TOK_VAR,
+	"const":     TOK_CONST,
 	"raw":       TOK_RAW,
 	"true":      TOK_TRUE,
 	"false":     TOK_FALSE,
@@ -196,7 +203,7 @@ var reservedNames = map[string]bool{
 	"if": true, "then": true, "else": true, "elseif": true, "end": true,
 	"while": true, "do": true, "for": true, "in": true, "function": true,
 	"return": true, "break": true, "continue": true, "try": true, "catch": true,
-	"and": true, "or": true, "not": true, "var": true, "raw": true,
+	"and": true, "or": true, "not": true, "var": true, "const": true, "raw": true,
 	"true": true, "false": true, "nil": true, "self": true,
 }
 
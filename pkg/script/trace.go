@@ -0,0 +1,123 @@
+// trace.go - Execution tracing (duso -trace)
+//
+// With a trace writer attached, the evaluator logs where it is as it runs:
+// the file and line each time execution moves to a new line, and every
+// script function call with its arguments and its return value (or error),
+// indented by call depth. Like the limits in limits.go it is off by default
+// and shares their flag, so it costs Eval nothing extra.
+//
+// Each entry is written with one Write call, so parallel() branches and
+// spawned scripts sharing a writer such as os.Stderr don't split lines.
+package script
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// traceValueMax caps how much of a value a trace line shows
+const traceValueMax = 60
+
+// SetTrace makes the evaluator log its execution to w; nil turns tracing off
+func (e *Evaluator) SetTrace(w io.Writer) {
+	e.trace = w
+	e.traceFile, e.traceLine = "", 0
+	e.updateLimited()
+}
+
+// Trace returns the writer set with SetTrace, or nil
+func (e *Evaluator) Trace() io.Writer {
+	return e.trace
+}
+
+// traceNode logs the node's line if execution just moved there. Only
+// statements and calls are checked; every other expression sits on the line
+// of the statement it belongs to.
+func (e *Evaluator) traceNode(node Node) {
+	var pos Position
+	switch n := node.(type) {
+	case *IfStatement:
+		pos = n.Pos
+	case *WhileStatement:
+		pos = n.Pos
+	case *ForStatement:
+		pos = n.Pos
+	case *FunctionDef:
+		pos = n.Pos
+	case *TryStatement:
+		pos = n.Pos
+	case *ReturnStatement:
+		pos = n.Pos
+	case *BreakStatement:
+		pos = n.Pos
+	case *ContinueStatement:
+		pos = n.Pos
+	case *AssignStatement:
+		pos = n.Pos
+	case *CompoundAssignStatement:
+		pos = n.Pos
+	case *PostIncrementStatement:
+		pos = n.Pos
+	case *CallExpr:
+		pos = n.Pos
+	default:
+		return
+	}
+	if !pos.IsValid() || (pos.Line == e.traceLine && e.ctx.FilePath == e.traceFile) {
+		return
+	}
+	e.traceFile, e.traceLine = e.ctx.FilePath, pos.Line
+	e.traceWrite(fmt.Sprintf("%s:%d", e.ctx.FilePath, pos.Line))
+}
+
+// traceCall logs a call to fn once its parameters are bound in fnEnv
+func (e *Evaluator) traceCall(fn *ScriptFunction, fnEnv *Environment) {
+	args := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		val, _ := fnEnv.Get(param.Name)
+		args[i] = param.Name + " = " + traceValue(val)
+	}
+	e.traceWrite(fmt.Sprintf("call %s(%s)", traceFuncName(fn), strings.Join(args, ", ")))
+	e.traceLine = 0 // log the first line of the body
+}
+
+// traceReturn logs what a call to fn returned
+func (e *Evaluator) traceReturn(fn *ScriptFunction, result Value, err error) {
+	if err != nil {
+		msg := err.Error()
+		if dusoErr, ok := err.(*DusoError); ok {
+			msg = fmt.Sprintf("%v", dusoErr.Message)
+		}
+		e.traceWrite(fmt.Sprintf("error %s: %s", traceFuncName(fn), msg))
+	} else {
+		e.traceWrite(fmt.Sprintf("return %s: %s", traceFuncName(fn), traceValue(result)))
+	}
+	e.traceLine = 0 // log the caller's line again
+}
+
+// traceWrite writes one trace entry, indented by call depth
+func (e *Evaluator) traceWrite(entry string) {
+	io.WriteString(e.trace, "[trace] "+strings.Repeat("  ", e.callDepth)+entry+"\n")
+}
+
+func traceFuncName(fn *ScriptFunction) string {
+	if fn.Name == "" {
+		return "function"
+	}
+	return fn.Name
+}
+
+// traceValue formats a value in Duso syntax, cut short if it's long
+func traceValue(val Value) string {
+	s := ValueToDusoString(val)
+	if len(s) > traceValueMax {
+		cut := traceValueMax - 3
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut] + "..."
+	}
+	return strings.ReplaceAll(s, "\n", "\\n")
+}
@@ -19,6 +19,7 @@ package script
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -182,12 +183,14 @@ func NewRegex(pattern string, compiled *regexp.Regexp) Value {
 type ScriptFunction struct {
 	Name       string
 	FilePath   string        // File where function was defined (for error reporting)
+	Pos        Position      // Where the definition starts
 	Parameters []*Parameter
 	Body       []Node
 	Closure    *Environment
 	paramFlags uint64          // precomputed MarkParameter state, copied onto each call env
 	paramMap   map[string]bool // uncommon param names; shared read-only across calls
 	poolable   bool            // body creates no closures; call envs may be reused (see resolver.go)
+	variadic   bool            // last parameter is "...rest"; precomputed by initParams
 }
 
 // initParams precomputes the parameter-marking state so call paths copy two
@@ -204,6 +207,20 @@ func (f *ScriptFunction) initParams() {
 			f.paramMap[p.Name] = true
 		}
 	}
+	if n := len(f.Parameters); n > 0 && f.Parameters[n-1].Variadic {
+		f.variadic = true
+	}
+}
+
+// paramIndex returns the position of the parameter called name, or -1 for a
+// named argument that binds to no parameter
+func (f *ScriptFunction) paramIndex(name string) int {
+	for i, p := range f.Parameters {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
 }
 
 // Type checking
@@ -433,6 +450,20 @@ func (v Value) String() string {
 // This is used to convert script values to Go types for external functions.
 // For arrays, returns *[]Value directly to allow in-place mutations by builtins.
 func ValueToInterface(v Value) any {
+	return valueToInterfacePath(v, nil)
+}
+
+// containerID identifies the array or map behind a value's data, for
+// spotting containers that hold themselves
+func containerID(data any) uintptr {
+	return reflect.ValueOf(data).Pointer()
+}
+
+// valueToInterfacePath converts v; path maps the objects being converted
+// around it to their results, so an object that contains itself becomes a
+// map that contains itself rather than endless recursion. path is nil until
+// a nested object turns up, so flat values don't allocate it.
+func valueToInterfacePath(v Value, path map[uintptr]map[string]any) any {
 	switch v.Type {
 	case VAL_NIL:
 		return nil
@@ -447,10 +478,23 @@ func ValueToInterface(v Value) any {
 		return v.Data.(*[]Value)
 	case VAL_OBJECT:
 		obj := v.Data.(map[string]Value)
+		id := containerID(obj)
+		if done, ok := path[id]; ok {
+			return done
+		}
 		result := make(map[string]any)
+		if path != nil {
+			path[id] = result
+			defer delete(path, id)
+		}
 		for k, val := range obj {
-			result[k] = ValueToInterface(val)
+			if val.Type == VAL_OBJECT && path == nil {
+				path = map[uintptr]map[string]any{id: result}
+			}
+			result[k] = valueToInterfacePath(val, path)
 		}
+		CopyOrder(obj, result)
+		copyFrozen(obj, result)
 		return result
 	case VAL_FUNCTION:
 		return &ValueRef{Val: v} // Wrap function so it survives the any conversion
@@ -480,6 +524,12 @@ func interfaceToValue(i any) Value {
 // InterfaceToValue converts Go any to script values.
 // This is used to convert Go values to script Values for builtins.
 func InterfaceToValue(i any) Value {
+	return interfaceToValuePath(i, nil)
+}
+
+// interfaceToValuePath converts i, turning a map that contains itself back
+// into an object that contains itself (see valueToInterfacePath)
+func interfaceToValuePath(i any, path map[uintptr]map[string]Value) Value {
 	if i == nil {
 		return NewNil()
 	}
@@ -508,18 +558,39 @@ func InterfaceToValue(i any) Value {
 	case []any:
 		arr := make([]Value, len(v))
 		for i, item := range v {
-			arr[i] = InterfaceToValue(item)
+			arr[i] = interfaceToValuePath(item, path)
 		}
 		return NewArray(arr)
 	case *[]Value:
 		// Already a pointer array, just wrap it
 		return Value{Type: VAL_ARRAY, Data: v}
 	case map[string]any:
+		id := containerID(v)
+		if done, ok := path[id]; ok {
+			return NewObject(done)
+		}
 		obj := make(map[string]Value)
+		if path != nil {
+			path[id] = obj
+			defer delete(path, id)
+		}
 		for k, val := range v {
-			obj[k] = InterfaceToValue(val)
+			switch val.(type) {
+			case map[string]any, []any:
+				if path == nil {
+					path = map[uintptr]map[string]Value{id: obj}
+				}
+			}
+			obj[k] = interfaceToValuePath(val, path)
 		}
+		CopyOrder(v, obj)
+		copyFrozen(v, obj)
 		return NewObject(obj)
+	case GoFunction:
+		return NewGoFunction(v)
+	case func(*Evaluator, map[string]any) (any, error):
+		// Unnamed func literal, e.g. a method in a returned map[string]any
+		return NewGoFunction(v)
 	default:
 		return NewNil()
 	}
@@ -541,6 +612,7 @@ func DeepCopy(v Value) Value {
 		for k, val := range obj {
 			newObj[k] = DeepCopy(val)
 		}
+		CopyOrder(obj, newObj)
 		return NewObject(newObj)
 	case VAL_CODE, VAL_ERROR, VAL_BINARY:
 		// Code, error, and binary values are immutable (binary is just a pointer)
@@ -574,6 +646,7 @@ func DeepCopyAny(val any) any {
 		for k, elem := range v {
 			newObj[k] = DeepCopyAny(elem)
 		}
+		CopyOrder(v, newObj)
 		return newObj
 	default:
 		return v
@@ -2,8 +2,8 @@ package script
 
 import (
 	"fmt"
-	"sort"
 	"strings"
+	"unicode"
 )
 
 // ValueForDisplay converts a value to a display string for print/output.
@@ -22,11 +22,13 @@ func ValueForDisplay(val Value) string {
 // This is used for tostring(), templates, and any place Duso syntax is needed.
 // The result is valid Duso syntax that can be parsed back with parse().
 func ValueToDusoString(val Value) string {
-	return valueToDusoStringInner(val, 0)
+	return valueToDusoStringInner(val, nil)
 }
 
-// valueToDusoStringInner is the recursive implementation with depth tracking.
-func valueToDusoStringInner(val Value, depth int) string {
+// valueToDusoStringInner is the recursive implementation. path holds the
+// arrays and objects being formatted around val, so one that contains itself
+// is cut off as [Circular]; it's nil until a nested array or object turns up.
+func valueToDusoStringInner(val Value, path map[uintptr]bool) string {
 	switch val.Type {
 	case VAL_NIL:
 		return "nil"
@@ -53,10 +55,15 @@ func valueToDusoStringInner(val Value, depth int) string {
 		if len(arr) == 0 {
 			return "[]"
 		}
+		path, id, circular := enterContainer(path, val)
+		if circular {
+			return "[Circular]"
+		}
 		var parts []string
 		for _, item := range arr {
-			parts = append(parts, valueToDusoStringInner(item, depth+1))
+			parts = append(parts, valueToDusoStringInner(item, path))
 		}
+		delete(path, id)
 		return "[" + strings.Join(parts, ", ") + "]"
 
 	case VAL_OBJECT:
@@ -64,17 +71,18 @@ func valueToDusoStringInner(val Value, depth int) string {
 		if len(obj) == 0 {
 			return "{}"
 		}
-
-		// Sort keys for consistent output
-		keys := make([]string, 0, len(obj))
-		for k := range obj {
-			keys = append(keys, k)
+		path, id, circular := enterContainer(path, val)
+		if circular {
+			return "[Circular]"
 		}
-		sort.Strings(keys)
+		defer delete(path, id)
+
+		// Sort keys for consistent output (ordered objects keep their order)
+		keys := SortedKeys(obj)
 
 		var parts []string
 		for _, k := range keys {
-			valStr := valueToDusoStringInner(obj[k], depth+1)
+			valStr := valueToDusoStringInner(obj[k], path)
 			parts = append(parts, fmt.Sprintf("%s=%s", k, valStr))
 		}
 		return "{" + strings.Join(parts, ", ") + "}"
@@ -88,7 +96,7 @@ func valueToDusoStringInner(val Value, depth int) string {
 	case VAL_ERROR:
 		errVal := val.AsErrorVal()
 		if errVal != nil {
-			msgStr := valueToDusoStringInner(errVal.Message, depth+1)
+			msgStr := valueToDusoStringInner(errVal.Message, path)
 			return fmt.Sprintf("error(%s)", msgStr)
 		}
 		return "error(<unknown>)"
@@ -113,6 +121,34 @@ func valueToDusoStringInner(val Value, depth int) string {
 	}
 }
 
+// enterContainer records the array or object val in path before its
+// elements are formatted, and reports whether it's already there. path is
+// only created when the container holds another array or object.
+func enterContainer(path map[uintptr]bool, val Value) (map[uintptr]bool, uintptr, bool) {
+	id := containerID(val.Data)
+	if path[id] {
+		return path, id, true
+	}
+	if path == nil {
+		nested := false
+		if val.IsArray() {
+			for _, item := range val.AsArray() {
+				nested = nested || item.IsArray() || item.IsObject()
+			}
+		} else {
+			for _, item := range val.AsObject() {
+				nested = nested || item.IsArray() || item.IsObject()
+			}
+		}
+		if !nested {
+			return nil, id, false
+		}
+		path = make(map[uintptr]bool)
+	}
+	path[id] = true
+	return path, id, false
+}
+
 // escapeString escapes special characters in a string for Duso syntax
 // Escapes newlines, tabs, and other control characters as literals (\n, \t, etc)
 func escapeString(s string) string {
@@ -123,3 +159,107 @@ func escapeString(s string) string {
 	s = strings.ReplaceAll(s, "\t", "\\t")
 	return s
 }
+
+// inspectInlineMax is the longest array or object InspectValue keeps on
+// one line
+const inspectInlineMax = 60
+
+// InspectValue renders a value for reading while debugging. Arrays and
+// objects are laid out one element per line, indented by nesting, unless
+// they fit on a short line. Strings are quoted and functions are shown with
+// their parameters. maxDepth limits how many levels are expanded (0 for no
+// limit); deeper arrays and objects show only their size. An array or object
+// found inside itself is shown as [Circular].
+func InspectValue(val Value, maxDepth int) string {
+	ins := &inspector{maxDepth: maxDepth, path: make(map[uintptr]bool)}
+	return ins.format(val, 0)
+}
+
+type inspector struct {
+	maxDepth int
+	path     map[uintptr]bool // arrays and objects being formatted, to spot cycles
+}
+
+func (ins *inspector) format(val Value, depth int) string {
+	switch val.Type {
+	case VAL_ARRAY, VAL_OBJECT:
+	case VAL_FUNCTION:
+		if fn, ok := val.Data.(*ScriptFunction); ok {
+			params := make([]string, len(fn.Parameters))
+			for i, param := range fn.Parameters {
+				params[i] = param.Name
+				if param.Variadic {
+					params[i] = "..." + param.Name
+				}
+			}
+			if fn.Name == "" {
+				return fmt.Sprintf("<function(%s)>", strings.Join(params, ", "))
+			}
+			return fmt.Sprintf("<function %s(%s)>", fn.Name, strings.Join(params, ", "))
+		}
+		return "<builtin function>"
+	case VAL_REGEX:
+		if re := val.AsRegex(); re != nil {
+			return "~" + re.Pattern + "~"
+		}
+		return "<regex>"
+	default:
+		return valueToDusoStringInner(val, nil)
+	}
+
+	id := containerID(val.Data)
+	if ins.path[id] {
+		return "[Circular]"
+	}
+
+	var items []string
+	open, close := "[", "]"
+	if val.IsArray() {
+		arr := val.AsArray()
+		if len(arr) == 0 {
+			return "[]"
+		}
+		if ins.maxDepth > 0 && depth >= ins.maxDepth {
+			return fmt.Sprintf("[... %d items]", len(arr))
+		}
+		ins.path[id] = true
+		for _, item := range arr {
+			items = append(items, ins.format(item, depth+1))
+		}
+	} else {
+		obj := val.AsObject()
+		if len(obj) == 0 {
+			return "{}"
+		}
+		if ins.maxDepth > 0 && depth >= ins.maxDepth {
+			return fmt.Sprintf("{... %d keys}", len(obj))
+		}
+		ins.path[id] = true
+		for _, k := range SortedKeys(obj) {
+			items = append(items, inspectKey(k)+" = "+ins.format(obj[k], depth+1))
+		}
+		open, close = "{", "}"
+	}
+	delete(ins.path, id)
+
+	// Strings are escaped, so a newline means a nested multi-line item
+	inline := open + strings.Join(items, ", ") + close
+	if len(inline) <= inspectInlineMax && !strings.Contains(inline, "\n") {
+		return inline
+	}
+	indent := strings.Repeat("  ", depth+1)
+	return open + "\n" + indent + strings.Join(items, ",\n"+indent) + "\n" + strings.Repeat("  ", depth) + close
+}
+
+// inspectKey quotes an object key unless it's a plain identifier
+func inspectKey(key string) string {
+	for i, r := range key {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return fmt.Sprintf("\"%s\"", escapeString(key))
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
@@ -94,7 +94,7 @@ Prism.languages.duso = {
   'keyword': [
     // Control flow keywords
     {
-      pattern: /\b(?:if|then|elseif|else|end|while|do|for|in|function|return|break|continue|try|catch|var|raw)\b/
+      pattern: /\b(?:if|then|elseif|else|end|while|do|for|in|function|return|break|continue|try|catch|var|const|raw)\b/
     },
     // Logical operators
     {